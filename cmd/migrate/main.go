@@ -0,0 +1,107 @@
+// Command migrate copies the module versions of the storage that one Athens
+// configuration file sets up to the storage that another one sets up, such
+// as when moving from mongo to S3 or from disk to GCS. Each end is
+// configured by the StorageType and the Storage section of its file.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	stdlog "log"
+	"net/http"
+	"os"
+	"os/signal"
+
+	"github.com/gomods/athens/cmd/proxy/actions"
+	"github.com/gomods/athens/internal/shutdown"
+	"github.com/gomods/athens/pkg/build"
+	"github.com/gomods/athens/pkg/config"
+	athenslog "github.com/gomods/athens/pkg/log"
+	"github.com/gomods/athens/pkg/migrate"
+	"github.com/gomods/athens/pkg/storage"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+)
+
+var (
+	from     = flag.String("from", "", "The path to the config file of the source storage")
+	to       = flag.String("to", "", "The path to the config file of the destination storage")
+	workers  = flag.Int("workers", 10, "The number of module versions copied at the same time")
+	pageSize = flag.Int("page_size", 1000, "The number of module versions read from the catalog of the source at a time")
+	token    = flag.String("token", "", "The catalog token to resume a migration from, as logged after every page")
+	dryRun   = flag.Bool("dry_run", false, "Report what would be copied without copying it")
+	version  = flag.Bool("version", false, "Print version information and exit")
+)
+
+func main() {
+	flag.Parse()
+	if *version {
+		fmt.Println(build.String())
+		os.Exit(0)
+	}
+	if *from == "" || *to == "" {
+		stdlog.Fatal("Both -from and -to config files are needed")
+	}
+	src, conf, err := getStorage(*from)
+	if err != nil {
+		stdlog.Fatalf("Could not get the source storage: %v", err)
+	}
+	dst, _, err := getStorage(*to)
+	if err != nil {
+		stdlog.Fatalf("Could not get the destination storage: %v", err)
+	}
+
+	logLvl, err := athenslog.ParseLevel(conf.LogLevel)
+	if err != nil {
+		stdlog.Fatalf("Could not parse log level %q: %v", conf.LogLevel, err)
+	}
+	logger := athenslog.New(conf.CloudRuntime, logLvl, conf.LogFormat)
+
+	// the pages that were finished are not copied again when
+	// resuming from the last token logged before a shutdown signal.
+	ctx, stop := signal.NotifyContext(context.Background(), shutdown.GetSignals()...)
+	defer stop()
+	report, err := migrate.Migrate(ctx, src, dst, migrate.Options{
+		Workers:  *workers,
+		PageSize: *pageSize,
+		Token:    *token,
+		DryRun:   *dryRun,
+		Progress: func(token string, r *migrate.Report) {
+			logger.WithFields(map[string]any{
+				"copied":  r.Copied,
+				"skipped": r.Skipped,
+				"failed":  len(r.Failed),
+				"token":   token,
+			}).Infof("Migrated a page of the catalog")
+		},
+	})
+	if err != nil {
+		logger.Fatalf("Could not migrate the storage: %v", err)
+	}
+	for _, f := range report.Failed {
+		logger.WithFields(map[string]any{"module": f.Module, "version": f.Version}).Errorf("Could not migrate: %s", f.Error)
+	}
+	logger.WithFields(map[string]any{
+		"copied":  report.Copied,
+		"skipped": report.Skipped,
+		"failed":  len(report.Failed),
+		"dry_run": *dryRun,
+	}).Infof("Migration done")
+	if len(report.Failed) > 0 {
+		os.Exit(1)
+	}
+}
+
+// getStorage returns the storage that the config file at path sets up.
+func getStorage(path string) (storage.Backend, *config.Config, error) {
+	conf, err := config.Load(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("loading config file %q: %w", path, err)
+	}
+	client := &http.Client{Transport: otelhttp.NewTransport(http.DefaultTransport)}
+	s, err := actions.GetStorage(conf.StorageType, conf.Storage, conf.TimeoutDuration(), client)
+	if err != nil {
+		return nil, nil, err
+	}
+	return s, conf, nil
+}
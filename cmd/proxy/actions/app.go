@@ -6,9 +6,9 @@ import (
 	"sync"
 
 	"github.com/gomods/athens/pkg/config"
+	"github.com/gomods/athens/pkg/download/mode"
 	"github.com/gomods/athens/pkg/log"
 	mw "github.com/gomods/athens/pkg/middleware"
-	"github.com/gomods/athens/pkg/module"
 	"github.com/gomods/athens/pkg/observ"
 	"github.com/gorilla/mux"
 	"github.com/unrolled/secure"
@@ -24,30 +24,19 @@ const Service = "proxy"
 //
 // App returns the HTTP handler, a cleanup function that should be called
 // when the server is shutting down (to flush and stop exporters), and an error.
-func App(logger *log.Logger, conf *config.Config) (http.Handler, func(), error) {
+// conf was loaded from configFile, which App loads again to reload the filter,
+// the download mode and the validator hook when they change.
+func App(logger *log.Logger, conf *config.Config, configFile string) (http.Handler, func(), error) {
 	noop := func() {}
-	if conf.GithubToken != "" {
-		if conf.NETRCPath != "" {
-			return nil, noop, fmt.Errorf("cannot provide both GithubToken and NETRCPath")
-		}
-
-		if err := netrcFromToken(conf.GithubToken); err != nil {
-			return nil, noop, fmt.Errorf("creating netrc from token: %w", err)
-		}
-	}
-
-	// mount .netrc to home dir
-	// to have access to private repos.
-	if err := initializeAuthFile(conf.NETRCPath); err != nil {
-		return nil, noop, fmt.Errorf("initializing auth file from netrc: %w", err)
-	}
-
-	// mount .hgrc to home dir
-	// to have access to private repos.
-	if err := initializeAuthFile(conf.HGRCPath); err != nil {
-		return nil, noop, fmt.Errorf("initializing auth file from hgrc: %w", err)
+	if err := initializeAuth(conf); err != nil {
+		return nil, noop, err
 	}
 
+	// handlers abort the responses they cut short, such as streamed zips,
+	// by hijacking and closing the connection or, when it cannot be hijacked,
+	// by panicking with http.ErrAbortHandler. Middlewares that wrap the
+	// response writer must implement Unwrap, and any that recovers panics
+	// must panic again with http.ErrAbortHandler.
 	r := mux.NewRouter()
 	r.Use(
 		mw.WithRequestID,
@@ -99,9 +88,13 @@ func App(logger *log.Logger, conf *config.Config) (http.Handler, func(), error)
 		cleanupStats = flushStats
 	}
 
+	stopQueue := noop
+	stopReload := noop
 	var once sync.Once
 	cleanup := func() {
 		once.Do(func() {
+			stopReload()
+			stopQueue()
 			cleanupTraces()
 			cleanupStats()
 		})
@@ -112,21 +105,33 @@ func App(logger *log.Logger, conf *config.Config) (http.Handler, func(), error)
 		r.Use(basicAuth(user, pass))
 	}
 
-	if !conf.FilterOff() {
-		mf, err := module.NewFilter(conf.FilterFile)
-		if err != nil {
-			return nil, cleanup, fmt.Errorf("creating new filter: %w", err)
-		}
-		r.Use(mw.NewFilterMiddleware(mf, conf.GlobalEndpoint))
+	// the filter, the download mode, the validator hook and the
+	// vulnerability database are swapped for new ones when they are
+	// reloaded, so their middlewares are in place even if unset.
+	filter, err := getFilter(conf)
+	if err != nil {
+		return nil, cleanup, err
 	}
+	r.Use(mw.NewFilterMiddleware(filter, conf.GlobalEndpoint))
 
 	client := &http.Client{
 		Transport: otelhttp.NewTransport(http.DefaultTransport),
 	}
 
-	// Having the hook set means we want to use it
-	if vHook := conf.ValidatorHook; vHook != "" {
-		r.Use(mw.NewValidationMiddleware(client, vHook))
+	// an empty hook turns validation off
+	hook := mw.NewValidatorHook(conf.ValidatorHook)
+	r.Use(mw.NewHookValidationMiddleware(client, hook))
+
+	// a database without advisories denies nothing
+	vulns, err := getVulnDB(conf)
+	if err != nil {
+		return nil, cleanup, err
+	}
+	r.Use(mw.NewVulnMiddleware(vulns))
+
+	df, err := mode.NewFile(conf.DownloadMode, conf.DownloadURL)
+	if err != nil {
+		return nil, cleanup, err
 	}
 
 	store, err := GetStorage(conf.StorageType, conf.Storage, conf.TimeoutDuration(), client)
@@ -138,9 +143,12 @@ func App(logger *log.Logger, conf *config.Config) (http.Handler, func(), error)
 	if subRouter != nil {
 		proxyRouter = subRouter
 	}
-	if err := addProxyRoutes(proxyRouter, store, logger, conf); err != nil {
+	stopRoutes, err := addProxyRoutes(proxyRouter, store, logger, conf, filter, df)
+	if err != nil {
 		return nil, cleanup, fmt.Errorf("adding proxy routes: %w", err)
 	}
+	stopQueue = stopRoutes
+	stopReload = runReloader(&reloader{logger: logger, configFile: configFile, conf: conf, filter: filter, df: df, hook: hook, vulns: vulns}, conf)
 
 	h := otelhttp.NewHandler(r, Service)
 
@@ -6,8 +6,11 @@ import (
 	"fmt"
 	"net/http"
 	"net/url"
+	"os"
 	"path"
+	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/gomods/athens/pkg/config"
 	"github.com/gomods/athens/pkg/download"
@@ -18,12 +21,26 @@ import (
 	"github.com/gomods/athens/pkg/index/mysql"
 	"github.com/gomods/athens/pkg/index/nop"
 	"github.com/gomods/athens/pkg/index/postgres"
+	"github.com/gomods/athens/pkg/listcache"
+	listcachemem "github.com/gomods/athens/pkg/listcache/mem"
+	listcacheredis "github.com/gomods/athens/pkg/listcache/redis"
 	"github.com/gomods/athens/pkg/log"
+	mw "github.com/gomods/athens/pkg/middleware"
 	"github.com/gomods/athens/pkg/module"
+	"github.com/gomods/athens/pkg/notfound"
+	notfoundmem "github.com/gomods/athens/pkg/notfound/mem"
+	notfoundredis "github.com/gomods/athens/pkg/notfound/redis"
+	"github.com/gomods/athens/pkg/queue"
+	"github.com/gomods/athens/pkg/queue/bolt"
+	queuemem "github.com/gomods/athens/pkg/queue/mem"
+	"github.com/gomods/athens/pkg/queue/redis"
 	"github.com/gomods/athens/pkg/stash"
 	"github.com/gomods/athens/pkg/storage"
+	"github.com/gomods/athens/pkg/vuln"
+	"github.com/gomods/athens/pkg/warm"
 	"github.com/gorilla/mux"
 	"github.com/spf13/afero"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
 )
 
 func addProxyRoutes(
@@ -31,27 +48,28 @@ func addProxyRoutes(
 	s storage.Backend,
 	l *log.Logger,
 	c *config.Config,
-) error {
+	filter *module.Filter,
+	df *mode.DownloadFile,
+) (func(), error) {
 	r.HandleFunc("/", proxyHomeHandler(c))
 	r.HandleFunc("/healthz", healthHandler)
-	r.HandleFunc("/readyz", getReadinessHandler(s))
 	r.HandleFunc("/version", versionHandler)
 	r.HandleFunc("/catalog", catalogHandler(s))
 	r.HandleFunc("/robots.txt", robotsHandler(c))
 
 	indexer, err := getIndex(c)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	r.HandleFunc("/index", indexHandler(indexer))
 
 	for _, sumdb := range c.SumDBs {
 		sumdbURL, err := url.Parse(sumdb)
 		if err != nil {
-			return err
+			return nil, err
 		}
 		if sumdbURL.Scheme != "https" {
-			return fmt.Errorf("sumdb: %v must have an https scheme", sumdb)
+			return nil, fmt.Errorf("sumdb: %v must have an https scheme", sumdb)
 		}
 		supportPath := path.Join("/sumdb", sumdbURL.Host, "/supported")
 		r.HandleFunc(supportPath, func(w http.ResponseWriter, r *http.Request) {
@@ -86,38 +104,123 @@ func addProxyRoutes(
 	// 2. The singleflight passes the stash to its parent: stashpool.
 	// 3. The stashpool manages limiting concurrent requests and passes them to stash.
 	// 4. The plain stash.New just takes a request from upstream and saves it into storage.
-	fs := afero.NewOsFs()
-
-	if !c.GoBinaryEnvVars.HasKey("GONOSUMDB") {
-		c.GoBinaryEnvVars.Add("GONOSUMDB", strings.Join(c.NoSumPatterns, ","))
-	}
-	if err := c.GoBinaryEnvVars.Validate(); err != nil {
-		return err
+	checker := storage.WithChecker(s)
+	dpOpts := &download.Opts{
+		Storage:      s,
+		DownloadFile: df,
+		NetworkMode:  c.NetworkMode,
 	}
-	mf, err := module.NewGoGetFetcher(c.GoBinary, c.GoGetDir, c.GoBinaryEnvVars, fs)
+
+	admin := r.PathPrefix("/admin").Subrouter()
+	admin.Use(adminAuth(c))
+	admin.HandleFunc("/filter", filterHandler(filter)).Methods(http.MethodGet)
+
+	nfc, err := getNotFoundCache(c)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	if nfc != nil {
+		admin.HandleFunc("/notfound", notFoundHandler(nfc)).Methods(http.MethodDelete)
 	}
 
-	lister := module.NewVCSLister(c.GoBinary, c.GoBinaryEnvVars, fs, c.TimeoutDuration())
-	checker := storage.WithChecker(s)
-	withSingleFlight, err := getSingleFlight(l, c, s, checker)
-	if err != nil {
-		return err
+	var st stash.Stasher
+	stopReplication := func() {}
+	// without a fetcher, frontends find the times of quarantined versions in storage only.
+	var qf module.ModFetcher
+	if c.FrontendOnly {
+		// frontends never reach upstream: the workers stash the versions
+		// they queue, and the versions they list come from storage.
+		if c.QueueType != "redis" {
+			return nil, fmt.Errorf("FrontendOnly needs a queue shared with the workers, such as redis, and not: %q", c.QueueType)
+		}
+		if c.LazyZipFetch {
+			return nil, errors.New("LazyZipFetch is not supported by frontends")
+		}
+		dpOpts.NetworkMode = download.Offline
+		r.HandleFunc("/readyz", getReadinessHandler(s, nil))
+	} else {
+		mf, lister, err := getUpstream(c, df)
+		if err != nil {
+			return nil, err
+		}
+		reporter, _ := mf.(module.HealthReporter)
+		r.HandleFunc("/readyz", getReadinessHandler(s, reporter))
+		if nfc != nil {
+			ttl := time.Duration(c.NotFoundCache.TTL) * time.Second
+			mf, lister = notfound.NewFetcher(mf, nfc, ttl), notfound.NewLister(lister, nfc, ttl)
+		}
+		lister, err = getListCache(c, lister, df)
+		if err != nil {
+			return nil, err
+		}
+
+		withSingleFlight, err := getSingleFlight(l, c, s, checker)
+		if err != nil {
+			return nil, err
+		}
+		// zips are spooled to GoGetDir while they are stashed, so that
+		// the clients waiting for them can be served at the same time.
+		zipStreams := stash.NewZipStreams(c.GoGetDir)
+		withPrefetch := getPrefetch(c, s, filter, df)
+		withReplication, stop, err := getReplication(l, c, s, &http.Client{Transport: otelhttp.NewTransport(http.DefaultTransport)}, withSingleFlight)
+		if err != nil {
+			return nil, err
+		}
+		stopReplication = stop
+		st = stash.New(zipStreams.Fetcher(mf), s, indexer, c.StashTimeoutDuration(), withReplication, stash.WithTimeouts(df), stash.WithPool(c.GoGetWorkers), withSingleFlight, withPrefetch)
+		dpOpts.Stasher, dpOpts.Lister, dpOpts.ZipStreams = st, lister, zipStreams
+		if f, ok := mf.(module.ModFetcher); ok {
+			qf = stash.WantedMods(f, filter, df)
+		}
+		if c.LazyZipFetch {
+			ms, ok := stash.NewModStasher(mf, s, c.StashTimeoutDuration())
+			if !ok {
+				return nil, fmt.Errorf("LazyZipFetch is not supported by the %q storage type", c.StorageType)
+			}
+			dpOpts.ModStasher = stash.ModWithTimeouts(ms, df)
+		}
 	}
-	st := stash.New(mf, s, indexer, c.StashTimeoutDuration(), stash.WithPool(c.GoGetWorkers), withSingleFlight)
 
-	df, err := mode.NewFile(c.DownloadMode, c.DownloadURL)
+	quarantine := module.NewQuarantineChecker(filter, s, qf)
+	dpOpts.Quarantine = quarantine
+	r.Use(mw.NewQuarantineMiddleware(quarantine))
+
+	// the async download modes leave stashing to the queue's workers,
+	// which retry the stashes that fail.
+	q, err := getQueue(c)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	dpOpts.Queue = q
+	admin.HandleFunc("/jobs", jobsHandler(q, checker))
+	stopWorkers := func() {}
+	if c.FrontendOnly {
+		dpOpts.Stasher = stash.WithTimeouts(df)(stash.NewQueueStasher(q, c.StashTimeoutDuration()))
+	} else {
+		stopWorkers = runWorkers(l, c, q, st)
+	}
+	admin.HandleFunc("/warm", warmHandler(warm.New(stash.WithWanted(filter, df)(dpOpts.Stasher), s, c.GoGetWorkers))).Methods(http.MethodPost)
+	stop := func() {
+		stopWorkers()
+		stopReplication()
+		if err := q.Close(); err != nil {
+			l.Errorf("closing the download queue: %v", err)
+		}
 	}
 
-	dpOpts := &download.Opts{
-		Storage:      s,
-		Stasher:      st,
-		Lister:       lister,
-		DownloadFile: df,
-		NetworkMode:  c.NetworkMode,
+	if c.VulnDBMirror != nil && c.VulnDBMirror.Enabled {
+		m := vuln.NewMirror(s)
+		r.PathPrefix("/vulndb/").Handler(
+			http.StripPrefix(strings.TrimSuffix(c.PathPrefix, "/")+"/vulndb", vulnDBHandler(m)),
+		)
+		admin.HandleFunc("/vulndb", vulnDBImportHandler(m)).Methods(http.MethodPost)
+		client := &http.Client{Transport: otelhttp.NewTransport(http.DefaultTransport)}
+		stopMirror := runVulnDBMirror(l, c, m, client)
+		stopQueue := stop
+		stop = func() {
+			stopMirror()
+			stopQueue()
+		}
 	}
 
 	dp := download.New(dpOpts, addons.WithPool(c.ProtocolWorkers))
@@ -125,7 +228,7 @@ func addProxyRoutes(
 	handlerOpts := &download.HandlerOpts{Protocol: dp, Logger: l, DownloadFile: df}
 	download.RegisterHandlers(r, handlerOpts)
 
-	return nil
+	return stop, nil
 }
 
 // athensLoggerForRedis implements pkg/stash.RedisLogger.
@@ -188,6 +291,173 @@ func getSingleFlight(l *log.Logger, c *config.Config, s storage.Backend, checker
 	}
 }
 
+// getUpstream returns the fetcher and lister of the modules
+// that are not in storage yet.
+func getUpstream(c *config.Config, df *mode.DownloadFile) (module.Fetcher, module.UpstreamLister, error) {
+	if !c.GoBinaryEnvVars.HasKey("GONOSUMDB") {
+		c.GoBinaryEnvVars.Add("GONOSUMDB", strings.Join(c.NoSumPatterns, ","))
+	}
+	if err := c.GoBinaryEnvVars.Validate(); err != nil {
+		return nil, nil, err
+	}
+	return getFetcher(c, afero.NewOsFs(), df)
+}
+
+// runWorkers stashes the jobs of q with st in the background,
+// until the returned function is called.
+func runWorkers(l *log.Logger, c *config.Config, q queue.Queue, st stash.Stasher) func() {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		queue.Process(ctx, q, c.Queue.Workers, queueRetryPolicy(c.Queue), l, st.Stash)
+	}()
+	return func() {
+		cancel()
+		<-done
+	}
+}
+
+// getNotFoundCache returns the cache of what upstream does not have,
+// or nil if NotFoundCacheType is none.
+func getNotFoundCache(c *config.Config) (notfound.Cache, error) {
+	switch c.NotFoundCacheType {
+	case "", "none":
+		return nil, nil
+	case "memory":
+		return notfoundmem.New(), nil
+	case "redis":
+		return notfoundredis.New(c.NotFoundCache.Redis.Endpoint, c.NotFoundCache.Redis.Password, c.NotFoundCache.Redis.Cluster)
+	}
+	return nil, fmt.Errorf("unknown not found cache type: %q", c.NotFoundCacheType)
+}
+
+// getListCache returns l, caching what it lists if ListCacheType is set.
+func getListCache(c *config.Config, l module.UpstreamLister, df *mode.DownloadFile) (module.UpstreamLister, error) {
+	var cache listcache.Cache
+	switch c.ListCacheType {
+	case "", "none":
+		return l, nil
+	case "memory":
+		cache = listcachemem.New()
+	case "redis":
+		var err error
+		cache, err = listcacheredis.New(c.ListCache.Redis.Endpoint, c.ListCache.Redis.Password, c.ListCache.Redis.Cluster)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unknown list cache type: %q", c.ListCacheType)
+	}
+	ttl := time.Duration(c.ListCache.TTL) * time.Second
+	return listcache.NewLister(l, cache, listcache.Options{
+		TTL:      func(mod string) time.Duration { return df.ListTTL(mod, ttl) },
+		StaleTTL: time.Duration(c.ListCache.StaleTTL) * time.Second,
+		ServeStaleOnError: func(mod string) bool {
+			return df.NetworkMode(mod, c.NetworkMode) == download.Fallback
+		},
+	}), nil
+}
+
+// getPrefetch returns the wrapper that prefetches the requirements
+// of the module versions that are stashed, if PrefetchDepth is set.
+func getPrefetch(c *config.Config, s storage.Backend, filter *module.Filter, df *mode.DownloadFile) stash.Wrapper {
+	if c.PrefetchDepth == 0 {
+		return func(st stash.Stasher) stash.Stasher { return st }
+	}
+	return stash.WithPrefetch(c.PrefetchDepth, c.GoGetWorkers, s, filter, df)
+}
+
+// getFilter returns the filter of FilterFile, or a filter that
+// includes every module if there is none, so that it can be
+// reloaded with the filter of a FilterFile that is set later.
+func getFilter(c *config.Config) (*module.Filter, error) {
+	if c.FilterOff() {
+		return &module.Filter{}, nil
+	}
+	filter, err := module.NewFilter(c.FilterFile)
+	if err != nil {
+		return nil, fmt.Errorf("creating new filter: %w", err)
+	}
+	return filter, nil
+}
+
+func getFetcher(c *config.Config, fs afero.Fs, df *mode.DownloadFile) (module.Fetcher, module.UpstreamLister, error) {
+	switch c.FetcherType {
+	case "", "gobinary":
+		return getGoBinaryFetcher(c, fs, df)
+	case "goproxy":
+		specs, err := module.ParseUpstreamList(c.UpstreamProxy)
+		if err != nil {
+			return nil, nil, err
+		}
+		var direct module.Upstream
+		client := &http.Client{
+			Transport: otelhttp.NewTransport(http.DefaultTransport),
+		}
+		upstreams := make([]module.Upstream, 0, len(specs))
+		for _, spec := range specs {
+			u := module.Upstream{Name: spec.URL, FallThroughOnError: spec.FallThroughOnError}
+			if spec.URL == "direct" {
+				if direct.Fetcher == nil {
+					direct.Fetcher, direct.Lister, err = getGoBinaryFetcher(c, fs, df)
+					if err != nil {
+						return nil, nil, err
+					}
+				}
+				u.Fetcher, u.Lister = direct.Fetcher, direct.Lister
+			} else {
+				u.Fetcher, err = module.NewGoProxyFetcher(spec.URL, client)
+				if err != nil {
+					return nil, nil, err
+				}
+				u.Lister, err = module.NewGoProxyLister(spec.URL, client, c.TimeoutDuration(), df)
+				if err != nil {
+					return nil, nil, err
+				}
+			}
+			upstreams = append(upstreams, u)
+		}
+		chain, err := module.NewUpstreamChain(upstreams, c.UpstreamMaxFailures, c.UpstreamCooldownDuration())
+		if err != nil {
+			return nil, nil, err
+		}
+		return chain, chain, nil
+	case "git":
+		resolver := module.NewRepoResolver(&http.Client{
+			Transport: otelhttp.NewTransport(http.DefaultTransport),
+			Timeout:   c.TimeoutDuration(),
+		})
+		dir := filepath.Join(c.GoGetDir, "athens-git")
+		if c.GoGetDir == "" {
+			dir = filepath.Join(os.TempDir(), "athens-git")
+		}
+		maxSize := int64(c.GoCacheMaxSizeMB) << 20
+		mf, err := module.NewGitFetcher(dir, maxSize, resolver)
+		if err != nil {
+			return nil, nil, err
+		}
+		return mf, module.NewGitLister(dir, maxSize, resolver, c.TimeoutDuration(), df), nil
+	}
+	return nil, nil, fmt.Errorf("unknown fetcher type: %q", c.FetcherType)
+}
+
+func getGoBinaryFetcher(c *config.Config, fs afero.Fs, df *mode.DownloadFile) (module.Fetcher, module.UpstreamLister, error) {
+	var cache *module.GoCache
+	if c.GoCacheDir != "" {
+		var err error
+		cache, err = module.NewGoCache(fs, c.GoCacheDir, int64(c.GoCacheMaxSizeMB)<<20)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	mf, err := module.NewGoGetFetcher(c.GoBinary, c.GoGetDir, c.GoBinaryEnvVars, fs, cache, df)
+	if err != nil {
+		return nil, nil, err
+	}
+	return mf, module.NewVCSLister(c.GoBinary, c.GoBinaryEnvVars, fs, c.TimeoutDuration(), cache, df), nil
+}
+
 func getIndex(c *config.Config) (index.Indexer, error) {
 	switch c.IndexType {
 	case "", "none":
@@ -201,3 +471,44 @@ func getIndex(c *config.Config) (index.Indexer, error) {
 	}
 	return nil, fmt.Errorf("unknown index type: %q", c.IndexType)
 }
+
+func getQueue(c *config.Config) (queue.Queue, error) {
+	switch c.QueueType {
+	case "", "memory":
+		return queuemem.New(), nil
+	case "bolt":
+		return bolt.New(c.Queue.Bolt.Path)
+	case "redis":
+		return redis.New(c.Queue.Redis.Endpoint, c.Queue.Redis.Password, c.Queue.Redis.Cluster, c.StashTimeoutDuration())
+	}
+	return nil, fmt.Errorf("unknown queue type: %q", c.QueueType)
+}
+
+func queueRetryPolicy(c *config.Queue) queue.RetryPolicy {
+	return queue.RetryPolicy{
+		MaxAttempts:      c.MaxAttempts,
+		MinBackoff:       time.Duration(c.MinBackoff) * time.Second,
+		MaxBackoff:       time.Duration(c.MaxBackoff) * time.Second,
+		RateLimitBackoff: time.Duration(c.RateLimitBackoff) * time.Second,
+	}
+}
+
+// getVulnDB returns the vulnerability database of VulnDB, or one without
+// advisories if there is none, so that it can be reloaded with the
+// database of a path that is set later.
+func getVulnDB(c *config.Config) (*vuln.DB, error) {
+	if c.VulnDB == nil || c.VulnDB.Path == "" {
+		return &vuln.DB{}, nil
+	}
+	db, err := vuln.Open(c.VulnDB.Path, vuln.Policy{
+		Action:      c.VulnDB.Action,
+		MinSeverity: c.VulnDB.MinSeverity,
+		Ignore:      c.VulnDB.Ignore,
+		Exceptions:  c.VulnDB.Exceptions,
+		FilterList:  c.VulnDB.FilterList,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("opening the vulnerability database: %w", err)
+	}
+	return db, nil
+}
@@ -11,7 +11,9 @@ import (
 
 	"github.com/gomods/athens/pkg/build"
 	"github.com/gomods/athens/pkg/config"
+	"github.com/gomods/athens/pkg/download/mode"
 	"github.com/gomods/athens/pkg/log"
+	"github.com/gomods/athens/pkg/module"
 	"github.com/gomods/athens/pkg/storage/mem"
 	"github.com/gorilla/mux"
 	"github.com/stretchr/testify/assert"
@@ -35,8 +37,11 @@ func TestProxyRoutes(t *testing.T) {
 	c.NoSumPatterns = []string{"*"} // catch all patterns with noSumWrapper to ensure the sumdb handler doesn't make a real http request to the sumdb server.
 	c.PathPrefix = "/prefix"
 	subRouter := r.PathPrefix(c.PathPrefix).Subrouter()
-	err = addProxyRoutes(subRouter, s, l, c)
+	df, err := mode.NewFile(c.DownloadMode, c.DownloadURL)
 	require.NoError(t, err)
+	stop, err := addProxyRoutes(subRouter, s, l, c, &module.Filter{}, df)
+	require.NoError(t, err)
+	defer stop()
 
 	baseURL := "https://athens.azurefd.net" + c.PathPrefix
 
@@ -110,3 +115,18 @@ func TestProxyRoutes(t *testing.T) {
 		})
 	}
 }
+
+func TestFrontendOnlyNeedsSharedQueue(t *testing.T) {
+	s, err := mem.NewStorage()
+	require.NoError(t, err)
+	c, err := config.Load("")
+	require.NoError(t, err)
+	c.FrontendOnly = true
+	df, err := mode.NewFile(c.DownloadMode, c.DownloadURL)
+	require.NoError(t, err)
+	for _, queueType := range []string{"memory", "bolt"} {
+		c.QueueType = queueType
+		_, err = addProxyRoutes(mux.NewRouter(), s, log.NoOpLogger(), c, &module.Filter{}, df)
+		require.ErrorContains(t, err, "shared with the workers")
+	}
+}
@@ -14,7 +14,7 @@ func TestAppReturnsCleanup(t *testing.T) {
 	c, err := config.Load("")
 	require.NoError(t, err)
 
-	handler, cleanup, err := App(l, c)
+	handler, cleanup, err := App(l, c, "")
 	require.NoError(t, err)
 	assert.NotNil(t, handler)
 	assert.NotNil(t, cleanup)
@@ -32,7 +32,7 @@ func TestAppReturnsCleanupWithExporters(t *testing.T) {
 	c.TraceExporter = "otlp"
 	c.StatsExporter = "prometheus"
 
-	handler, cleanup, err := App(l, c)
+	handler, cleanup, err := App(l, c, "")
 	require.NoError(t, err)
 	assert.NotNil(t, handler)
 	assert.NotNil(t, cleanup)
@@ -7,9 +7,38 @@ import (
 	"runtime"
 	"strings"
 
+	"github.com/gomods/athens/pkg/config"
 	"github.com/mitchellh/go-homedir"
 )
 
+// initializeAuth sets up the credentials that the go command
+// needs to access private repos, from conf.
+func initializeAuth(conf *config.Config) error {
+	if conf.GithubToken != "" {
+		if conf.NETRCPath != "" {
+			return fmt.Errorf("cannot provide both GithubToken and NETRCPath")
+		}
+
+		if err := netrcFromToken(conf.GithubToken); err != nil {
+			return fmt.Errorf("creating netrc from token: %w", err)
+		}
+	}
+
+	// mount .netrc to home dir
+	// to have access to private repos.
+	if err := initializeAuthFile(conf.NETRCPath); err != nil {
+		return fmt.Errorf("initializing auth file from netrc: %w", err)
+	}
+
+	// mount .hgrc to home dir
+	// to have access to private repos.
+	if err := initializeAuthFile(conf.HGRCPath); err != nil {
+		return fmt.Errorf("initializing auth file from hgrc: %w", err)
+	}
+
+	return nil
+}
+
 // initializeAuthFile checks if provided auth file is at a pre-configured path
 // and moves to home directory -- note that this will override whatever
 // .netrc/.hgrc file you have in your home directory.
@@ -5,11 +5,13 @@ import (
 	"net/http"
 	"regexp"
 
+	"github.com/gomods/athens/pkg/config"
 	"github.com/gorilla/mux"
 )
 
 // basicAuthExcludedPaths is a regular expression that matches paths that should not be protected by HTTP basic authentication.
-var basicAuthExcludedPaths = regexp.MustCompile("^/(health|ready)z$")
+// The /admin endpoints have credentials of their own, see adminAuth.
+var basicAuthExcludedPaths = regexp.MustCompile("^/((health|ready)z|admin/.*)$")
 
 func basicAuth(user, pass string) mux.MiddlewareFunc {
 	return func(h http.Handler) http.Handler {
@@ -26,6 +28,27 @@ func basicAuth(user, pass string) mux.MiddlewareFunc {
 	}
 }
 
+// adminAuth protects the /admin endpoints with the AdminUser and AdminPass
+// of c, and disables them if either is unset, since they change what
+// Athens serves or make it fetch from upstream.
+func adminAuth(c *config.Config) mux.MiddlewareFunc {
+	user, pass, ok := c.AdminAuth()
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !ok {
+				http.Error(w, "the admin endpoints are disabled: set AdminUser and AdminPass to enable them", http.StatusForbidden)
+				return
+			}
+			if !checkAuth(r, user, pass) {
+				w.Header().Set("WWW-Authenticate", `Basic realm="admin auth required"`)
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			h.ServeHTTP(w, r)
+		})
+	}
+}
+
 func checkAuth(r *http.Request, user, pass string) bool {
 	givenUser, givenPass, ok := r.BasicAuth()
 	if !ok {
@@ -8,6 +8,7 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/gomods/athens/pkg/config"
 	"github.com/gomods/athens/pkg/log"
 )
 
@@ -88,3 +89,30 @@ func TestBasicAuth(t *testing.T) {
 func mockHandler(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(200)
 }
+
+func TestAdminAuth(t *testing.T) {
+	tests := []struct {
+		name       string
+		conf       *config.Config
+		user, pass string
+		status     int
+	}{
+		{name: "disabled", conf: &config.Config{}, user: "admin", pass: "secret", status: http.StatusForbidden},
+		{name: "no credentials", conf: &config.Config{AdminUser: "admin", AdminPass: "secret"}, status: http.StatusUnauthorized},
+		{name: "wrong password", conf: &config.Config{AdminUser: "admin", AdminPass: "secret"}, user: "admin", pass: "wrong", status: http.StatusUnauthorized},
+		{name: "happy path", conf: &config.Config{AdminUser: "admin", AdminPass: "secret"}, user: "admin", pass: "secret", status: http.StatusOK},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, "/admin/jobs", nil)
+			if tc.user != "" {
+				r.SetBasicAuth(tc.user, tc.pass)
+			}
+			adminAuth(tc.conf)(http.HandlerFunc(mockHandler)).ServeHTTP(w, r)
+			if w.Code != tc.status {
+				t.Fatalf("expected http status to be %v but got %v", tc.status, w.Code)
+			}
+		})
+	}
+}
@@ -3,6 +3,7 @@ package actions
 import (
 	"encoding/json"
 	"net/http"
+	"slices"
 	"strconv"
 
 	"github.com/gomods/athens/pkg/errors"
@@ -47,6 +48,10 @@ func catalogHandler(s storage.Backend) http.HandlerFunc {
 			return
 		}
 
+		// the modules that Athens keeps for its own use are not served.
+		modulesAndVersions = slices.DeleteFunc(modulesAndVersions, func(p paths.AllPathParams) bool {
+			return storage.IsInternal(p.Module)
+		})
 		res := catalogRes{modulesAndVersions, newToken}
 		if err = json.NewEncoder(w).Encode(res); err != nil {
 			lggr.SystemErr(errors.E(op, err))
@@ -0,0 +1,53 @@
+package actions
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/gomods/athens/pkg/errors"
+	"github.com/gomods/athens/pkg/log"
+	"github.com/gomods/athens/pkg/module"
+)
+
+// filterMatch is the response of the filter handler.
+type filterMatch struct {
+	Module  string `json:"module"`
+	Version string `json:"version,omitempty"`
+	Rule    string `json:"rule"`
+	Line    int    `json:"line,omitempty"`
+	Text    string `json:"text,omitempty"`
+	// QuarantineDays is how many days old a version must be, for the quarantine rule.
+	QuarantineDays int `json:"quarantineDays,omitempty"`
+}
+
+// filterHandler implements GET baseURL/admin/filter?module=&version=,
+// which reports the filter rule applied to a module version, and the
+// line of the filter file that it comes from.
+func filterHandler(f *module.Filter) http.HandlerFunc {
+	const op errors.Op = "actions.filterHandler"
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		mod, ver := r.FormValue("module"), r.FormValue("version")
+		if mod == "" {
+			err := errors.E(op, "module is required", errors.KindBadRequest, slog.LevelInfo)
+			log.EntryFromContext(ctx).SystemErr(err)
+			http.Error(w, err.Error(), errors.Kind(err))
+			return
+		}
+		m := f.Match(mod, ver)
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		res := filterMatch{
+			Module:         mod,
+			Version:        ver,
+			Rule:           m.Rule.String(),
+			Line:           m.Line,
+			Text:           m.Text,
+			QuarantineDays: int(m.Quarantine / (24 * time.Hour)),
+		}
+		if err := json.NewEncoder(w).Encode(res); err != nil {
+			log.EntryFromContext(ctx).SystemErr(err)
+		}
+	}
+}
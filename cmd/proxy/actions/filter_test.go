@@ -0,0 +1,45 @@
+package actions
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gomods/athens/pkg/module"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFilterHandler(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "filter")
+	require.NoError(t, os.WriteFile(file, []byte("-\n+ github.com/*/public >=v1.2.0\n"), 0o600))
+	f, err := module.NewFilter(file)
+	require.NoError(t, err)
+	h := filterHandler(f)
+
+	w := httptest.NewRecorder()
+	h(w, httptest.NewRequest(http.MethodGet, "/admin/filter?module=github.com/a/public&version=v1.3.0", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	var res filterMatch
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&res))
+	require.Equal(t, filterMatch{
+		Module:  "github.com/a/public",
+		Version: "v1.3.0",
+		Rule:    "include",
+		Line:    2,
+		Text:    "+ github.com/*/public >=v1.2.0",
+	}, res)
+
+	w = httptest.NewRecorder()
+	h(w, httptest.NewRequest(http.MethodGet, "/admin/filter?module=github.com/a/public&version=v1.1.0", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&res))
+	require.Equal(t, "exclude", res.Rule)
+	require.Equal(t, 1, res.Line)
+
+	w = httptest.NewRecorder()
+	h(w, httptest.NewRequest(http.MethodGet, "/admin/filter", nil))
+	require.Equal(t, http.StatusBadRequest, w.Code)
+}
@@ -0,0 +1,55 @@
+package actions
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+
+	"github.com/gomods/athens/pkg/errors"
+	"github.com/gomods/athens/pkg/log"
+	"github.com/gomods/athens/pkg/queue"
+	"github.com/gomods/athens/pkg/storage"
+)
+
+// jobsHandler implements GET baseURL/admin/jobs?module=&version=,
+// which reports the state of the background stash of a module version.
+func jobsHandler(q queue.Queue, checker storage.Checker) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		job, err := getJob(r, q, checker)
+		if err != nil {
+			log.EntryFromContext(ctx).SystemErr(err)
+			http.Error(w, err.Error(), errors.Kind(err))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		if err := json.NewEncoder(w).Encode(job); err != nil {
+			log.EntryFromContext(ctx).SystemErr(err)
+		}
+	}
+}
+
+func getJob(r *http.Request, q queue.Queue, checker storage.Checker) (*queue.Job, error) {
+	const op errors.Op = "actions.jobsHandler"
+	mod, ver := r.FormValue("module"), r.FormValue("version")
+	if mod == "" || ver == "" {
+		return nil, errors.E(op, "module and version are required", errors.KindBadRequest, slog.LevelInfo)
+	}
+	job, err := q.Get(r.Context(), mod, ver)
+	if err == nil {
+		return job, nil
+	}
+	if !errors.IsNotFoundErr(err) {
+		return nil, errors.E(op, err)
+	}
+	// done jobs are removed from the queue.
+	exists, err := checker.Exists(r.Context(), mod, ver)
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+	if !exists {
+		return nil, errors.E(op, errors.M(mod), errors.V(ver), "no stash job", errors.KindNotFound, slog.LevelInfo)
+	}
+	return &queue.Job{Module: mod, Version: ver, State: queue.StateDone}, nil
+}
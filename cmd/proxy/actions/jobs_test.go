@@ -0,0 +1,71 @@
+package actions
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gomods/athens/pkg/queue"
+	"github.com/gomods/athens/pkg/queue/mem"
+	"github.com/gomods/athens/pkg/storage"
+	storagemem "github.com/gomods/athens/pkg/storage/mem"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJobsHandler(t *testing.T) {
+	ctx := t.Context()
+	q := mem.New()
+	s, err := storagemem.NewStorage()
+	require.NoError(t, err)
+	h := jobsHandler(q, storage.WithChecker(s))
+
+	require.NoError(t, q.Enqueue(ctx, "pending", "v1.0.0"))
+	require.NoError(t, q.Enqueue(ctx, "dead", "v1.0.0"))
+	for range 2 {
+		job, err := q.Dequeue(ctx)
+		require.NoError(t, err)
+		if job.Module == "dead" {
+			job.Attempts = 5
+			job.LastError = "upstream is down"
+			require.NoError(t, q.Bury(ctx, job))
+			continue
+		}
+		job.NextAttempt = time.Now().Add(time.Hour)
+		require.NoError(t, q.Retry(ctx, job))
+	}
+	require.NoError(t, s.Save(ctx, "done", "v1.0.0", []byte("mod"), strings.NewReader("zip"), nil, []byte("info")))
+
+	tests := []struct {
+		name  string
+		query string
+		code  int
+		state queue.State
+	}{
+		{name: "pending", query: "module=pending&version=v1.0.0", code: http.StatusOK, state: queue.StatePending},
+		{name: "dead", query: "module=dead&version=v1.0.0", code: http.StatusOK, state: queue.StateDead},
+		{name: "done", query: "module=done&version=v1.0.0", code: http.StatusOK, state: queue.StateDone},
+		{name: "unknown", query: "module=unknown&version=v1.0.0", code: http.StatusNotFound},
+		{name: "no version", query: "module=pending", code: http.StatusBadRequest},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			h(w, httptest.NewRequest(http.MethodGet, "/admin/jobs?"+tc.query, nil))
+			require.Equal(t, tc.code, w.Code)
+			if tc.code != http.StatusOK {
+				return
+			}
+			var job queue.Job
+			require.NoError(t, json.NewDecoder(w.Body).Decode(&job))
+			require.Equal(t, tc.state, job.State)
+			if tc.state == queue.StateDead {
+				require.Equal(t, 5, job.Attempts)
+				require.Equal(t, "upstream is down", job.LastError)
+				require.False(t, job.EnqueuedAt.IsZero())
+			}
+		})
+	}
+}
@@ -0,0 +1,34 @@
+package actions
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/gomods/athens/pkg/errors"
+	"github.com/gomods/athens/pkg/log"
+	"github.com/gomods/athens/pkg/notfound"
+)
+
+// notFoundHandler implements DELETE baseURL/admin/notfound?module=&version=,
+// which purges what is cached as not found upstream for a module version,
+// or for a module and all of its versions if version is empty.
+func notFoundHandler(c notfound.Cache) http.HandlerFunc {
+	const op errors.Op = "actions.notFoundHandler"
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		mod, ver := r.FormValue("module"), r.FormValue("version")
+		if mod == "" {
+			err := errors.E(op, "module is required", errors.KindBadRequest, slog.LevelInfo)
+			log.EntryFromContext(ctx).SystemErr(err)
+			http.Error(w, err.Error(), errors.Kind(err))
+			return
+		}
+		if err := c.Purge(ctx, mod, ver); err != nil {
+			err = errors.E(op, err)
+			log.EntryFromContext(ctx).SystemErr(err)
+			http.Error(w, err.Error(), errors.Kind(err))
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,40 @@
+package actions
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gomods/athens/pkg/notfound/mem"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNotFoundHandler(t *testing.T) {
+	ctx := t.Context()
+	c := mem.New()
+	h := notFoundHandler(c)
+	require.NoError(t, c.Set(ctx, "mod", "v1.0.0", "unknown revision", time.Minute))
+	require.NoError(t, c.Set(ctx, "mod", "v1.1.0", "unknown revision", time.Minute))
+
+	w := httptest.NewRecorder()
+	h(w, httptest.NewRequest(http.MethodDelete, "/admin/notfound?module=mod&version=v1.0.0", nil))
+	require.Equal(t, http.StatusNoContent, w.Code)
+	_, ok, err := c.Get(ctx, "mod", "v1.0.0")
+	require.NoError(t, err)
+	require.False(t, ok)
+	_, ok, err = c.Get(ctx, "mod", "v1.1.0")
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	w = httptest.NewRecorder()
+	h(w, httptest.NewRequest(http.MethodDelete, "/admin/notfound?module=mod", nil))
+	require.Equal(t, http.StatusNoContent, w.Code)
+	_, ok, err = c.Get(ctx, "mod", "v1.1.0")
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	w = httptest.NewRecorder()
+	h(w, httptest.NewRequest(http.MethodDelete, "/admin/notfound", nil))
+	require.Equal(t, http.StatusBadRequest, w.Code)
+}
@@ -1,16 +1,34 @@
 package actions
 
 import (
+	"encoding/json"
 	"net/http"
 
+	"github.com/gomods/athens/pkg/module"
 	"github.com/gomods/athens/pkg/storage"
 )
 
-func getReadinessHandler(s storage.Backend) http.HandlerFunc {
+type readiness struct {
+	Upstreams []module.UpstreamHealth `json:"upstreams,omitempty"`
+}
+
+// getReadinessHandler reports whether storage is reachable and, if the fetcher
+// tracks them, the health of the upstreams. Unhealthy upstreams do not make
+// Athens unready, since it still serves every module already in storage.
+func getReadinessHandler(s storage.Backend, hr module.HealthReporter) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		status := http.StatusOK
 		if _, err := s.List(r.Context(), "github.com/gomods/athens"); err != nil {
-			w.Header().Set("Content-Type", "application/json; charset=utf-8")
-			w.WriteHeader(http.StatusInternalServerError)
+			status = http.StatusInternalServerError
+		}
+		var rd readiness
+		if hr != nil {
+			rd.Upstreams = hr.Health()
+		}
+		w.WriteHeader(status)
+		if rd.Upstreams != nil {
+			_ = json.NewEncoder(w).Encode(rd)
 		}
 	}
 }
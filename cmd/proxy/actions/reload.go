@@ -0,0 +1,159 @@
+package actions
+
+import (
+	"context"
+	"maps"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/gomods/athens/pkg/config"
+	"github.com/gomods/athens/pkg/download/mode"
+	"github.com/gomods/athens/pkg/log"
+	mw "github.com/gomods/athens/pkg/middleware"
+	"github.com/gomods/athens/pkg/module"
+	"github.com/gomods/athens/pkg/observ"
+	"github.com/gomods/athens/pkg/vuln"
+)
+
+// reloader swaps the filter, the download mode file, the validator hook and
+// the vulnerability database that Athens was started with for the ones of
+// the configuration file, when
+// the files they come from change or on SIGHUP. Settings that do not parse
+// are logged, and the previous ones are kept.
+type reloader struct {
+	logger     *log.Logger
+	configFile string
+	conf       *config.Config
+	filter     *module.Filter
+	df         *mode.DownloadFile
+	// hook is nil for the workers, which do not validate.
+	hook *mw.ValidatorHook
+	// vulns is nil for the workers, which do not check vulnerabilities.
+	vulns  *vuln.DB
+	stamps map[string]fileStamp
+}
+
+// fileStamp tells whether a file changed. A file
+// that cannot be read has the zero fileStamp.
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+// runReloader runs r in the background until the returned function is called.
+func runReloader(r *reloader, c *config.Config) func() {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		r.run(ctx, time.Duration(c.ReloadInterval)*time.Second)
+	}()
+	return func() {
+		cancel()
+		<-done
+	}
+}
+
+// run reloads until ctx is done, on SIGHUP and, if interval is
+// not zero, when one of the files checked every interval changed.
+func (r *reloader) run(ctx context.Context, interval time.Duration) {
+	sig := make(chan os.Signal, 1)
+	if len(reloadSignals) > 0 {
+		signal.Notify(sig, reloadSignals...)
+		defer signal.Stop(sig)
+	}
+	var tick <-chan time.Time
+	if interval > 0 {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+	r.stamps = r.stat()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-sig:
+			r.logger.Infof("reloading the configuration on SIGHUP")
+			r.reload(ctx)
+		case <-tick:
+			if stamps := r.stat(); !maps.Equal(stamps, r.stamps) {
+				r.logger.Infof("reloading the configuration after a file changed")
+				r.reload(ctx)
+			}
+		}
+	}
+}
+
+// reload loads the configuration file again and swaps
+// in the settings of the files that parse.
+func (r *reloader) reload(ctx context.Context) {
+	defer func() { r.stamps = r.stat() }()
+	conf, err := config.Load(r.configFile)
+	if err != nil {
+		r.failed(ctx, "config", err)
+		return
+	}
+	r.conf = conf
+	observ.RecordConfigReload(ctx, "config", "success")
+
+	if filter, err := getFilter(conf); err != nil {
+		r.failed(ctx, "filter", err)
+	} else {
+		r.filter.Update(filter)
+		observ.RecordConfigReload(ctx, "filter", "success")
+	}
+
+	if df, err := mode.NewFile(conf.DownloadMode, conf.DownloadURL); err != nil {
+		r.failed(ctx, "download", err)
+	} else {
+		r.df.Update(df)
+		observ.RecordConfigReload(ctx, "download", "success")
+	}
+
+	if r.hook != nil {
+		r.hook.Set(conf.ValidatorHook)
+	}
+
+	if r.vulns != nil {
+		if db, err := getVulnDB(conf); err != nil {
+			r.failed(ctx, "vulndb", err)
+		} else {
+			r.vulns.Update(db)
+			observ.RecordConfigReload(ctx, "vulndb", "success")
+		}
+	}
+}
+
+func (r *reloader) failed(ctx context.Context, file string, err error) {
+	r.logger.WithFields(map[string]any{"file": file}).Errorf("keeping the previous configuration: %v", err)
+	observ.RecordConfigReload(ctx, file, "failure")
+}
+
+// stat returns the stamps of the configuration file and of
+// the files that the last configuration that loaded points to.
+func (r *reloader) stat() map[string]fileStamp {
+	files := []string{r.configFile, r.conf.FilterFile}
+	if f, ok := strings.CutPrefix(string(r.conf.DownloadMode), "file:"); ok {
+		files = append(files, f)
+	}
+	// the index of a database directory changes whenever its advisories do.
+	if r.vulns != nil && r.conf.VulnDB != nil && r.conf.VulnDB.Path != "" {
+		files = append(files, r.conf.VulnDB.Path, filepath.Join(r.conf.VulnDB.Path, "index", "db.json"))
+	}
+	stamps := map[string]fileStamp{}
+	for _, f := range files {
+		if f == "" {
+			continue
+		}
+		var stamp fileStamp
+		if fi, err := os.Stat(f); err == nil {
+			stamp = fileStamp{modTime: fi.ModTime(), size: fi.Size()}
+		}
+		stamps[f] = stamp
+	}
+	return stamps
+}
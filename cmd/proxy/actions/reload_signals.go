@@ -0,0 +1,11 @@
+//go:build unix
+
+package actions
+
+import (
+	"os"
+	"syscall"
+)
+
+// reloadSignals are the signals that make Athens reload its configuration.
+var reloadSignals = []os.Signal{syscall.SIGHUP}
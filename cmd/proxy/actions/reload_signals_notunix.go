@@ -0,0 +1,8 @@
+//go:build !unix
+
+package actions
+
+import "os"
+
+// reloadSignals are the signals that make Athens reload its configuration.
+var reloadSignals []os.Signal
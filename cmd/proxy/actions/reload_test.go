@@ -0,0 +1,96 @@
+package actions
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gomods/athens/pkg/config"
+	"github.com/gomods/athens/pkg/download/mode"
+	"github.com/gomods/athens/pkg/log"
+	mw "github.com/gomods/athens/pkg/middleware"
+	"github.com/gomods/athens/pkg/module"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReloader(t *testing.T) {
+	dir := t.TempDir()
+	filterFile := filepath.Join(dir, "filter.conf")
+	downloadFile := filepath.Join(dir, "download.hcl")
+	configFile := filepath.Join(dir, "athens.toml")
+	write := func(file, content string) {
+		t.Helper()
+		require.NoError(t, os.WriteFile(file, []byte(content), 0o600))
+	}
+	write(filterFile, "- github.com/blocked\n")
+	write(downloadFile, "mode = \"sync\"\ndownloadURL = \"\"\n")
+	write(configFile, "FilterFile = \""+filterFile+"\"\nDownloadMode = \"file:"+downloadFile+"\"\nValidatorHook = \"\"\n")
+
+	conf, err := config.Load(configFile)
+	require.NoError(t, err)
+	filter, err := getFilter(conf)
+	require.NoError(t, err)
+	df, err := mode.NewFile(conf.DownloadMode, conf.DownloadURL)
+	require.NoError(t, err)
+	r := &reloader{
+		logger:     log.NoOpLogger(),
+		configFile: configFile,
+		conf:       conf,
+		filter:     filter,
+		df:         df,
+		hook:       mw.NewValidatorHook(conf.ValidatorHook),
+	}
+	r.stamps = r.stat()
+	require.Equal(t, module.Exclude, filter.Rule("github.com/blocked", ""))
+
+	write(filterFile, "- github.com/other\n")
+	write(downloadFile, "mode = \"none\"\ndownloadURL = \"\"\n")
+	write(configFile, "FilterFile = \""+filterFile+"\"\nDownloadMode = \"file:"+downloadFile+"\"\nValidatorHook = \"https://validator.example\"\n")
+	require.NotEqual(t, r.stamps, r.stat())
+	r.reload(t.Context())
+	require.Equal(t, module.Include, filter.Rule("github.com/blocked", ""))
+	require.Equal(t, module.Exclude, filter.Rule("github.com/other", ""))
+	require.Equal(t, mode.None, df.Match("github.com/pkg/errors"))
+	require.Equal(t, "https://validator.example", r.hook.URL())
+	require.Equal(t, r.stamps, r.stat())
+
+	// a file that does not parse leaves the previous settings in place.
+	write(filterFile, "? github.com/other\n")
+	write(downloadFile, "mode = \"sometimes\"\n")
+	r.reload(t.Context())
+	require.Equal(t, module.Exclude, filter.Rule("github.com/other", ""))
+	require.Equal(t, mode.None, df.Match("github.com/pkg/errors"))
+}
+
+func TestReloaderVulnDB(t *testing.T) {
+	dir := t.TempDir()
+	dbDir := filepath.Join(dir, "vulndb")
+	configFile := filepath.Join(dir, "athens.toml")
+	write := func(file, content string) {
+		t.Helper()
+		require.NoError(t, os.MkdirAll(filepath.Dir(file), 0o755))
+		require.NoError(t, os.WriteFile(file, []byte(content), 0o600))
+	}
+	write(configFile, "")
+	conf, err := config.Load(configFile)
+	require.NoError(t, err)
+	vulns, err := getVulnDB(conf)
+	require.NoError(t, err)
+	r := &reloader{logger: log.NoOpLogger(), configFile: configFile, conf: conf, filter: &module.Filter{}, df: &mode.DownloadFile{}, vulns: vulns}
+	r.stamps = r.stat()
+	require.Empty(t, vulns.Check("github.com/a/b", "v1.0.0").Denied)
+
+	entry := `{"id": "GO-2024-0001", "affected": [{"package": {"name": "github.com/a/b"}}]}`
+	write(filepath.Join(dbDir, "ID", "GO-2024-0001.json"), entry)
+	write(filepath.Join(dbDir, "index", "db.json"), `{"modified": "2024-01-01T00:00:00Z"}`)
+	write(configFile, "[VulnDB]\nPath = \""+dbDir+"\"\nAction = \"deny\"\n")
+	r.reload(t.Context())
+	require.Equal(t, []string{"GO-2024-0001"}, vulns.Check("github.com/a/b", "v1.0.0").Denied)
+
+	// a new advisory updates the index of the database
+	write(filepath.Join(dbDir, "ID", "GO-2024-0002.json"), `{"id": "GO-2024-0002", "affected": [{"package": {"name": "github.com/c/d"}}]}`)
+	write(filepath.Join(dbDir, "index", "db.json"), `{"modified": "2024-01-02T00:00:00.000Z"}`)
+	require.NotEqual(t, r.stamps, r.stat())
+	r.reload(t.Context())
+	require.Equal(t, []string{"GO-2024-0002"}, vulns.Check("github.com/c/d", "v1.0.0").Denied)
+}
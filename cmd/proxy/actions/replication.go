@@ -0,0 +1,104 @@
+package actions
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gomods/athens/pkg/config"
+	"github.com/gomods/athens/pkg/log"
+	"github.com/gomods/athens/pkg/queue"
+	"github.com/gomods/athens/pkg/queue/bolt"
+	queuemem "github.com/gomods/athens/pkg/queue/mem"
+	"github.com/gomods/athens/pkg/queue/redis"
+	"github.com/gomods/athens/pkg/replication"
+	"github.com/gomods/athens/pkg/stash"
+	"github.com/gomods/athens/pkg/storage"
+)
+
+// getReplication returns the stash wrapper that queues the module versions
+// stashed to s for replication to the targets of c.Replication, and runs
+// the replicator in the background until the returned function is called.
+// The wrapper does nothing if there are no targets. The instances that
+// share s take turns reconciling the targets with the lock of withLock,
+// the singleflight of the stashes.
+func getReplication(l *log.Logger, c *config.Config, s storage.Backend, client *http.Client, withLock stash.Wrapper) (stash.Wrapper, func(), error) {
+	if c.Replication == nil || len(c.Replication.Targets) == 0 {
+		return func(st stash.Stasher) stash.Stasher { return st }, func() {}, nil
+	}
+	targets := make([]replication.Target, 0, len(c.Replication.Targets))
+	for _, t := range c.Replication.Targets {
+		ts, err := GetStorage(t.StorageType, t.Storage, c.TimeoutDuration(), client)
+		if err != nil {
+			return nil, nil, fmt.Errorf("getting the storage of the replication target %q: %w", t.Name, err)
+		}
+		targets = append(targets, replication.Target{Name: t.Name, Storage: ts})
+	}
+	q, err := getReplicationQueue(c)
+	if err != nil {
+		return nil, nil, err
+	}
+	r := replication.New(s, q, targets...)
+	stopWorkers := runReplication(l, c, r, q, withLock(r.Reconciler()))
+	return stash.WithReplication(q), func() {
+		stopWorkers()
+		if err := q.Close(); err != nil {
+			l.Errorf("closing the replication queue: %v", err)
+		}
+	}, nil
+}
+
+// getReplicationQueue returns a queue of QueueType apart from the queue
+// of the stashes, so that their workers do not handle each other's jobs.
+func getReplicationQueue(c *config.Config) (queue.Queue, error) {
+	switch c.QueueType {
+	case "", "memory":
+		return queuemem.New(), nil
+	case "bolt":
+		return bolt.New(c.Replication.BoltPath)
+	case "redis":
+		return redis.NewNamed(c.Queue.Redis.Endpoint, c.Queue.Redis.Password, c.Queue.Redis.Cluster, c.StashTimeoutDuration(), "replication")
+	}
+	return nil, fmt.Errorf("unknown queue type: %q", c.QueueType)
+}
+
+// runReplication replicates the jobs of q with r in the background, and
+// reconciles the targets with reconciler every ReconcileInterval, until
+// the returned function is called.
+func runReplication(l *log.Logger, c *config.Config, r *replication.Replicator, q queue.Queue, reconciler stash.Stasher) func() {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{}, 2)
+	go func() {
+		defer func() { done <- struct{}{} }()
+		queue.Process(ctx, q, c.Replication.Workers, queueRetryPolicy(c.Queue), l, r.Replicate)
+	}()
+	go func() {
+		defer func() { done <- struct{}{} }()
+		if c.Replication.ReconcileInterval <= 0 {
+			return
+		}
+		interval := time.Duration(c.Replication.ReconcileInterval) * time.Second
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				// the first instance to tick in a round reconciles it,
+				// the others find it done.
+				ver := replication.Round(time.Now(), interval)
+				_, err := reconciler.Stash(log.SetEntryInContext(ctx, l), replication.RoundModule, ver)
+				if err != nil && ctx.Err() == nil {
+					l.Errorf("reconciling the replication targets: %v", err)
+				}
+			}
+		}
+	}()
+	return func() {
+		cancel()
+		<-done
+		<-done
+	}
+}
@@ -0,0 +1,55 @@
+package actions
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gomods/athens/pkg/config"
+	"github.com/gomods/athens/pkg/log"
+	"github.com/gomods/athens/pkg/stash"
+	"github.com/gomods/athens/pkg/storage"
+	"github.com/gomods/athens/pkg/storage/fs"
+	storagemem "github.com/gomods/athens/pkg/storage/mem"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/require"
+)
+
+type savingStasher struct {
+	s storage.Backend
+}
+
+func (st savingStasher) Stash(ctx context.Context, mod, ver string) (string, error) {
+	return ver, st.s.Save(ctx, mod, ver, []byte("module"), strings.NewReader("zip"), nil, []byte(ver))
+}
+
+func TestGetReplication(t *testing.T) {
+	s, err := storagemem.NewStorage()
+	require.NoError(t, err)
+	root := t.TempDir()
+	c := &config.Config{
+		QueueType: "memory",
+		Queue:     &config.Queue{MaxAttempts: 1},
+		Replication: &config.Replication{
+			Workers: 1,
+			Targets: []*config.ReplicationTarget{
+				{Name: "dr", StorageType: "disk", Storage: &config.Storage{Disk: &config.DiskConfig{RootPath: root}}},
+			},
+		},
+	}
+
+	withReplication, stop, err := getReplication(log.NoOpLogger(), c, s, http.DefaultClient, stash.WithSingleflight)
+	require.NoError(t, err)
+	defer stop()
+	_, err = withReplication(savingStasher{s}).Stash(t.Context(), "github.com/gomods/athens", "v1.0.0")
+	require.NoError(t, err)
+
+	dr, err := fs.NewStorage(root, afero.NewOsFs())
+	require.NoError(t, err)
+	require.Eventually(t, func() bool {
+		ok, err := storage.WithChecker(dr).Exists(t.Context(), "github.com/gomods/athens", "v1.0.0")
+		return err == nil && ok
+	}, 5*time.Second, 10*time.Millisecond)
+}
@@ -4,6 +4,8 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
@@ -11,13 +13,16 @@ import (
 	"github.com/gomods/athens/pkg/errors"
 	"github.com/gomods/athens/pkg/storage"
 	"github.com/gomods/athens/pkg/storage/azureblob"
+	"github.com/gomods/athens/pkg/storage/bolt"
 	"github.com/gomods/athens/pkg/storage/external"
 	"github.com/gomods/athens/pkg/storage/fs"
 	"github.com/gomods/athens/pkg/storage/gcp"
 	"github.com/gomods/athens/pkg/storage/mem"
 	"github.com/gomods/athens/pkg/storage/minio"
 	"github.com/gomods/athens/pkg/storage/mongo"
+	"github.com/gomods/athens/pkg/storage/overlay"
 	"github.com/gomods/athens/pkg/storage/s3"
+	"github.com/gomods/athens/pkg/storage/tiered"
 	"github.com/spf13/afero"
 )
 
@@ -43,6 +48,11 @@ func GetStorage(storageType string, storageConfig *config.Storage, timeout time.
 			return nil, errors.E(op, errStr)
 		}
 		return s, nil
+	case "bolt":
+		if storageConfig.Bolt == nil {
+			return nil, errors.E(op, "Invalid Bolt Storage Configuration")
+		}
+		return bolt.New(storageConfig.Bolt.Path)
 	case "minio":
 		if storageConfig.Minio == nil {
 			return nil, errors.E(op, "Invalid Minio Storage Configuration")
@@ -70,6 +80,40 @@ func GetStorage(storageType string, storageConfig *config.Storage, timeout time.
 			return nil, errors.E(op, "Invalid External Storage Configuration")
 		}
 		return external.NewClient(storageConfig.External.URL, client), nil
+	case "tiered":
+		if storageConfig.Tiered == nil || storageConfig.Tiered.Remote == "tiered" {
+			return nil, errors.E(op, "Invalid Tiered Storage Configuration")
+		}
+		remote, err := GetStorage(storageConfig.Tiered.Remote, storageConfig, timeout, client)
+		if err != nil {
+			return nil, errors.E(op, err)
+		}
+		maxSize := int64(storageConfig.Tiered.MaxSizeMB) << 20
+		return tiered.New(remote, storageConfig.Tiered.RootPath, afero.NewOsFs(), maxSize)
+	case "overlay":
+		if storageConfig.Overlay == nil {
+			return nil, errors.E(op, "Invalid Overlay Storage Configuration")
+		}
+		layers := make([]storage.Backend, 0, len(storageConfig.Overlay.Layers))
+		for _, layer := range storageConfig.Overlay.Layers {
+			var (
+				s   storage.Backend
+				err error
+			)
+			switch path, isDir := strings.CutPrefix(layer, "disk:"); {
+			case isDir:
+				s, err = fs.NewStorage(path, afero.NewOsFs())
+			case layer == "overlay":
+				err = errors.E(op, "an overlay layer cannot be an overlay")
+			default:
+				s, err = GetStorage(layer, storageConfig, timeout, client)
+			}
+			if err != nil {
+				return nil, errors.E(op, err)
+			}
+			layers = append(layers, s)
+		}
+		return overlay.New(layers, slices.Index(storageConfig.Overlay.Layers, storageConfig.Overlay.Writable))
 	default:
 		return nil, fmt.Errorf("storage type %s is unknown", storageType)
 	}
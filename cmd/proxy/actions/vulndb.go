@@ -0,0 +1,100 @@
+package actions
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gomods/athens/pkg/config"
+	"github.com/gomods/athens/pkg/errors"
+	"github.com/gomods/athens/pkg/log"
+	"github.com/gomods/athens/pkg/vuln"
+)
+
+// vulnDBHandler serves the files of the vulnerability database that m
+// mirrors, such as /index/db.json, /index/modules.json and /ID/{id}.json.
+func vulnDBHandler(m *vuln.Mirror) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, ok := m.File(strings.TrimPrefix(r.URL.Path, "/"))
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Last-Modified", m.Modified().UTC().Format(http.TimeFormat))
+		_, _ = w.Write(b)
+	})
+}
+
+// vulnDBImportHandler implements POST baseURL/admin/vulndb, which imports
+// the database archive in the body into the mirror. Archives larger than
+// vuln.MaxArchiveSize are rejected.
+func vulnDBImportHandler(m *vuln.Mirror) http.HandlerFunc {
+	const op errors.Op = "actions.vulnDBImportHandler"
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		archive, err := io.ReadAll(http.MaxBytesReader(w, r.Body, vuln.MaxArchiveSize))
+		var tooLarge *http.MaxBytesError
+		if errors.AsErr(err, &tooLarge) {
+			err = errors.E(op, err, errors.KindBadRequest)
+		}
+		if err == nil {
+			err = m.Import(ctx, archive)
+		}
+		if err != nil {
+			err = errors.E(op, err)
+			log.EntryFromContext(ctx).SystemErr(err)
+			http.Error(w, err.Error(), errors.Kind(err))
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// runVulnDBMirror loads m from storage, then syncs it from upstream, or loads
+// the archives that other instances import if there is no upstream, every
+// SyncInterval, until the returned function is called.
+func runVulnDBMirror(l *log.Logger, c *config.Config, m *vuln.Mirror, client *http.Client) func() {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	upstream := c.VulnDBMirror.UpstreamURL
+	if c.FrontendOnly {
+		upstream = ""
+	}
+	update := func() {
+		var err error
+		if upstream != "" {
+			err = m.Sync(ctx, client, upstream)
+		} else {
+			err = m.Load(ctx)
+		}
+		if err != nil && ctx.Err() == nil {
+			l.Errorf("updating the vulnerability database mirror: %v", err)
+		}
+	}
+	go func() {
+		defer close(done)
+		if err := m.Load(ctx); err != nil && !errors.IsNotFoundErr(err) {
+			l.Errorf("loading the vulnerability database mirror: %v", err)
+		}
+		if upstream != "" {
+			update()
+		}
+		ticker := time.NewTicker(time.Duration(c.VulnDBMirror.SyncInterval) * time.Second)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				update()
+			}
+		}
+	}()
+	return func() {
+		cancel()
+		<-done
+	}
+}
@@ -0,0 +1,52 @@
+package actions
+
+import (
+	"archive/zip"
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gomods/athens/pkg/storage/mem"
+	"github.com/gomods/athens/pkg/vuln"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVulnDBHandlers(t *testing.T) {
+	var archive bytes.Buffer
+	zw := zip.NewWriter(&archive)
+	for name, content := range map[string]string{
+		"index/db.json":        `{"modified": "2024-02-01T00:00:00Z"}`,
+		"index/modules.json":   `[{"path": "github.com/a/b", "vulns": [{"id": "GO-2024-0001"}]}]`,
+		"ID/GO-2024-0001.json": `{"id": "GO-2024-0001"}`,
+	} {
+		w, err := zw.Create(name)
+		require.NoError(t, err)
+		_, err = w.Write([]byte(content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, zw.Close())
+
+	s, err := mem.NewStorage()
+	require.NoError(t, err)
+	m := vuln.NewMirror(s)
+	serve := http.StripPrefix("/vulndb", vulnDBHandler(m))
+
+	w := httptest.NewRecorder()
+	serve.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/vulndb/index/db.json", nil))
+	require.Equal(t, http.StatusNotFound, w.Code)
+
+	w = httptest.NewRecorder()
+	vulnDBImportHandler(m)(w, httptest.NewRequest(http.MethodPost, "/admin/vulndb", bytes.NewReader(archive.Bytes())))
+	require.Equal(t, http.StatusNoContent, w.Code)
+
+	w = httptest.NewRecorder()
+	serve.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/vulndb/ID/GO-2024-0001.json", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Equal(t, `{"id": "GO-2024-0001"}`, w.Body.String())
+	require.Equal(t, "Thu, 01 Feb 2024 00:00:00 GMT", w.Header().Get("Last-Modified"))
+
+	w = httptest.NewRecorder()
+	vulnDBImportHandler(m)(w, httptest.NewRequest(http.MethodPost, "/admin/vulndb", bytes.NewReader([]byte("not a zip"))))
+	require.Equal(t, http.StatusBadRequest, w.Code)
+}
@@ -0,0 +1,49 @@
+package actions
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+
+	"github.com/gomods/athens/pkg/errors"
+	"github.com/gomods/athens/pkg/log"
+	"github.com/gomods/athens/pkg/warm"
+)
+
+// maxWarmBody is the largest go.mod, go.sum or go.work that can be warmed.
+const maxWarmBody = 10 << 20
+
+// warmHandler implements POST baseURL/admin/warm, which stashes the
+// module versions that the go.mod, go.sum or go.work in the body needs
+// and reports which were cached already, fetched or failed.
+func warmHandler(wr *warm.Warmer) http.HandlerFunc {
+	const op errors.Op = "actions.warmHandler"
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		lggr := log.EntryFromContext(ctx)
+		data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxWarmBody))
+		if err != nil {
+			err = errors.E(op, err, errors.KindBadRequest, slog.LevelInfo)
+			lggr.SystemErr(err)
+			http.Error(w, err.Error(), errors.Kind(err))
+			return
+		}
+		if len(data) == 0 {
+			http.Error(w, "a go.mod, go.sum or go.work is required", http.StatusBadRequest)
+			return
+		}
+		report, err := wr.Warm(ctx, data)
+		if err != nil {
+			err = errors.E(op, err, slog.LevelInfo)
+			lggr.SystemErr(err)
+			http.Error(w, err.Error(), errors.Kind(err))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		if err := json.NewEncoder(w).Encode(report); err != nil {
+			lggr.SystemErr(errors.E(op, err))
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package actions
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gomods/athens/pkg/storage/mem"
+	"github.com/gomods/athens/pkg/warm"
+	"github.com/stretchr/testify/require"
+)
+
+type stashFunc func(ctx context.Context, mod, ver string) (string, error)
+
+func (f stashFunc) Stash(ctx context.Context, mod, ver string) (string, error) {
+	return f(ctx, mod, ver)
+}
+
+func TestWarmHandler(t *testing.T) {
+	s, err := mem.NewStorage()
+	require.NoError(t, err)
+	st := stashFunc(func(ctx context.Context, mod, ver string) (string, error) {
+		return ver, s.Save(ctx, mod, ver, []byte("module "+mod), strings.NewReader("zip"), nil, []byte("info"))
+	})
+	h := warmHandler(warm.New(st, s, 1))
+
+	w := httptest.NewRecorder()
+	h(w, httptest.NewRequest(http.MethodPost, "/admin/warm", strings.NewReader("mod v1.0.0 h1:abc=\n")))
+	require.Equal(t, http.StatusOK, w.Code)
+	var report warm.Report
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&report))
+	require.Equal(t, []string{"mod@v1.0.0"}, report.Fetched)
+
+	for _, body := range []string{"", "module main\n\nrequire\n"} {
+		w = httptest.NewRecorder()
+		h(w, httptest.NewRequest(http.MethodPost, "/admin/warm", strings.NewReader(body)))
+		require.Equal(t, http.StatusBadRequest, w.Code)
+	}
+}
@@ -0,0 +1,86 @@
+package actions
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gomods/athens/pkg/config"
+	"github.com/gomods/athens/pkg/download/mode"
+	"github.com/gomods/athens/pkg/log"
+	"github.com/gomods/athens/pkg/notfound"
+	"github.com/gomods/athens/pkg/queue"
+	"github.com/gomods/athens/pkg/stash"
+	"github.com/gomods/athens/pkg/storage"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+)
+
+// Worker stashes the module versions that FrontendOnly proxies queue,
+// until ctx is done. Workers share the storage, index and queue of the
+// frontends and are the only ones to fetch modules from upstream. conf was
+// loaded from configFile, which Worker loads again to reload the filter and
+// the download mode when they change.
+func Worker(ctx context.Context, logger *log.Logger, conf *config.Config, configFile string) error {
+	if conf.QueueType != "redis" {
+		return fmt.Errorf("workers need a queue shared with the frontends, such as redis, and not: %q", conf.QueueType)
+	}
+	if err := initializeAuth(conf); err != nil {
+		return err
+	}
+	client := &http.Client{
+		Transport: otelhttp.NewTransport(http.DefaultTransport),
+	}
+	s, err := GetStorage(conf.StorageType, conf.Storage, conf.TimeoutDuration(), client)
+	if err != nil {
+		return fmt.Errorf("getting storage configuration: %w", err)
+	}
+	indexer, err := getIndex(conf)
+	if err != nil {
+		return err
+	}
+	df, err := mode.NewFile(conf.DownloadMode, conf.DownloadURL)
+	if err != nil {
+		return err
+	}
+	filter, err := getFilter(conf)
+	if err != nil {
+		return err
+	}
+	mf, _, err := getUpstream(conf, df)
+	if err != nil {
+		return err
+	}
+	nfc, err := getNotFoundCache(conf)
+	if err != nil {
+		return err
+	}
+	if nfc != nil {
+		mf = notfound.NewFetcher(mf, nfc, time.Duration(conf.NotFoundCache.TTL)*time.Second)
+	}
+	withSingleFlight, err := getSingleFlight(logger, conf, s, storage.WithChecker(s))
+	if err != nil {
+		return err
+	}
+	withPrefetch := getPrefetch(conf, s, filter, df)
+	withReplication, stopReplication, err := getReplication(logger, conf, s, client, withSingleFlight)
+	if err != nil {
+		return err
+	}
+	defer stopReplication()
+	st := stash.New(mf, s, indexer, conf.StashTimeoutDuration(), withReplication, stash.WithTimeouts(df), stash.WithPool(conf.GoGetWorkers), withSingleFlight, withPrefetch)
+
+	q, err := getQueue(conf)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := q.Close(); err != nil {
+			logger.Errorf("closing the download queue: %v", err)
+		}
+	}()
+	defer runReloader(&reloader{logger: logger, configFile: configFile, conf: conf, filter: filter, df: df}, conf)()
+	logger.WithFields(map[string]any{"workers": conf.Queue.Workers}).Infof("Starting workers")
+	queue.Process(ctx, q, conf.Queue.Workers, queueRetryPolicy(conf.Queue), logger, st.Stash)
+	return nil
+}
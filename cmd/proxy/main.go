@@ -57,7 +57,7 @@ func main() {
 			"run it under an init such as tini or `docker/podman run --init` to avoid zombie processes")
 	}
 
-	handler, cleanup, err := actions.App(logger, conf)
+	handler, cleanup, err := actions.App(logger, conf, *configFile)
 	if err != nil {
 		logger.Fatalf("Could not create App: %v", err)
 	}
@@ -0,0 +1,54 @@
+// Command worker stashes the module versions queued by the Athens
+// proxies that run with FrontendOnly, from the same configuration file.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	stdlog "log"
+	"log/slog"
+	"os"
+	"os/signal"
+
+	"github.com/gomods/athens/cmd/proxy/actions"
+	"github.com/gomods/athens/internal/shutdown"
+	"github.com/gomods/athens/pkg/build"
+	"github.com/gomods/athens/pkg/config"
+	athenslog "github.com/gomods/athens/pkg/log"
+)
+
+var (
+	configFile = flag.String("config_file", "", "The path to the config file")
+	version    = flag.Bool("version", false, "Print version information and exit")
+)
+
+func main() {
+	flag.Parse()
+	if *version {
+		fmt.Println(build.String())
+		os.Exit(0)
+	}
+	conf, err := config.Load(*configFile)
+	if err != nil {
+		stdlog.Fatalf("Could not load config file: %v", err)
+	}
+
+	logLvl, err := athenslog.ParseLevel(conf.LogLevel)
+	if err != nil {
+		stdlog.Fatalf("Could not parse log level %q: %v", conf.LogLevel, err)
+	}
+
+	logger := athenslog.New(conf.CloudRuntime, logLvl, conf.LogFormat)
+	stdlog.SetOutput(logger.StdLogger(slog.LevelError).Writer())
+	stdlog.SetFlags(stdlog.Flags() &^ (stdlog.Ldate | stdlog.Ltime))
+
+	// the workers finish the jobs they are handling, or put them
+	// back on the queue, before exiting on a shutdown signal.
+	ctx, stop := signal.NotifyContext(context.Background(), shutdown.GetSignals()...)
+	defer stop()
+	if err := actions.Worker(ctx, logger, conf, *configFile); err != nil {
+		logger.Fatalf("Could not run workers: %v", err)
+	}
+	logger.Infof("Workers stopped")
+}
@@ -0,0 +1,7 @@
+package config
+
+// BoltConfig specifies the properties required to use a bolt database
+// file as the storage backend.
+type BoltConfig struct {
+	Path string `envconfig:"ATHENS_BOLT_STORAGE_PATH" validate:"required"`
+}
@@ -6,6 +6,7 @@ import (
 	"os"
 	"path/filepath"
 	"runtime"
+	"slices"
 	"strconv"
 	"strings"
 
@@ -27,6 +28,12 @@ type Config struct {
 	GoBinaryEnvVars       EnvList   `envconfig:"ATHENS_GO_BINARY_ENV_VARS"`
 	GoGetWorkers          int       `envconfig:"ATHENS_GOGET_WORKERS"           validate:"required"`
 	GoGetDir              string    `envconfig:"ATHENS_GOGET_DIR"`
+	GoCacheDir            string    `envconfig:"ATHENS_GO_CACHE_DIR"`
+	GoCacheMaxSizeMB      int       `envconfig:"ATHENS_GO_CACHE_MAX_SIZE_MB"    validate:"min=0"`
+	FetcherType           string    `envconfig:"ATHENS_FETCHER_TYPE"            validate:"oneof=gobinary goproxy git"`
+	UpstreamProxy         string    `envconfig:"ATHENS_UPSTREAM_PROXY"          validate:"required_if=FetcherType goproxy"`
+	UpstreamMaxFailures   int       `envconfig:"ATHENS_UPSTREAM_MAX_FAILURES"   validate:"min=0"`
+	UpstreamCooldown      int       `envconfig:"ATHENS_UPSTREAM_COOLDOWN"       validate:"min=0"`
 	ProtocolWorkers       int       `envconfig:"ATHENS_PROTOCOL_WORKERS"        validate:"required"`
 	LogLevel              string    `envconfig:"ATHENS_LOG_LEVEL"               validate:"required"`
 	LogFormat             string    `envconfig:"ATHENS_LOG_FORMAT"              validate:"oneof='' 'json' 'plain'"`
@@ -44,6 +51,8 @@ type Config struct {
 	UnixSocket            string    `envconfig:"ATHENS_UNIX_SOCKET"`
 	BasicAuthUser         string    `envconfig:"BASIC_AUTH_USER"`
 	BasicAuthPass         string    `envconfig:"BASIC_AUTH_PASS"`
+	AdminUser             string    `envconfig:"ATHENS_ADMIN_USER"`
+	AdminPass             string    `envconfig:"ATHENS_ADMIN_PASS"`
 	HomeTemplatePath      string    `envconfig:"ATHENS_HOME_TEMPLATE_PATH"`
 	ForceSSL              bool      `envconfig:"PROXY_FORCE_SSL"`
 	ValidatorHook         string    `envconfig:"ATHENS_PROXY_VALIDATOR"`
@@ -61,11 +70,24 @@ type Config struct {
 	SingleFlightType      string    `envconfig:"ATHENS_SINGLE_FLIGHT_TYPE"`
 	RobotsFile            string    `envconfig:"ATHENS_ROBOTS_FILE"`
 	IndexType             string    `envconfig:"ATHENS_INDEX_TYPE"`
+	QueueType             string    `envconfig:"ATHENS_QUEUE_TYPE"`
+	ListCacheType         string    `envconfig:"ATHENS_LIST_CACHE_TYPE"`
+	NotFoundCacheType     string    `envconfig:"ATHENS_NOT_FOUND_CACHE_TYPE"`
 	ShutdownTimeout       int       `envconfig:"ATHENS_SHUTDOWN_TIMEOUT"        validate:"min=0"`
 	StashTimeout          int       `envconfig:"ATHENS_STASH_TIMEOUT"`
+	LazyZipFetch          bool      `envconfig:"ATHENS_LAZY_ZIP_FETCH"`
+	FrontendOnly          bool      `envconfig:"ATHENS_FRONTEND_ONLY"`
+	PrefetchDepth         int       `envconfig:"ATHENS_PREFETCH_DEPTH"          validate:"min=0"`
+	ReloadInterval        int       `envconfig:"ATHENS_RELOAD_INTERVAL"         validate:"min=0"`
 	SingleFlight          *SingleFlight
 	Storage               *Storage
 	Index                 *Index
+	Queue                 *Queue
+	ListCache             *ListCache
+	NotFoundCache         *NotFoundCache
+	VulnDB                *VulnDB
+	VulnDBMirror          *VulnDBMirror
+	Replication           *Replication
 }
 
 // EnvList is a list of key-value environment
@@ -152,6 +174,10 @@ func defaultConfig() *Config {
 		GoBinaryEnvVars:       EnvList{"GOPROXY=direct"},
 		GoEnv:                 "development",
 		GoGetWorkers:          10,
+		GoCacheMaxSizeMB:      10240,
+		FetcherType:           "gobinary",
+		UpstreamMaxFailures:   5,
+		UpstreamCooldown:      60,
 		ProtocolWorkers:       30,
 		LogLevel:              "debug",
 		LogFormat:             "plain",
@@ -174,8 +200,12 @@ func defaultConfig() *Config {
 		NetworkMode:           "strict",
 		RobotsFile:            "robots.txt",
 		IndexType:             "none",
+		QueueType:             "memory",
+		ListCacheType:         "none",
+		NotFoundCacheType:     "none",
 		ShutdownTimeout:       60,
 		StashTimeout:          600,
+		ReloadInterval:        10,
 		SingleFlight: &SingleFlight{
 			Etcd:  &Etcd{"localhost:2379,localhost:22379,localhost:32379"},
 			Redis: &Redis{Endpoint: "127.0.0.1:6379", LockConfig: DefaultRedisLockConfig()},
@@ -215,6 +245,30 @@ func defaultConfig() *Config {
 				},
 			},
 		},
+		Queue: &Queue{
+			Workers:          5,
+			MaxAttempts:      5,
+			MinBackoff:       10,
+			MaxBackoff:       600,
+			RateLimitBackoff: 60,
+			Bolt:             &BoltQueue{Path: "/var/lib/athens/queue.db"},
+			Redis:            &Redis{Endpoint: "127.0.0.1:6379", LockConfig: DefaultRedisLockConfig()},
+		},
+		ListCache: &ListCache{
+			TTL:      60,
+			StaleTTL: 3600,
+			Redis:    &Redis{Endpoint: "127.0.0.1:6379", LockConfig: DefaultRedisLockConfig()},
+		},
+		NotFoundCache: &NotFoundCache{
+			TTL:   300,
+			Redis: &Redis{Endpoint: "127.0.0.1:6379", LockConfig: DefaultRedisLockConfig()},
+		},
+		VulnDB: &VulnDB{Action: "deny", Ignore: []string{}, Exceptions: []string{}},
+		VulnDBMirror: &VulnDBMirror{
+			UpstreamURL:  "https://vuln.go.dev",
+			SyncInterval: 3600,
+		},
+		Replication: &Replication{Workers: 2},
 	}
 }
 
@@ -227,6 +281,15 @@ func (c *Config) BasicAuth() (user, pass string, ok bool) {
 	return user, pass, ok
 }
 
+// AdminAuth returns AdminUser and AdminPass
+// and ok if neither of them are empty.
+func (c *Config) AdminAuth() (user, pass string, ok bool) {
+	user = c.AdminUser
+	pass = c.AdminPass
+	ok = user != "" && pass != ""
+	return user, pass, ok
+}
+
 // FilterOff returns true if the FilterFile is empty.
 func (c *Config) FilterOff() bool {
 	return c.FilterFile == ""
@@ -289,7 +352,7 @@ func ensurePortFormat(s string) string {
 
 func validateConfig(config Config) error {
 	validate := validator.New()
-	err := validate.StructExcept(config, "Storage", "Index")
+	err := validate.StructExcept(config, "Storage", "Index", "Queue", "ListCache", "NotFoundCache")
 	if err != nil {
 		return err
 	}
@@ -301,6 +364,22 @@ func validateConfig(config Config) error {
 	if err != nil {
 		return err
 	}
+	err = validateQueue(validate, config.QueueType, config.Queue)
+	if err != nil {
+		return err
+	}
+	err = validateListCache(validate, config.ListCacheType, config.ListCache)
+	if err != nil {
+		return err
+	}
+	err = validateNotFoundCache(validate, config.NotFoundCacheType, config.NotFoundCache)
+	if err != nil {
+		return err
+	}
+	err = validateReplication(validate, config.QueueType, config.Replication)
+	if err != nil {
+		return err
+	}
 	return nil
 }
 
@@ -312,6 +391,8 @@ func validateStorage(validate *validator.Validate, storageType string, config *S
 		return validate.Struct(config.Mongo)
 	case "disk":
 		return validate.Struct(config.Disk)
+	case "bolt":
+		return validate.Struct(config.Bolt)
 	case "minio":
 		return validate.Struct(config.Minio)
 	case "gcp":
@@ -322,11 +403,73 @@ func validateStorage(validate *validator.Validate, storageType string, config *S
 		return validate.Struct(config.AzureBlob)
 	case "external":
 		return validate.Struct(config.External)
+	case "tiered":
+		if err := validate.Struct(config.Tiered); err != nil {
+			return err
+		}
+		return validateStorage(validate, config.Tiered.Remote, config)
+	case "overlay":
+		return validateOverlay(validate, config)
 	default:
 		return fmt.Errorf("storage type %q is unknown", storageType)
 	}
 }
 
+func validateOverlay(validate *validator.Validate, config *Storage) error {
+	if err := validate.Struct(config.Overlay); err != nil {
+		return err
+	}
+	if !slices.Contains(config.Overlay.Layers, config.Overlay.Writable) {
+		return fmt.Errorf("the writable overlay layer %q is not one of the layers", config.Overlay.Writable)
+	}
+	for _, layer := range config.Overlay.Layers {
+		if path, ok := strings.CutPrefix(layer, "disk:"); ok {
+			if path == "" {
+				return fmt.Errorf("the overlay layer %q has no path", layer)
+			}
+			continue
+		}
+		if layer == "overlay" {
+			return fmt.Errorf("an overlay layer cannot be an overlay")
+		}
+		if err := validateStorage(validate, layer, config); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validateReplication(validate *validator.Validate, queueType string, config *Replication) error {
+	if config == nil || len(config.Targets) == 0 {
+		return nil
+	}
+	if queueType == "bolt" && config.BoltPath == "" {
+		return fmt.Errorf("replicating with a bolt queue needs a Replication.BoltPath")
+	}
+	// the replications queued in memory are lost on restarts,
+	// and only reconciling brings them back.
+	if (queueType == "" || queueType == "memory") && config.ReconcileInterval == 0 {
+		return fmt.Errorf("replicating with a memory queue needs a Replication.ReconcileInterval")
+	}
+	names := map[string]bool{}
+	for _, t := range config.Targets {
+		if t.Name == "" {
+			return fmt.Errorf("a replication target has no name")
+		}
+		if names[t.Name] {
+			return fmt.Errorf("the replication target %q is configured twice", t.Name)
+		}
+		names[t.Name] = true
+		if t.Storage == nil {
+			return fmt.Errorf("the replication target %q has no storage", t.Name)
+		}
+		if err := validateStorage(validate, t.StorageType, t.Storage); err != nil {
+			return fmt.Errorf("replication target %q: %w", t.Name, err)
+		}
+	}
+	return nil
+}
+
 func validateIndex(validate *validator.Validate, indexType string, config *Index) error {
 	switch indexType {
 	case "", "none", "memory":
@@ -340,6 +483,54 @@ func validateIndex(validate *validator.Validate, indexType string, config *Index
 	}
 }
 
+func validateQueue(validate *validator.Validate, queueType string, config *Queue) error {
+	if err := validate.StructExcept(config, "Bolt", "Redis"); err != nil {
+		return err
+	}
+	switch queueType {
+	case "", "memory":
+		return nil
+	case "bolt":
+		return validate.Struct(config.Bolt)
+	case "redis":
+		return validate.Var(config.Redis.Endpoint, "required")
+	default:
+		return fmt.Errorf("queue type %q is unknown", queueType)
+	}
+}
+
+func validateListCache(validate *validator.Validate, listCacheType string, config *ListCache) error {
+	switch listCacheType {
+	case "", "none":
+		return nil
+	case "memory":
+		return validate.StructExcept(config, "Redis")
+	case "redis":
+		if err := validate.StructExcept(config, "Redis"); err != nil {
+			return err
+		}
+		return validate.Var(config.Redis.Endpoint, "required")
+	default:
+		return fmt.Errorf("list cache type %q is unknown", listCacheType)
+	}
+}
+
+func validateNotFoundCache(validate *validator.Validate, notFoundCacheType string, config *NotFoundCache) error {
+	switch notFoundCacheType {
+	case "", "none":
+		return nil
+	case "memory":
+		return validate.StructExcept(config, "Redis")
+	case "redis":
+		if err := validate.StructExcept(config, "Redis"); err != nil {
+			return err
+		}
+		return validate.Var(config.Redis.Endpoint, "required")
+	default:
+		return fmt.Errorf("not found cache type %q is unknown", notFoundCacheType)
+	}
+}
+
 // GetConf accepts the path to a file, constructs an absolute path to the file,
 // and attempts to parse it into a Config struct.
 func GetConf(path string) (*Config, error) {
@@ -8,6 +8,7 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/go-playground/validator/v10"
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/go-cmp/cmp/cmpopts"
 	"github.com/kelseyhightower/envconfig"
@@ -53,6 +54,18 @@ func compareStorageConfigs(parsedStorage *Storage, expStorage *Storage, t *testi
 	if !eq {
 		t.Errorf("Parsed Example Storage configuration did not match expected values. Expected: %+v. Actual: %+v", expStorage.S3, parsedStorage.S3)
 	}
+	eq = cmp.Equal(parsedStorage.Tiered, expStorage.Tiered)
+	if !eq {
+		t.Errorf("Parsed Example Storage configuration did not match expected values. Expected: %+v. Actual: %+v", expStorage.Tiered, parsedStorage.Tiered)
+	}
+	eq = cmp.Equal(parsedStorage.Overlay, expStorage.Overlay)
+	if !eq {
+		t.Errorf("Parsed Example Storage configuration did not match expected values. Expected: %+v. Actual: %+v", expStorage.Overlay, parsedStorage.Overlay)
+	}
+	eq = cmp.Equal(parsedStorage.Bolt, expStorage.Bolt)
+	if !eq {
+		t.Errorf("Parsed Example Storage configuration did not match expected values. Expected: %+v. Actual: %+v", expStorage.Bolt, parsedStorage.Bolt)
+	}
 }
 
 func TestPortDefaultsCorrectly(t *testing.T) {
@@ -87,6 +100,8 @@ func TestEnvOverrides(t *testing.T) {
 		PprofPort:        ":3001",
 		BasicAuthUser:    "testuser",
 		BasicAuthPass:    "testpass",
+		AdminUser:        "testadmin",
+		AdminPass:        "testadminpass",
 		ForceSSL:         true,
 		ValidatorHook:    "testhook.io",
 		PathPrefix:       "prefix",
@@ -97,6 +112,47 @@ func TestEnvOverrides(t *testing.T) {
 		SingleFlight:     &SingleFlight{},
 		RobotsFile:       "robots.txt",
 		Index:            &Index{},
+		QueueType:        "bolt",
+		PrefetchDepth:    2,
+		ReloadInterval:   30,
+		Queue: &Queue{
+			Workers:          3,
+			MaxAttempts:      7,
+			MinBackoff:       1,
+			MaxBackoff:       30,
+			RateLimitBackoff: 120,
+			Bolt:             &BoltQueue{Path: "/tmp/athens/queue.db"},
+			Redis:            &Redis{Endpoint: "redis:6379", Password: "sekret", LockConfig: &RedisLockConfig{}},
+		},
+		ListCacheType: "memory",
+		ListCache: &ListCache{
+			TTL:      30,
+			StaleTTL: 600,
+			Redis:    &Redis{Endpoint: "redis:6379", Password: "sekret", LockConfig: &RedisLockConfig{}},
+		},
+		NotFoundCacheType: "redis",
+		NotFoundCache: &NotFoundCache{
+			TTL:   60,
+			Redis: &Redis{Endpoint: "redis:6379", Password: "sekret", LockConfig: &RedisLockConfig{}},
+		},
+		VulnDB: &VulnDB{
+			Path:        "/var/lib/athens/vulndb",
+			Action:      "flag",
+			MinSeverity: "high",
+			Ignore:      []string{"GO-2024-0001", "CVE-2024-0002"},
+			Exceptions:  []string{"github.com/acme/*", "github.com/b/c@GO-2024-0003"},
+			FilterList:  true,
+		},
+		VulnDBMirror: &VulnDBMirror{
+			Enabled:      true,
+			UpstreamURL:  "https://vulndb.example.com",
+			SyncInterval: 600,
+		},
+		Replication: &Replication{
+			Workers:           4,
+			ReconcileInterval: 3600,
+			BoltPath:          "/var/lib/athens/replication.db",
+		},
 	}
 
 	envVars := getEnvMap(expConf)
@@ -187,6 +243,16 @@ func TestStorageEnvOverrides(t *testing.T) {
 			Token:  "s3Token",
 			Bucket: "s3Bucket",
 		},
+		Tiered: &TieredConfig{
+			Remote:    "s3",
+			RootPath:  "/my/cache/path",
+			MaxSizeMB: 512,
+		},
+		Overlay: &OverlayConfig{
+			Layers:   []string{"disk:/my/bundle/path", "disk:/my/other/bundle/path", "s3"},
+			Writable: "s3",
+		},
+		Bolt: &BoltConfig{Path: "/my/storage.db"},
 	}
 	envVars := getEnvMap(&Config{Storage: expStorage})
 	for k, v := range envVars {
@@ -258,6 +324,16 @@ func TestParseExampleConfig(t *testing.T) {
 			ContainerName:             "MY_AZURE_BLOB_CONTAINER_NAME",
 		},
 		External: &External{URL: ""},
+		Tiered: &TieredConfig{
+			Remote:    "s3",
+			RootPath:  "/path/on/disk/cache",
+			MaxSizeMB: 10240,
+		},
+		Overlay: &OverlayConfig{
+			Layers:   []string{"disk:/path/on/disk/bundle", "s3"},
+			Writable: "s3",
+		},
+		Bolt: &BoltConfig{Path: "/path/on/disk/storage.db"},
 	}
 
 	expSingleFlight := &SingleFlight{
@@ -278,13 +354,17 @@ func TestParseExampleConfig(t *testing.T) {
 	}
 
 	expConf := &Config{
-		GoEnv:           "development",
-		LogLevel:        "debug",
-		LogFormat:       "plain",
-		GoBinary:        "go",
-		GoGetWorkers:    10,
-		ProtocolWorkers: 30,
-		CloudRuntime:    "none",
+		GoEnv:               "development",
+		LogLevel:            "debug",
+		LogFormat:           "plain",
+		GoBinary:            "go",
+		GoGetWorkers:        10,
+		GoCacheMaxSizeMB:    10240,
+		FetcherType:         "gobinary",
+		UpstreamMaxFailures: 5,
+		UpstreamCooldown:    60,
+		ProtocolWorkers:     30,
+		CloudRuntime:        "none",
 		TimeoutConf: TimeoutConf{
 			Timeout: 300,
 		},
@@ -297,6 +377,8 @@ func TestParseExampleConfig(t *testing.T) {
 		PprofPort:             ":3001",
 		BasicAuthUser:         "",
 		BasicAuthPass:         "",
+		AdminUser:             "",
+		AdminPass:             "",
 		Storage:               expStorage,
 		TraceExporterURL:      "http://localhost:4317",
 		TraceExporter:         "",
@@ -312,7 +394,35 @@ func TestParseExampleConfig(t *testing.T) {
 		IndexType:             "none",
 		ShutdownTimeout:       60,
 		StashTimeout:          600,
+		ReloadInterval:        10,
 		Index:                 &Index{},
+		QueueType:             "memory",
+		Queue: &Queue{
+			Workers:          5,
+			MaxAttempts:      5,
+			MinBackoff:       10,
+			MaxBackoff:       600,
+			RateLimitBackoff: 60,
+			Bolt:             &BoltQueue{Path: "/var/lib/athens/queue.db"},
+			Redis:            &Redis{Endpoint: "127.0.0.1:6379", LockConfig: DefaultRedisLockConfig()},
+		},
+		ListCacheType: "none",
+		ListCache: &ListCache{
+			TTL:      60,
+			StaleTTL: 3600,
+			Redis:    &Redis{Endpoint: "127.0.0.1:6379", LockConfig: DefaultRedisLockConfig()},
+		},
+		NotFoundCacheType: "none",
+		NotFoundCache: &NotFoundCache{
+			TTL:   300,
+			Redis: &Redis{Endpoint: "127.0.0.1:6379", LockConfig: DefaultRedisLockConfig()},
+		},
+		VulnDB: &VulnDB{Action: "deny", Ignore: []string{}, Exceptions: []string{}},
+		VulnDBMirror: &VulnDBMirror{
+			UpstreamURL:  "https://vuln.go.dev",
+			SyncInterval: 3600,
+		},
+		Replication: &Replication{Workers: 2},
 	}
 
 	absPath, err := filepath.Abs(testConfigFile(t))
@@ -345,6 +455,8 @@ func getEnvMap(config *Config) map[string]string {
 	envVars["ATHENS_PPROF_PORT"] = config.PprofPort
 	envVars["BASIC_AUTH_USER"] = config.BasicAuthUser
 	envVars["BASIC_AUTH_PASS"] = config.BasicAuthPass
+	envVars["ATHENS_ADMIN_USER"] = config.AdminUser
+	envVars["ATHENS_ADMIN_PASS"] = config.AdminPass
 	envVars["PROXY_FORCE_SSL"] = strconv.FormatBool(config.ForceSSL)
 	envVars["ATHENS_HOME_TEMPLATE_PATH"] = config.HomeTemplatePath
 	envVars["ATHENS_PROXY_VALIDATOR"] = config.ValidatorHook
@@ -387,6 +499,64 @@ func getEnvMap(config *Config) map[string]string {
 			envVars["AWS_FORCE_PATH_STYLE"] = strconv.FormatBool(storage.S3.ForcePathStyle)
 			envVars["ATHENS_S3_BUCKET_NAME"] = storage.S3.Bucket
 		}
+		if storage.Tiered != nil {
+			envVars["ATHENS_TIERED_STORAGE_REMOTE"] = storage.Tiered.Remote
+			envVars["ATHENS_TIERED_STORAGE_ROOT"] = storage.Tiered.RootPath
+			envVars["ATHENS_TIERED_STORAGE_MAX_SIZE_MB"] = strconv.Itoa(storage.Tiered.MaxSizeMB)
+		}
+		if storage.Overlay != nil {
+			envVars["ATHENS_OVERLAY_STORAGE_LAYERS"] = strings.Join(storage.Overlay.Layers, ",")
+			envVars["ATHENS_OVERLAY_STORAGE_WRITABLE"] = storage.Overlay.Writable
+		}
+		if storage.Bolt != nil {
+			envVars["ATHENS_BOLT_STORAGE_PATH"] = storage.Bolt.Path
+		}
+	}
+
+	envVars["ATHENS_QUEUE_TYPE"] = config.QueueType
+	envVars["ATHENS_PREFETCH_DEPTH"] = strconv.Itoa(config.PrefetchDepth)
+	envVars["ATHENS_RELOAD_INTERVAL"] = strconv.Itoa(config.ReloadInterval)
+	if queue := config.Queue; queue != nil {
+		envVars["ATHENS_QUEUE_WORKERS"] = strconv.Itoa(queue.Workers)
+		envVars["ATHENS_QUEUE_MAX_ATTEMPTS"] = strconv.Itoa(queue.MaxAttempts)
+		envVars["ATHENS_QUEUE_MIN_BACKOFF"] = strconv.Itoa(queue.MinBackoff)
+		envVars["ATHENS_QUEUE_MAX_BACKOFF"] = strconv.Itoa(queue.MaxBackoff)
+		envVars["ATHENS_QUEUE_RATE_LIMIT_BACKOFF"] = strconv.Itoa(queue.RateLimitBackoff)
+		if queue.Bolt != nil {
+			envVars["ATHENS_QUEUE_BOLT_PATH"] = queue.Bolt.Path
+		}
+		if queue.Redis != nil {
+			envVars["ATHENS_REDIS_ENDPOINT"] = queue.Redis.Endpoint
+			envVars["ATHENS_REDIS_PASSWORD"] = queue.Redis.Password
+		}
+	}
+
+	envVars["ATHENS_LIST_CACHE_TYPE"] = config.ListCacheType
+	if listCache := config.ListCache; listCache != nil {
+		envVars["ATHENS_LIST_CACHE_TTL"] = strconv.Itoa(listCache.TTL)
+		envVars["ATHENS_LIST_CACHE_STALE_TTL"] = strconv.Itoa(listCache.StaleTTL)
+	}
+	envVars["ATHENS_NOT_FOUND_CACHE_TYPE"] = config.NotFoundCacheType
+	if notFoundCache := config.NotFoundCache; notFoundCache != nil {
+		envVars["ATHENS_NOT_FOUND_CACHE_TTL"] = strconv.Itoa(notFoundCache.TTL)
+	}
+	if vulnDB := config.VulnDB; vulnDB != nil {
+		envVars["ATHENS_VULN_DB_PATH"] = vulnDB.Path
+		envVars["ATHENS_VULN_DB_ACTION"] = vulnDB.Action
+		envVars["ATHENS_VULN_DB_MIN_SEVERITY"] = vulnDB.MinSeverity
+		envVars["ATHENS_VULN_DB_IGNORE"] = strings.Join(vulnDB.Ignore, ",")
+		envVars["ATHENS_VULN_DB_EXCEPTIONS"] = strings.Join(vulnDB.Exceptions, ",")
+		envVars["ATHENS_VULN_DB_FILTER_LIST"] = strconv.FormatBool(vulnDB.FilterList)
+	}
+	if mirror := config.VulnDBMirror; mirror != nil {
+		envVars["ATHENS_VULN_DB_MIRROR_ENABLED"] = strconv.FormatBool(mirror.Enabled)
+		envVars["ATHENS_VULN_DB_MIRROR_UPSTREAM_URL"] = mirror.UpstreamURL
+		envVars["ATHENS_VULN_DB_MIRROR_SYNC_INTERVAL"] = strconv.Itoa(mirror.SyncInterval)
+	}
+	if replication := config.Replication; replication != nil {
+		envVars["ATHENS_REPLICATION_WORKERS"] = strconv.Itoa(replication.Workers)
+		envVars["ATHENS_REPLICATION_RECONCILE_INTERVAL"] = strconv.Itoa(replication.ReconcileInterval)
+		envVars["ATHENS_REPLICATION_BOLT_PATH"] = replication.BoltPath
 	}
 
 	singleFlight := config.SingleFlight
@@ -693,3 +863,17 @@ func testDecode(t *testing.T, tc decodeTestCase) {
 	}
 	require.Equal(t, tc.expected, config.GoBinaryEnvVars)
 }
+
+func TestValidateReplication(t *testing.T) {
+	validate := validator.New()
+	target := func(name string) *ReplicationTarget {
+		return &ReplicationTarget{Name: name, StorageType: "disk", Storage: &Storage{Disk: &DiskConfig{RootPath: "/dr"}}}
+	}
+	require.NoError(t, validateReplication(validate, "memory", &Replication{Workers: 1}))
+	require.NoError(t, validateReplication(validate, "memory", &Replication{Workers: 1, ReconcileInterval: 3600, Targets: []*ReplicationTarget{target("dr")}}))
+	require.Error(t, validateReplication(validate, "memory", &Replication{Workers: 1, Targets: []*ReplicationTarget{target("dr")}}))
+	require.NoError(t, validateReplication(validate, "redis", &Replication{Workers: 1, Targets: []*ReplicationTarget{target("dr")}}))
+	require.Error(t, validateReplication(validate, "bolt", &Replication{Workers: 1, Targets: []*ReplicationTarget{target("dr")}}))
+	require.Error(t, validateReplication(validate, "memory", &Replication{Workers: 1, ReconcileInterval: 3600, Targets: []*ReplicationTarget{target("dr"), target("dr")}}))
+	require.Error(t, validateReplication(validate, "memory", &Replication{Workers: 1, ReconcileInterval: 3600, Targets: []*ReplicationTarget{{Name: "dr", StorageType: "s3", Storage: &Storage{}}}}))
+}
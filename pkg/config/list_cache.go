@@ -0,0 +1,9 @@
+package config
+
+// ListCache is the config for the cache of the versions that upstream
+// lists for modules. The TTLs are in seconds.
+type ListCache struct {
+	TTL      int `envconfig:"ATHENS_LIST_CACHE_TTL"       validate:"min=0"`
+	StaleTTL int `envconfig:"ATHENS_LIST_CACHE_STALE_TTL" validate:"min=0"`
+	Redis    *Redis
+}
@@ -0,0 +1,8 @@
+package config
+
+// NotFoundCache is the config for the cache of the modules and
+// versions that upstream does not have. The TTL is in seconds.
+type NotFoundCache struct {
+	TTL   int `envconfig:"ATHENS_NOT_FOUND_CACHE_TTL" validate:"min=1"`
+	Redis *Redis
+}
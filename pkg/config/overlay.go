@@ -0,0 +1,11 @@
+package config
+
+// OverlayConfig specifies the properties required to merge several storage
+// backends in priority order. A layer is the type of a storage backend,
+// configured in its own section, or disk:{path} for a directory in the layout
+// of the Disk storage, such as a bundle of modules shipped to an air-gapped
+// site. Writable is the layer that versions are saved to.
+type OverlayConfig struct {
+	Layers   []string `envconfig:"ATHENS_OVERLAY_STORAGE_LAYERS"   validate:"min=1"`
+	Writable string   `envconfig:"ATHENS_OVERLAY_STORAGE_WRITABLE" validate:"required"`
+}
@@ -0,0 +1,19 @@
+package config
+
+// Queue is the config for the queue of module versions
+// that the async download modes stash in the background.
+// The backoffs are in seconds.
+type Queue struct {
+	Workers          int `envconfig:"ATHENS_QUEUE_WORKERS"            validate:"min=1"`
+	MaxAttempts      int `envconfig:"ATHENS_QUEUE_MAX_ATTEMPTS"       validate:"min=1"`
+	MinBackoff       int `envconfig:"ATHENS_QUEUE_MIN_BACKOFF"        validate:"min=0"`
+	MaxBackoff       int `envconfig:"ATHENS_QUEUE_MAX_BACKOFF"        validate:"gtefield=MinBackoff"`
+	RateLimitBackoff int `envconfig:"ATHENS_QUEUE_RATE_LIMIT_BACKOFF" validate:"min=0"`
+	Bolt             *BoltQueue
+	Redis            *Redis
+}
+
+// BoltQueue is the config for a queue kept in a bolt database.
+type BoltQueue struct {
+	Path string `envconfig:"ATHENS_QUEUE_BOLT_PATH" validate:"required"`
+}
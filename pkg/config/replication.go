@@ -0,0 +1,21 @@
+package config
+
+// Replication is the config for replicating the module versions that are
+// stashed to secondary storage backends. The versions to replicate are
+// queued in a queue of QueueType apart from the queue of the stashes,
+// which for bolt is the database at BoltPath. ReconcileInterval is in
+// seconds, zero never reconciles.
+type Replication struct {
+	Workers           int                  `envconfig:"ATHENS_REPLICATION_WORKERS"            validate:"min=1"`
+	ReconcileInterval int                  `envconfig:"ATHENS_REPLICATION_RECONCILE_INTERVAL" validate:"min=0"`
+	BoltPath          string               `envconfig:"ATHENS_REPLICATION_BOLT_PATH"`
+	Targets           []*ReplicationTarget `ignored:"true"`
+}
+
+// ReplicationTarget is a storage backend that module versions are
+// replicated to, whose Storage is configured as the one of Athens is.
+type ReplicationTarget struct {
+	Name        string
+	StorageType string
+	Storage     *Storage
+}
@@ -9,4 +9,7 @@ type Storage struct {
 	S3        *S3Config
 	AzureBlob *AzureBlobConfig
 	External  *External
+	Tiered    *TieredConfig
+	Overlay   *OverlayConfig
+	Bolt      *BoltConfig
 }
@@ -0,0 +1,9 @@
+package config
+
+// TieredConfig specifies the properties required to use a local disk cache
+// in front of a remote storage backend.
+type TieredConfig struct {
+	Remote    string `envconfig:"ATHENS_TIERED_STORAGE_REMOTE"      validate:"oneof=s3 gcp azureblob minio"`
+	RootPath  string `envconfig:"ATHENS_TIERED_STORAGE_ROOT"        validate:"required"`
+	MaxSizeMB int    `envconfig:"ATHENS_TIERED_STORAGE_MAX_SIZE_MB" validate:"min=1"`
+}
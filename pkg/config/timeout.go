@@ -21,3 +21,8 @@ func GetTimeoutDuration(timeout int) time.Duration {
 func (c *Config) StashTimeoutDuration() time.Duration {
 	return GetTimeoutDuration(c.StashTimeout)
 }
+
+// UpstreamCooldownDuration returns the upstream circuit breaker cool-down as time.Duration.
+func (c *Config) UpstreamCooldownDuration() time.Duration {
+	return GetTimeoutDuration(c.UpstreamCooldown)
+}
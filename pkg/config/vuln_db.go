@@ -0,0 +1,12 @@
+package config
+
+// VulnDB is the config for denying the module versions that an
+// offline Go vulnerability database has advisories for.
+type VulnDB struct {
+	Path        string   `envconfig:"ATHENS_VULN_DB_PATH"`
+	Action      string   `envconfig:"ATHENS_VULN_DB_ACTION"       validate:"oneof=deny flag"`
+	MinSeverity string   `envconfig:"ATHENS_VULN_DB_MIN_SEVERITY" validate:"omitempty,oneof=low moderate high critical"`
+	Ignore      []string `envconfig:"ATHENS_VULN_DB_IGNORE"`
+	Exceptions  []string `envconfig:"ATHENS_VULN_DB_EXCEPTIONS"`
+	FilterList  bool     `envconfig:"ATHENS_VULN_DB_FILTER_LIST"`
+}
@@ -0,0 +1,9 @@
+package config
+
+// VulnDBMirror is the config for serving a mirror of a Go vulnerability
+// database at /vulndb, which is kept in storage. SyncInterval is in seconds.
+type VulnDBMirror struct {
+	Enabled      bool   `envconfig:"ATHENS_VULN_DB_MIRROR_ENABLED"`
+	UpstreamURL  string `envconfig:"ATHENS_VULN_DB_MIRROR_UPSTREAM_URL"`
+	SyncInterval int    `envconfig:"ATHENS_VULN_DB_MIRROR_SYNC_INTERVAL" validate:"min=1"`
+}
@@ -2,15 +2,19 @@ package download
 
 import (
 	"context"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"testing/iotest"
 
 	"github.com/gomods/athens/pkg/download/mode"
 	"github.com/gomods/athens/pkg/errors"
 	"github.com/gomods/athens/pkg/log"
 	"github.com/gomods/athens/pkg/storage"
 	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/require"
 )
 
 func TestRedirect(t *testing.T) {
@@ -44,6 +48,63 @@ func TestRedirect(t *testing.T) {
 	}
 }
 
+func TestZipCopyError(t *testing.T) {
+	path := "/github.com/gomods/athens/@v/v0.4.0.zip"
+	serve := func(zip io.Reader, size int64) *httptest.ResponseRecorder {
+		r := mux.NewRouter()
+		RegisterHandlers(r, &HandlerOpts{
+			Protocol:     zipProtocol{zip: storage.NewSizer(io.NopCloser(zip), size)},
+			Logger:       log.NoOpLogger(),
+			DownloadFile: &mode.DownloadFile{Mode: mode.Sync},
+		})
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w
+	}
+	broken := iotest.ErrReader(errors.E("read", "disk failure"))
+
+	// nothing was written, so the error is reported.
+	w := serve(broken, 0)
+	require.Equal(t, http.StatusInternalServerError, w.Code)
+
+	// a truncated zip with a Content-Length is left to the client to detect.
+	w = serve(io.MultiReader(strings.NewReader("zip"), broken), 10)
+	require.Equal(t, "zip", w.Body.String())
+
+	// a truncated zip that is streamed is aborted.
+	require.PanicsWithValue(t, http.ErrAbortHandler, func() {
+		serve(io.MultiReader(strings.NewReader("zip"), broken), 0)
+	})
+}
+
+func TestZipCopyErrorClosesConnection(t *testing.T) {
+	broken := io.MultiReader(strings.NewReader("zip"), iotest.ErrReader(errors.E("read", "disk failure")))
+	r := mux.NewRouter()
+	RegisterHandlers(r, &HandlerOpts{
+		Protocol:     zipProtocol{zip: storage.NewSizer(io.NopCloser(broken), 0)},
+		Logger:       log.NoOpLogger(),
+		DownloadFile: &mode.DownloadFile{Mode: mode.Sync},
+	})
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/github.com/gomods/athens/@v/v0.4.0.zip")
+	require.NoError(t, err)
+	defer func() { _ = resp.Body.Close() }()
+	// the connection is closed before the end of the chunked body.
+	_, err = io.ReadAll(resp.Body)
+	require.ErrorIs(t, err, io.ErrUnexpectedEOF)
+}
+
+type zipProtocol struct {
+	Protocol
+	zip storage.SizeReadCloser
+}
+
+func (p zipProtocol) Zip(context.Context, string, string) (storage.SizeReadCloser, error) {
+	return p.zip, nil
+}
+
 type mockProtocol struct {
 	Protocol
 }
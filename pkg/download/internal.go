@@ -0,0 +1,49 @@
+package download
+
+import (
+	"context"
+
+	"github.com/gomods/athens/pkg/errors"
+	"github.com/gomods/athens/pkg/storage"
+)
+
+// hideInternal answers a KindNotFound error for the modules that
+// Athens keeps in storage for its own use, see storage.IsInternal.
+type hideInternal struct {
+	Protocol
+}
+
+func (p hideInternal) List(ctx context.Context, mod string) ([]string, error) {
+	if storage.IsInternal(mod) {
+		return nil, errors.E("download.List", errors.M(mod), errors.KindNotFound)
+	}
+	return p.Protocol.List(ctx, mod)
+}
+
+func (p hideInternal) Info(ctx context.Context, mod, ver string) ([]byte, error) {
+	if storage.IsInternal(mod) {
+		return nil, errors.E("download.Info", errors.M(mod), errors.V(ver), errors.KindNotFound)
+	}
+	return p.Protocol.Info(ctx, mod, ver)
+}
+
+func (p hideInternal) Latest(ctx context.Context, mod string) (*storage.RevInfo, error) {
+	if storage.IsInternal(mod) {
+		return nil, errors.E("download.Latest", errors.M(mod), errors.KindNotFound)
+	}
+	return p.Protocol.Latest(ctx, mod)
+}
+
+func (p hideInternal) GoMod(ctx context.Context, mod, ver string) ([]byte, error) {
+	if storage.IsInternal(mod) {
+		return nil, errors.E("download.GoMod", errors.M(mod), errors.V(ver), errors.KindNotFound)
+	}
+	return p.Protocol.GoMod(ctx, mod, ver)
+}
+
+func (p hideInternal) Zip(ctx context.Context, mod, ver string) (storage.SizeReadCloser, error) {
+	if storage.IsInternal(mod) {
+		return nil, errors.E("download.Zip", errors.M(mod), errors.V(ver), errors.KindNotFound)
+	}
+	return p.Protocol.Zip(ctx, mod, ver)
+}
@@ -0,0 +1,30 @@
+package download
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/gomods/athens/pkg/errors"
+	"github.com/gomods/athens/pkg/storage/mem"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHideInternal(t *testing.T) {
+	ctx := t.Context()
+	const mod, ver = "athens.invalid/vulndb", "v0.0.0-20240201000000-000000000000"
+	s, err := mem.NewStorage()
+	require.NoError(t, err)
+	require.NoError(t, s.Save(ctx, mod, ver, []byte("module "+mod), bytes.NewReader([]byte("zip")), nil, []byte("{}")))
+	dp := New(&Opts{Storage: s, Lister: &mockLister{}, NetworkMode: Offline})
+
+	_, err = dp.List(ctx, mod)
+	require.True(t, errors.IsNotFoundErr(err))
+	_, err = dp.Latest(ctx, mod)
+	require.True(t, errors.IsNotFoundErr(err))
+	_, err = dp.Info(ctx, mod, ver)
+	require.True(t, errors.IsNotFoundErr(err))
+	_, err = dp.GoMod(ctx, mod, ver)
+	require.True(t, errors.IsNotFoundErr(err))
+	_, err = dp.Zip(ctx, mod, ver)
+	require.True(t, errors.IsNotFoundErr(err))
+}
@@ -0,0 +1,121 @@
+package download
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/gomods/athens/pkg/errors"
+	"github.com/gomods/athens/pkg/log"
+	"github.com/gomods/athens/pkg/storage"
+	"golang.org/x/mod/modfile"
+	modpath "golang.org/x/mod/module"
+	"golang.org/x/mod/semver"
+)
+
+// latestFromStorage resolves the latest version of mod among the versions
+// in s, as the go command would: the highest release, or else the highest
+// prerelease, or else the pseudo-version with the latest commit time.
+// Versions retracted by the go.mod of that version are left out, unless
+// every version is retracted.
+func latestFromStorage(ctx context.Context, s storage.Backend, mod string) (*storage.RevInfo, error) {
+	const op errors.Op = "download.latestFromStorage"
+	vers, err := s.List(ctx, mod)
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+	latest, err := pickLatest(ctx, s, mod, vers)
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+
+	// like the go command, read retractions from the go.mod
+	// of the latest version, whether it is retracted or not.
+	gomod, err := s.GoMod(ctx, mod, latest.Version)
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+	f, err := modfile.ParseLax("go.mod", gomod, nil)
+	if err != nil {
+		log.EntryFromContext(ctx).Debugf("ignoring the retractions of %s@%s: %v", mod, latest.Version, err)
+		return latest, nil
+	}
+	var kept []string
+	for _, v := range vers {
+		if !retracted(f.Retract, v) {
+			kept = append(kept, v)
+		}
+	}
+	if len(kept) == 0 || len(kept) == len(vers) {
+		return latest, nil
+	}
+	latest, err = pickLatest(ctx, s, mod, kept)
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+	return latest, nil
+}
+
+func pickLatest(ctx context.Context, s storage.Backend, mod string, vers []string) (*storage.RevInfo, error) {
+	const op errors.Op = "download.pickLatest"
+	var release, prerelease string
+	var pseudo *storage.RevInfo
+	for _, v := range vers {
+		switch {
+		case !semver.IsValid(v):
+			continue
+		case modpath.IsPseudoVersion(v):
+			info, err := storedInfo(ctx, s, mod, v)
+			if err != nil {
+				return nil, errors.E(op, err)
+			}
+			if pseudo == nil || info.Time.After(pseudo.Time) ||
+				(info.Time.Equal(pseudo.Time) && semver.Compare(v, pseudo.Version) > 0) {
+				pseudo = info
+			}
+		case semver.Prerelease(v) != "":
+			if prerelease == "" || semver.Compare(v, prerelease) > 0 {
+				prerelease = v
+			}
+		default:
+			if release == "" || semver.Compare(v, release) > 0 {
+				release = v
+			}
+		}
+	}
+	switch {
+	case release != "":
+		return storedInfo(ctx, s, mod, release)
+	case prerelease != "":
+		return storedInfo(ctx, s, mod, prerelease)
+	case pseudo != nil:
+		return pseudo, nil
+	}
+	return nil, errors.E(op, errors.M(mod), "no versions in storage", errors.KindNotFound)
+}
+
+func storedInfo(ctx context.Context, s storage.Backend, mod, ver string) (*storage.RevInfo, error) {
+	const op errors.Op = "download.storedInfo"
+	b, err := s.Info(ctx, mod, ver)
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+	var info storage.RevInfo
+	if err := json.Unmarshal(b, &info); err != nil {
+		return nil, errors.E(op, errors.M(mod), errors.V(ver), err)
+	}
+	// the version is what the go command asked for, so
+	// it cannot be missing from the stored .info.
+	if info.Version == "" {
+		info.Version = ver
+	}
+	return &info, nil
+}
+
+func retracted(rs []*modfile.Retract, ver string) bool {
+	for _, r := range rs {
+		if semver.Compare(ver, r.Low) >= 0 && semver.Compare(ver, r.High) <= 0 {
+			return true
+		}
+	}
+	return false
+}
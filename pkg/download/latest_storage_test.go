@@ -0,0 +1,127 @@
+package download
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/gomods/athens/pkg/errors"
+	"github.com/gomods/athens/pkg/storage/mem"
+	"github.com/stretchr/testify/require"
+)
+
+type storedVersion struct {
+	version string
+	time    time.Time
+	gomod   string
+}
+
+func TestLatestFromStorage(t *testing.T) {
+	const mod = "github.com/athens-artifacts/happy-path"
+	t0 := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name        string
+		networkMode string
+		upstreamErr error
+		stored      []storedVersion
+		want        string
+		wantErr     bool
+	}{
+		{
+			name:        "highest release",
+			networkMode: Offline,
+			stored: []storedVersion{
+				{version: "v1.0.0"},
+				{version: "v1.2.0"},
+				{version: "v1.10.0-rc.1"},
+				{version: "v1.1.0"},
+			},
+			want: "v1.2.0",
+		},
+		{
+			name:        "highest prerelease",
+			networkMode: Offline,
+			stored: []storedVersion{
+				{version: "v1.0.0-rc.1"},
+				{version: "v1.0.0-rc.2"},
+				{version: "v1.0.1-0.20240102000000-abcdefabcdef", time: t0.Add(24 * time.Hour)},
+			},
+			want: "v1.0.0-rc.2",
+		},
+		{
+			name:        "latest pseudo-version by commit time",
+			networkMode: Offline,
+			stored: []storedVersion{
+				{version: "v0.0.0-20240103000000-abcdefabcdef", time: t0.Add(48 * time.Hour)},
+				{version: "v0.0.0-20240102000000-bcdefabcdefa", time: t0.Add(72 * time.Hour)},
+			},
+			want: "v0.0.0-20240102000000-bcdefabcdefa",
+		},
+		{
+			name:        "retracted",
+			networkMode: Offline,
+			stored: []storedVersion{
+				{version: "v1.0.0"},
+				{version: "v1.1.0"},
+				{version: "v1.2.0", gomod: "module " + mod + "\n\nretract [v1.1.0, v1.2.0]\n"},
+			},
+			want: "v1.0.0",
+		},
+		{
+			name:        "all retracted",
+			networkMode: Offline,
+			stored: []storedVersion{
+				{version: "v1.0.0"},
+				{version: "v1.1.0", gomod: "module " + mod + "\n\nretract [v1.0.0, v1.1.0]\n"},
+			},
+			want: "v1.1.0",
+		},
+		{
+			name:        "nothing stored",
+			networkMode: Offline,
+			wantErr:     true,
+		},
+		{
+			name:        "fallback",
+			networkMode: Fallback,
+			upstreamErr: errors.E("test", "unexpected error"),
+			stored:      []storedVersion{{version: "v1.0.0"}},
+			want:        "v1.0.0",
+		},
+		{
+			name:        "fallback with nothing stored",
+			networkMode: Fallback,
+			upstreamErr: errors.E("test", "unexpected error"),
+			wantErr:     true,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := t.Context()
+			s, err := mem.NewStorage()
+			require.NoError(t, err)
+			for _, v := range tc.stored {
+				gomod := v.gomod
+				if gomod == "" {
+					gomod = "module " + mod + "\n"
+				}
+				info := fmt.Sprintf(`{"Version":%q,"Time":%q}`, v.version, v.time.Format(time.RFC3339))
+				require.NoError(t, s.Save(ctx, mod, v.version, []byte(gomod), bytes.NewReader([]byte("zip")), nil, []byte(info)))
+			}
+			ml := &mockLister{err: tc.upstreamErr}
+			dp := &protocol{storage: s, lister: ml, networkMode: tc.networkMode}
+
+			info, err := dp.Latest(ctx, mod)
+			if tc.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.want, info.Version)
+			if tc.networkMode == Offline {
+				require.False(t, ml.called, "upstream lister must not be called in offline mode")
+			}
+		})
+	}
+}
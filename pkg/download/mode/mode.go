@@ -6,6 +6,8 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/gomods/athens/pkg/errors"
 	"github.com/gomods/athens/pkg/paths"
@@ -30,8 +32,12 @@ const (
 
 // DownloadFile represents a custom HCL format of
 // how to handle module@version requests that are
-// not found in storage.
+// not found in storage. Its settings can be replaced
+// with Update while it is in use, so they should only
+// be read through its methods once it is shared.
 type DownloadFile struct {
+	mu sync.RWMutex
+
 	Mode        Mode            `hcl:"mode"`
 	DownloadURL string          `hcl:"downloadURL"`
 	Paths       []*DownloadPath `hcl:"download,block"`
@@ -43,6 +49,21 @@ type DownloadPath struct {
 	Pattern     string `hcl:"pattern,label"`
 	Mode        Mode   `hcl:"mode"`
 	DownloadURL string `hcl:"downloadURL,optional"`
+	// ListTTL, in seconds, overrides how long the upstream versions
+	// of the matching modules are cached. 0 disables the cache.
+	ListTTL *int `hcl:"listTTL,optional"`
+	// NetworkMode overrides the NetworkMode of the matching modules.
+	NetworkMode string `hcl:"networkMode,optional"`
+	// Timeout, in seconds, overrides how long listing the
+	// upstream versions of the matching modules may take.
+	Timeout *int `hcl:"timeout,optional"`
+	// GoEnv holds KEY=VALUE environment variables, such as GOFLAGS or
+	// GOPRIVATE, that the go command gets for the matching modules on
+	// top of GoBinaryEnvVars.
+	GoEnv []string `hcl:"goEnv,optional"`
+	// StashTimeout, in seconds, overrides how long stashing
+	// a version of the matching modules may take.
+	StashTimeout *int `hcl:"stashTimeout,optional"`
 }
 
 // NewFile takes a mode and returns a DownloadFile.
@@ -108,16 +129,45 @@ func (d *DownloadFile) validate() error {
 		default:
 			return errors.E(op, fmt.Errorf("unrecognized mode for %v: %v", p.Pattern, p.Mode))
 		}
+		if p.ListTTL != nil && *p.ListTTL < 0 {
+			return errors.E(op, fmt.Errorf("negative listTTL for %v: %v", p.Pattern, *p.ListTTL))
+		}
+		switch p.NetworkMode {
+		case "", "strict", "offline", "fallback":
+		default:
+			return errors.E(op, fmt.Errorf("unrecognized networkMode for %v: %v", p.Pattern, p.NetworkMode))
+		}
+		if p.Timeout != nil && *p.Timeout <= 0 {
+			return errors.E(op, fmt.Errorf("timeout for %v is not positive: %v", p.Pattern, *p.Timeout))
+		}
+		if p.StashTimeout != nil && *p.StashTimeout <= 0 {
+			return errors.E(op, fmt.Errorf("stashTimeout for %v is not positive: %v", p.Pattern, *p.StashTimeout))
+		}
+		for _, e := range p.GoEnv {
+			if k, _, ok := strings.Cut(e, "="); !ok || k == "" {
+				return errors.E(op, fmt.Errorf("goEnv for %v is not KEY=VALUE: %q", p.Pattern, e))
+			}
+		}
 	}
 	return nil
 }
 
+// Update replaces the settings of d with the settings
+// of other, which must not be used afterwards.
+func (d *DownloadFile) Update(other *DownloadFile) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.Mode, d.DownloadURL, d.Paths = other.Mode, other.DownloadURL, other.Paths
+}
+
 // Match returns the Mode that matches the given
 // module. A pattern is prioritized by order in
 // which it appears in the HCL file, while the
 // default Mode will be returned if no patterns
 // exist or match.
 func (d *DownloadFile) Match(mod string) Mode {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
 	for _, p := range d.Paths {
 		if paths.MatchesPattern(p.Pattern, mod) {
 			return p.Mode
@@ -130,6 +180,8 @@ func (d *DownloadFile) Match(mod string) Mode {
 // to the given module. If no pattern matches,
 // the top level downloadURL is returned.
 func (d *DownloadFile) URL(mod string) string {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
 	for _, p := range d.Paths {
 		if paths.MatchesPattern(p.Pattern, mod) {
 			if p.DownloadURL != "" {
@@ -139,3 +191,63 @@ func (d *DownloadFile) URL(mod string) string {
 	}
 	return d.DownloadURL
 }
+
+// ListTTL returns how long the upstream versions of the given
+// module are cached. The first pattern that matches the module
+// and sets a listTTL wins, while def is returned otherwise.
+func (d *DownloadFile) ListTTL(mod string, def time.Duration) time.Duration {
+	return d.seconds(mod, def, func(p *DownloadPath) *int { return p.ListTTL })
+}
+
+// NetworkMode returns the network mode of the given module. The first
+// pattern that matches the module and sets a networkMode wins, while
+// def is returned otherwise.
+func (d *DownloadFile) NetworkMode(mod, def string) string {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	for _, p := range d.Paths {
+		if p.NetworkMode != "" && paths.MatchesPattern(p.Pattern, mod) {
+			return p.NetworkMode
+		}
+	}
+	return def
+}
+
+// Timeout returns how long listing the upstream versions of the
+// given module may take. The first pattern that matches the module
+// and sets a timeout wins, while def is returned otherwise.
+func (d *DownloadFile) Timeout(mod string, def time.Duration) time.Duration {
+	return d.seconds(mod, def, func(p *DownloadPath) *int { return p.Timeout })
+}
+
+// StashTimeout returns how long stashing a version of the given
+// module may take. The first pattern that matches the module and
+// sets a stashTimeout wins, while def is returned otherwise.
+func (d *DownloadFile) StashTimeout(mod string, def time.Duration) time.Duration {
+	return d.seconds(mod, def, func(p *DownloadPath) *int { return p.StashTimeout })
+}
+
+// GoEnv returns the environment variables that the go command gets
+// for the given module on top of the global ones. The first pattern
+// that matches the module and sets a goEnv wins.
+func (d *DownloadFile) GoEnv(mod string) []string {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	for _, p := range d.Paths {
+		if p.GoEnv != nil && paths.MatchesPattern(p.Pattern, mod) {
+			return p.GoEnv
+		}
+	}
+	return nil
+}
+
+func (d *DownloadFile) seconds(mod string, def time.Duration, field func(*DownloadPath) *int) time.Duration {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	for _, p := range d.Paths {
+		if s := field(p); s != nil && paths.MatchesPattern(p.Pattern, mod) {
+			return time.Duration(*s) * time.Second
+		}
+	}
+	return def
+}
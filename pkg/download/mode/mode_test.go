@@ -1,8 +1,10 @@
 package mode
 
 import (
+	"encoding/base64"
 	"fmt"
 	"testing"
+	"time"
 )
 
 var testCases = []struct {
@@ -152,3 +154,111 @@ func TestNewFile_err(t *testing.T) {
 		})
 	}
 }
+
+func TestListTTL(t *testing.T) {
+	hcl := `
+mode = "sync"
+downloadURL = ""
+
+download "github.com/gomods/*" {
+    mode = "sync"
+}
+
+download "github.com/gomods/athens" {
+    mode = "sync"
+    listTTL = 0
+}
+
+download "github.com/*" {
+    mode = "sync"
+    listTTL = 3600
+}
+`
+	df, err := NewFile(Mode("custom:"+base64.StdEncoding.EncodeToString([]byte(hcl))), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for mod, want := range map[string]time.Duration{
+		"github.com/gomods/athens": 0,
+		"github.com/gomods/other":  time.Hour,
+		"github.com/pkg/errors":    time.Hour,
+		"golang.org/x/mod":         time.Minute,
+	} {
+		if got := df.ListTTL(mod, time.Minute); got != want {
+			t.Errorf("expected the listTTL of %s to be %v but got %v", mod, want, got)
+		}
+	}
+}
+
+func TestFetchSettings(t *testing.T) {
+	hcl := `
+mode = "sync"
+downloadURL = ""
+
+download "git.mycompany.com/*" {
+    mode = "sync"
+    networkMode = "strict"
+    timeout = 600
+    stashTimeout = 1200
+    goEnv = ["GOFLAGS=-insecure", "GOPRIVATE=git.mycompany.com"]
+}
+
+download "github.com/*" {
+    mode = "sync"
+    networkMode = "fallback"
+}
+`
+	df, err := NewFile(Mode("custom:"+base64.StdEncoding.EncodeToString([]byte(hcl))), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		mod          string
+		networkMode  string
+		timeout      time.Duration
+		stashTimeout time.Duration
+		goEnv        int
+	}{
+		{"git.mycompany.com/team/lib", "strict", 10 * time.Minute, 20 * time.Minute, 2},
+		{"github.com/pkg/errors", "fallback", time.Minute, 5 * time.Minute, 0},
+		{"golang.org/x/mod", "offline", time.Minute, 5 * time.Minute, 0},
+	} {
+		if got := df.NetworkMode(tc.mod, "offline"); got != tc.networkMode {
+			t.Errorf("expected the networkMode of %s to be %q but got %q", tc.mod, tc.networkMode, got)
+		}
+		if got := df.Timeout(tc.mod, time.Minute); got != tc.timeout {
+			t.Errorf("expected the timeout of %s to be %v but got %v", tc.mod, tc.timeout, got)
+		}
+		if got := df.StashTimeout(tc.mod, 5*time.Minute); got != tc.stashTimeout {
+			t.Errorf("expected the stashTimeout of %s to be %v but got %v", tc.mod, tc.stashTimeout, got)
+		}
+		if got := df.GoEnv(tc.mod); len(got) != tc.goEnv {
+			t.Errorf("expected %d goEnv variables for %s but got %v", tc.goEnv, tc.mod, got)
+		}
+	}
+
+	for _, invalid := range []string{
+		`networkMode = "sometimes"`,
+		`timeout = 0`,
+		`stashTimeout = -1`,
+		`goEnv = ["GOFLAGS"]`,
+	} {
+		hcl := "mode = \"sync\"\ndownloadURL = \"\"\ndownload \"github.com/*\" {\n    mode = \"sync\"\n    " + invalid + "\n}\n"
+		if _, err := NewFile(Mode("custom:"+base64.StdEncoding.EncodeToString([]byte(hcl))), ""); err == nil {
+			t.Errorf("expected %s to be rejected", invalid)
+		}
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	df := &DownloadFile{Mode: Sync, Paths: []*DownloadPath{
+		{Pattern: "github.com/gomods/*", Mode: None},
+	}}
+	df.Update(&DownloadFile{Mode: Redirect, DownloadURL: "gomods.io"})
+	if got := df.Match("github.com/gomods/athens"); got != Redirect {
+		t.Fatalf("expected the updated mode to be %q but got %q", Redirect, got)
+	}
+	if got := df.URL("github.com/gomods/athens"); got != "gomods.io" {
+		t.Fatalf("expected the updated DownloadURL to be %q but got %q", "gomods.io", got)
+	}
+}
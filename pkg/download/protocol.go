@@ -12,6 +12,7 @@ import (
 	"github.com/gomods/athens/pkg/log"
 	"github.com/gomods/athens/pkg/module"
 	"github.com/gomods/athens/pkg/observ"
+	"github.com/gomods/athens/pkg/queue"
 	"github.com/gomods/athens/pkg/requestid"
 	"github.com/gomods/athens/pkg/stash"
 	"github.com/gomods/athens/pkg/storage"
@@ -46,6 +47,19 @@ type Opts struct {
 	Lister       module.UpstreamLister
 	DownloadFile *mode.DownloadFile
 	NetworkMode  string
+	// ModStasher, if set, stashes only the .info and .mod files
+	// on .info and .mod misses. The zip is stashed on a .zip miss.
+	ModStasher stash.ModStasher
+	// ZipStreams, if set, lets a .zip miss in sync mode be served while the
+	// zip is being stashed. The stasher's fetcher must be wrapped by it.
+	ZipStreams *stash.ZipStreams
+	// Queue, if set, holds the versions that the async modes stash in the
+	// background, so that their stash is retried and survives restarts.
+	// Whole versions are queued, including their zip.
+	Queue queue.Queue
+	// Quarantine, if set, leaves the versions that it holds
+	// back out of the version lists and @latest.
+	Quarantine *module.QuarantineChecker
 }
 
 // NetworkMode constants.
@@ -64,7 +78,7 @@ func New(opts *Opts, wrappers ...Wrapper) Protocol {
 	if opts.DownloadFile == nil {
 		opts.DownloadFile = &mode.DownloadFile{Mode: mode.Sync}
 	}
-	var p Protocol = &protocol{opts.DownloadFile, opts.Storage, opts.Stasher, opts.Lister, opts.NetworkMode}
+	var p Protocol = hideInternal{&protocol{opts.DownloadFile, opts.Storage, opts.Stasher, opts.Lister, opts.NetworkMode, opts.ModStasher, opts.ZipStreams, opts.Queue, opts.Quarantine}}
 	for _, w := range wrappers {
 		p = w(p)
 	}
@@ -72,20 +86,38 @@ func New(opts *Opts, wrappers ...Wrapper) Protocol {
 	return p
 }
 
+// stashFunc is the signature of stash.Stasher's Stash.
+type stashFunc func(ctx context.Context, mod, ver string) (string, error)
+
 type protocol struct {
 	df          *mode.DownloadFile
 	storage     storage.Backend
 	stasher     stash.Stasher
 	lister      module.UpstreamLister
 	networkMode string
+	modStasher  stash.ModStasher
+	zipStreams  *stash.ZipStreams
+	queue       queue.Queue
+	quarantine  *module.QuarantineChecker
 }
 
 func (p *protocol) List(ctx context.Context, mod string) ([]string, error) {
+	vers, rev, err := p.list(ctx, mod)
+	if err != nil || p.quarantine == nil {
+		return vers, err
+	}
+	p.quarantine.Remember(mod, rev)
+	return p.quarantine.Filter(ctx, mod, vers), nil
+}
+
+// list also returns the latest version that the lister found, if any.
+func (p *protocol) list(ctx context.Context, mod string) ([]string, *storage.RevInfo, error) {
 	const op errors.Op = "protocol.List"
 	ctx, span := observ.StartSpan(ctx, op.String())
 	defer span.End()
 
 	var strList, goList []string
+	var goRev *storage.RevInfo
 	var sErr, goErr error
 	var wg sync.WaitGroup
 
@@ -100,13 +132,14 @@ func (p *protocol) List(ctx context.Context, mod string) ([]string, error) {
 
 		UnionLister(listers ...Lister): combines any number of listers.
 	*/
+	networkMode := p.networkModeOf(mod)
 	wg.Go(func() {
 		strList, sErr = p.storage.List(ctx, mod)
 	})
 
-	if p.networkMode != Offline {
+	if networkMode != Offline {
 		wg.Go(func() {
-			_, goList, goErr = p.lister.List(ctx, mod)
+			goRev, goList, goErr = p.lister.List(ctx, mod)
 		})
 	}
 
@@ -115,25 +148,25 @@ func (p *protocol) List(ctx context.Context, mod string) ([]string, error) {
 	// if we got an unexpected storage err then we can not guarantee that the end result contains all versions
 	// a tag or repo could have been deleted
 	if sErr != nil {
-		return nil, errors.E(op, sErr)
+		return nil, nil, errors.E(op, sErr)
 	}
 
 	// if we're in offline mode, just return what came from storage.
-	if p.networkMode == Offline {
-		return strList, nil
+	if networkMode == Offline {
+		return strList, nil, nil
 	}
 
 	// if i.e. github is unavailable we should fail as well so that the behavior of the proxy is stable.
 	// otherwise we will get different results the next time because i.e. GH is up again
 	isUnexpGoErr := goErr != nil && !errors.IsRepoNotFoundErr(goErr)
-	if isUnexpGoErr && p.networkMode == Strict {
-		return nil, errors.E(op, goErr)
+	if isUnexpGoErr && networkMode == Strict {
+		return nil, nil, errors.E(op, goErr)
 	}
 
 	// if we're in fallback mode, and VCS is down, just return what we have in storage,
 	// don't remove any pseudo versions.
-	if isUnexpGoErr && p.networkMode == Fallback {
-		return strList, nil
+	if isUnexpGoErr && networkMode == Fallback {
+		return strList, nil, nil
 	}
 
 	isRepoNotFoundErr := goErr != nil && errors.IsRepoNotFoundErr(goErr)
@@ -141,7 +174,7 @@ func (p *protocol) List(ctx context.Context, mod string) ([]string, error) {
 	// if storage has no versions, and the repo was deleted/not-found, we know for sure
 	// there are no versions that Athens can serve, so just return an error.
 	if isRepoNotFoundErr && storageEmpty {
-		return nil, errors.E(op, errors.M(mod), errors.KindNotFound, goErr)
+		return nil, nil, errors.E(op, errors.M(mod), errors.KindNotFound, goErr)
 	}
 
 	strListSemVers := removePseudoVersions(strList)
@@ -150,7 +183,7 @@ func (p *protocol) List(ctx context.Context, mod string) ([]string, error) {
 	// we should only do that if exclusively pseudo-versions have been saved
 	// otherwise @latest would not return the latest stable version but latest commit
 	if isRepoNotFoundErr && len(strListSemVers) == 0 {
-		return strList, nil
+		return strList, nil, nil
 	}
 	// if the repo exists we have to filter out pseudo versions to prevent following scenario:
 	// user does go get github.com/my/mod
@@ -158,7 +191,7 @@ func (p *protocol) List(ctx context.Context, mod string) ([]string, error) {
 	// Athens saves the pseudo version x1
 	// from now on every time user runs go get github.com/my/mod she/he will get pseudo version x1 even if a newer version x2 exists
 	// this is because /list returns non-empty list of versions (x1) and so /latest wont get hit
-	return union(goList, strListSemVers), nil
+	return union(goList, strListSemVers), goRev, nil
 }
 
 var pseudoVersionRE = regexp.MustCompile(`^v[0-9]+\.(0\.0-|\d+\.\d+-([^+]*\.)?0\.)\d{14}-[A-Za-z0-9]+(\+incompatible)?$`)
@@ -176,15 +209,35 @@ func removePseudoVersions(allVersions []string) []string {
 }
 
 func (p *protocol) Latest(ctx context.Context, mod string) (*storage.RevInfo, error) {
+	lr, err := p.latest(ctx, mod)
+	if err != nil || p.quarantine == nil {
+		return lr, err
+	}
+	return p.latestOutOfQuarantine(ctx, mod, lr)
+}
+
+func (p *protocol) latest(ctx context.Context, mod string) (*storage.RevInfo, error) {
 	const op errors.Op = "protocol.Latest"
 	ctx, span := observ.StartSpan(ctx, op.String())
 	defer span.End()
-	if p.networkMode == Offline {
-		// Go never pings the /@latest endpoint _first_. It always tries /list and if that
-		// endpoint returns an empty list then it fallsback to calling /@latest.
-		return nil, errors.E(op, "Athens is in offline mode, use /list endpoint", errors.KindNotFound)
+	// Go never pings the /@latest endpoint _first_. It always tries /list and if that
+	// endpoint returns an empty list then it fallsback to calling /@latest, which
+	// offline mode answers from the versions in storage.
+	networkMode := p.networkModeOf(mod)
+	if networkMode == Offline {
+		lr, err := latestFromStorage(ctx, p.storage, mod)
+		if err != nil {
+			return nil, errors.E(op, err)
+		}
+		return lr, nil
 	}
 	lr, _, err := p.lister.List(ctx, mod)
+	if err != nil && networkMode == Fallback {
+		// if i.e. VCS is unavailable, resolve @latest from what we have in storage.
+		if slr, sErr := latestFromStorage(ctx, p.storage, mod); sErr == nil {
+			return slr, nil
+		}
+	}
 	if err != nil {
 		return nil, errors.E(op, err)
 	}
@@ -201,7 +254,7 @@ func (p *protocol) Info(ctx context.Context, mod, ver string) ([]byte, error) {
 		observ.RecordCacheLookup(ctx, "hit", "info")
 	} else if errors.IsNotFoundErr(err) {
 		observ.RecordCacheLookup(ctx, "miss", "info")
-		err = p.processDownload(ctx, mod, ver, func(newVer string) error {
+		err = p.processDownload(ctx, mod, ver, p.stashMetadata, func(newVer string) error {
 			info, err = p.storage.Info(ctx, mod, newVer)
 			return err
 		})
@@ -222,7 +275,7 @@ func (p *protocol) GoMod(ctx context.Context, mod, ver string) ([]byte, error) {
 		observ.RecordCacheLookup(ctx, "hit", "gomod")
 	} else if errors.IsNotFoundErr(err) {
 		observ.RecordCacheLookup(ctx, "miss", "gomod")
-		err = p.processDownload(ctx, mod, ver, func(newVer string) error {
+		err = p.processDownload(ctx, mod, ver, p.stashMetadata, func(newVer string) error {
 			goMod, err = p.storage.GoMod(ctx, mod, newVer)
 			return err
 		})
@@ -242,10 +295,14 @@ func (p *protocol) Zip(ctx context.Context, mod, ver string) (storage.SizeReadCl
 		observ.RecordCacheLookup(ctx, "hit", "zip")
 	} else if errors.IsNotFoundErr(err) {
 		observ.RecordCacheLookup(ctx, "miss", "zip")
-		err = p.processDownload(ctx, mod, ver, func(newVer string) error {
-			zip, err = p.storage.Zip(ctx, mod, newVer)
-			return err
-		})
+		if p.zipStreams != nil && p.df.Match(mod) == mode.Sync {
+			zip, err = p.stashAndFollow(ctx, mod, ver)
+		} else {
+			err = p.processDownload(ctx, mod, ver, p.stasher.Stash, func(newVer string) error {
+				zip, err = p.storage.Zip(ctx, mod, newVer)
+				return err
+			})
+		}
 	}
 	if err != nil {
 		return nil, errors.E(op, err)
@@ -254,7 +311,53 @@ func (p *protocol) Zip(ctx context.Context, mod, ver string) (storage.SizeReadCl
 	return zip, nil
 }
 
-func (p *protocol) processDownload(ctx context.Context, mod, ver string, f func(newVer string) error) error {
+// stashAndFollow stashes the version like processDownload does in sync mode,
+// but returns the zip as soon as it is being fetched rather than reading it
+// back from storage once it is saved.
+func (p *protocol) stashAndFollow(ctx context.Context, mod, ver string) (storage.SizeReadCloser, error) {
+	const op errors.Op = "protocol.stashAndFollow"
+	stashCtx, cancel := copyContextWithCustomTimeout(ctx, time.Minute*15)
+	var (
+		newVer   string
+		stashErr error
+	)
+	done := make(chan struct{})
+	go func() {
+		defer cancel()
+		defer close(done)
+		newVer, stashErr = p.stasher.Stash(stashCtx, mod, ver)
+	}()
+	if zip, ok := p.zipStreams.Follow(ctx, mod, ver, done); ok {
+		return zip, nil
+	}
+
+	// the zip was saved before it could be followed, or it was not fetched
+	// at all because the version already existed, so read it from storage.
+	select {
+	case <-done:
+	case <-ctx.Done():
+		return nil, errors.E(op, ctx.Err())
+	}
+	if stashErr != nil {
+		return nil, errors.E(op, stashErr)
+	}
+	zip, err := p.storage.Zip(ctx, mod, newVer)
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+	return zip, nil
+}
+
+// stashMetadata stashes what the .info and .mod endpoints need, which
+// is only those two files if the protocol has a ModStasher.
+func (p *protocol) stashMetadata(ctx context.Context, mod, ver string) (string, error) {
+	if p.modStasher != nil {
+		return p.modStasher.StashMod(ctx, mod, ver)
+	}
+	return p.stasher.Stash(ctx, mod, ver)
+}
+
+func (p *protocol) processDownload(ctx context.Context, mod, ver string, stashFn stashFunc, f func(newVer string) error) error {
 	const op errors.Op = "protocol.processDownload"
 	// Create a new context with custom deadline and ditch whatever deadline was passed by the caller.
 	// This is needed so that the async go routines can continue even after the HTTP request is complete (which leads to context cancellation).
@@ -262,18 +365,18 @@ func (p *protocol) processDownload(ctx context.Context, mod, ver string, f func(
 	defer cancel()
 	switch p.df.Match(mod) {
 	case mode.Sync:
-		newVer, err := p.stasher.Stash(ctx, mod, ver)
+		newVer, err := stashFn(ctx, mod, ver)
 		if err != nil {
 			return errors.E(op, err)
 		}
 		return f(newVer)
 	case mode.Async:
-		go func() { _, _ = p.stasher.Stash(ctx, mod, ver) }()
+		p.stashAsync(ctx, mod, ver, stashFn)
 		return errors.E(op, "async: module not found", errors.KindNotFound)
 	case mode.Redirect:
 		return errors.E(op, "redirect", errors.KindRedirect)
 	case mode.AsyncRedirect:
-		go func() { _, _ = p.stasher.Stash(ctx, mod, ver) }()
+		p.stashAsync(ctx, mod, ver, stashFn)
 		return errors.E(op, "async_redirect: module not found", errors.KindRedirect)
 	case mode.None:
 		return errors.E(op, "none", errors.KindNotFound)
@@ -281,6 +384,31 @@ func (p *protocol) processDownload(ctx context.Context, mod, ver string, f func(
 	return nil
 }
 
+// stashAsync stashes the version in the background, through the queue if there is one.
+func (p *protocol) stashAsync(ctx context.Context, mod, ver string, stashFn stashFunc) {
+	const op errors.Op = "protocol.stashAsync"
+	if p.queue != nil {
+		err := p.queue.Enqueue(ctx, mod, ver)
+		if err == nil {
+			return
+		}
+		log.EntryFromContext(ctx).SystemErr(errors.E(op, err))
+	}
+	go func() { _, _ = stashFn(ctx, mod, ver) }()
+}
+
+// networkModeOf returns the network mode of mod, which its download path
+// may override. Without a lister, as on frontends, it is always offline.
+func (p *protocol) networkModeOf(mod string) string {
+	switch {
+	case p.lister == nil:
+		return Offline
+	case p.df == nil:
+		return p.networkMode
+	}
+	return p.df.NetworkMode(mod, p.networkMode)
+}
+
 // union concatenates two version lists and removes duplicates.
 func union(list1, list2 []string) []string {
 	if list1 == nil {
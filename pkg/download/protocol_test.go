@@ -19,6 +19,8 @@ import (
 	"github.com/gomods/athens/pkg/index/nop"
 	"github.com/gomods/athens/pkg/log"
 	"github.com/gomods/athens/pkg/module"
+	"github.com/gomods/athens/pkg/queue"
+	queuemem "github.com/gomods/athens/pkg/queue/mem"
 	"github.com/gomods/athens/pkg/stash"
 	"github.com/gomods/athens/pkg/storage"
 	"github.com/gomods/athens/pkg/storage/mem"
@@ -38,7 +40,7 @@ func getDP(t *testing.T) Protocol {
 	}
 	goBin := conf.GoBinary
 	fs := afero.NewOsFs()
-	mf, err := module.NewGoGetFetcher(goBin, conf.GoGetDir, conf.GoBinaryEnvVars, fs)
+	mf, err := module.NewGoGetFetcher(goBin, conf.GoGetDir, conf.GoBinaryEnvVars, fs, nil, nil)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -50,7 +52,7 @@ func getDP(t *testing.T) Protocol {
 	return New(&Opts{
 		Storage:     s,
 		Stasher:     st,
-		Lister:      module.NewVCSLister(goBin, conf.GoBinaryEnvVars, fs, conf.TimeoutDuration()),
+		Lister:      module.NewVCSLister(goBin, conf.GoBinaryEnvVars, fs, conf.TimeoutDuration(), nil, nil),
 		NetworkMode: Strict,
 	})
 }
@@ -181,6 +183,33 @@ func TestListMode(t *testing.T) {
 	}
 }
 
+func TestListModePerPattern(t *testing.T) {
+	ctx := t.Context()
+	strg, err := mem.NewStorage()
+	require.NoError(t, err)
+	err = strg.Save(ctx, "git.mycompany.com/lib", "v0.0.4", []byte("mod"), bytes.NewReader([]byte("zip")), nil, []byte("info"))
+	require.NoError(t, err)
+	ml := &mockLister{list: []string{"v0.0.1"}}
+	dp := &protocol{
+		df: &mode.DownloadFile{Mode: mode.Sync, Paths: []*mode.DownloadPath{
+			{Pattern: "git.mycompany.com/*", Mode: mode.Sync, NetworkMode: Offline},
+		}},
+		storage:     strg,
+		lister:      ml,
+		networkMode: Strict,
+	}
+
+	versions, err := dp.List(ctx, "git.mycompany.com/lib")
+	require.NoError(t, err)
+	require.Equal(t, []string{"v0.0.4"}, versions)
+	require.False(t, ml.called, "upstream lister must not be called in offline mode")
+
+	versions, err = dp.List(ctx, "github.com/pkg/errors")
+	require.NoError(t, err)
+	require.Equal(t, []string{"v0.0.1"}, versions)
+	require.True(t, ml.called)
+}
+
 func TestConcurrentLists(t *testing.T) {
 	dp := getDP(t)
 	ctx := t.Context()
@@ -383,7 +412,7 @@ func TestDownloadProtocol(t *testing.T) {
 	}
 	mp := &mockFetcher{}
 	st := stash.New(mp, s, nop.New(), 10*time.Minute)
-	dp := New(&Opts{s, st, nil, nil, Strict})
+	dp := New(&Opts{Storage: s, Stasher: st, NetworkMode: Strict})
 	ctx := t.Context()
 
 	var eg errgroup.Group
@@ -422,6 +451,97 @@ func (m *mockFetcher) Fetch(ctx context.Context, mod, ver string) (*storage.Vers
 	}, nil
 }
 
+type lazyFetcher struct {
+	mockFetcher
+	fetches, modFetches int
+}
+
+func (m *lazyFetcher) Fetch(ctx context.Context, mod, ver string) (*storage.Version, error) {
+	m.fetches++
+	v, err := m.mockFetcher.Fetch(ctx, mod, ver)
+	if err != nil {
+		return nil, err
+	}
+	v.Semver = ver
+	return v, nil
+}
+
+func (m *lazyFetcher) FetchMod(ctx context.Context, mod, ver string) (*storage.Version, error) {
+	m.modFetches++
+	bts := []byte(mod + "@" + ver)
+	return &storage.Version{Mod: bts, Info: bts, Semver: ver}, nil
+}
+
+func TestDownloadProtocolLazyZip(t *testing.T) {
+	s, err := mem.NewStorage()
+	require.NoError(t, err)
+	mf := &lazyFetcher{}
+	ms, ok := stash.NewModStasher(mf, s, 10*time.Minute)
+	require.True(t, ok)
+	dp := New(&Opts{
+		Storage:    s,
+		Stasher:    stash.New(mf, s, nop.New(), 10*time.Minute),
+		ModStasher: ms,
+	})
+	ctx := t.Context()
+	mod, ver := "github.com/athens-artifacts/lazy", "v1.0.0"
+
+	goMod, err := dp.GoMod(ctx, mod, ver)
+	require.NoError(t, err)
+	require.Equal(t, mod+"@"+ver, string(goMod))
+	_, err = dp.Info(ctx, mod, ver)
+	require.NoError(t, err)
+	require.Equal(t, 1, mf.modFetches)
+	require.Equal(t, 0, mf.fetches, "the zip must not be fetched for .mod and .info requests")
+
+	zip, err := dp.Zip(ctx, mod, ver)
+	require.NoError(t, err)
+	defer zip.Close()
+	require.Equal(t, 1, mf.fetches)
+	exists, err := storage.WithChecker(s).Exists(ctx, mod, ver)
+	require.NoError(t, err)
+	require.True(t, exists)
+}
+
+type pipeFetcher struct {
+	r *io.PipeReader
+}
+
+func (m *pipeFetcher) Fetch(ctx context.Context, mod, ver string) (*storage.Version, error) {
+	bts := []byte(mod + "@" + ver)
+	return &storage.Version{Mod: bts, Info: bts, Zip: m.r, Semver: ver}, nil
+}
+
+func TestDownloadProtocolStreamsZip(t *testing.T) {
+	s, err := mem.NewStorage()
+	require.NoError(t, err)
+	r, w := io.Pipe()
+	streams := stash.NewZipStreams(t.TempDir())
+	dp := New(&Opts{
+		Storage:    s,
+		Stasher:    stash.New(streams.Fetcher(&pipeFetcher{r}), s, nop.New(), 10*time.Minute),
+		ZipStreams: streams,
+	})
+	ctx := t.Context()
+	mod, ver := "github.com/athens-artifacts/streamed", "v1.0.0"
+
+	// the zip is returned before upstream sent a single byte.
+	zip, err := dp.Zip(ctx, mod, ver)
+	require.NoError(t, err)
+	defer zip.Close()
+	go func() {
+		_, _ = w.Write([]byte("zip contents"))
+		_ = w.Close()
+	}()
+	bts, err := io.ReadAll(zip)
+	require.NoError(t, err)
+	require.Equal(t, "zip contents", string(bts))
+	require.Eventually(t, func() bool {
+		exists, err := storage.WithChecker(s).Exists(ctx, mod, ver)
+		return err == nil && exists
+	}, 5*time.Second, 10*time.Millisecond, "the zip must be stashed as well")
+}
+
 func TestDownloadProtocolWhenFetchFails(t *testing.T) {
 	s, err := mem.NewStorage()
 	if err != nil {
@@ -435,7 +555,7 @@ func TestDownloadProtocolWhenFetchFails(t *testing.T) {
 	}
 	mp := &notFoundFetcher{}
 	st := stash.New(mp, s, nop.New(), 10*time.Minute)
-	dp := New(&Opts{s, st, nil, nil, Strict})
+	dp := New(&Opts{Storage: s, Stasher: st, NetworkMode: Strict})
 	_, err = dp.GoMod(t.Context(), fakeMod.mod, fakeMod.ver)
 	if err != nil {
 		t.Errorf("Download protocol should succeed, instead it gave error %s \n", err)
@@ -465,6 +585,28 @@ func TestAsyncRedirect(t *testing.T) {
 	require.Equal(t, string(info), "info", "expected async fetch to be successful")
 }
 
+func TestAsyncQueue(t *testing.T) {
+	s, err := mem.NewStorage()
+	require.NoError(t, err)
+	q := queuemem.New()
+	dp := New(&Opts{
+		Stasher:      &mockStasher{s, make(chan bool)},
+		Storage:      s,
+		DownloadFile: &mode.DownloadFile{Mode: mode.AsyncRedirect, DownloadURL: "https://gomods.io"},
+		Queue:        q,
+	})
+	mod, ver := "github.com/athens-artifacts/happy-path", "v0.0.1"
+	_, err = dp.GoMod(t.Context(), mod, ver)
+	require.Equal(t, errors.KindRedirect, errors.Kind(err))
+
+	// the stash is left to the queue's workers.
+	job, err := q.Get(t.Context(), mod, ver)
+	require.NoError(t, err)
+	require.Equal(t, queue.StatePending, job.State)
+	_, err = s.GoMod(t.Context(), mod, ver)
+	require.True(t, errors.IsNotFoundErr(err))
+}
+
 type mockStasher struct {
 	s  storage.Backend
 	ch chan bool
@@ -0,0 +1,55 @@
+package download
+
+import (
+	"context"
+
+	"github.com/gomods/athens/pkg/errors"
+	"github.com/gomods/athens/pkg/log"
+	"github.com/gomods/athens/pkg/storage"
+	"golang.org/x/mod/semver"
+)
+
+// latestOutOfQuarantine returns lr if it is not in quarantine, and otherwise
+// the highest version of mod that is not, so that go get -u does not pick
+// a version that would be refused.
+func (p *protocol) latestOutOfQuarantine(ctx context.Context, mod string, lr *storage.RevInfo) (*storage.RevInfo, error) {
+	const op errors.Op = "protocol.latestOutOfQuarantine"
+	until, err := p.quarantine.Until(ctx, mod, lr.Version)
+	if err == nil && until.IsZero() {
+		return lr, nil
+	}
+	if err != nil {
+		log.EntryFromContext(ctx).SystemErr(err)
+	}
+	vers, err := p.List(ctx, mod)
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+	var release, prerelease string
+	for _, v := range vers {
+		switch {
+		case !semver.IsValid(v):
+			continue
+		case semver.Prerelease(v) != "":
+			if prerelease == "" || semver.Compare(v, prerelease) > 0 {
+				prerelease = v
+			}
+		default:
+			if release == "" || semver.Compare(v, release) > 0 {
+				release = v
+			}
+		}
+	}
+	latest := release
+	if latest == "" {
+		latest = prerelease
+	}
+	if latest == "" {
+		return nil, errors.E(op, errors.M(mod), "every version is quarantined", errors.KindNotFound)
+	}
+	t, err := p.quarantine.Time(ctx, mod, latest)
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+	return &storage.RevInfo{Version: latest, Time: t}, nil
+}
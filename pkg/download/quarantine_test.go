@@ -0,0 +1,57 @@
+package download
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gomods/athens/pkg/errors"
+	"github.com/gomods/athens/pkg/module"
+	"github.com/gomods/athens/pkg/storage/mem"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQuarantine(t *testing.T) {
+	ctx := t.Context()
+	const mod = "github.com/athens-artifacts/happy-path"
+	file := filepath.Join(t.TempDir(), "filter")
+	require.NoError(t, os.WriteFile(file, []byte("Q7 github.com/athens-artifacts\n+ github.com/athens-artifacts/happy-path v1.3\n"), 0o600))
+	f, err := module.NewFilter(file)
+	require.NoError(t, err)
+
+	s, err := mem.NewStorage()
+	require.NoError(t, err)
+	now := time.Now()
+	for ver, tm := range map[string]time.Time{
+		"v1.0.0":       now.AddDate(0, 0, -30),
+		"v1.1.0":       now.AddDate(0, 0, -10),
+		"v1.2.0-rc.1":  now.AddDate(0, 0, -3),
+		"v1.2.0":       now.AddDate(0, 0, -1),
+		"v1.3.0-rc.1":  now.AddDate(0, 0, -1),
+		"v2.0.0-alpha": now,
+	} {
+		info := fmt.Sprintf(`{"Version":%q,"Time":%q}`, ver, tm.Format(time.RFC3339))
+		require.NoError(t, s.Save(ctx, mod, ver, []byte("module "+mod), bytes.NewReader([]byte("zip")), nil, []byte(info)))
+	}
+	dp := &protocol{storage: s, lister: &mockLister{}, networkMode: Offline, quarantine: module.NewQuarantineChecker(f, s, nil)}
+
+	vers, err := dp.List(ctx, mod)
+	require.NoError(t, err)
+	require.ElementsMatch(t, []string{"v1.0.0", "v1.1.0", "v1.3.0-rc.1"}, vers)
+
+	// the latest version in storage is quarantined, so
+	// the highest release out of quarantine is picked.
+	info, err := dp.Latest(ctx, mod)
+	require.NoError(t, err)
+	require.Equal(t, "v1.1.0", info.Version)
+
+	require.NoError(t, os.WriteFile(file, []byte("Q90 github.com/athens-artifacts\n"), 0o600))
+	nf, err := module.NewFilter(file)
+	require.NoError(t, err)
+	f.Update(nf)
+	_, err = dp.Latest(ctx, mod)
+	require.True(t, errors.IsNotFoundErr(err))
+}
@@ -51,10 +51,37 @@ func ZipHandler(dp Protocol, lggr log.Entry, df *mode.DownloadFile) http.Handler
 		if r.Method == http.MethodHead {
 			return
 		}
-		_, err = io.Copy(w, zip)
+		n, err := io.Copy(w, zip)
 		if err != nil {
-			lggr.SystemErr(errors.E(op, errors.M(mod), errors.V(ver), err))
+			err = errors.E(op, errors.M(mod), errors.V(ver), err)
+			lggr.SystemErr(err)
+			switch {
+			case n == 0:
+				// nothing was written yet, so the error can still be reported.
+				w.Header().Del("Content-Type")
+				w.Header().Del("Content-Length")
+				w.WriteHeader(errors.Kind(err))
+			case size <= 0:
+				// the zip is streamed while it is being fetched, without a
+				// Content-Length. Abort the response so that the client does
+				// not mistake a truncated zip for a complete one.
+				abortResponse(w)
+			}
+			// otherwise the response is shorter than its Content-Length,
+			// which the client sees as an error.
 		}
 	}
 	return http.HandlerFunc(f)
 }
+
+// abortResponse closes the connection of a response that was cut short, so
+// that the client sees an error instead of the end of the response. Connections
+// that cannot be hijacked, such as HTTP/2 ones, are aborted by panicking with
+// http.ErrAbortHandler, which net/http recovers from without logging it.
+func abortResponse(w http.ResponseWriter) {
+	conn, _, err := http.NewResponseController(w).Hijack()
+	if err != nil {
+		panic(http.ErrAbortHandler)
+	}
+	_ = conn.Close()
+}
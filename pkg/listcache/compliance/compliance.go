@@ -0,0 +1,38 @@
+package compliance
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gomods/athens/pkg/errors"
+	"github.com/gomods/athens/pkg/listcache"
+	"github.com/gomods/athens/pkg/storage"
+	"github.com/stretchr/testify/require"
+)
+
+// RunTests runs compliance tests for the given Cache implementation.
+func RunTests(t *testing.T, c listcache.Cache) {
+	ctx := t.Context()
+	_, err := c.Get(ctx, "github.com/athens-artifacts/missing")
+	require.True(t, errors.IsNotFoundErr(err))
+
+	listedAt := time.Now().Truncate(time.Second).UTC()
+	e := &listcache.Entry{
+		Latest:   &storage.RevInfo{Version: "v1.1.0", Time: listedAt},
+		Versions: []string{"v1.0.0", "v1.1.0"},
+		ListedAt: listedAt,
+	}
+	require.NoError(t, c.Set(ctx, "github.com/athens-artifacts/happy-path", e))
+	got, err := c.Get(ctx, "github.com/athens-artifacts/happy-path")
+	require.NoError(t, err)
+	require.Equal(t, e.Versions, got.Versions)
+	require.Equal(t, e.Latest.Version, got.Latest.Version)
+	require.True(t, e.ListedAt.Equal(got.ListedAt))
+
+	// entries are replaced.
+	e.Versions = append(e.Versions, "v1.2.0")
+	require.NoError(t, c.Set(ctx, "github.com/athens-artifacts/happy-path", e))
+	got, err = c.Get(ctx, "github.com/athens-artifacts/happy-path")
+	require.NoError(t, err)
+	require.Equal(t, e.Versions, got.Versions)
+}
@@ -0,0 +1,31 @@
+// Package listcache caches the versions that upstream lists for modules,
+// so that /@v/list and /@latest do not reach upstream on every request.
+package listcache
+
+import (
+	"context"
+	"time"
+
+	"github.com/gomods/athens/pkg/storage"
+)
+
+// Retention is how long caches keep entries, well after they are stale,
+// so that the fallback network mode can serve them when upstream is down.
+const Retention = 7 * 24 * time.Hour
+
+// Entry is what upstream listed for a module, and when.
+type Entry struct {
+	Latest   *storage.RevInfo `json:"latest,omitempty"`
+	Versions []string         `json:"versions"`
+	ListedAt time.Time        `json:"listedAt"`
+}
+
+// Cache stores the entries of modules.
+type Cache interface {
+	// Get returns the entry of mod.
+	// It returns a KindNotFound error if there is none.
+	Get(ctx context.Context, mod string) (*Entry, error)
+
+	// Set stores the entry of mod, for Retention at least.
+	Set(ctx context.Context, mod string, e *Entry) error
+}
@@ -0,0 +1,104 @@
+package listcache
+
+import (
+	"context"
+	"time"
+
+	"github.com/gomods/athens/pkg/errors"
+	"github.com/gomods/athens/pkg/log"
+	"github.com/gomods/athens/pkg/module"
+	"github.com/gomods/athens/pkg/observ"
+	"github.com/gomods/athens/pkg/storage"
+	"golang.org/x/sync/singleflight"
+)
+
+// Options configures the lister that NewLister returns.
+type Options struct {
+	// TTL returns how long the listing of a module is fresh.
+	// Modules with no TTL are not cached.
+	TTL func(mod string) time.Duration
+	// StaleTTL is how long a listing is served after it is stale,
+	// while it is refreshed in the background.
+	StaleTTL time.Duration
+	// ServeStaleOnError returns whether listings of a module that are
+	// older than that are served too, if refreshing them fails, as the
+	// fallback network mode wants. Nil never serves them.
+	ServeStaleOnError func(mod string) bool
+}
+
+// NewLister returns an UpstreamLister that caches in c what l lists.
+func NewLister(l module.UpstreamLister, c Cache, opts Options) module.UpstreamLister {
+	return &lister{lister: l, cache: c, opts: opts}
+}
+
+type lister struct {
+	lister module.UpstreamLister
+	cache  Cache
+	opts   Options
+	sfg    singleflight.Group
+}
+
+func (l *lister) List(ctx context.Context, mod string) (*storage.RevInfo, []string, error) {
+	const op errors.Op = "listcache.List"
+	ctx, span := observ.StartSpan(ctx, op.String())
+	defer span.End()
+	ttl := l.opts.TTL(mod)
+	if ttl <= 0 {
+		return l.lister.List(ctx, mod)
+	}
+
+	e, err := l.cache.Get(ctx, mod)
+	if err != nil && !errors.IsNotFoundErr(err) {
+		// a cache that fails is a cache that misses.
+		log.EntryFromContext(ctx).SystemErr(errors.E(op, err))
+	}
+	if e != nil {
+		age := time.Since(e.ListedAt)
+		if age < ttl {
+			return e.Latest, e.Versions, nil
+		}
+		if age < ttl+l.opts.StaleTTL {
+			go func() {
+				ctx := context.WithoutCancel(ctx)
+				if _, err := l.refresh(ctx, mod); err != nil {
+					log.EntryFromContext(ctx).Infof("could not refresh the versions of %s: %v", mod, err)
+				}
+			}()
+			return e.Latest, e.Versions, nil
+		}
+	}
+
+	fresh, err := l.refresh(ctx, mod)
+	if err != nil {
+		// a module that is gone upstream has no versions to list anymore.
+		if e != nil && l.opts.ServeStaleOnError != nil && l.opts.ServeStaleOnError(mod) && !errors.IsRepoNotFoundErr(err) {
+			return e.Latest, e.Versions, nil
+		}
+		return nil, nil, errors.E(op, err)
+	}
+	return fresh.Latest, fresh.Versions, nil
+}
+
+// refresh lists mod upstream and caches the result.
+func (l *lister) refresh(ctx context.Context, mod string) (*Entry, error) {
+	const op errors.Op = "listcache.refresh"
+	v, err, _ := l.sfg.Do(mod, func() (any, error) {
+		latest, vers, err := l.lister.List(ctx, mod)
+		if err != nil {
+			return nil, err
+		}
+		e := &Entry{Latest: latest, Versions: vers, ListedAt: time.Now()}
+		if err := l.cache.Set(ctx, mod, e); err != nil {
+			log.EntryFromContext(ctx).SystemErr(errors.E(op, err))
+		}
+		return e, nil
+	})
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+	e, ok := v.(*Entry)
+	if !ok {
+		return nil, errors.E(op, "unexpected type assertion failure for entry", errors.KindUnexpected)
+	}
+	return e, nil
+}
@@ -0,0 +1,110 @@
+package listcache_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gomods/athens/pkg/errors"
+	"github.com/gomods/athens/pkg/listcache"
+	"github.com/gomods/athens/pkg/listcache/mem"
+	"github.com/gomods/athens/pkg/storage"
+	"github.com/stretchr/testify/require"
+)
+
+const mod = "github.com/athens-artifacts/happy-path"
+
+type countingLister struct {
+	mu       sync.Mutex
+	calls    int
+	versions []string
+	err      error
+}
+
+func (l *countingLister) List(ctx context.Context, mod string) (*storage.RevInfo, []string, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.calls++
+	if l.err != nil {
+		return nil, nil, l.err
+	}
+	return &storage.RevInfo{Version: l.versions[len(l.versions)-1]}, l.versions, nil
+}
+
+func (l *countingLister) Calls() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.calls
+}
+
+func ttl(d time.Duration) func(string) time.Duration {
+	return func(string) time.Duration { return d }
+}
+
+func TestListerFresh(t *testing.T) {
+	ctx := t.Context()
+	up := &countingLister{versions: []string{"v1.0.0"}}
+	l := listcache.NewLister(up, mem.New(), listcache.Options{TTL: ttl(time.Minute)})
+	for range 3 {
+		latest, vers, err := l.List(ctx, mod)
+		require.NoError(t, err)
+		require.Equal(t, []string{"v1.0.0"}, vers)
+		require.Equal(t, "v1.0.0", latest.Version)
+	}
+	require.Equal(t, 1, up.Calls())
+}
+
+func TestListerNoTTL(t *testing.T) {
+	ctx := t.Context()
+	up := &countingLister{versions: []string{"v1.0.0"}}
+	l := listcache.NewLister(up, mem.New(), listcache.Options{TTL: ttl(0)})
+	for range 2 {
+		_, _, err := l.List(ctx, mod)
+		require.NoError(t, err)
+	}
+	require.Equal(t, 2, up.Calls())
+}
+
+func TestListerStaleWhileRevalidate(t *testing.T) {
+	ctx := t.Context()
+	c := mem.New()
+	require.NoError(t, c.Set(ctx, mod, &listcache.Entry{Versions: []string{"v1.0.0"}, ListedAt: time.Now().Add(-2 * time.Minute)}))
+	up := &countingLister{versions: []string{"v1.0.0", "v1.1.0"}}
+	l := listcache.NewLister(up, c, listcache.Options{TTL: ttl(time.Minute), StaleTTL: time.Hour})
+
+	_, vers, err := l.List(ctx, mod)
+	require.NoError(t, err)
+	require.Equal(t, []string{"v1.0.0"}, vers, "a stale listing must be served right away")
+	require.Eventually(t, func() bool {
+		e, err := c.Get(ctx, mod)
+		return err == nil && len(e.Versions) == 2
+	}, time.Second, 10*time.Millisecond)
+	_, vers, err = l.List(ctx, mod)
+	require.NoError(t, err)
+	require.Equal(t, []string{"v1.0.0", "v1.1.0"}, vers)
+	require.Equal(t, 1, up.Calls())
+}
+
+func TestListerExpired(t *testing.T) {
+	for _, serveStale := range []bool{false, true} {
+		ctx := t.Context()
+		c := mem.New()
+		require.NoError(t, c.Set(ctx, mod, &listcache.Entry{Versions: []string{"v1.0.0"}, ListedAt: time.Now().Add(-2 * time.Hour)}))
+		up := &countingLister{err: errors.E("test", "unexpected error")}
+		l := listcache.NewLister(up, c, listcache.Options{TTL: ttl(time.Minute), StaleTTL: time.Hour, ServeStaleOnError: func(string) bool { return serveStale }})
+
+		_, vers, err := l.List(ctx, mod)
+		if !serveStale {
+			require.Error(t, err)
+			continue
+		}
+		require.NoError(t, err)
+		require.Equal(t, []string{"v1.0.0"}, vers)
+
+		// a module that is gone upstream is not served stale.
+		up.err = errors.E("test", "remote: Repository not found", errors.KindNotFound)
+		_, _, err = l.List(ctx, mod)
+		require.Error(t, err)
+	}
+}
@@ -0,0 +1,54 @@
+// Package mem provides a listcache.Cache that lives in memory.
+package mem
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/gomods/athens/pkg/errors"
+	"github.com/gomods/athens/pkg/listcache"
+)
+
+// purgeEvery is how many entries are set between two purges of
+// the entries that are older than listcache.Retention.
+const purgeEvery = 1000
+
+// New returns a listcache.Cache that is lost when Athens restarts.
+func New() listcache.Cache {
+	return &cache{entries: map[string]*listcache.Entry{}}
+}
+
+type cache struct {
+	mu      sync.Mutex
+	entries map[string]*listcache.Entry
+	sets    int
+}
+
+func (c *cache) Get(ctx context.Context, mod string) (*listcache.Entry, error) {
+	const op errors.Op = "mem.Get"
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[mod]
+	if !ok || time.Since(e.ListedAt) > listcache.Retention {
+		return nil, errors.E(op, errors.M(mod), errors.KindNotFound)
+	}
+	cp := *e
+	return &cp, nil
+}
+
+func (c *cache) Set(ctx context.Context, mod string, e *listcache.Entry) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	cp := *e
+	c.entries[mod] = &cp
+	c.sets++
+	if c.sets%purgeEvery == 0 {
+		for m, e := range c.entries {
+			if time.Since(e.ListedAt) > listcache.Retention {
+				delete(c.entries, m)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,11 @@
+package mem
+
+import (
+	"testing"
+
+	"github.com/gomods/athens/pkg/listcache/compliance"
+)
+
+func TestMem(t *testing.T) {
+	compliance.RunTests(t, New())
+}
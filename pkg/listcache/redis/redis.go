@@ -0,0 +1,58 @@
+// Package redis provides a listcache.Cache that is kept in redis,
+// so that several Athens instances can share it.
+package redis
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/gomods/athens/pkg/errors"
+	"github.com/gomods/athens/pkg/listcache"
+	"github.com/gomods/athens/pkg/redisclient"
+	"github.com/redis/go-redis/v9"
+)
+
+const keyPrefix = "athens:list:"
+
+// New returns a listcache.Cache kept in the redis at endpoint, which is a
+// comma separated list of addresses if cluster is set.
+func New(endpoint, password string, cluster bool) (listcache.Cache, error) {
+	const op errors.Op = "redis.New"
+	client, err := redisclient.New(endpoint, password, cluster)
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+	return &cache{client: client}, nil
+}
+
+type cache struct {
+	client redis.UniversalClient
+}
+
+func (c *cache) Get(ctx context.Context, mod string) (*listcache.Entry, error) {
+	const op errors.Op = "redis.Get"
+	b, err := c.client.Get(ctx, keyPrefix+mod).Bytes()
+	if errors.IsErr(err, redis.Nil) {
+		return nil, errors.E(op, errors.M(mod), errors.KindNotFound)
+	}
+	if err != nil {
+		return nil, errors.E(op, errors.M(mod), err)
+	}
+	var e listcache.Entry
+	if err := json.Unmarshal(b, &e); err != nil {
+		return nil, errors.E(op, errors.M(mod), err)
+	}
+	return &e, nil
+}
+
+func (c *cache) Set(ctx context.Context, mod string, e *listcache.Entry) error {
+	const op errors.Op = "redis.Set"
+	b, err := json.Marshal(e)
+	if err != nil {
+		return errors.E(op, errors.M(mod), err)
+	}
+	if err := c.client.Set(ctx, keyPrefix+mod, b, listcache.Retention).Err(); err != nil {
+		return errors.E(op, errors.M(mod), err)
+	}
+	return nil
+}
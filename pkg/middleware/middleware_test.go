@@ -175,3 +175,27 @@ func (suite *HookTestsSuite) TestHookUnexpectedError() {
 	r.True(suite.mock.invoked)
 	r.Equal(http.StatusInternalServerError, res.Code)
 }
+
+func TestHookValidationMiddlewareSet(t *testing.T) {
+	var invoked bool
+	server := ht.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		invoked = true
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer server.Close()
+
+	hook := NewValidatorHook("")
+	r := mux.NewRouter()
+	r.Use(NewHookValidationMiddleware(http.DefaultClient, hook))
+	r.HandleFunc(pathVersionInfo, func(w http.ResponseWriter, r *http.Request) {})
+	w := ht.New(r)
+
+	res := w.JSON("/github.com/athens-artifacts/happy-path/@v/v1.0.0.info").Get()
+	require.False(t, invoked, "an empty hook must not be called")
+	require.Equal(t, http.StatusOK, res.Code)
+
+	hook.Set(server.URL)
+	res = w.JSON("/github.com/athens-artifacts/happy-path/@v/v1.0.0.info").Get()
+	require.True(t, invoked)
+	require.Equal(t, http.StatusForbidden, res.Code)
+}
@@ -0,0 +1,50 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gomods/athens/pkg/errors"
+	"github.com/gomods/athens/pkg/log"
+	"github.com/gomods/athens/pkg/module"
+	"github.com/gomods/athens/pkg/paths"
+	"github.com/gorilla/mux"
+)
+
+// NewQuarantineMiddleware builds a middleware function that refuses the requests
+// for the versions that the Quarantine rules of the filter file hold back, with
+// a 403 that tells when they will be available. The version lists and @latest
+// leave them out in the download protocol.
+func NewQuarantineMiddleware(q *module.QuarantineChecker) mux.MiddlewareFunc {
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			mod, err := paths.GetModule(r)
+			if err != nil {
+				// if there is no module the path we are hitting is not one related to modules, like /
+				h.ServeHTTP(w, r)
+				return
+			}
+			// list and @latest requests have no version
+			ver, _ := paths.GetVersion(r)
+			if ver == "" {
+				h.ServeHTTP(w, r)
+				return
+			}
+			ctx := r.Context()
+			until, err := q.Until(ctx, mod, ver)
+			if err != nil && !errors.IsNotFoundErr(err) {
+				log.EntryFromContext(ctx).SystemErr(err)
+				w.WriteHeader(errors.Kind(err))
+				return
+			}
+			// a version that does not exist is left to the handler to not find
+			if until.IsZero() {
+				h.ServeHTTP(w, r)
+				return
+			}
+			msg := fmt.Sprintf("%s@%s is quarantined until %s, when it will become available\n", mod, ver, until.UTC().Format(time.RFC3339))
+			http.Error(w, msg, http.StatusForbidden)
+		})
+	}
+}
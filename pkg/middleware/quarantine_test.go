@@ -0,0 +1,52 @@
+package middleware
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	ht "github.com/gobuffalo/httptest"
+	"github.com/gomods/athens/pkg/module"
+	"github.com/gomods/athens/pkg/storage/mem"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQuarantineMiddleware(t *testing.T) {
+	ctx := t.Context()
+	const mod = "github.com/athens-artifacts/happy-path"
+	file := filepath.Join(t.TempDir(), "filter")
+	require.NoError(t, os.WriteFile(file, []byte("Q7 github.com/athens-artifacts\n"), 0o600))
+	f, err := module.NewFilter(file)
+	require.NoError(t, err)
+
+	s, err := mem.NewStorage()
+	require.NoError(t, err)
+	published := time.Now().Add(-48 * time.Hour).UTC().Truncate(time.Second)
+	for ver, tm := range map[string]time.Time{"v1.0.0": published.AddDate(0, 0, -30), "v1.1.0": published} {
+		info := fmt.Sprintf(`{"Version":%q,"Time":%q}`, ver, tm.Format(time.RFC3339))
+		require.NoError(t, s.Save(ctx, mod, ver, []byte("module "+mod), bytes.NewReader([]byte("zip")), nil, []byte(info)))
+	}
+
+	r := mux.NewRouter()
+	r.Use(NewQuarantineMiddleware(module.NewQuarantineChecker(f, s, nil)))
+	h := func(w http.ResponseWriter, r *http.Request) {}
+	r.HandleFunc(pathList, h)
+	r.HandleFunc(pathVersionInfo, h)
+	w := ht.New(r)
+
+	res := w.JSON("/" + mod + "/@v/list").Get()
+	require.Equal(t, http.StatusOK, res.Code)
+	res = w.JSON("/" + mod + "/@v/v1.0.0.info").Get()
+	require.Equal(t, http.StatusOK, res.Code)
+	res = w.JSON("/" + mod + "/@v/v1.1.0.info").Get()
+	require.Equal(t, http.StatusForbidden, res.Code)
+	require.Contains(t, res.Body.String(), "quarantined until "+published.AddDate(0, 0, 7).Format(time.RFC3339))
+	// versions that do not exist are left to the handler
+	res = w.JSON("/" + mod + "/@v/v9.9.9.info").Get()
+	require.Equal(t, http.StatusOK, res.Code)
+}
@@ -19,6 +19,11 @@ func (rw *responseWriter) WriteHeader(statusCode int) {
 	rw.ResponseWriter.WriteHeader(statusCode)
 }
 
+// Unwrap lets http.ResponseController reach the wrapped writer.
+func (rw *responseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
+
 // RequestLogger logs request params to standard output
 // it should only be used during dev.
 func RequestLogger(h http.Handler) http.Handler {
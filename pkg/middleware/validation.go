@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	"io"
 	"net/http"
+	"sync/atomic"
 
 	"github.com/gomods/athens/pkg/errors"
 	"github.com/gomods/athens/pkg/log"
@@ -13,11 +14,45 @@ import (
 	"github.com/gorilla/mux"
 )
 
+// ValidatorHook holds the URL of the validation webhook, which
+// can be replaced while requests are being validated. An empty
+// URL turns validation off.
+type ValidatorHook struct {
+	url atomic.Pointer[string]
+}
+
+// NewValidatorHook returns a ValidatorHook that calls url.
+func NewValidatorHook(url string) *ValidatorHook {
+	var v ValidatorHook
+	v.Set(url)
+	return &v
+}
+
+// Set replaces the URL of the webhook.
+func (v *ValidatorHook) Set(url string) {
+	v.url.Store(&url)
+}
+
+// URL returns the URL of the webhook.
+func (v *ValidatorHook) URL() string {
+	return *v.url.Load()
+}
+
 // NewValidationMiddleware builds a middleware function that performs validation checks by calling
 // an external webhook.
 func NewValidationMiddleware(client *http.Client, validatorHook string) mux.MiddlewareFunc {
+	return NewHookValidationMiddleware(client, NewValidatorHook(validatorHook))
+}
+
+// NewHookValidationMiddleware is NewValidationMiddleware for a webhook whose URL may change.
+func NewHookValidationMiddleware(client *http.Client, hook *ValidatorHook) mux.MiddlewareFunc {
 	return func(h http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			validatorHook := hook.URL()
+			if validatorHook == "" {
+				h.ServeHTTP(w, r)
+				return
+			}
 			mod, err := paths.GetModule(r)
 			if err != nil {
 				// if there is no module the path we are hitting is not one related to modules, like /
@@ -0,0 +1,86 @@
+package middleware
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gomods/athens/pkg/log"
+	"github.com/gomods/athens/pkg/paths"
+	"github.com/gomods/athens/pkg/vuln"
+	"github.com/gorilla/mux"
+)
+
+// NewVulnMiddleware builds a middleware function that refuses the requests for
+// the module versions that the vulnerability database denies, with a 403 that
+// lists the advisories, and logs the ones it only flags. If the policy of the
+// database says so, the denied versions are also left out of the version lists.
+func NewVulnMiddleware(db *vuln.DB) mux.MiddlewareFunc {
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			mod, err := paths.GetModule(r)
+			if err != nil {
+				// if there is no module the path we are hitting is not one related to modules, like /
+				h.ServeHTTP(w, r)
+				return
+			}
+			ver, _ := paths.GetVersion(r)
+			if ver == "" {
+				if strings.HasSuffix(r.URL.Path, "/@v/list") && db.FilterList() {
+					serveFilteredList(h, w, r, db, mod)
+					return
+				}
+				h.ServeHTTP(w, r)
+				return
+			}
+			res := db.Check(mod, ver)
+			if len(res.Flagged) > 0 {
+				log.EntryFromContext(r.Context()).Warnf("%s@%s has known vulnerabilities: %s", mod, ver, strings.Join(res.Flagged, ", "))
+			}
+			if len(res.Denied) > 0 {
+				msg := fmt.Sprintf("%s@%s is denied for known vulnerabilities: %s\n", mod, ver, strings.Join(res.Denied, ", "))
+				http.Error(w, msg, http.StatusForbidden)
+				return
+			}
+			h.ServeHTTP(w, r)
+		})
+	}
+}
+
+// serveFilteredList serves the version list of mod
+// without the versions that the database denies.
+func serveFilteredList(h http.Handler, w http.ResponseWriter, r *http.Request, db *vuln.DB, mod string) {
+	rec := &responseRecorder{header: http.Header{}, code: http.StatusOK}
+	h.ServeHTTP(rec, r)
+	for k, v := range rec.header {
+		w.Header()[k] = v
+	}
+	if rec.code != http.StatusOK {
+		w.WriteHeader(rec.code)
+		_, _ = w.Write(rec.body.Bytes())
+		return
+	}
+	var list bytes.Buffer
+	for v := range strings.FieldsSeq(rec.body.String()) {
+		if len(db.Check(mod, v).Denied) == 0 {
+			list.WriteString(v + "\n")
+		}
+	}
+	w.Header().Set("Content-Length", strconv.Itoa(list.Len()))
+	_, _ = w.Write(list.Bytes())
+}
+
+// responseRecorder buffers a response so that it can be rewritten.
+type responseRecorder struct {
+	header http.Header
+	code   int
+	body   bytes.Buffer
+}
+
+func (rr *responseRecorder) Header() http.Header { return rr.header }
+
+func (rr *responseRecorder) Write(b []byte) (int, error) { return rr.body.Write(b) }
+
+func (rr *responseRecorder) WriteHeader(code int) { rr.code = code }
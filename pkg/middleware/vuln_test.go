@@ -0,0 +1,54 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	ht "github.com/gobuffalo/httptest"
+	"github.com/gomods/athens/pkg/vuln"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVulnMiddleware(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "ID"), 0o755))
+	entry := `{"id": "GO-2024-0001", "affected": [{"package": {"name": "github.com/a/b"},
+		"ranges": [{"type": "SEMVER", "events": [{"introduced": "0"}, {"fixed": "1.2.0"}]}]}]}`
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "ID", "GO-2024-0001.json"), []byte(entry), 0o600))
+
+	newApp := func(p vuln.Policy) *ht.Handler {
+		db, err := vuln.Open(dir, p)
+		require.NoError(t, err)
+		r := mux.NewRouter()
+		r.Use(NewVulnMiddleware(db))
+		r.HandleFunc(pathList, func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, "v1.0.0\nv1.1.0\nv1.2.0\n")
+		})
+		r.HandleFunc(pathVersionInfo, func(w http.ResponseWriter, r *http.Request) {})
+		return ht.New(r)
+	}
+
+	w := newApp(vuln.Policy{Action: vuln.Deny})
+	res := w.JSON("/github.com/a/b/@v/v1.1.0.info").Get()
+	require.Equal(t, http.StatusForbidden, res.Code)
+	require.Contains(t, res.Body.String(), "github.com/a/b@v1.1.0 is denied for known vulnerabilities: GO-2024-0001")
+	res = w.JSON("/github.com/a/b/@v/v1.2.0.info").Get()
+	require.Equal(t, http.StatusOK, res.Code)
+	res = w.JSON("/github.com/a/b/@v/list").Get()
+	require.Equal(t, "v1.0.0\nv1.1.0\nv1.2.0\n", res.Body.String())
+
+	w = newApp(vuln.Policy{Action: vuln.Deny, FilterList: true})
+	res = w.JSON("/github.com/a/b/@v/list").Get()
+	require.Equal(t, http.StatusOK, res.Code)
+	require.Equal(t, "v1.2.0\n", res.Body.String())
+
+	w = newApp(vuln.Policy{Action: vuln.Flag, FilterList: true})
+	res = w.JSON("/github.com/a/b/@v/v1.1.0.info").Get()
+	require.Equal(t, http.StatusOK, res.Code)
+	res = w.JSON("/github.com/a/b/@v/list").Get()
+	require.Equal(t, "v1.0.0\nv1.1.0\nv1.2.0\n", res.Body.String())
+}
@@ -0,0 +1,201 @@
+// Package migrate copies the module versions of a storage backend to
+// another one, such as when moving from mongo to S3 or from disk to GCS.
+package migrate
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"io"
+	"sync"
+
+	"github.com/gomods/athens/pkg/errors"
+	"github.com/gomods/athens/pkg/log"
+	"github.com/gomods/athens/pkg/observ"
+	"github.com/gomods/athens/pkg/paths"
+	"github.com/gomods/athens/pkg/storage"
+	"golang.org/x/sync/errgroup"
+)
+
+// Options tell how Migrate copies the module versions.
+type Options struct {
+	// Workers is the number of module versions copied at the same time.
+	Workers int
+	// PageSize is the number of module versions read from the
+	// catalog of the source at a time.
+	PageSize int
+	// Token is the catalog token that the migration resumes from,
+	// or empty to start from the beginning.
+	Token string
+	// DryRun reports what would be copied without copying it.
+	DryRun bool
+	// Progress, if set, is called after every page of the catalog with
+	// the token that a migration stopped then resumes from, empty once
+	// the catalog is done, and the report so far.
+	Progress func(token string, r *Report)
+}
+
+// Report tells what became of the module versions that were migrated.
+type Report struct {
+	// Copied is the number of module versions copied,
+	// or that would be copied in a dry run.
+	Copied int `json:"copied"`
+	// Skipped is the number of module versions
+	// that the destination had already.
+	Skipped int       `json:"skipped"`
+	Failed  []Failure `json:"failed"`
+}
+
+// Failure is a module version that could not be copied.
+type Failure struct {
+	Module  string `json:"module"`
+	Version string `json:"version"`
+	Error   string `json:"error"`
+}
+
+// Migrate copies the .info, .mod and .zip files of every module version in
+// the catalog of src to dst, opts.Workers at a time, and skips the versions
+// that dst has already, so that a migration that stopped can start over or
+// resume from the token of the last page it finished. The files are read
+// back from dst and their checksums compared with the ones of src, and a
+// version whose checksums do not match is deleted from dst again. Versions
+// that cannot be copied are reported as failed, while an error is returned
+// if the catalog of src cannot be read, which is a KindNotImplemented error
+// if src has no catalog.
+func Migrate(ctx context.Context, src, dst storage.Backend, opts Options) (*Report, error) {
+	const op errors.Op = "migrate.Migrate"
+	ctx, span := observ.StartSpan(ctx, op.String())
+	defer span.End()
+	cs, ok := src.(storage.Cataloger)
+	if !ok {
+		return nil, errors.E(op, "the source storage has no catalog", errors.KindNotImplemented)
+	}
+	checker := storage.WithChecker(dst)
+	report := &Report{}
+	var mu sync.Mutex
+	token := opts.Token
+	for {
+		page, next, err := cs.Catalog(ctx, token, max(opts.PageSize, 1))
+		if err != nil {
+			return report, errors.E(op, err)
+		}
+		var g errgroup.Group
+		g.SetLimit(max(opts.Workers, 1))
+		for _, p := range page {
+			// the modules that Athens keeps for its own use
+			// belong to the Athens that they were saved by.
+			if storage.IsInternal(p.Module) {
+				continue
+			}
+			g.Go(func() error {
+				exists, err := checker.Exists(ctx, p.Module, p.Version)
+				if err == nil && !exists && !opts.DryRun {
+					err = migrate(ctx, src, dst, p)
+				}
+				mu.Lock()
+				defer mu.Unlock()
+				switch {
+				case err != nil:
+					report.Failed = append(report.Failed, Failure{Module: p.Module, Version: p.Version, Error: err.Error()})
+				case exists:
+					report.Skipped++
+				default:
+					report.Copied++
+				}
+				return nil
+			})
+		}
+		_ = g.Wait()
+		if err := ctx.Err(); err != nil {
+			// the versions of this page may not all have been copied.
+			return report, errors.E(op, err)
+		}
+		if opts.Progress != nil {
+			opts.Progress(next, report)
+		}
+		if next == "" {
+			return report, nil
+		}
+		token = next
+	}
+}
+
+// migrate copies p from src to dst and verifies the copy.
+func migrate(ctx context.Context, src, dst storage.Backend, p paths.AllPathParams) error {
+	const op errors.Op = "migrate.migrate"
+	info, err := src.Info(ctx, p.Module, p.Version)
+	if err != nil {
+		return errors.E(op, err)
+	}
+	gomod, err := src.GoMod(ctx, p.Module, p.Version)
+	if err != nil {
+		return errors.E(op, err)
+	}
+	zip, err := src.Zip(ctx, p.Module, p.Version)
+	if err != nil {
+		return errors.E(op, err)
+	}
+	defer func() { _ = zip.Close() }()
+	h := sha256.New()
+	tee := io.TeeReader(zip, h)
+	err = dst.Save(ctx, p.Module, p.Version, gomod, tee, nil, info)
+	// it was copied in the meantime, which the verification checks.
+	if err != nil && !errors.Is(err, errors.KindAlreadyExists) {
+		return errors.E(op, err)
+	}
+	// the checksum covers the zip that Save did not read to the end.
+	if _, err := io.Copy(io.Discard, tee); err != nil {
+		return errors.E(op, err)
+	}
+	file, err := mismatch(ctx, dst, p, info, gomod, h.Sum(nil))
+	if err != nil {
+		return errors.E(op, err)
+	}
+	if file != "" {
+		// so that the next migration copies it again.
+		if err := dst.Delete(ctx, p.Module, p.Version); err != nil {
+			log.EntryFromContext(ctx).SystemErr(errors.E(op, err, errors.M(p.Module), errors.V(p.Version)))
+		}
+		return errors.E(op, "the checksums of the "+file+" file do not match", errors.M(p.Module), errors.V(p.Version))
+	}
+	return nil
+}
+
+// mismatch compares the files of p in dst with info, gomod and the SHA-256
+// checksum of the zip of the source, and returns the name of the first one
+// whose checksum does not match, or an empty string if they all match.
+func mismatch(ctx context.Context, dst storage.Backend, p paths.AllPathParams, info, gomod, zipSum []byte) (string, error) {
+	const op errors.Op = "migrate.mismatch"
+	got, err := dst.Info(ctx, p.Module, p.Version)
+	if err != nil {
+		return "", errors.E(op, err)
+	}
+	if !bytes.Equal(checksum(got), checksum(info)) {
+		return ".info", nil
+	}
+	got, err = dst.GoMod(ctx, p.Module, p.Version)
+	if err != nil {
+		return "", errors.E(op, err)
+	}
+	if !bytes.Equal(checksum(got), checksum(gomod)) {
+		return ".mod", nil
+	}
+	zip, err := dst.Zip(ctx, p.Module, p.Version)
+	if err != nil {
+		return "", errors.E(op, err)
+	}
+	defer func() { _ = zip.Close() }()
+	h := sha256.New()
+	if _, err := io.Copy(h, zip); err != nil {
+		return "", errors.E(op, err)
+	}
+	if !bytes.Equal(h.Sum(nil), zipSum) {
+		return ".zip", nil
+	}
+	return "", nil
+}
+
+func checksum(b []byte) []byte {
+	sum := sha256.Sum256(b)
+	return sum[:]
+}
@@ -0,0 +1,104 @@
+package migrate
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/gomods/athens/pkg/errors"
+	"github.com/gomods/athens/pkg/storage"
+	"github.com/gomods/athens/pkg/storage/compliance/fixtures"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMigrate(t *testing.T) {
+	src, dst := fixtures.NewStorage(t), fixtures.NewStorage(t)
+	for _, v := range []string{"v1.0.0", "v1.1.0", "v1.2.0"} {
+		fixtures.Save(t, src, v, "zip of "+v)
+	}
+	fixtures.Save(t, dst, "v1.0.0", "zip of v1.0.0")
+
+	r, err := Migrate(t.Context(), src, dst, Options{Workers: 2, PageSize: 2})
+	require.NoError(t, err)
+	require.Equal(t, &Report{Copied: 2, Skipped: 1}, r)
+	fixtures.RequireZip(t, dst, "v1.2.0", "zip of v1.2.0")
+
+	r, err = Migrate(t.Context(), src, dst, Options{Workers: 2, PageSize: 2})
+	require.NoError(t, err)
+	require.Equal(t, &Report{Skipped: 3}, r)
+}
+
+func TestSkipInternal(t *testing.T) {
+	src, dst := fixtures.NewStorage(t), fixtures.NewStorage(t)
+	fixtures.Save(t, src, "v1.0.0", "zip of v1.0.0")
+	const internal, ver = "athens.invalid/vulndb", "v0.0.0-20240201000000-000000000000"
+	require.NoError(t, src.Save(t.Context(), internal, ver, []byte("module"), strings.NewReader("zip"), nil, []byte("info")))
+
+	r, err := Migrate(t.Context(), src, dst, Options{})
+	require.NoError(t, err)
+	require.Equal(t, &Report{Copied: 1}, r)
+	ok, err := storage.WithChecker(dst).Exists(t.Context(), internal, ver)
+	require.NoError(t, err)
+	require.False(t, ok)
+}
+
+func TestDryRun(t *testing.T) {
+	src, dst := fixtures.NewStorage(t), fixtures.NewStorage(t)
+	fixtures.Save(t, src, "v1.0.0", "zip of v1.0.0")
+
+	r, err := Migrate(t.Context(), src, dst, Options{DryRun: true})
+	require.NoError(t, err)
+	require.Equal(t, &Report{Copied: 1}, r)
+	fixtures.RequireExists(t, dst, "v1.0.0", false)
+}
+
+func TestResume(t *testing.T) {
+	src := fixtures.NewStorage(t)
+	for _, v := range []string{"v1.0.0", "v1.1.0", "v1.2.0"} {
+		fixtures.Save(t, src, v, "zip of "+v)
+	}
+	var tokens []string
+	_, err := Migrate(t.Context(), src, fixtures.NewStorage(t), Options{PageSize: 1, Progress: func(token string, _ *Report) {
+		tokens = append(tokens, token)
+	}})
+	require.NoError(t, err)
+	require.NotEmpty(t, tokens[0])
+	require.Empty(t, tokens[len(tokens)-1])
+
+	dst := fixtures.NewStorage(t)
+	r, err := Migrate(t.Context(), src, dst, Options{PageSize: 1, Token: tokens[0]})
+	require.NoError(t, err)
+	require.Equal(t, &Report{Copied: 2}, r)
+	fixtures.RequireExists(t, dst, "v1.0.0", false)
+}
+
+func TestChecksumMismatch(t *testing.T) {
+	src, dst := fixtures.NewStorage(t), fixtures.NewStorage(t)
+	fixtures.Save(t, src, "v1.0.0", "zip of v1.0.0")
+
+	r, err := Migrate(t.Context(), src, corruptingSaver{dst}, Options{})
+	require.NoError(t, err)
+	require.Len(t, r.Failed, 1)
+	require.Contains(t, r.Failed[0].Error, ".zip")
+	// the corrupted copy is deleted, so that it is copied again.
+	fixtures.RequireExists(t, dst, "v1.0.0", false)
+}
+
+func TestNoCatalog(t *testing.T) {
+	_, err := Migrate(t.Context(), fixtures.NoCatalog{Backend: fixtures.NewStorage(t)}, fixtures.NewStorage(t), Options{})
+	require.True(t, errors.Is(err, errors.KindNotImplemented))
+}
+
+type corruptingSaver struct {
+	storage.Backend
+}
+
+func (s corruptingSaver) Save(ctx context.Context, mod, ver string, gomod []byte, zip io.Reader, zipMD5, info []byte) error {
+	b, err := io.ReadAll(zip)
+	if err != nil {
+		return err
+	}
+	return s.Backend.Save(ctx, mod, ver, gomod, bytes.NewReader(b[1:]), zipMD5, info)
+}
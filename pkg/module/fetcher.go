@@ -12,3 +12,11 @@ type Fetcher interface {
 	// .info, .mod, and .zip files.
 	Fetch(ctx context.Context, mod, ver string) (*storage.Version, error)
 }
+
+// ModFetcher is implemented by fetchers that can fetch the .info and .mod
+// files of a module version without downloading its source.
+type ModFetcher interface {
+	// FetchMod resolves the version and returns the corresponding .info and
+	// .mod files. The returned version has no Zip.
+	FetchMod(ctx context.Context, mod, ver string) (*storage.Version, error)
+}
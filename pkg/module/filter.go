@@ -2,10 +2,13 @@ package module
 
 import (
 	"bufio"
+	"log"
 	"os"
 	"path/filepath"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/gomods/athens/pkg/errors"
 )
@@ -15,15 +18,33 @@ var (
 	versionSeparator = "."
 )
 
-// Filter is a filter of modules.
+// Filter is a filter of modules. It is safe for concurrent use,
+// and its rules can be replaced with Update while it is in use.
+// The zero Filter includes every module.
 type Filter struct {
+	mu       sync.RWMutex
 	root     ruleNode
+	patterns []patternRule
+	// order counts the rules added, so that the later of two rules that
+	// match as much of a module path wins.
+	order    int
 	filePath string
 }
 
+// FilterMatch is the rule that a Filter applies to a module version,
+// and the line of the filter file that it comes from. Line is 0 if no
+// line matched, or if the rule that matched was added with AddRule.
+type FilterMatch struct {
+	Rule FilterRule
+	Line int
+	Text string
+	// Quarantine is how old a version must be to be served, if Rule is Quarantine.
+	Quarantine time.Duration
+}
+
 // NewFilter creates new filter based on rules defined in a configuration file.
-// WARNING: this is not concurrently safe.
-// Configuration consists of two operations: + for include and - for exclude:
+// Configuration consists of four operations: + for include, - for exclude,
+// D for direct and Q<days> for quarantine (see QuarantineChecker):
 // e.g.
 //   - github.com/a
 //   - github.com/a/b
@@ -35,6 +56,17 @@ type Filter struct {
 //	+ github.com/a
 //
 // will exclude all items from communication except github.com/a.
+//
+// Besides a path, a rule can have a glob pattern such as github.com/*/internal-*,
+// which matches a path and its children as a path does, or a regular expression
+// between slashes, such as /github\.com/.+/internal/, which must match the whole
+// path. Whatever the kind of the rules, the rule that matches the most segments of
+// the path wins, and a regular expression matches all of them. Between rules that
+// match as many segments, the last one in the file wins.
+//
+// The path can be followed by the versions the rule applies to, either as a
+// comma separated list of the versions that are accepted, or as a semver constraint
+// expression such as ">=v1.2.0 <v2.0.0, !=v1.4.3" (see parseConstraint).
 func NewFilter(filterFilePath string) (*Filter, error) {
 	// Do not return an error if the file path is empty
 	// Do not attempt to parse it as well.
@@ -45,14 +77,34 @@ func NewFilter(filterFilePath string) (*Filter, error) {
 	return initFromConfig(filterFilePath)
 }
 
+// Update replaces the rules of f with the rules of other,
+// which must not be used afterwards.
+func (f *Filter) Update(other *Filter) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.root, f.patterns, f.order, f.filePath = other.root, other.patterns, other.order, other.filePath
+}
+
 // AddRule adds rule for specified path.
 func (f *Filter) AddRule(path string, qualifiers []string, rule FilterRule) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.addRule(path, ruleNode{rule: rule, qualifiers: qualifiers})
+}
+
+func (f *Filter) addRule(path string, rn ruleNode) {
+	f.order++
+	rn.order = f.order
+	if f.root.next == nil {
+		f.root.next = map[string]ruleNode{}
+	}
 	f.ensurePath(path)
 
 	segments := getPathSegments(path)
 
 	if len(segments) == 0 {
-		f.root.rule = rule
+		rn.next = f.root.next
+		f.root = rn
 		return
 	}
 
@@ -64,21 +116,38 @@ func (f *Filter) AddRule(path string, qualifiers []string, rule FilterRule) {
 
 	// replace with updated node
 	last := segments[len(segments)-1]
-	rn := latest.next[last]
-	rn.rule = rule
-	rn.qualifiers = qualifiers
+	rn.next = latest.next[last].next
 	latest.next[last] = rn
 }
 
 // Rule returns the filter rule to be applied to the given path.
 func (f *Filter) Rule(path, version string) FilterRule {
-	segs := getPathSegments(path)
-	rule := f.getAssociatedRule(version, segs...)
-	if rule == Default {
-		rule = Include
+	return f.Match(path, version).Rule
+}
+
+// Match returns the filter rule to be applied to the given path,
+// along with the line of the filter file that it comes from.
+// The rule of a path that no line matches is Include.
+func (f *Filter) Match(path, version string) FilterMatch {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	m := f.match(version, getPathSegments(path)...)
+	if m.rule == Default {
+		m.rule = Include
 	}
+	return FilterMatch{Rule: m.rule, Line: m.line, Text: m.text, Quarantine: m.quarantine}
+}
 
-	return rule
+// hasRule reports whether a rule was added for the given path.
+func (f *Filter) hasRule(path string) bool {
+	rn := f.root
+	for _, p := range getPathSegments(path) {
+		var ok bool
+		if rn, ok = rn.next[p]; !ok {
+			return false
+		}
+	}
+	return rn.rule != Default
 }
 
 func (f *Filter) ensurePath(path string) {
@@ -93,42 +162,31 @@ func (f *Filter) ensurePath(path string) {
 	}
 }
 
-func (f *Filter) getAssociatedRule(version string, path ...string) FilterRule {
-	if len(path) == 0 {
-		return f.root.rule
-	}
-
-	rules := make([]FilterRule, 0, len(path))
+// match returns the rule that matches the most segments of path, and
+// the latest one of those if several do. The rule of the root matches
+// no segment, so it applies only if no other rule matches.
+func (f *Filter) match(version string, path ...string) ruleNode {
+	best, depth := f.root, 0
 	rn := f.root
-	for _, p := range path {
+	for i, p := range path {
 		if _, ok := rn.next[p]; !ok {
 			break
 		}
 		rn = rn.next[p]
-		// default to true if no version filter, false otherwise
-		match := len(rn.qualifiers) == 0
-		for _, q := range rn.qualifiers {
-			if matches(version, q) {
-				match = true
-				break
-			}
-		}
-		if match || version == "" {
-			rules = append(rules, rn.rule)
+		if rn.rule != Default && rn.matchesVersion(version) {
+			best, depth = rn, i+1
 		}
 	}
-
-	if len(rules) == 0 {
-		return f.root.rule
-	}
-
-	for i := len(rules) - 1; i >= 0; i-- {
-		if rules[i] != Default {
-			return rules[i]
+	for _, pr := range f.patterns {
+		d, ok := pr.matchPath(path)
+		if !ok || !pr.matchesVersion(version) {
+			continue
+		}
+		if d > depth || (d == depth && pr.order > best.order) {
+			best, depth = pr.ruleNode, d
 		}
 	}
-
-	return f.root.rule
+	return best
 }
 
 func initFromConfig(filePath string) (*Filter, error) {
@@ -138,11 +196,10 @@ func initFromConfig(filePath string) (*Filter, error) {
 		return nil, err
 	}
 
-	rn := newRule(Default)
 	f := &Filter{
 		filePath: filePath,
 	}
-	f.root = rn
+	f.root = newRule(Default)
 
 	for idx, line := range lines {
 		// Ignore newline
@@ -152,46 +209,84 @@ func initFromConfig(filePath string) (*Filter, error) {
 		if len(line) > 0 && line[0] == '#' {
 			continue
 		}
-
-		split := strings.Split(line, " ")
-		if len(split) > 3 {
-			return nil, errors.E(op, "Invalid configuration found in filter file at the line "+strconv.Itoa(idx+1))
+		lineErr := func(msg string) error {
+			return errors.E(op, msg+" in filter file at the line "+strconv.Itoa(idx+1))
 		}
 
-		ruleSign := strings.TrimSpace(split[0])
-		var rule FilterRule
-		switch ruleSign {
-		case "+":
-			rule = Include
-		case "-":
-			rule = Exclude
-		case "D":
-			rule = Direct
+		fields := strings.Fields(line)
+		rn := ruleNode{line: idx + 1, text: line}
+		switch sign := fields[0]; {
+		case sign == "+":
+			rn.rule = Include
+		case sign == "-":
+			rn.rule = Exclude
+		case sign == "D":
+			rn.rule = Direct
+		case strings.HasPrefix(sign, "Q"):
+			days, err := strconv.Atoi(sign[1:])
+			if err != nil || days <= 0 {
+				return nil, lineErr("Invalid quarantine days found")
+			}
+			rn.rule, rn.quarantine = Quarantine, time.Duration(days)*24*time.Hour
 		default:
-			return nil, errors.E(op, "Invalid configuration found in filter file at the line "+strconv.Itoa(idx+1))
+			return nil, lineErr("Invalid configuration found")
 		}
 		// is root config
-		if len(split) == 1 {
-			f.AddRule("", nil, rule)
+		if len(fields) == 1 {
+			f.addRule("", rn)
 			continue
 		}
-		var qual []string
-		if len(split) == 3 {
-			qual = strings.Split(split[2], ",")
-			for i := range qual {
-				qual[i] = strings.TrimRight(qual[i], "*")
-				if qual[i][len(qual[i])-1] != '.' && strings.Count(qual[i], ".") < 2 {
-					qual[i] += "."
-				}
+		if len(fields) > 2 {
+			versions := strings.Join(fields[2:], " ")
+			legacy, deprecated := isLegacy(versions)
+			if deprecated {
+				log.Printf("The < of the versions %q in filter file at the line %d includes the version and is deprecated, write <= instead", versions, idx+1)
+			}
+			if legacy {
+				rn.qualifiers = parseQualifiers(versions)
+			} else if rn.constraint, err = parseConstraint(versions); err != nil {
+				return nil, lineErr("Invalid versions: " + err.Error())
 			}
 		}
 
-		path := strings.TrimSpace(split[1])
-		f.AddRule(path, qual, rule)
+		path := fields[1]
+		pr, isPattern, err := parsePattern(path)
+		if err != nil {
+			return nil, lineErr("Invalid pattern: " + err.Error())
+		}
+		if !isPattern && !f.hasRule(path) {
+			f.addRule(path, rn)
+			continue
+		}
+		// the trie has a single rule per path, so the rules of
+		// a path that already has one are kept as literal globs.
+		if !isPattern {
+			pr.glob = getPathSegments(path)
+		}
+		f.order++
+		rn.order = f.order
+		pr.ruleNode = rn
+		f.patterns = append(f.patterns, pr)
 	}
 	return f, nil
 }
 
+// parseQualifiers parses a comma separated list of versions,
+// which a version matches if it matches any of them.
+func parseQualifiers(versions string) []string {
+	qual := strings.Split(versions, ",")
+	for i := range qual {
+		qual[i] = strings.TrimRight(qual[i], "*")
+		if qual[i] == "" {
+			continue
+		}
+		if qual[i][len(qual[i])-1] != '.' && strings.Count(qual[i], ".") < 2 {
+			qual[i] += "."
+		}
+	}
+	return qual
+}
+
 // matches checks if the given version matches the given qualifier.
 // Qualifiers can be:
 // - plain versions.
@@ -286,6 +381,41 @@ func getSegments(path, separator string) []string {
 	return strings.Split(path, separator)
 }
 
+type ruleNode struct {
+	next       map[string]ruleNode
+	rule       FilterRule
+	qualifiers []string
+	constraint constraint
+	// quarantine is how old the versions of a Quarantine rule must be.
+	quarantine time.Duration
+	// order is the position of the rule in the filter,
+	// line and text the line of the filter file it comes from.
+	order int
+	line  int
+	text  string
+}
+
+// matchesVersion reports whether the rule applies to the given version.
+// A rule applies to every version if it has no versions, and every rule
+// applies to the empty version.
+func (rn ruleNode) matchesVersion(version string) bool {
+	if version == "" {
+		return true
+	}
+	if rn.constraint != nil {
+		return rn.constraint.matches(version)
+	}
+	if len(rn.qualifiers) == 0 {
+		return true
+	}
+	for _, q := range rn.qualifiers {
+		if matches(version, q) {
+			return true
+		}
+	}
+	return false
+}
+
 func newRule(r FilterRule) ruleNode {
 	rn := ruleNode{}
 	rn.next = make(map[string]ruleNode)
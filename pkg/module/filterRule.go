@@ -13,4 +13,22 @@ const (
 	Exclude
 	// Direct filter rule forces the package to be fetched directly from upstream proxy.
 	Direct
+	// Quarantine filter rule holds back the versions of a module until they are old enough.
+	Quarantine
 )
+
+// String returns the name of the rule.
+func (r FilterRule) String() string {
+	switch r {
+	case Include:
+		return "include"
+	case Exclude:
+		return "exclude"
+	case Direct:
+		return "direct"
+	case Quarantine:
+		return "quarantine"
+	default:
+		return "default"
+	}
+}
@@ -0,0 +1,53 @@
+package module
+
+import (
+	"path"
+	"regexp"
+	"strings"
+)
+
+// patternRule is a rule whose path is a glob pattern or a regular expression.
+type patternRule struct {
+	ruleNode
+	// glob holds the segments of a glob pattern, re a regular expression.
+	glob []string
+	re   *regexp.Regexp
+}
+
+// parsePattern parses the path of a filter rule. A path between slashes is a
+// regular expression, and a path with any of the characters *?[ is a glob
+// pattern. ok is false if the path is neither.
+func parsePattern(p string) (pr patternRule, ok bool, err error) {
+	if len(p) > 1 && strings.HasPrefix(p, "/") && strings.HasSuffix(p, "/") {
+		pr.re, err = regexp.Compile("^(?:" + p[1:len(p)-1] + ")$")
+		return pr, true, err
+	}
+	if !strings.ContainsAny(p, "*?[") {
+		return pr, false, nil
+	}
+	pr.glob = getPathSegments(p)
+	for _, g := range pr.glob {
+		if _, err := path.Match(g, ""); err != nil {
+			return pr, true, err
+		}
+	}
+	return pr, true, nil
+}
+
+// matchPath reports whether the rule matches the given path segments
+// and how many of them it matches. A glob pattern matches a path whose
+// first segments match its own, and a regular expression the whole path.
+func (pr patternRule) matchPath(segs []string) (int, bool) {
+	if pr.re != nil {
+		return len(segs), pr.re.MatchString(strings.Join(segs, pathSeparator))
+	}
+	if len(segs) < len(pr.glob) {
+		return 0, false
+	}
+	for i, g := range pr.glob {
+		if ok, _ := path.Match(g, segs[i]); !ok {
+			return 0, false
+		}
+	}
+	return len(pr.glob), true
+}
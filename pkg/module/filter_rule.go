@@ -1,7 +0,0 @@
-package module
-
-type ruleNode struct {
-	next       map[string]ruleNode
-	rule       FilterRule
-	qualifiers []string
-}
@@ -59,6 +59,24 @@ func (t *FilterTests) Test_IgnoreSimple() {
 	r.Equal(Include, f.Rule("bitbucket.com/a/b", ""))
 }
 
+func (t *FilterTests) Test_Update() {
+	r := t.Require()
+
+	filter := tempFilterFile(t.T())
+	defer os.Remove(filter)
+
+	f, err := NewFilter(filter)
+	r.NoError(err)
+	f.AddRule("github.com/a", nil, Exclude)
+	other, err := NewFilter(filter)
+	r.NoError(err)
+	other.AddRule("github.com/b", nil, Exclude)
+
+	f.Update(other)
+	r.Equal(Include, f.Rule("github.com/a", ""))
+	r.Equal(Exclude, f.Rule("github.com/b", ""))
+}
+
 func (t *FilterTests) Test_IgnoreParentAllowChildren() {
 	r := t.Require()
 
@@ -224,6 +242,101 @@ func (t *FilterTests) Test_initFromConfig() {
 	r.NoError(err)
 }
 
+func (t *FilterTests) Test_patterns() {
+	r := t.Require()
+	filterFile := tempFilterFile(t.T())
+	defer os.Remove(filterFile)
+
+	input := []byte("- github.com/*/internal-*\n+ github.com/acme/internal-ok\nD /gitlab\\.com/.+/vendor/\n")
+	r.NoError(os.WriteFile(filterFile, input, 0o644))
+	f, err := initFromConfig(filterFile)
+	r.NoError(err)
+
+	r.Equal(Exclude, f.Rule("github.com/a/internal-x", ""))
+	r.Equal(Exclude, f.Rule("github.com/a/internal-x/sub", ""))
+	r.Equal(Include, f.Rule("github.com/a/public", ""))
+	r.Equal(Include, f.Rule("github.com/acme/internal-ok", ""))
+	r.Equal(Direct, f.Rule("gitlab.com/a/b/vendor", ""))
+	r.Equal(Include, f.Rule("gitlab.com/a/b/vendor/c", ""))
+}
+
+func (t *FilterTests) Test_precedence() {
+	r := t.Require()
+	filterFile := tempFilterFile(t.T())
+	defer os.Remove(filterFile)
+
+	input := []byte("-\n+ github.com/a/*\nD github.com/*/b\n- github.com/a\n")
+	r.NoError(os.WriteFile(filterFile, input, 0o644))
+	f, err := initFromConfig(filterFile)
+	r.NoError(err)
+
+	// github.com/a/* and github.com/*/b match as many segments, the later wins
+	r.Equal(FilterMatch{Rule: Direct, Line: 3, Text: "D github.com/*/b"}, f.Match("github.com/a/b", ""))
+	r.Equal(FilterMatch{Rule: Include, Line: 2, Text: "+ github.com/a/*"}, f.Match("github.com/a/c", ""))
+	// the longer match wins, wherever it is in the file
+	r.Equal(FilterMatch{Rule: Exclude, Line: 4, Text: "- github.com/a"}, f.Match("github.com/a", ""))
+	r.Equal(FilterMatch{Rule: Exclude, Line: 1, Text: "-"}, f.Match("bitbucket.org/a", ""))
+}
+
+func (t *FilterTests) Test_versionConstraints() {
+	r := t.Require()
+	filterFile := tempFilterFile(t.T())
+	defer os.Remove(filterFile)
+
+	input := []byte("-\n+ github.com/a/b >=v1.2.0 <v2.0.0, !=v1.4.3\n+ github.com/c/d ~v1.2.3 || ^v3.1.0\n+ github.com/e/f =v1.2 || v2\n+ github.com/g/h ~1.2.3\n+ github.com/i/j v0.1,~v1.2.3\n+ github.com/k/l <v2.0.0\n+ github.com/m/n v0.1,<v2.0.0\n")
+	r.NoError(os.WriteFile(filterFile, input, 0o644))
+	f, err := initFromConfig(filterFile)
+	r.NoError(err)
+
+	r.Equal(Include, f.Rule("github.com/a/b", "v1.2.0"))
+	r.Equal(Include, f.Rule("github.com/a/b", "v1.9.9"))
+	r.Equal(Exclude, f.Rule("github.com/a/b", "v1.4.3"))
+	r.Equal(Exclude, f.Rule("github.com/a/b", "v1.1.9"))
+	r.Equal(Exclude, f.Rule("github.com/a/b", "v2.0.0"))
+
+	r.Equal(Include, f.Rule("github.com/c/d", "v1.2.9"))
+	r.Equal(Exclude, f.Rule("github.com/c/d", "v1.3.0"))
+	r.Equal(Include, f.Rule("github.com/c/d", "v3.9.0"))
+	r.Equal(Exclude, f.Rule("github.com/c/d", "v3.0.9"))
+
+	r.Equal(Include, f.Rule("github.com/e/f", "v1.2.7"))
+	r.Equal(Exclude, f.Rule("github.com/e/f", "v1.3.0"))
+	r.Equal(Include, f.Rule("github.com/e/f", "v2.5.0"))
+	r.Equal(Exclude, f.Rule("github.com/e/f", "not-a-version"))
+
+	// versions that are not exactly of the legacy forms are constraints.
+	r.Equal(Include, f.Rule("github.com/g/h", "v1.2.5"))
+	r.Equal(Exclude, f.Rule("github.com/g/h", "v1.3.0"))
+	r.Equal(Include, f.Rule("github.com/i/j", "v0.1.7"))
+	r.Equal(Include, f.Rule("github.com/i/j", "v1.2.4"))
+	r.Equal(Exclude, f.Rule("github.com/i/j", "v1.3.0"))
+
+	// the deprecated legacy < includes the version.
+	r.Equal(Include, f.Rule("github.com/k/l", "v2.0.0"))
+	r.Equal(Exclude, f.Rule("github.com/k/l", "v2.0.1"))
+	r.Equal(Include, f.Rule("github.com/m/n", "v2.0.0"))
+	r.Equal(Exclude, f.Rule("github.com/m/n", "v2.1.0"))
+}
+
+func (t *FilterTests) Test_parseErrors() {
+	r := t.Require()
+	filterFile := tempFilterFile(t.T())
+	defer os.Remove(filterFile)
+
+	for _, input := range []string{
+		"+ github.com/a\n\n- github.com/[b\n",
+		"+ github.com/a\n\n- /github.com/(b/\n",
+		"+ github.com/a\n\n- github.com/b >=v1.x\n",
+		"+ github.com/a\n\n- github.com/b >=v1.0.0 ||\n",
+		"+ github.com/a\n\nQ github.com/b\n",
+		"+ github.com/a\n\nQ0 github.com/b\n",
+	} {
+		r.NoError(os.WriteFile(filterFile, []byte(input), 0o644))
+		_, err := initFromConfig(filterFile)
+		r.ErrorContains(err, "at the line 3", input)
+	}
+}
+
 func tempFilterFile(t *testing.T) (path string) {
 	filter, err := os.CreateTemp(os.TempDir(), "filter-")
 	if err != nil {
@@ -0,0 +1,174 @@
+package module
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"golang.org/x/mod/semver"
+)
+
+// constraint is a semver constraint expression: a version
+// matches it if it matches all the terms of any of its alternatives.
+type constraint [][]versionTerm
+
+// versionTerm is a single comparison of a version, such as >=v1.2.0.
+type versionTerm struct {
+	op      string
+	version string
+	// partial is true for versions such as v1 or v1.2.*,
+	// which match every version that starts with them.
+	partial bool
+}
+
+// constraintOps are the operators of a version term,
+// longest first so that >= is not read as >.
+var constraintOps = []string{">=", "<=", "!=", ">", "<", "=", "~", "^"}
+
+// legacyVersion matches the versions of the comma separated lists of filter
+// files from before constraint expressions: a version prefix, such as v1.2 or
+// v1.2.*, or a three part version after ~ or ^.
+var legacyVersion = regexp.MustCompile(`^(v[0-9][0-9A-Za-z.+-]*\*?|[~^]v[0-9]+\.[0-9]+\.[0-9]+)$`)
+
+// legacyLess matches the < of those lists, which includes the version
+// unlike the < of constraints. It is deprecated in favour of <=.
+var legacyLess = regexp.MustCompile(`^<v[0-9]+\.[0-9]+\.[0-9]+$`)
+
+// isLegacy reports whether the versions of a filter rule are a comma separated
+// list of versions of the legacy forms rather than a constraint expression,
+// and whether the list has a deprecated legacy <.
+func isLegacy(versions string) (legacy, deprecated bool) {
+	if strings.ContainsAny(versions, " \t|>=!") {
+		return false, false
+	}
+	for _, v := range strings.Split(versions, ",") {
+		switch {
+		case legacyLess.MatchString(v):
+			deprecated = true
+		case !legacyVersion.MatchString(v):
+			return false, false
+		}
+	}
+	return true, deprecated
+}
+
+// parseConstraint parses a semver constraint expression. Alternatives are
+// separated with ||, and the terms of an alternative with commas or spaces.
+// A term is a version preceded by one of the operators >=, <=, !=, >, <, =,
+// ~ (same minor version and at least that version) or ^ (same major version
+// and at least that version). A version without an operator, or with =,
+// matches that version only, unless it is partial, such as v1.2 or v1.2.*,
+// in which case it matches all the versions that start with it.
+func parseConstraint(expr string) (constraint, error) {
+	var c constraint
+	for _, alt := range strings.Split(expr, "||") {
+		fields := strings.FieldsFunc(alt, func(r rune) bool {
+			return r == ',' || r == ' ' || r == '\t'
+		})
+		if len(fields) == 0 {
+			return nil, fmt.Errorf("empty alternative in %q", expr)
+		}
+		terms := make([]versionTerm, 0, len(fields))
+		// an operator may be separated from its version, as in ">= v1.2.0"
+		for i := 0; i < len(fields); i++ {
+			f := fields[i]
+			if isOp(f) && i+1 < len(fields) {
+				i++
+				f += fields[i]
+			}
+			t, err := parseTerm(f)
+			if err != nil {
+				return nil, err
+			}
+			terms = append(terms, t)
+		}
+		c = append(c, terms)
+	}
+	return c, nil
+}
+
+func isOp(s string) bool {
+	for _, op := range constraintOps {
+		if s == op {
+			return true
+		}
+	}
+	return false
+}
+
+func parseTerm(s string) (versionTerm, error) {
+	var t versionTerm
+	for _, op := range constraintOps {
+		if strings.HasPrefix(s, op) {
+			t.op = op
+			break
+		}
+	}
+	v := strings.TrimPrefix(s[len(t.op):], "v")
+	v = "v" + strings.TrimSuffix(strings.TrimSuffix(v, "*"), ".")
+	if t.op == "" {
+		t.op = "="
+	}
+	if !semver.IsValid(v) {
+		return t, fmt.Errorf("invalid version %q", s)
+	}
+	t.version = v
+	t.partial = semver.Canonical(v) != v && !strings.ContainsAny(v, "-+") && strings.Count(v, ".") < 2
+	if t.partial && t.op != "=" && t.op != "!=" {
+		// compare partial versions as the lowest version they match
+		t.version, t.partial = semver.Canonical(v), false
+	}
+	return t, nil
+}
+
+func (c constraint) matches(version string) bool {
+	if !semver.IsValid(version) {
+		return false
+	}
+	for _, terms := range c {
+		ok := true
+		for _, t := range terms {
+			if !t.matches(version) {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			return true
+		}
+	}
+	return false
+}
+
+func (t versionTerm) matches(version string) bool {
+	cmp := semver.Compare(version, t.version)
+	switch t.op {
+	case "=":
+		return t.equals(version)
+	case "!=":
+		return !t.equals(version)
+	case ">=":
+		return cmp >= 0
+	case "<=":
+		return cmp <= 0
+	case ">":
+		return cmp > 0
+	case "<":
+		return cmp < 0
+	case "~":
+		return cmp >= 0 && semver.MajorMinor(version) == semver.MajorMinor(t.version)
+	case "^":
+		return cmp >= 0 && semver.Major(version) == semver.Major(t.version)
+	}
+	return false
+}
+
+func (t versionTerm) equals(version string) bool {
+	if !t.partial {
+		return semver.Compare(version, t.version) == 0
+	}
+	if strings.Count(t.version, ".") == 0 {
+		return semver.Major(version) == t.version
+	}
+	return semver.MajorMinor(version) == t.version
+}
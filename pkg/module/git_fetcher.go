@@ -0,0 +1,383 @@
+package module
+
+import (
+	"context"
+	"crypto/md5" //nolint:gosec
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/gomods/athens/pkg/errors"
+	"github.com/gomods/athens/pkg/observ"
+	"github.com/gomods/athens/pkg/storage"
+	"github.com/spf13/afero"
+	"golang.org/x/mod/modfile"
+	modpath "golang.org/x/mod/module"
+	modzip "golang.org/x/mod/zip"
+)
+
+type gitFetcher struct {
+	dir     string
+	maxSize int64
+	resolve RepoResolver
+}
+
+// gitMirrorLocks serializes access to each mirror, by path, since go-git
+// repositories are not safe for concurrent use.
+var gitMirrorLocks = &mirrorLocks{locks: map[string]*mirrorLock{}}
+
+// NewGitFetcher creates a fetcher which clones git repositories itself and builds
+// module zips without invoking the go binary or git. Mirrors of the repositories
+// are kept in dir and checked against the remote on every fetch, and the least
+// recently used ones are removed once they take more than maxSize bytes. A maxSize
+// of zero keeps every mirror. The resolver maps module paths to repositories;
+// NewRepoResolver is used when it is nil.
+func NewGitFetcher(dir string, maxSize int64, resolve RepoResolver) (Fetcher, error) {
+	const op errors.Op = "module.NewGitFetcher"
+	if dir == "" {
+		dir = os.TempDir()
+	}
+	if err := os.MkdirAll(dir, os.ModeDir|os.ModePerm); err != nil {
+		return nil, errors.E(op, err)
+	}
+	return newGitFetcher(dir, maxSize, resolve), nil
+}
+
+func newGitFetcher(dir string, maxSize int64, resolve RepoResolver) *gitFetcher {
+	if resolve == nil {
+		resolve = NewRepoResolver(nil)
+	}
+	return &gitFetcher{dir: dir, maxSize: maxSize, resolve: resolve}
+}
+
+// Fetch resolves the version in the module's repository and returns the
+// corresponding .info, .mod, and .zip files.
+func (g *gitFetcher) Fetch(ctx context.Context, mod, ver string) (*storage.Version, error) {
+	const op errors.Op = "gitFetcher.Fetch"
+	ctx, span := observ.StartSpan(ctx, op.String())
+	defer span.End()
+
+	var v *storage.Version
+	err := g.withRepo(ctx, mod, func(r *gitRepo, m gitModule) error {
+		rev, err := r.resolve(m, ver)
+		if err != nil {
+			return err
+		}
+		v, err = buildVersion(r, m, rev, g.dir)
+		return err
+	})
+	if err != nil {
+		return nil, errors.E(op, errors.M(mod), errors.V(ver), err)
+	}
+	return v, nil
+}
+
+// FetchMod resolves the version in the module's repository and returns
+// the corresponding .info and .mod files without building the zip.
+func (g *gitFetcher) FetchMod(ctx context.Context, mod, ver string) (*storage.Version, error) {
+	const op errors.Op = "gitFetcher.FetchMod"
+	ctx, span := observ.StartSpan(ctx, op.String())
+	defer span.End()
+
+	var v *storage.Version
+	err := g.withRepo(ctx, mod, func(r *gitRepo, m gitModule) error {
+		rev, err := r.resolve(m, ver)
+		if err != nil {
+			return err
+		}
+		v, err = buildMetadata(r, m, rev)
+		return err
+	})
+	if err != nil {
+		return nil, errors.E(op, errors.M(mod), errors.V(ver), err)
+	}
+	return v, nil
+}
+
+// withRepo opens an up to date mirror of the repository hosting mod and calls
+// f while holding the mirror's lock. The least recently used mirrors are then
+// evicted.
+func (g *gitFetcher) withRepo(ctx context.Context, mod string, f func(r *gitRepo, m gitModule) error) error {
+	const op errors.Op = "gitFetcher.withRepo"
+	root, err := g.resolve(ctx, mod)
+	if err != nil {
+		return errors.E(op, err)
+	}
+	m, err := newGitModule(mod, root)
+	if err != nil {
+		return errors.E(op, err)
+	}
+
+	repoDir := gitMirrorDir(g.dir, root.URL)
+	unlock := gitMirrorLocks.lock(repoDir)
+	r, err := openGitRepo(ctx, repoDir, root.URL)
+	if err == nil {
+		err = f(r, m)
+		now := time.Now()
+		_ = os.Chtimes(repoDir, now, now)
+	}
+	unlock()
+	_ = g.evict()
+	if err != nil {
+		return errors.E(op, err)
+	}
+	return nil
+}
+
+// evict removes the least recently used mirrors until they fit in the
+// maximum size. Mirrors in use are kept, the next call will catch up.
+func (g *gitFetcher) evict() error {
+	const op errors.Op = "gitFetcher.evict"
+	if g.maxSize <= 0 {
+		return nil
+	}
+	fs := afero.NewOsFs()
+	entries, err := afero.ReadDir(fs, g.dir)
+	if err != nil {
+		return errors.E(op, err)
+	}
+	var repos []cachedRepo
+	for _, e := range entries {
+		if !e.IsDir() || !isGitMirrorDir(e.Name()) {
+			continue
+		}
+		repo := cachedRepo{dir: filepath.Join(g.dir, e.Name()), lastUsed: e.ModTime()}
+		repo.size, err = dirSize(fs, repo.dir)
+		if err != nil {
+			return errors.E(op, err)
+		}
+		repos = append(repos, repo)
+	}
+
+	// no mirror can be locked while they are removed.
+	gitMirrorLocks.mu.Lock()
+	defer gitMirrorLocks.mu.Unlock()
+	err = removeLeastRecentlyUsed(repos, g.maxSize, func(repo cachedRepo) (bool, error) {
+		if _, ok := gitMirrorLocks.locks[repo.dir]; ok {
+			return false, nil
+		}
+		return true, os.RemoveAll(repo.dir)
+	})
+	if err != nil {
+		return errors.E(op, err)
+	}
+	return nil
+}
+
+// mirrorLocks holds a lock for every mirror in use, and
+// only for those, so that it does not grow with every
+// repository ever fetched.
+type mirrorLocks struct {
+	mu    sync.Mutex
+	locks map[string]*mirrorLock
+}
+
+type mirrorLock struct {
+	sync.Mutex
+	// users is the number of callers holding or waiting for the lock.
+	users int
+}
+
+// lock locks the mirror in dir until the returned func is called.
+func (l *mirrorLocks) lock(dir string) func() {
+	l.mu.Lock()
+	ml, ok := l.locks[dir]
+	if !ok {
+		ml = &mirrorLock{}
+		l.locks[dir] = ml
+	}
+	ml.users++
+	l.mu.Unlock()
+
+	ml.Lock()
+	return func() {
+		ml.Unlock()
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		ml.users--
+		if ml.users == 0 {
+			delete(l.locks, dir)
+		}
+	}
+}
+
+// buildVersion returns the .info, .mod and .zip files of the revision,
+// the zip being written to a temporary file in tmpDir.
+func buildVersion(r *gitRepo, m gitModule, rev *gitRevision, tmpDir string) (*storage.Version, error) {
+	const op errors.Op = "module.buildVersion"
+	v, err := buildMetadata(r, m, rev)
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+	tree, err := rev.commit.Tree()
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+	v.Zip, v.ZipMD5, err = buildZip(tree, m.codeDir(tree), modpath.Version{Path: m.path, Version: rev.version}, tmpDir)
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+	return v, nil
+}
+
+// buildMetadata returns the .info and .mod files of the revision.
+func buildMetadata(r *gitRepo, m gitModule, rev *gitRevision) (*storage.Version, error) {
+	const op errors.Op = "module.buildMetadata"
+	tree, err := rev.commit.Tree()
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+	codeDir := m.codeDir(tree)
+
+	goMod, err := readGoMod(tree, codeDir, m.path)
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+
+	info, err := json.Marshal(storage.RevInfo{
+		Version: rev.version,
+		Time:    rev.commit.Committer.When.UTC(),
+		Origin: &storage.Origin{
+			VCS:    "git",
+			URL:    r.url,
+			Subdir: m.subdir,
+			Ref:    rev.ref,
+			Hash:   rev.commit.Hash.String(),
+		},
+	})
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+
+	return &storage.Version{
+		Semver: rev.version,
+		Info:   info,
+		Mod:    goMod,
+	}, nil
+}
+
+// readGoMod returns the go.mod file of the module, or a synthesized
+// one if the module predates go.mod files.
+func readGoMod(tree *object.Tree, codeDir, mod string) ([]byte, error) {
+	const op errors.Op = "module.readGoMod"
+	f, err := tree.File(path.Join(codeDir, "go.mod"))
+	if errors.IsErr(err, object.ErrFileNotFound) {
+		return []byte(fmt.Sprintf("module %s\n", modfile.AutoQuote(mod))), nil
+	}
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+	contents, err := f.Contents()
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+	if got := modfile.ModulePath([]byte(contents)); got != mod {
+		return nil, errors.E(op, fmt.Sprintf("go.mod has module path %q, expected %q", got, mod), errors.KindNotFound)
+	}
+	return []byte(contents), nil
+}
+
+// buildZip writes the module zip of codeDir to a temporary file in dir, following the
+// same rules as the go command: nested modules and vendor directories are left out
+// and, for modules in a subdirectory, the repository's LICENSE is included.
+func buildZip(tree *object.Tree, codeDir string, mv modpath.Version, dir string) (io.ReadCloser, []byte, error) {
+	const op errors.Op = "module.buildZip"
+	modTree := tree
+	if codeDir != "" {
+		var err error
+		modTree, err = tree.Tree(codeDir)
+		if err != nil {
+			return nil, nil, errors.E(op, err)
+		}
+	}
+	var files []modzip.File
+	hasLicense := false
+	err := modTree.Files().ForEach(func(f *object.File) error {
+		hasLicense = hasLicense || f.Name == "LICENSE"
+		files = append(files, gitZipFile{f})
+		return nil
+	})
+	if err != nil {
+		return nil, nil, errors.E(op, err)
+	}
+	if codeDir != "" && !hasLicense {
+		if f, err := tree.File("LICENSE"); err == nil {
+			files = append(files, gitZipFile{f})
+		}
+	}
+
+	// files that the go command would not zip make the version unusable,
+	// while Create may still fail to read the files of a valid one.
+	if _, err := modzip.CheckFiles(files); err != nil {
+		return nil, nil, errors.E(op, err, errors.KindNotFound)
+	}
+	tmpDir, err := os.MkdirTemp(dir, "athens-git-zip")
+	if err != nil {
+		return nil, nil, errors.E(op, err)
+	}
+	osFs := afero.NewOsFs()
+	zip, err := os.Create(filepath.Join(tmpDir, "module.zip"))
+	if err != nil {
+		_ = os.RemoveAll(tmpDir)
+		return nil, nil, errors.E(op, err)
+	}
+	rc := &zipReadCloser{zip: zip, fs: osFs, goPath: tmpDir}
+
+	//nolint:gosec
+	hash := md5.New()
+	if err := modzip.Create(io.MultiWriter(zip, hash), mv, files); err != nil {
+		_ = rc.Close()
+		return nil, nil, errors.E(op, err)
+	}
+	if _, err := zip.Seek(0, io.SeekStart); err != nil {
+		_ = rc.Close()
+		return nil, nil, errors.E(op, err)
+	}
+	return rc, hash.Sum(nil), nil
+}
+
+// gitZipFile adapts a file of a git tree to zip.File.
+type gitZipFile struct {
+	f *object.File
+}
+
+func (z gitZipFile) Path() string {
+	return z.f.Name
+}
+
+func (z gitZipFile) Lstat() (fs.FileInfo, error) {
+	return gitFileInfo{z.f}, nil
+}
+
+func (z gitZipFile) Open() (io.ReadCloser, error) {
+	return z.f.Reader()
+}
+
+type gitFileInfo struct {
+	f *object.File
+}
+
+func (i gitFileInfo) Name() string       { return path.Base(i.f.Name) }
+func (i gitFileInfo) Size() int64        { return i.f.Size }
+func (i gitFileInfo) ModTime() time.Time { return time.Time{} }
+func (i gitFileInfo) IsDir() bool        { return false }
+func (i gitFileInfo) Sys() any           { return nil }
+
+func (i gitFileInfo) Mode() fs.FileMode {
+	switch i.f.Mode {
+	case filemode.Executable:
+		return 0o755
+	case filemode.Symlink:
+		return fs.ModeSymlink | 0o777
+	default:
+		return 0o644
+	}
+}
@@ -0,0 +1,339 @@
+package module
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/gomods/athens/pkg/errors"
+	"github.com/gomods/athens/pkg/storage"
+	"github.com/spf13/afero"
+)
+
+var gitTestTime = time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+// testGitRepo builds a git repository in a temp dir, one commit at a time.
+type testGitRepo struct {
+	s    *ModuleSuite
+	dir  string
+	repo *git.Repository
+	n    int
+}
+
+func (s *ModuleSuite) newTestGitRepo() *testGitRepo {
+	dir := s.T().TempDir()
+	repo, err := git.PlainInit(dir, false)
+	s.Require().NoError(err)
+	return &testGitRepo{s: s, dir: dir, repo: repo}
+}
+
+// commit writes the files, an empty content deletes the file, and commits them.
+func (tr *testGitRepo) commit(files map[string]string) plumbing.Hash {
+	r := tr.s.Require()
+	wt, err := tr.repo.Worktree()
+	r.NoError(err)
+	for name, content := range files {
+		p := filepath.Join(tr.dir, name)
+		if content == "" {
+			_, err = wt.Remove(name)
+			r.NoError(err)
+			continue
+		}
+		r.NoError(os.MkdirAll(filepath.Dir(p), 0o755))
+		r.NoError(os.WriteFile(p, []byte(content), 0o644))
+		_, err = wt.Add(name)
+		r.NoError(err)
+	}
+	tr.n++
+	sig := &object.Signature{Name: "gopher", Email: "gopher@example.com", When: gitTestTime.Add(time.Duration(tr.n) * time.Hour)}
+	h, err := wt.Commit("commit", &git.CommitOptions{Author: sig, Committer: sig})
+	r.NoError(err)
+	return h
+}
+
+func (tr *testGitRepo) tag(name string, h plumbing.Hash, annotated bool) {
+	var opts *git.CreateTagOptions
+	if annotated {
+		opts = &git.CreateTagOptions{
+			Tagger:  &object.Signature{Name: "gopher", Email: "gopher@example.com", When: gitTestTime},
+			Message: name,
+		}
+	}
+	_, err := tr.repo.CreateTag(name, h, opts)
+	tr.s.Require().NoError(err)
+}
+
+// bare clones the repository into a new bare repository and returns its path.
+// The in process file transport only serves git directories, hence the .git.
+func (tr *testGitRepo) bare() string {
+	serveLocalReposInProcess()
+	dir := tr.s.T().TempDir()
+	_, err := git.PlainClone(dir, true, &git.CloneOptions{URL: filepath.Join(tr.dir, ".git"), Tags: git.AllTags})
+	tr.s.Require().NoError(err)
+	return dir
+}
+
+func staticResolver(root, url string) RepoResolver {
+	return func(ctx context.Context, mod string) (RepoRoot, error) {
+		return RepoRoot{Root: root, URL: url}, nil
+	}
+}
+
+func (s *ModuleSuite) zipFiles(v *storage.Version) []string {
+	r := s.Require()
+	defer v.Zip.Close()
+	b, err := io.ReadAll(v.Zip)
+	r.NoError(err)
+	zr, err := zip.NewReader(bytes.NewReader(b), int64(len(b)))
+	r.NoError(err)
+	var names []string
+	for _, f := range zr.File {
+		names = append(names, f.Name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (s *ModuleSuite) TestGitFetcherFetch() {
+	r := s.Require()
+	ctx := s.T().Context()
+	tr := s.newTestGitRepo()
+	c1 := tr.commit(map[string]string{
+		"go.mod":               "module example.com/repo\n",
+		"a.go":                 "package a\n",
+		"vendor/x/x.go":        "package x\n",
+		"nested/go.mod":        "module example.com/repo/nested\n",
+		"nested/nested.go":     "package nested\n",
+		"internal/internal.go": "package internal\n",
+	})
+	tr.tag("v1.0.0", c1, true)
+	c2 := tr.commit(map[string]string{"b.go": "package a\n"})
+	url := tr.bare()
+
+	fetcher, err := NewGitFetcher(s.T().TempDir(), 0, staticResolver("example.com/repo", url))
+	r.NoError(err)
+
+	v, err := fetcher.Fetch(ctx, "example.com/repo", "v1.0.0")
+	r.NoError(err)
+	r.Equal("v1.0.0", v.Semver)
+	r.Equal("module example.com/repo\n", string(v.Mod))
+	var info storage.RevInfo
+	r.NoError(json.Unmarshal(v.Info, &info))
+	r.Equal("v1.0.0", info.Version)
+	r.Equal(gitTestTime.Add(time.Hour), info.Time)
+	r.Equal(&storage.Origin{VCS: "git", URL: url, Ref: "refs/tags/v1.0.0", Hash: c1.String()}, info.Origin)
+	r.Equal([]string{
+		"example.com/repo@v1.0.0/a.go",
+		"example.com/repo@v1.0.0/go.mod",
+		"example.com/repo@v1.0.0/internal/internal.go",
+	}, s.zipFiles(v))
+
+	// a branch resolves to a pseudo-version based on the last tag.
+	pseudo := "v1.0.1-0.20240301140000-" + c2.String()[:12]
+	v, err = fetcher.Fetch(ctx, "example.com/repo", "master")
+	r.NoError(err)
+	r.Equal(pseudo, v.Semver)
+	r.NoError(json.Unmarshal(v.Info, &info))
+	r.Equal("refs/heads/master", info.Origin.Ref)
+	r.Contains(s.zipFiles(v), "example.com/repo@"+pseudo+"/b.go")
+
+	v, err = fetcher.Fetch(ctx, "example.com/repo", pseudo)
+	r.NoError(err)
+	r.Equal(pseudo, v.Semver)
+	v.Zip.Close()
+
+	// a commit hash with a tag resolves to the tag.
+	v, err = fetcher.Fetch(ctx, "example.com/repo", c1.String()[:8])
+	r.NoError(err)
+	r.Equal("v1.0.0", v.Semver)
+	v.Zip.Close()
+
+	// fetching only the metadata resolves versions the same way.
+	v, err = fetcher.(ModFetcher).FetchMod(ctx, "example.com/repo", "master")
+	r.NoError(err)
+	r.Equal(pseudo, v.Semver)
+	r.Equal("module example.com/repo\n", string(v.Mod))
+	r.Nil(v.Zip)
+
+	for _, ver := range []string{"v1.2.3", "v1.0.1-0.20240301150000-" + c2.String()[:12], "nobranch", "v2.0.0"} {
+		_, err = fetcher.Fetch(ctx, "example.com/repo", ver)
+		r.Equal(errors.KindNotFound, errors.Kind(err), ver)
+	}
+
+	// a pseudo-version must be based on a tag that the commit descends from.
+	c3 := tr.commit(map[string]string{"c.go": "package a\n"})
+	tr.tag("v1.1.0", c3, false)
+	fetcher, err = NewGitFetcher(s.T().TempDir(), 0, staticResolver("example.com/repo", tr.bare()))
+	r.NoError(err)
+	for _, ver := range []string{"v1.1.1-0.20240301140000-" + c2.String()[:12], "v1.0.6-0.20240301140000-" + c2.String()[:12]} {
+		_, err = fetcher.Fetch(ctx, "example.com/repo", ver)
+		r.Equal(errors.KindNotFound, errors.Kind(err), ver)
+	}
+	v, err = fetcher.Fetch(ctx, "example.com/repo", pseudo)
+	r.NoError(err)
+	v.Zip.Close()
+}
+
+func (s *ModuleSuite) TestGitFetcherSubdirAndMajorVersions() {
+	r := s.Require()
+	ctx := s.T().Context()
+	tr := s.newTestGitRepo()
+	c1 := tr.commit(map[string]string{
+		"LICENSE":        "license\n",
+		"sub/go.mod":     "module example.com/repo/sub\n",
+		"sub/sub.go":     "package sub\n",
+		"sub/v2/go.mod":  "module example.com/repo/sub/v2\n",
+		"sub/v2/sub.go":  "package sub\n",
+		"legacy/old.go":  "package legacy\n",
+		"legacy/README":  "readme\n",
+		"legacy/LICENSE": "legacy license\n",
+	})
+	tr.tag("sub/v0.1.0", c1, false)
+	tr.tag("sub/v2.1.0", c1, false)
+	tr.tag("legacy/v3.0.0", c1, true)
+	url := tr.bare()
+	fetcher, err := NewGitFetcher(s.T().TempDir(), 0, staticResolver("example.com/repo", url))
+	r.NoError(err)
+
+	v, err := fetcher.Fetch(ctx, "example.com/repo/sub", "v0.1.0")
+	r.NoError(err)
+	var info storage.RevInfo
+	r.NoError(json.Unmarshal(v.Info, &info))
+	r.Equal("sub", info.Origin.Subdir)
+	r.Equal("refs/tags/sub/v0.1.0", info.Origin.Ref)
+	r.Equal([]string{
+		"example.com/repo/sub@v0.1.0/LICENSE",
+		"example.com/repo/sub@v0.1.0/go.mod",
+		"example.com/repo/sub@v0.1.0/sub.go",
+	}, s.zipFiles(v))
+
+	// v2 lives in a major version subdirectory.
+	v, err = fetcher.Fetch(ctx, "example.com/repo/sub/v2", "v2.1.0")
+	r.NoError(err)
+	r.Equal("module example.com/repo/sub/v2\n", string(v.Mod))
+	r.Equal([]string{
+		"example.com/repo/sub/v2@v2.1.0/LICENSE",
+		"example.com/repo/sub/v2@v2.1.0/go.mod",
+		"example.com/repo/sub/v2@v2.1.0/sub.go",
+	}, s.zipFiles(v))
+
+	// a v2+ tag without go.mod is only available as +incompatible.
+	_, err = fetcher.Fetch(ctx, "example.com/repo/legacy", "v3.0.0")
+	r.Equal(errors.KindNotFound, errors.Kind(err))
+	v, err = fetcher.Fetch(ctx, "example.com/repo/legacy", "v3.0.0+incompatible")
+	r.NoError(err)
+	r.Equal("module example.com/repo/legacy\n", string(v.Mod))
+	r.Equal([]string{
+		"example.com/repo/legacy@v3.0.0+incompatible/LICENSE",
+		"example.com/repo/legacy@v3.0.0+incompatible/README",
+		"example.com/repo/legacy@v3.0.0+incompatible/old.go",
+	}, s.zipFiles(v))
+}
+
+func (s *ModuleSuite) TestGitFetcherEvictsMirrors() {
+	r := s.Require()
+	ctx := s.T().Context()
+	urls := map[string]string{}
+	for _, name := range []string{"old", "new"} {
+		tr := s.newTestGitRepo()
+		tr.tag("v1.0.0", tr.commit(map[string]string{"go.mod": "module example.com/" + name + "\n"}), false)
+		urls["example.com/"+name] = tr.bare()
+	}
+	resolve := func(ctx context.Context, mod string) (RepoRoot, error) {
+		return RepoRoot{Root: mod, URL: urls[mod]}, nil
+	}
+	g := newGitFetcher(s.T().TempDir(), 0, resolve)
+	for _, mod := range []string{"example.com/old", "example.com/new"} {
+		_, err := g.FetchMod(ctx, mod, "v1.0.0")
+		r.NoError(err)
+	}
+	oldDir, newDir := gitMirrorDir(g.dir, urls["example.com/old"]), gitMirrorDir(g.dir, urls["example.com/new"])
+	r.NoError(os.Chtimes(oldDir, gitTestTime, gitTestTime))
+	size, err := dirSize(afero.NewOsFs(), newDir)
+	r.NoError(err)
+	g.maxSize = size
+
+	r.NoError(g.evict())
+	r.NoDirExists(oldDir)
+	r.DirExists(newDir)
+
+	// a mirror in use is kept whatever its size, and its lock
+	// is dropped once it is no longer used.
+	g.maxSize = 1
+	unlock := gitMirrorLocks.lock(newDir)
+	r.NoError(g.evict())
+	r.DirExists(newDir)
+	unlock()
+	r.Empty(gitMirrorLocks.locks)
+}
+
+func (s *ModuleSuite) TestGitLister() {
+	r := s.Require()
+	ctx := s.T().Context()
+	tr := s.newTestGitRepo()
+	c1 := tr.commit(map[string]string{"go.mod": "module example.com/repo\n"})
+	tr.tag("v0.1.0", c1, false)
+	c2 := tr.commit(map[string]string{"a.go": "package a\n"})
+	tr.tag("v0.2.0-rc.1", c2, true)
+	tr.tag("v0.1.1", c2, false)
+	tr.tag("not-a-version", c2, false)
+	tr.commit(map[string]string{"b.go": "package a\n"})
+
+	lister := NewGitLister(s.T().TempDir(), 0, staticResolver("example.com/repo", tr.bare()), time.Minute, nil)
+	rev, versions, err := lister.List(ctx, "example.com/repo")
+	r.NoError(err)
+	r.Equal([]string{"v0.1.0", "v0.1.1", "v0.2.0-rc.1"}, versions)
+	r.Equal("v0.1.1", rev.Version)
+	r.Equal(gitTestTime.Add(2*time.Hour), rev.Time)
+
+	// without tags the latest version is a pseudo-version of the default branch.
+	untagged := s.newTestGitRepo()
+	head := untagged.commit(map[string]string{"go.mod": "module example.com/untagged\n"})
+	lister = NewGitLister(s.T().TempDir(), 0, staticResolver("example.com/untagged", untagged.bare()), time.Minute, nil)
+	rev, versions, err = lister.List(ctx, "example.com/untagged")
+	r.NoError(err)
+	r.Empty(versions)
+	r.Equal("v0.0.0-20240301130000-"+head.String()[:12], rev.Version)
+
+	missing := filepath.Join(s.T().TempDir(), "missing")
+	lister = NewGitLister(s.T().TempDir(), 0, staticResolver("example.com/missing", missing), time.Minute, nil)
+	_, _, err = lister.List(ctx, "example.com/missing")
+	r.Equal(errors.KindNotFound, errors.Kind(err))
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func (s *ModuleSuite) TestRepoResolver() {
+	r := s.Require()
+	root, err := NewRepoResolver(nil)(s.T().Context(), "github.com/NYTimes/gizmo/server/v2")
+	r.NoError(err)
+	r.Equal(RepoRoot{Root: "github.com/NYTimes/gizmo", URL: "https://github.com/NYTimes/gizmo"}, root)
+
+	// a host that cannot be reached is not a module that does not exist
+	unreachable := &http.Client{Transport: roundTripFunc(func(*http.Request) (*http.Response, error) {
+		return nil, errors.E("dial", "connection refused")
+	})}
+	_, err = NewRepoResolver(unreachable)(s.T().Context(), "example.com/repo")
+	r.Equal(errors.KindUnexpected, errors.Kind(err))
+
+	imports := parseGoImports(strings.NewReader(`<html><head>
+<meta name="go-import" content="example.com/repo git https://git.example.com/repo.git">
+<meta name="go-source" content="example.com/repo _ _ _">
+</head><body><meta name="go-import" content="example.com/ignored git https://ignored"></body></html>`))
+	r.Equal([]goImport{{prefix: "example.com/repo", vcs: "git", repo: "https://git.example.com/repo.git"}}, imports)
+}
@@ -0,0 +1,79 @@
+package module
+
+import (
+	"context"
+	"time"
+
+	"github.com/gomods/athens/pkg/download/mode"
+	"github.com/gomods/athens/pkg/errors"
+	"github.com/gomods/athens/pkg/observ"
+	"github.com/gomods/athens/pkg/storage"
+	"golang.org/x/sync/singleflight"
+)
+
+type gitLister struct {
+	g       *gitFetcher
+	sfg     *singleflight.Group
+	timeout time.Duration
+	df      *mode.DownloadFile
+}
+
+// NewGitLister creates an UpstreamLister which lists the tags of a module's git
+// repository without invoking the go binary or git. It shares the repository
+// mirrors in dir, and their maxSize, with a NewGitFetcher using the same directory.
+// If df is not nil, the download path that matches a module may override timeout.
+func NewGitLister(dir string, maxSize int64, resolve RepoResolver, timeout time.Duration, df *mode.DownloadFile) UpstreamLister {
+	return &gitLister{
+		g:       newGitFetcher(dir, maxSize, resolve),
+		sfg:     &singleflight.Group{},
+		timeout: timeout,
+		df:      df,
+	}
+}
+
+// List returns the tagged versions of the module. The latest version is the
+// highest release, or prerelease if there are none, and otherwise a
+// pseudo-version of the default branch.
+func (l *gitLister) List(ctx context.Context, mod string) (*storage.RevInfo, []string, error) {
+	const op errors.Op = "gitLister.List"
+	ctx, span := observ.StartSpan(ctx, op.String())
+	defer span.End()
+	sfResp, err, _ := l.sfg.Do(mod, func() (any, error) {
+		timeout := l.timeout
+		if l.df != nil {
+			timeout = l.df.Timeout(mod, timeout)
+		}
+		timeoutCtx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+
+		var resp listSFResp
+		err := l.g.withRepo(timeoutCtx, mod, func(r *gitRepo, m gitModule) error {
+			versions, err := r.versions(m)
+			if err != nil {
+				return err
+			}
+			query := "HEAD"
+			if len(versions) > 0 {
+				query = highestVersion(versions)
+			}
+			rev, err := r.resolve(m, query)
+			if err != nil {
+				return err
+			}
+			resp = listSFResp{
+				rev:      &storage.RevInfo{Version: rev.version, Time: rev.commit.Committer.When.UTC()},
+				versions: versions,
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, errors.E(op, errors.M(mod), err)
+		}
+		return resp, nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	ret := sfResp.(listSFResp)
+	return ret.rev, ret.versions, nil
+}
@@ -0,0 +1,523 @@
+package module
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	gitconfig "github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/storer"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"github.com/go-git/go-git/v5/plumbing/transport/client"
+	"github.com/go-git/go-git/v5/plumbing/transport/server"
+	"github.com/gomods/athens/pkg/errors"
+	modpath "golang.org/x/mod/module"
+	"golang.org/x/mod/semver"
+)
+
+// gitMirrorRefSpecs mirrors every branch and tag of the remote.
+var gitMirrorRefSpecs = []gitconfig.RefSpec{
+	"+refs/heads/*:refs/heads/*",
+	"+refs/tags/*:refs/tags/*",
+}
+
+var installFileTransport sync.Once
+
+// serveLocalReposInProcess makes go-git serve file:// and local path remotes
+// itself, as by default it shells out to git-upload-pack for them.
+func serveLocalReposInProcess() {
+	installFileTransport.Do(func() {
+		client.InstallProtocol("file", server.DefaultServer)
+	})
+}
+
+// gitRepo is a local bare mirror of a remote git repository.
+type gitRepo struct {
+	url  string
+	repo *git.Repository
+}
+
+// gitMirrorDir returns the directory inside dir that mirrors url.
+func gitMirrorDir(dir, url string) string {
+	sum := sha256.Sum256([]byte(url))
+	return filepath.Join(dir, hex.EncodeToString(sum[:]))
+}
+
+// isGitMirrorDir reports whether name is the name gitMirrorDir gives mirrors.
+func isGitMirrorDir(name string) bool {
+	return len(name) == 2*sha256.Size && strings.Trim(name, "0123456789abcdef") == ""
+}
+
+// openGitRepo opens the mirror of url in repoDir, creating it if
+// needed, and brings it up to date with the remote.
+func openGitRepo(ctx context.Context, repoDir, url string) (*gitRepo, error) {
+	const op errors.Op = "module.openGitRepo"
+	serveLocalReposInProcess()
+
+	r, err := git.PlainOpen(repoDir)
+	if errors.IsErr(err, git.ErrRepositoryNotExists) {
+		r, err = git.PlainInit(repoDir, true)
+		if err == nil {
+			_, err = r.CreateRemote(&gitconfig.RemoteConfig{Name: git.DefaultRemoteName, URLs: []string{url}})
+		}
+	}
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+	remote, err := r.Remote(git.DefaultRemoteName)
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+
+	refs, err := remote.ListContext(ctx, &git.ListOptions{})
+	if err != nil {
+		return nil, errors.E(op, err, gitErrKind(ctx, err))
+	}
+	// the refs that were just listed tell whether the mirror is up to
+	// date, which saves the round trips of a fetch when it is.
+	if !upToDate(r, refs) {
+		err = remote.FetchContext(ctx, &git.FetchOptions{
+			RefSpecs: gitMirrorRefSpecs,
+			Tags:     git.NoTags,
+			Force:    true,
+		})
+		if err != nil && !errors.IsErr(err, git.NoErrAlreadyUpToDate) {
+			return nil, errors.E(op, err, gitErrKind(ctx, err))
+		}
+	}
+	if err := setHead(r, refs); err != nil {
+		return nil, errors.E(op, err)
+	}
+	return &gitRepo{url: url, repo: r}, nil
+}
+
+// upToDate reports whether every branch and tag of the remote,
+// as listed in refs, points to the same object in the mirror.
+func upToDate(r *git.Repository, refs []*plumbing.Reference) bool {
+	for _, ref := range refs {
+		if !ref.Name().IsBranch() && !ref.Name().IsTag() {
+			continue
+		}
+		local, err := r.Reference(ref.Name(), false)
+		if err != nil || local.Hash() != ref.Hash() {
+			return false
+		}
+	}
+	return true
+}
+
+// setHead points the mirror's HEAD at the remote's default branch.
+func setHead(r *git.Repository, refs []*plumbing.Reference) error {
+	var head *plumbing.Reference
+	for _, ref := range refs {
+		if ref.Name() == plumbing.HEAD {
+			head = ref
+		}
+	}
+	if head == nil {
+		return nil
+	}
+	target := head.Target()
+	if head.Type() == plumbing.HashReference {
+		// the remote did not advertise where HEAD points to,
+		// pick the branch it resolves to.
+		for _, ref := range refs {
+			if ref.Name().IsBranch() && ref.Hash() == head.Hash() {
+				target = ref.Name()
+				break
+			}
+		}
+	}
+	if target == "" {
+		return nil
+	}
+	return r.Storer.SetReference(plumbing.NewSymbolicReference(plumbing.HEAD, target))
+}
+
+func gitErrKind(ctx context.Context, err error) int {
+	switch {
+	case errors.IsErr(ctx.Err(), context.DeadlineExceeded):
+		return errors.KindGatewayTimeout
+	case errors.IsErr(err, transport.ErrRepositoryNotFound),
+		errors.IsErr(err, transport.ErrEmptyRemoteRepository),
+		errors.IsErr(err, transport.ErrAuthenticationRequired),
+		errors.IsErr(err, transport.ErrAuthorizationFailed):
+		return errors.KindNotFound
+	default:
+		return errors.KindUnexpected
+	}
+}
+
+// gitModule describes where a module lives inside a git repository.
+type gitModule struct {
+	// path is the module path.
+	path string
+	// pathMajor is the major version suffix of the module path, like "/v2" or ".v1".
+	pathMajor string
+	// subdir is the directory of the module relative to the repository root,
+	// without any major version subdirectory.
+	subdir string
+	// tagPrefix is the prefix of the tags that belong to the module.
+	tagPrefix string
+}
+
+func newGitModule(mod string, root RepoRoot) (gitModule, error) {
+	const op errors.Op = "module.newGitModule"
+	prefix, pathMajor, ok := modpath.SplitPathVersion(mod)
+	if !ok {
+		return gitModule{}, errors.E(op, fmt.Sprintf("invalid module path %q", mod), errors.KindBadRequest)
+	}
+	m := gitModule{path: mod, pathMajor: pathMajor}
+	switch {
+	case prefix == root.Root, mod == root.Root:
+	case strings.HasPrefix(prefix, root.Root+"/"):
+		m.subdir = prefix[len(root.Root)+1:]
+		m.tagPrefix = m.subdir + "/"
+	default:
+		return gitModule{}, errors.E(op, fmt.Sprintf("module %s is not inside repository %s", mod, root.Root), errors.KindBadRequest)
+	}
+	return m, nil
+}
+
+// codeDir returns the directory of the module at the given commit. A module
+// with a major version suffix may live in a major version subdirectory.
+func (m gitModule) codeDir(tree *object.Tree) string {
+	if strings.HasPrefix(m.pathMajor, "/") {
+		dir := path.Join(m.subdir, m.pathMajor[1:])
+		if _, err := tree.File(path.Join(dir, "go.mod")); err == nil {
+			return dir
+		}
+	}
+	return m.subdir
+}
+
+// hasGoMod reports whether the module has a go.mod file at the given commit.
+func (m gitModule) hasGoMod(c *object.Commit) (bool, error) {
+	tree, err := c.Tree()
+	if err != nil {
+		return false, err
+	}
+	_, err = tree.File(path.Join(m.codeDir(tree), "go.mod"))
+	if errors.IsErr(err, object.ErrFileNotFound) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// compatibleVersion returns the version a tag stands for, adding +incompatible
+// to v2 and higher tags of a module without a major version suffix or go.mod file.
+// It returns false if the tag does not belong to this major version of the module.
+func (m gitModule) compatibleVersion(c *object.Commit, tag string) (string, bool, error) {
+	if m.pathMajor == "" && semver.Major(tag) != "v0" && semver.Major(tag) != "v1" {
+		hasMod, err := m.hasGoMod(c)
+		if err != nil || hasMod {
+			return "", false, err
+		}
+		return tag + "+incompatible", true, nil
+	}
+	return tag, modpath.CheckPathMajor(tag, m.pathMajor) == nil, nil
+}
+
+// gitRevision is a module version resolved to a commit.
+type gitRevision struct {
+	version string
+	commit  *object.Commit
+	// ref is the full name of the reference the version was resolved from, if any.
+	ref string
+}
+
+// resolve resolves a version, pseudo-version, branch, tag or commit hash
+// to the canonical module version and the commit it points to.
+func (r *gitRepo) resolve(m gitModule, query string) (*gitRevision, error) {
+	const op errors.Op = "gitRepo.resolve"
+	if modpath.IsPseudoVersion(query) {
+		return r.resolvePseudoVersion(m, query)
+	}
+	if tag := strings.TrimSuffix(query, "+incompatible"); semver.IsValid(tag) && semver.Canonical(tag) == tag {
+		ref := "refs/tags/" + m.tagPrefix + tag
+		c, err := r.refCommit(plumbing.ReferenceName(ref))
+		if err != nil {
+			return nil, errors.E(op, err)
+		}
+		ver, ok, err := m.compatibleVersion(c, tag)
+		if err != nil {
+			return nil, errors.E(op, err)
+		}
+		if !ok || ver != query {
+			return nil, errors.E(op, fmt.Sprintf("%s@%s: invalid version for module path", m.path, query), errors.KindNotFound)
+		}
+		return &gitRevision{version: ver, commit: c, ref: ref}, nil
+	}
+
+	var (
+		c   *object.Commit
+		ref string
+		err error
+	)
+	switch query {
+	case "latest", "HEAD":
+		ref = plumbing.HEAD.String()
+	default:
+		for _, name := range []string{"refs/heads/" + query, "refs/tags/" + query} {
+			if _, err := r.repo.Reference(plumbing.ReferenceName(name), false); err == nil {
+				ref = name
+				break
+			}
+		}
+	}
+	if ref != "" {
+		c, err = r.refCommit(plumbing.ReferenceName(ref))
+		if ref == plumbing.HEAD.String() {
+			// HEAD moves, record the branch it pointed to.
+			if head, herr := r.repo.Reference(plumbing.HEAD, false); herr == nil && head.Type() == plumbing.SymbolicReference {
+				ref = head.Target().String()
+			}
+		}
+	} else {
+		c, err = r.hashCommit(query)
+	}
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+	ver, err := r.canonicalVersion(m, c)
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+	return &gitRevision{version: ver, commit: c, ref: ref}, nil
+}
+
+func (r *gitRepo) resolvePseudoVersion(m gitModule, ver string) (*gitRevision, error) {
+	const op errors.Op = "gitRepo.resolvePseudoVersion"
+	if err := modpath.CheckPathMajor(ver, m.pathMajor); err != nil {
+		return nil, errors.E(op, err, errors.KindNotFound)
+	}
+	rev, err := modpath.PseudoVersionRev(ver)
+	if err != nil {
+		return nil, errors.E(op, err, errors.KindBadRequest)
+	}
+	t, err := modpath.PseudoVersionTime(ver)
+	if err != nil {
+		return nil, errors.E(op, err, errors.KindBadRequest)
+	}
+	base, err := modpath.PseudoVersionBase(ver)
+	if err != nil {
+		return nil, errors.E(op, err, errors.KindBadRequest)
+	}
+	c, err := r.hashCommit(rev)
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+	if !c.Committer.When.UTC().Truncate(time.Second).Equal(t) {
+		return nil, errors.E(op, fmt.Sprintf("%s: does not match commit time %s", ver, c.Committer.When.UTC().Format(time.RFC3339)), errors.KindNotFound)
+	}
+	// like the go command, a pseudo-version must be based on a tag that
+	// the commit descends from, so that it sorts after that tag.
+	if base != "" {
+		tag := m.tagPrefix + strings.TrimSuffix(base, "+incompatible")
+		tc, err := r.refCommit(plumbing.ReferenceName("refs/tags/" + tag))
+		if err != nil {
+			return nil, errors.E(op, fmt.Sprintf("%s: base version %s is not tagged", ver, base), errors.KindNotFound)
+		}
+		ok, err := tc.IsAncestor(c)
+		if err != nil {
+			return nil, errors.E(op, err)
+		}
+		if !ok {
+			return nil, errors.E(op, fmt.Sprintf("%s: base version %s is not an ancestor of the commit", ver, base), errors.KindNotFound)
+		}
+	}
+	return &gitRevision{version: ver, commit: c}, nil
+}
+
+// refCommit returns the commit the reference points to, peeling annotated tags.
+func (r *gitRepo) refCommit(name plumbing.ReferenceName) (*object.Commit, error) {
+	const op errors.Op = "gitRepo.refCommit"
+	ref, err := r.repo.Reference(name, true)
+	if err != nil {
+		return nil, errors.E(op, fmt.Sprintf("unknown revision %s", name.Short()), errors.KindNotFound)
+	}
+	c, err := r.peel(ref.Hash())
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+	return c, nil
+}
+
+// hashCommit returns the commit whose hash starts with the given hex prefix.
+func (r *gitRepo) hashCommit(rev string) (*object.Commit, error) {
+	const op errors.Op = "gitRepo.hashCommit"
+	if len(rev) < 7 || strings.Trim(rev, "0123456789abcdef") != "" {
+		return nil, errors.E(op, fmt.Sprintf("unknown revision %s", rev), errors.KindNotFound)
+	}
+	h, err := r.repo.ResolveRevision(plumbing.Revision(rev))
+	if err != nil || !strings.HasPrefix(h.String(), rev) {
+		return nil, errors.E(op, fmt.Sprintf("unknown revision %s", rev), errors.KindNotFound)
+	}
+	c, err := r.repo.CommitObject(*h)
+	if err != nil {
+		return nil, errors.E(op, err, errors.KindNotFound)
+	}
+	return c, nil
+}
+
+func (r *gitRepo) peel(h plumbing.Hash) (*object.Commit, error) {
+	if tag, err := r.repo.TagObject(h); err == nil {
+		return tag.Commit()
+	}
+	return r.repo.CommitObject(h)
+}
+
+// tags returns the semver tags of the module by the commit they point to.
+func (r *gitRepo) tags(m gitModule) (map[plumbing.Hash][]string, error) {
+	const op errors.Op = "gitRepo.tags"
+	iter, err := r.repo.Tags()
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+	tags := map[plumbing.Hash][]string{}
+	err = iter.ForEach(func(ref *plumbing.Reference) error {
+		name := strings.TrimPrefix(ref.Name().String(), "refs/tags/")
+		if !strings.HasPrefix(name, m.tagPrefix) {
+			return nil
+		}
+		tag := name[len(m.tagPrefix):]
+		if semver.Canonical(tag) != tag || modpath.IsPseudoVersion(tag) {
+			return nil
+		}
+		c, err := r.peel(ref.Hash())
+		if err != nil {
+			// tags of trees or blobs can't be module versions.
+			return nil
+		}
+		tags[c.Hash] = append(tags[c.Hash], tag)
+		return nil
+	})
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+	return tags, nil
+}
+
+// canonicalVersion returns the highest version tagged on the commit or,
+// if there is none, a pseudo-version based on the closest tagged ancestor.
+func (r *gitRepo) canonicalVersion(m gitModule, c *object.Commit) (string, error) {
+	const op errors.Op = "gitRepo.canonicalVersion"
+	tags, err := r.tags(m)
+	if err != nil {
+		return "", errors.E(op, err)
+	}
+	best, err := highestCompatible(m, c, tags[c.Hash])
+	if err != nil {
+		return "", errors.E(op, err)
+	}
+	if best != "" {
+		return best, nil
+	}
+
+	base, err := r.baseVersion(m, c, tags)
+	if err != nil {
+		return "", errors.E(op, err)
+	}
+	major := modpath.PathMajorPrefix(m.pathMajor)
+	if base != "" {
+		major = semver.Major(base)
+	}
+	return modpath.PseudoVersion(major, base, c.Committer.When, c.Hash.String()[:12]), nil
+}
+
+// baseVersion returns the highest version tagged on an ancestor of c, if
+// any. The history of c is only walked if some commit has a version tag,
+// and only until the commit with the highest one is found.
+func (r *gitRepo) baseVersion(m gitModule, c *object.Commit, tags map[plumbing.Hash][]string) (string, error) {
+	candidates := map[plumbing.Hash]string{}
+	var highest string
+	for h, tt := range tags {
+		tc, err := r.repo.CommitObject(h)
+		if err != nil {
+			return "", err
+		}
+		v, err := highestCompatible(m, tc, tt)
+		if err != nil {
+			return "", err
+		}
+		if v == "" {
+			continue
+		}
+		candidates[h] = v
+		if highest == "" || semver.Compare(v, highest) > 0 {
+			highest = v
+		}
+	}
+	if len(candidates) == 0 {
+		return "", nil
+	}
+
+	var base string
+	err := object.NewCommitPreorderIter(c, nil, nil).ForEach(func(a *object.Commit) error {
+		v, ok := candidates[a.Hash]
+		if !ok {
+			return nil
+		}
+		if base == "" || semver.Compare(v, base) > 0 {
+			base = v
+		}
+		if base == highest {
+			return storer.ErrStop
+		}
+		return nil
+	})
+	if err != nil && !errors.IsErr(err, storer.ErrStop) {
+		return "", err
+	}
+	return base, nil
+}
+
+func highestCompatible(m gitModule, c *object.Commit, tags []string) (string, error) {
+	var best string
+	for _, tag := range tags {
+		v, ok, err := m.compatibleVersion(c, tag)
+		if err != nil {
+			return "", err
+		}
+		if ok && (best == "" || semver.Compare(v, best) > 0) {
+			best = v
+		}
+	}
+	return best, nil
+}
+
+// versions returns every tagged version of the module.
+func (r *gitRepo) versions(m gitModule) ([]string, error) {
+	const op errors.Op = "gitRepo.versions"
+	tags, err := r.tags(m)
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+	versions := []string{}
+	for h, tt := range tags {
+		c, err := r.repo.CommitObject(h)
+		if err != nil {
+			return nil, errors.E(op, err)
+		}
+		for _, tag := range tt {
+			v, ok, err := m.compatibleVersion(c, tag)
+			if err != nil {
+				return nil, errors.E(op, err)
+			}
+			if ok {
+				versions = append(versions, v)
+			}
+		}
+	}
+	semver.Sort(versions)
+	return versions, nil
+}
@@ -0,0 +1,108 @@
+package module
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/gomods/athens/pkg/errors"
+	"golang.org/x/net/html"
+)
+
+// RepoRoot is the git repository that hosts a module.
+type RepoRoot struct {
+	// Root is the import path that corresponds to the root of the repository.
+	Root string
+	// URL is the URL the repository is cloned from.
+	URL string
+}
+
+// RepoResolver finds the git repository that hosts the given module path.
+type RepoResolver func(ctx context.Context, mod string) (RepoRoot, error)
+
+// wellKnownHosts are hosts whose repository root can be derived
+// from the import path alone, like the go command does.
+var wellKnownHosts = regexp.MustCompile(`^((?:github\.com|bitbucket\.org)/[A-Za-z0-9_.\-]+/[A-Za-z0-9_.\-]+)(/|$)`)
+
+// NewRepoResolver returns a RepoResolver that resolves well known hosts from
+// the import path and everything else using the go-import meta tag served at
+// https://{module}?go-get=1. Only repositories using git are supported.
+func NewRepoResolver(client *http.Client) RepoResolver {
+	if client == nil {
+		client = &http.Client{}
+	}
+	return func(ctx context.Context, mod string) (RepoRoot, error) {
+		const op errors.Op = "module.RepoResolver"
+		if m := wellKnownHosts.FindStringSubmatch(mod); m != nil {
+			return RepoRoot{Root: m[1], URL: "https://" + m[1]}, nil
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, "https://"+mod+"?go-get=1", nil)
+		if err != nil {
+			return RepoRoot{}, errors.E(op, err, errors.KindBadRequest)
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return RepoRoot{}, errors.E(op, err, gitErrKind(ctx, err))
+		}
+		defer func() { _ = resp.Body.Close() }()
+		imports := parseGoImports(io.LimitReader(resp.Body, 1<<20))
+		for _, imp := range imports {
+			if imp.prefix != mod && !strings.HasPrefix(mod, imp.prefix+"/") {
+				continue
+			}
+			if imp.vcs != "git" {
+				return RepoRoot{}, errors.E(op, fmt.Sprintf("%s is served by %s, only git is supported", mod, imp.vcs), errors.KindNotFound)
+			}
+			return RepoRoot{Root: imp.prefix, URL: imp.repo}, nil
+		}
+		return RepoRoot{}, errors.E(op, fmt.Sprintf("no go-import meta tag found for %s", mod), errors.KindNotFound)
+	}
+}
+
+type goImport struct {
+	prefix, vcs, repo string
+}
+
+// parseGoImports returns the go-import meta tags of an HTML document.
+func parseGoImports(r io.Reader) []goImport {
+	var imports []goImport
+	z := html.NewTokenizer(r)
+	for {
+		tt := z.Next()
+		switch tt {
+		case html.ErrorToken:
+			return imports
+		case html.EndTagToken:
+			if tn, _ := z.TagName(); string(tn) == "head" {
+				return imports
+			}
+		case html.StartTagToken, html.SelfClosingTagToken:
+			tn, hasAttr := z.TagName()
+			if string(tn) != "meta" || !hasAttr {
+				continue
+			}
+			var name, content string
+			for {
+				key, val, more := z.TagAttr()
+				switch string(key) {
+				case "name":
+					name = string(val)
+				case "content":
+					content = string(val)
+				}
+				if !more {
+					break
+				}
+			}
+			if name != "go-import" {
+				continue
+			}
+			if f := strings.Fields(content); len(f) == 3 {
+				imports = append(imports, goImport{prefix: f[0], vcs: f[1], repo: f[2]})
+			}
+		}
+	}
+}
@@ -0,0 +1,266 @@
+package module
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gomods/athens/pkg/errors"
+	"github.com/gomods/athens/pkg/storage"
+	"github.com/spf13/afero"
+	modpath "golang.org/x/mod/module"
+)
+
+// GoCache is a module cache (GOMODCACHE) shared by every go command that the
+// go get fetcher and the VCS lister run, so that repositories are cloned once
+// and only fetched incrementally afterwards.
+//
+// The go command already locks the cache for concurrent invocations. On top of
+// that, GoCache evicts the least recently used repositories once they take more
+// than the configured size, and makes sure that never happens while a go command
+// is running. Downloaded zips and extracted sources are removed once they are
+// read and no other fetch of the same module is in progress, since storage keeps
+// them from then on.
+type GoCache struct {
+	fs      afero.Fs
+	dir     string
+	maxSize int64
+	now     func() time.Time
+
+	// mu is held for reading by running go commands
+	// and for writing while evicting repositories.
+	mu sync.RWMutex
+	// evictions asks the background eviction to run.
+	evictions chan struct{}
+
+	// modsMu guards mods and downloads.
+	modsMu sync.Mutex
+	// mods counts the fetches in progress of every module. They may
+	// share downloads, which are only removed once the last one is done.
+	mods      map[string]int
+	downloads map[string][]download
+}
+
+// download is a version that the go command left in the cache.
+type download struct {
+	ver       string
+	sourceDir string
+}
+
+// NewGoCache returns a GoCache in dir that evicts repositories once they take
+// more than maxSize bytes. A maxSize of zero means the cache is unbounded.
+func NewGoCache(fs afero.Fs, dir string, maxSize int64) (*GoCache, error) {
+	const op errors.Op = "module.NewGoCache"
+	if err := fs.MkdirAll(dir, os.ModeDir|os.ModePerm); err != nil {
+		return nil, errors.E(op, err)
+	}
+	c := &GoCache{
+		fs:        fs,
+		dir:       dir,
+		maxSize:   maxSize,
+		now:       time.Now,
+		evictions: make(chan struct{}, 1),
+		mods:      map[string]int{},
+		downloads: map[string][]download{},
+	}
+	if maxSize > 0 {
+		go func() {
+			for range c.evictions {
+				_ = c.evictNow()
+			}
+		}()
+	}
+	return c, nil
+}
+
+// env returns the environment variable pointing the go command at the cache.
+func (c *GoCache) env() string {
+	return "GOMODCACHE=" + c.dir
+}
+
+// use marks the cache as in use by a go command until the returned func is called.
+func (c *GoCache) use() func() {
+	c.mu.RLock()
+	return c.mu.RUnlock
+}
+
+func (c *GoCache) vcsDir() string {
+	return filepath.Join(c.dir, "cache", "vcs")
+}
+
+// touch marks the repository the origin was fetched from as recently used.
+func (c *GoCache) touch(origin *storage.Origin) {
+	if origin == nil || origin.URL == "" {
+		return
+	}
+	infos, err := afero.Glob(c.fs, filepath.Join(c.vcsDir(), "*.info"))
+	if err != nil {
+		return
+	}
+	now := c.now()
+	for _, info := range infos {
+		// the go command writes "{vcs type}:{remote}" into
+		// the .info file next to each repository it clones.
+		key, err := afero.ReadFile(c.fs, info)
+		if err != nil {
+			continue
+		}
+		_, remote, _ := strings.Cut(strings.TrimSpace(string(key)), ":")
+		if remote == origin.URL {
+			_ = c.fs.Chtimes(info, now, now)
+		}
+	}
+}
+
+// hold marks mod as being fetched until the returned func is called,
+// so that the downloads of mod are kept until then.
+func (c *GoCache) hold(mod string) func() {
+	c.modsMu.Lock()
+	defer c.modsMu.Unlock()
+	c.mods[mod]++
+	return func() {
+		c.modsMu.Lock()
+		defer c.modsMu.Unlock()
+		c.mods[mod]--
+		if c.mods[mod] == 0 {
+			delete(c.mods, mod)
+			c.removeDownloads(mod)
+		}
+	}
+}
+
+// removeDownload removes the files the go command left in the cache for
+// mod@ver, other than the repository itself, once no fetch of mod holds them.
+func (c *GoCache) removeDownload(mod, ver, sourceDir string) {
+	c.modsMu.Lock()
+	defer c.modsMu.Unlock()
+	c.downloads[mod] = append(c.downloads[mod], download{ver: ver, sourceDir: sourceDir})
+	if c.mods[mod] == 0 {
+		c.removeDownloads(mod)
+	}
+}
+
+// removeDownloads removes the downloads of mod. The lock files are
+// kept, since go commands of other processes may be holding them.
+// c.modsMu must be held.
+func (c *GoCache) removeDownloads(mod string) {
+	for _, d := range c.downloads[mod] {
+		if d.sourceDir != "" && strings.HasPrefix(d.sourceDir, c.dir) {
+			_ = clearFiles(c.fs, d.sourceDir)
+		}
+		escMod, err := modpath.EscapePath(mod)
+		if err != nil {
+			continue
+		}
+		escVer, err := modpath.EscapeVersion(d.ver)
+		if err != nil {
+			continue
+		}
+		base := filepath.Join(c.dir, "cache", "download", escMod, "@v", escVer)
+		for _, ext := range []string{".info", ".mod", ".zip", ".ziphash"} {
+			_ = c.fs.Remove(base + ext)
+		}
+	}
+	delete(c.downloads, mod)
+}
+
+type cachedRepo struct {
+	dir      string
+	lastUsed time.Time
+	size     int64
+}
+
+// evict asks for an eviction in the background, unless one is already pending.
+func (c *GoCache) evict() {
+	if c.maxSize <= 0 {
+		return
+	}
+	select {
+	case c.evictions <- struct{}{}:
+	default:
+	}
+}
+
+// evictNow removes the least recently used repositories until the cache fits in
+// its maximum size. It waits for the running go commands to finish, and new ones
+// wait for it, so that it runs however busy the cache is.
+func (c *GoCache) evictNow() error {
+	const op errors.Op = "goCache.evictNow"
+	if c.maxSize <= 0 {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	infos, err := afero.Glob(c.fs, filepath.Join(c.vcsDir(), "*.info"))
+	if err != nil {
+		return errors.E(op, err)
+	}
+	var repos []cachedRepo
+	for _, info := range infos {
+		fi, err := c.fs.Stat(info)
+		if err != nil {
+			continue
+		}
+		repo := cachedRepo{dir: strings.TrimSuffix(info, ".info"), lastUsed: fi.ModTime()}
+		repo.size, err = dirSize(c.fs, repo.dir)
+		if err != nil {
+			return errors.E(op, err)
+		}
+		repos = append(repos, repo)
+	}
+
+	err = removeLeastRecentlyUsed(repos, c.maxSize, func(repo cachedRepo) (bool, error) {
+		if ok, _ := afero.DirExists(c.fs, repo.dir); ok {
+			if err := clearFiles(c.fs, repo.dir); err != nil {
+				return false, err
+			}
+		}
+		_ = c.fs.Remove(repo.dir + ".info")
+		_ = c.fs.Remove(repo.dir + ".lock")
+		return true, nil
+	})
+	if err != nil {
+		return errors.E(op, err)
+	}
+	return nil
+}
+
+// removeLeastRecentlyUsed calls remove for the least recently used repositories
+// until the others fit in maxSize bytes. remove returns false for a repository
+// it kept, which then still counts towards maxSize.
+func removeLeastRecentlyUsed(repos []cachedRepo, maxSize int64, remove func(cachedRepo) (bool, error)) error {
+	var total int64
+	for _, repo := range repos {
+		total += repo.size
+	}
+	sort.Slice(repos, func(i, j int) bool { return repos[i].lastUsed.Before(repos[j].lastUsed) })
+	for _, repo := range repos {
+		if total <= maxSize {
+			break
+		}
+		removed, err := remove(repo)
+		if err != nil {
+			return err
+		}
+		if removed {
+			total -= repo.size
+		}
+	}
+	return nil
+}
+
+// dirSize returns the size of the files in dir, which may not exist.
+func dirSize(fs afero.Fs, dir string) (int64, error) {
+	var size int64
+	err := afero.Walk(fs, dir, func(_ string, fi os.FileInfo, err error) error {
+		if err == nil && !fi.IsDir() {
+			size += fi.Size()
+		}
+		return nil
+	})
+	return size, err
+}
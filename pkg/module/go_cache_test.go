@@ -0,0 +1,130 @@
+package module
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/gomods/athens/pkg/storage"
+	"github.com/spf13/afero"
+)
+
+func (s *ModuleSuite) TestGoCacheEvict() {
+	r := s.Require()
+	cache, err := NewGoCache(s.fs, "/cache", 150)
+	r.NoError(err)
+	now := time.Now()
+	cache.now = func() time.Time { return now }
+
+	vcs := cache.vcsDir()
+	for i, name := range []string{"old", "used", "new"} {
+		dir := filepath.Join(vcs, name)
+		r.NoError(afero.WriteFile(s.fs, filepath.Join(dir, "objects", "pack"), make([]byte, 60), 0o644))
+		r.NoError(afero.WriteFile(s.fs, dir+".info", []byte("git3:https://example.com/"+name+"\n"), 0o644))
+		r.NoError(afero.WriteFile(s.fs, dir+".lock", nil, 0o644))
+		modTime := now.Add(time.Duration(i-10) * time.Minute)
+		r.NoError(s.fs.Chtimes(dir+".info", modTime, modTime))
+	}
+	// "used" is the most recently used now, so "old" and then "new" are evicted.
+	cache.touch(&storage.Origin{VCS: "git", URL: "https://example.com/used"})
+
+	done := cache.use()
+	evicted := make(chan error)
+	go func() { evicted <- cache.evictNow() }()
+	time.Sleep(50 * time.Millisecond)
+	exists, err := afero.DirExists(s.fs, filepath.Join(vcs, "old"))
+	r.NoError(err)
+	r.True(exists, "nothing must be evicted while the cache is in use")
+	// the eviction waits for the go command instead of giving up.
+	done()
+	r.NoError(<-evicted)
+
+	for name, want := range map[string]bool{"old": false, "new": true, "used": true} {
+		exists, err := afero.DirExists(s.fs, filepath.Join(vcs, name))
+		r.NoError(err)
+		r.Equal(want, exists, name)
+		exists, err = afero.Exists(s.fs, filepath.Join(vcs, name+".info"))
+		r.NoError(err)
+		r.Equal(want, exists, name)
+	}
+}
+
+func (s *ModuleSuite) TestGoCacheUnbounded() {
+	r := s.Require()
+	cache, err := NewGoCache(s.fs, "/cache", 0)
+	r.NoError(err)
+	dir := filepath.Join(cache.vcsDir(), "repo")
+	r.NoError(afero.WriteFile(s.fs, filepath.Join(dir, "objects", "pack"), make([]byte, 60), 0o644))
+	r.NoError(afero.WriteFile(s.fs, dir+".info", []byte("git3:https://example.com/repo\n"), 0o644))
+	r.NoError(cache.evictNow())
+	exists, err := afero.DirExists(s.fs, dir)
+	r.NoError(err)
+	r.True(exists)
+}
+
+func (s *ModuleSuite) TestGoCacheKeepsSharedDownloads() {
+	r := s.Require()
+	cache, err := NewGoCache(s.fs, "/cache", 0)
+	r.NoError(err)
+	base := filepath.Join("/cache", "cache", "download", "mockmod.xyz", "@v", "v1.2.3")
+	for _, ext := range []string{".info", ".mod", ".zip", ".lock"} {
+		r.NoError(afero.WriteFile(s.fs, base+ext, nil, 0o644))
+	}
+
+	// a fetch of the module is done while another is still in progress.
+	release := cache.hold("mockmod.xyz")
+	cache.hold("mockmod.xyz")()
+	cache.removeDownload("mockmod.xyz", "v1.2.3", "")
+	exists, err := afero.Exists(s.fs, base+".zip")
+	r.NoError(err)
+	r.True(exists, "a download must be kept while a fetch of its module is in progress")
+
+	release()
+	for ext, want := range map[string]bool{".info": false, ".mod": false, ".zip": false, ".lock": true} {
+		exists, err := afero.Exists(s.fs, base+ext)
+		r.NoError(err)
+		r.Equal(want, exists, ext)
+	}
+}
+
+func (s *ModuleSuite) TestGoGetFetcherWithCache() {
+	r := s.Require()
+	zipBytes, err := os.ReadFile("test_data/mockmod.xyz@v1.2.3.zip")
+	r.NoError(err)
+	mp := &mockProxy{paths: map[string][]byte{
+		"/mockmod.xyz/@v/v1.2.3.info": []byte(`{"Version":"v1.2.3"}`),
+		"/mockmod.xyz/@v/v1.2.3.mod":  []byte(`{"module mod}`),
+		"/mockmod.xyz/@v/v1.2.3.zip":  zipBytes,
+	}}
+	proxyAddr, closeProxy := s.getProxy(mp)
+	defer closeProxy()
+
+	fs := afero.NewOsFs()
+	cacheDir := s.T().TempDir()
+	cache, err := NewGoCache(fs, cacheDir, 1<<20)
+	r.NoError(err)
+	env := []string{"GONOSUMDB=mockmod.xyz", "GOPROXY=" + proxyAddr}
+	fetcher, err := NewGoGetFetcher(s.goBinaryName, "", env, fs, cache, nil)
+	r.NoError(err)
+	ver, err := fetcher.Fetch(s.T().Context(), "mockmod.xyz", "v1.2.3")
+	r.NoError(err)
+
+	download := filepath.Join(cacheDir, "cache", "download", "mockmod.xyz", "@v", "v1.2.3.zip")
+	exists, err := afero.Exists(fs, download)
+	r.NoError(err)
+	r.True(exists, "the go command must download into the shared cache")
+
+	zip, err := io.ReadAll(ver.Zip)
+	r.NoError(err)
+	r.Equal(zipBytes, zip)
+	r.NoError(ver.Zip.Close())
+
+	// once read, the download is removed since storage keeps it from then on.
+	exists, err = afero.Exists(fs, download)
+	r.NoError(err)
+	r.False(exists)
+	exists, err = afero.DirExists(fs, filepath.Join(cacheDir, "mockmod.xyz@v1.2.3"))
+	r.NoError(err)
+	r.False(exists)
+}
@@ -13,6 +13,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/gomods/athens/pkg/download/mode"
 	"github.com/gomods/athens/pkg/errors"
 	"github.com/gomods/athens/pkg/observ"
 	"github.com/gomods/athens/pkg/storage"
@@ -24,22 +25,28 @@ type goGetFetcher struct {
 	goBinaryName string
 	envVars      []string
 	gogetDir     string
+	cache        *GoCache
+	df           *mode.DownloadFile
 }
 
 type goModule struct {
-	Path     string `json:"path"`     // module path
-	Version  string `json:"version"`  // module version
-	Error    string `json:"error"`    // error loading module
-	Info     string `json:"info"`     // absolute path to cached .info file
-	GoMod    string `json:"goMod"`    // absolute path to cached .mod file
-	Zip      string `json:"zip"`      // absolute path to cached .zip file
-	Dir      string `json:"dir"`      // absolute path to cached source root directory
-	Sum      string `json:"sum"`      // checksum for path, version (as in go.sum)
-	GoModSum string `json:"goModSum"` // checksum for go.mod (as in go.sum)
+	Path     string          `json:"path"`     // module path
+	Version  string          `json:"version"`  // module version
+	Error    string          `json:"error"`    // error loading module
+	Info     string          `json:"info"`     // absolute path to cached .info file
+	GoMod    string          `json:"goMod"`    // absolute path to cached .mod file
+	Zip      string          `json:"zip"`      // absolute path to cached .zip file
+	Dir      string          `json:"dir"`      // absolute path to cached source root directory
+	Sum      string          `json:"sum"`      // checksum for path, version (as in go.sum)
+	GoModSum string          `json:"goModSum"` // checksum for go.mod (as in go.sum)
+	Origin   *storage.Origin `json:"origin"`   // provenance of module
 }
 
 // NewGoGetFetcher creates fetcher which uses go get tool to fetch modules.
-func NewGoGetFetcher(goBinaryName, gogetDir string, envVars []string, fs afero.Fs) (Fetcher, error) {
+// If cache is not nil, repositories are kept in it between fetches instead
+// of being cloned again for every module version. If df is not nil, the
+// go tool also gets the goEnv of the download path that matches a module.
+func NewGoGetFetcher(goBinaryName, gogetDir string, envVars []string, fs afero.Fs, cache *GoCache, df *mode.DownloadFile) (Fetcher, error) {
 	const op errors.Op = "module.NewGoGetFetcher"
 	if err := validGoBinary(goBinaryName); err != nil {
 		return nil, errors.E(op, err)
@@ -49,6 +56,8 @@ func NewGoGetFetcher(goBinaryName, gogetDir string, envVars []string, fs afero.F
 		goBinaryName: goBinaryName,
 		envVars:      envVars,
 		gogetDir:     gogetDir,
+		cache:        cache,
+		df:           df,
 	}, nil
 }
 
@@ -71,19 +80,37 @@ func (g *goGetFetcher) Fetch(ctx context.Context, mod, ver string) (*storage.Ver
 		return nil, errors.E(op, err)
 	}
 
+	envVars := g.env(mod)
+	var done func()
+	if g.cache != nil {
+		envVars = append(envVars, g.cache.env())
+		defer g.cache.hold(mod)()
+		done = g.cache.use()
+	}
 	m, err := downloadModule(
 		ctx,
 		g.goBinaryName,
-		g.envVars,
+		envVars,
 		goPathRoot,
 		modPath,
 		mod,
 		ver,
 	)
+	if done != nil {
+		done()
+	}
 	if err != nil {
 		_ = clearFiles(g.fs, goPathRoot)
 		return nil, errors.E(op, err)
 	}
+	var onClose func()
+	if g.cache != nil {
+		g.cache.touch(m.Origin)
+		onClose = func() {
+			g.cache.removeDownload(mod, m.Version, m.Dir)
+			g.cache.evict()
+		}
+	}
 
 	var storageVer storage.Version
 	storageVer.Semver = m.Version
@@ -127,12 +154,114 @@ func (g *goGetFetcher) Fetch(ctx context.Context, mod, ver string) (*storage.Ver
 	//
 	// if we close, then the caller will panic, and the alternative to make this work is
 	// that we read into memory and return an io.ReadCloser that reads out of memory
-	storageVer.Zip = &zipReadCloser{zip, g.fs, goPathRoot}
+	storageVer.Zip = &zipReadCloser{zip: zip, fs: g.fs, goPath: goPathRoot, onClose: onClose}
 	storageVer.ZipMD5 = zipMD5
 
 	return &storageVer, nil
 }
 
+// FetchMod resolves the version with the go binary and returns the
+// corresponding .info and .mod files, without downloading the zip.
+func (g *goGetFetcher) FetchMod(ctx context.Context, mod, ver string) (*storage.Version, error) {
+	const op errors.Op = "goGetFetcher.FetchMod"
+	ctx, span := observ.StartSpan(ctx, op.String())
+	defer span.End()
+
+	goPathRoot, err := afero.TempDir(g.fs, g.gogetDir, "athens")
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+	defer func() { _ = clearFiles(g.fs, goPathRoot) }()
+	modPath := filepath.Join(goPathRoot, "src", getRepoDirName(mod, ver))
+	if err := g.fs.MkdirAll(modPath, os.ModeDir|os.ModePerm); err != nil {
+		return nil, errors.E(op, err)
+	}
+
+	envVars := g.env(mod)
+	var done func()
+	if g.cache != nil {
+		envVars = append(envVars, g.cache.env())
+		defer g.cache.hold(mod)()
+		done = g.cache.use()
+	}
+	m, err := listModule(ctx, g.goBinaryName, envVars, goPathRoot, modPath, mod, ver)
+	if done != nil {
+		done()
+	}
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+	if g.cache != nil {
+		defer func() {
+			g.cache.removeDownload(mod, m.Version, "")
+			g.cache.evict()
+		}()
+	}
+
+	gomod, err := afero.ReadFile(g.fs, m.GoMod)
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+	// the go command saves the .info file next to the .mod file,
+	// including the origin of versions fetched from VCS.
+	info, err := afero.ReadFile(g.fs, strings.TrimSuffix(m.GoMod, ".mod")+".info")
+	if err != nil {
+		info, err = json.Marshal(storage.RevInfo{Version: m.Version, Time: m.Time})
+		if err != nil {
+			return nil, errors.E(op, err)
+		}
+	}
+	return &storage.Version{Semver: m.Version, Info: info, Mod: gomod}, nil
+}
+
+// env returns the environment variables that the go tool gets for mod.
+// The returned slice can be appended to without changing g.envVars.
+func (g *goGetFetcher) env(mod string) []string {
+	env := g.envVars[:len(g.envVars):len(g.envVars)]
+	if g.df != nil {
+		env = append(env, g.df.GoEnv(mod)...)
+	}
+	return env
+}
+
+// listedModule is the output of 'go list -m -json'.
+type listedModule struct {
+	Path    string
+	Version string
+	Time    time.Time
+	GoMod   string
+	Error   *struct{ Err string }
+}
+
+// listModule runs 'go list -m -json' on module@version, which only downloads
+// the .info and .mod files of the version into the module cache.
+func listModule(ctx context.Context, goBinaryName string, envVars []string, gopath, repoRoot, module, version string) (listedModule, error) {
+	const op errors.Op = "module.listModule"
+	fullURI := fmt.Sprintf("%s@%s", strings.TrimSuffix(module, "/"), version)
+	cmd := exec.CommandContext(ctx, goBinaryName, "list", "-e", "-m", "-json", fullURI)
+	cmd.Env = prepareEnv(gopath, envVars)
+	cmd.Dir = repoRoot
+	stdout := &bytes.Buffer{}
+	stderr := &bytes.Buffer{}
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	if err := cmd.Run(); err != nil {
+		return listedModule{}, errors.E(op, fmt.Errorf("%w: %s", err, stderr))
+	}
+
+	var m listedModule
+	if err := json.NewDecoder(stdout).Decode(&m); err != nil {
+		return listedModule{}, errors.E(op, err)
+	}
+	if m.Error != nil {
+		if isLimitHit(m.Error.Err) {
+			return listedModule{}, errors.E(op, m.Error.Err, errors.KindRateLimit)
+		}
+		return listedModule{}, errors.E(op, m.Error.Err, errors.KindNotFound)
+	}
+	return m, nil
+}
+
 // given a filesystem, gopath, repository root, module and version, runs 'go mod download -json'
 // on module@version from the repoRoot with GOPATH=gopath, and returns a non-nil error if anything went wrong.
 func downloadModule(
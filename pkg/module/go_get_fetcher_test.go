@@ -7,6 +7,7 @@ import (
 	"os"
 	"runtime"
 
+	"github.com/gomods/athens/pkg/download/mode"
 	"github.com/gomods/athens/pkg/errors"
 	"github.com/spf13/afero"
 	"github.com/stretchr/testify/assert"
@@ -14,14 +15,14 @@ import (
 
 func (s *ModuleSuite) TestNewGoGetFetcher() {
 	r := s.Require()
-	fetcher, err := NewGoGetFetcher(s.goBinaryName, "", s.env, s.fs)
+	fetcher, err := NewGoGetFetcher(s.goBinaryName, "", s.env, s.fs, nil, nil)
 	r.NoError(err)
 	_, ok := fetcher.(*goGetFetcher)
 	r.True(ok)
 }
 
 func (s *ModuleSuite) TestGoGetFetcherError() {
-	fetcher, err := NewGoGetFetcher("invalidpath", "", s.env, afero.NewOsFs())
+	fetcher, err := NewGoGetFetcher("invalidpath", "", s.env, afero.NewOsFs(), nil, nil)
 
 	assert.Nil(s.T(), fetcher)
 	if runtime.GOOS == "windows" {
@@ -35,7 +36,7 @@ func (s *ModuleSuite) TestGoGetFetcherFetch() {
 	r := s.Require()
 	// we need to use an OS filesystem because fetch executes vgo on the command line, which
 	// always writes to the filesystem
-	fetcher, err := NewGoGetFetcher(s.goBinaryName, "", s.env, afero.NewOsFs())
+	fetcher, err := NewGoGetFetcher(s.goBinaryName, "", s.env, afero.NewOsFs(), nil, nil)
 	r.NoError(err)
 	ver, err := fetcher.Fetch(s.T().Context(), repoURI, version)
 	r.NoError(err)
@@ -55,7 +56,7 @@ func (s *ModuleSuite) TestGoGetFetcherFetch() {
 
 func (s *ModuleSuite) TestNotFoundFetches() {
 	r := s.Require()
-	fetcher, err := NewGoGetFetcher(s.goBinaryName, "", s.env, afero.NewOsFs())
+	fetcher, err := NewGoGetFetcher(s.goBinaryName, "", s.env, afero.NewOsFs(), nil, nil)
 	r.NoError(err)
 	// when someone buys laks47dfjoijskdvjxuyyd.com, and implements
 	// a git server on top of it, this test will fail :)
@@ -83,18 +84,60 @@ func (s *ModuleSuite) TestGoGetFetcherSumDB() {
 	proxyAddr, close := s.getProxy(mp)
 	defer close()
 
-	fetcher, err := NewGoGetFetcher(s.goBinaryName, "", []string{"GOPROXY=" + proxyAddr}, afero.NewOsFs())
+	fetcher, err := NewGoGetFetcher(s.goBinaryName, "", []string{"GOPROXY=" + proxyAddr}, afero.NewOsFs(), nil, nil)
 	r.NoError(err)
 	_, err = fetcher.Fetch(s.T().Context(), "mockmod.xyz", "v1.2.3")
 	if err == nil {
 		s.T().Fatal("expected a gosum error but got nil")
 	}
-	fetcher, err = NewGoGetFetcher(s.goBinaryName, "", []string{"GONOSUMDB=mockmod.xyz", "GOPROXY=" + proxyAddr}, afero.NewOsFs())
+	fetcher, err = NewGoGetFetcher(s.goBinaryName, "", []string{"GONOSUMDB=mockmod.xyz", "GOPROXY=" + proxyAddr}, afero.NewOsFs(), nil, nil)
 	r.NoError(err)
 	_, err = fetcher.Fetch(s.T().Context(), "mockmod.xyz", "v1.2.3")
 	r.NoError(err, "expected the go sum to not be consulted but got an error")
 }
 
+func (s *ModuleSuite) TestGoGetFetcherGoEnv() {
+	r := s.Require()
+	mp := &mockProxy{paths: map[string][]byte{
+		"/mockmod.xyz/@v/v1.2.3.info": []byte(`{"Version":"v1.2.3"}`),
+		"/mockmod.xyz/@v/v1.2.3.mod":  []byte("module mockmod.xyz\n"),
+	}}
+	proxyAddr, closeProxy := s.getProxy(mp)
+	defer closeProxy()
+
+	df := &mode.DownloadFile{Mode: mode.Sync, Paths: []*mode.DownloadPath{
+		{Pattern: "mockmod.xyz", Mode: mode.Sync, GoEnv: []string{"GONOSUMDB=mockmod.xyz", "GOPROXY=" + proxyAddr}},
+	}}
+	fetcher, err := NewGoGetFetcher(s.goBinaryName, "", []string{"GOPROXY=off"}, afero.NewOsFs(), nil, df)
+	r.NoError(err)
+	ver, err := fetcher.(ModFetcher).FetchMod(s.T().Context(), "mockmod.xyz", "v1.2.3")
+	r.NoError(err, "expected the goEnv of the download path to override GOPROXY")
+	r.Equal("v1.2.3", ver.Semver)
+}
+
+func (s *ModuleSuite) TestGoGetFetcherFetchMod() {
+	r := s.Require()
+	mp := &mockProxy{paths: map[string][]byte{
+		"/mockmod.xyz/@v/v1.2.3.info": []byte(`{"Version":"v1.2.3","Time":"2024-03-01T12:00:00Z"}`),
+		"/mockmod.xyz/@v/v1.2.3.mod":  []byte("module mockmod.xyz\n"),
+	}}
+	proxyAddr, closeProxy := s.getProxy(mp)
+	defer closeProxy()
+
+	env := []string{"GONOSUMDB=mockmod.xyz", "GOPROXY=" + proxyAddr}
+	fetcher, err := NewGoGetFetcher(s.goBinaryName, "", env, afero.NewOsFs(), nil, nil)
+	r.NoError(err)
+	ver, err := fetcher.(ModFetcher).FetchMod(s.T().Context(), "mockmod.xyz", "v1.2.3")
+	r.NoError(err, "the zip must not be requested")
+	r.Equal("v1.2.3", ver.Semver)
+	r.Equal("module mockmod.xyz\n", string(ver.Mod))
+	r.JSONEq(`{"Version":"v1.2.3","Time":"2024-03-01T12:00:00Z"}`, string(ver.Info))
+	r.Nil(ver.Zip)
+
+	_, err = fetcher.(ModFetcher).FetchMod(s.T().Context(), "mockmod.xyz", "v9.9.9")
+	r.Equal(errors.KindNotFound, errors.Kind(err))
+}
+
 func (s *ModuleSuite) TestGoGetDir() {
 	r := s.Require()
 	t := s.T()
@@ -103,7 +146,7 @@ func (s *ModuleSuite) TestGoGetDir() {
 	t.Cleanup(func() {
 		os.RemoveAll(dir)
 	})
-	fetcher, err := NewGoGetFetcher(s.goBinaryName, dir, s.env, afero.NewOsFs())
+	fetcher, err := NewGoGetFetcher(s.goBinaryName, dir, s.env, afero.NewOsFs(), nil, nil)
 	r.NoError(err)
 
 	ver, err := fetcher.Fetch(s.T().Context(), repoURI, version)
@@ -0,0 +1,100 @@
+package module
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/gomods/athens/pkg/errors"
+	modpath "golang.org/x/mod/module"
+)
+
+// proxyClient talks the GOPROXY protocol to a single upstream module proxy.
+type proxyClient struct {
+	url string
+	c   *http.Client
+}
+
+// newProxyClient returns a client of the proxy at upstream,
+// or nil if upstream is empty.
+func newProxyClient(upstream string, c *http.Client) *proxyClient {
+	if c == nil {
+		c = &http.Client{}
+	}
+	upstream = strings.TrimSuffix(strings.TrimSpace(upstream), "/")
+	if upstream == "" {
+		return nil
+	}
+	return &proxyClient{url: upstream, c: c}
+}
+
+// get requests {url}/{escaped module}/{suffix} and returns the body.
+// The caller is responsible for closing the returned body.
+func (p *proxyClient) get(ctx context.Context, mod, suffix string) (io.ReadCloser, error) {
+	const op errors.Op = "proxyClient.get"
+	escMod, err := modpath.EscapePath(mod)
+	if err != nil {
+		return nil, errors.E(op, err, errors.KindBadRequest)
+	}
+	url := p.url + "/" + escMod + "/" + suffix
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+	resp, err := p.c.Do(req)
+	if err != nil {
+		if errors.IsErr(ctx.Err(), context.DeadlineExceeded) {
+			return nil, errors.E(op, err, errors.KindGatewayTimeout)
+		}
+		return nil, errors.E(op, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<10))
+		_ = resp.Body.Close()
+		err := fmt.Errorf("%s: unexpected status code %d: %s", url, resp.StatusCode, strings.TrimSpace(string(body)))
+		return nil, errors.E(op, err, proxyStatusKind(resp.StatusCode))
+	}
+	return resp.Body, nil
+}
+
+// getBytes is like get but reads the whole body into memory.
+func (p *proxyClient) getBytes(ctx context.Context, mod, suffix string) ([]byte, error) {
+	const op errors.Op = "proxyClient.getBytes"
+	body, err := p.get(ctx, mod, suffix)
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+	defer func() { _ = body.Close() }()
+	bts, err := io.ReadAll(body)
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+	return bts, nil
+}
+
+// versionPath returns the @v/{version}.{ext} path for the given version.
+func versionPath(ver, ext string) (string, error) {
+	escVer, err := modpath.EscapeVersion(ver)
+	if err != nil {
+		return "", err
+	}
+	return "@v/" + escVer + "." + ext, nil
+}
+
+// proxyStatusKind maps an upstream proxy response status to
+// an Athens error kind. 404 and 410 are the only statuses that
+// the go command treats as "not found" when walking GOPROXY.
+func proxyStatusKind(status int) int {
+	switch status {
+	case http.StatusNotFound, http.StatusGone:
+		return errors.KindNotFound
+	case http.StatusTooManyRequests:
+		return errors.KindRateLimit
+	case http.StatusGatewayTimeout:
+		return errors.KindGatewayTimeout
+	default:
+		return errors.KindUnexpected
+	}
+}
@@ -0,0 +1,110 @@
+package module
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/gomods/athens/pkg/errors"
+	"github.com/gomods/athens/pkg/observ"
+	"github.com/gomods/athens/pkg/storage"
+)
+
+type goProxyFetcher struct {
+	upstream *proxyClient
+}
+
+// NewGoProxyFetcher creates a fetcher which downloads modules from the
+// upstream module proxy using the GOPROXY protocol. Use an UpstreamChain
+// to fall through several upstreams.
+func NewGoProxyFetcher(upstream string, client *http.Client) (Fetcher, error) {
+	const op errors.Op = "module.NewGoProxyFetcher"
+	p := newProxyClient(upstream, client)
+	if p == nil {
+		return nil, errors.E(op, "an upstream proxy must be provided")
+	}
+	return &goProxyFetcher{upstream: p}, nil
+}
+
+// Fetch downloads the .info, .mod, and .zip files of the given module
+// version from the upstream proxy.
+func (g *goProxyFetcher) Fetch(ctx context.Context, mod, ver string) (*storage.Version, error) {
+	const op errors.Op = "goProxyFetcher.Fetch"
+	ctx, span := observ.StartSpan(ctx, op.String())
+	defer span.End()
+
+	v, err := fetchFromProxy(ctx, g.upstream, mod, ver)
+	if err != nil {
+		return nil, errors.E(op, errors.M(mod), errors.V(ver), err)
+	}
+	return v, nil
+}
+
+// FetchMod downloads the .info and .mod files of the given module
+// version from the upstream proxy.
+func (g *goProxyFetcher) FetchMod(ctx context.Context, mod, ver string) (*storage.Version, error) {
+	const op errors.Op = "goProxyFetcher.FetchMod"
+	ctx, span := observ.StartSpan(ctx, op.String())
+	defer span.End()
+
+	v, err := fetchModFromProxy(ctx, g.upstream, mod, ver)
+	if err != nil {
+		return nil, errors.E(op, errors.M(mod), errors.V(ver), err)
+	}
+	return v, nil
+}
+
+func fetchFromProxy(ctx context.Context, p *proxyClient, mod, ver string) (*storage.Version, error) {
+	const op errors.Op = "module.fetchFromProxy"
+	v, err := fetchModFromProxy(ctx, p, mod, ver)
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+	zipPath, err := versionPath(v.Semver, "zip")
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+	// The zip is streamed straight from the upstream response into storage,
+	// therefore there is no checksum available up front.
+	v.Zip, err = p.get(ctx, mod, zipPath)
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+	return v, nil
+}
+
+// fetchModFromProxy downloads the .info and .mod files of the version.
+func fetchModFromProxy(ctx context.Context, p *proxyClient, mod, ver string) (*storage.Version, error) {
+	const op errors.Op = "module.fetchModFromProxy"
+	infoPath, err := versionPath(ver, "info")
+	if err != nil {
+		return nil, errors.E(op, err, errors.KindBadRequest)
+	}
+	info, err := p.getBytes(ctx, mod, infoPath)
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+	var rev storage.RevInfo
+	if err := json.Unmarshal(info, &rev); err != nil {
+		return nil, errors.E(op, err)
+	}
+	if rev.Version == "" {
+		return nil, errors.E(op, "upstream returned an .info file without a version")
+	}
+
+	// The requested version may be a query such as a branch name,
+	// from here on only use the canonical version the upstream resolved.
+	modPath, err := versionPath(rev.Version, "mod")
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+	goMod, err := p.getBytes(ctx, mod, modPath)
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+	return &storage.Version{
+		Semver: rev.Version,
+		Info:   info,
+		Mod:    goMod,
+	}, nil
+}
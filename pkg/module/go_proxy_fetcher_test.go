@@ -0,0 +1,126 @@
+package module
+
+import (
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gomods/athens/pkg/download/mode"
+	"github.com/gomods/athens/pkg/errors"
+)
+
+func (s *ModuleSuite) TestGoProxyFetcherFetch() {
+	r := s.Require()
+	mp := &mockProxy{paths: map[string][]byte{
+		"/github.com/!n!y!times/gizmo/@v/master.info": []byte(`{"Version":"v1.2.3"}`),
+		"/github.com/!n!y!times/gizmo/@v/v1.2.3.mod":  []byte("module github.com/NYTimes/gizmo"),
+		"/github.com/!n!y!times/gizmo/@v/v1.2.3.zip":  []byte("zipfile"),
+	}}
+	found, closeFound := s.getProxy(mp)
+	defer closeFound()
+
+	fetcher, err := NewGoProxyFetcher(found+"/", nil)
+	r.NoError(err)
+	ver, err := fetcher.Fetch(s.T().Context(), repoURI, "master")
+	r.NoError(err)
+	defer ver.Zip.Close()
+
+	r.Equal("v1.2.3", ver.Semver)
+	r.Equal(`{"Version":"v1.2.3"}`, string(ver.Info))
+	r.Equal("module github.com/NYTimes/gizmo", string(ver.Mod))
+	zip, err := io.ReadAll(ver.Zip)
+	r.NoError(err)
+	r.Equal("zipfile", string(zip))
+}
+
+func (s *ModuleSuite) TestGoProxyFetcherFetchMod() {
+	r := s.Require()
+	mp := &mockProxy{paths: map[string][]byte{
+		"/github.com/!n!y!times/gizmo/@v/master.info": []byte(`{"Version":"v1.2.3"}`),
+		"/github.com/!n!y!times/gizmo/@v/v1.2.3.mod":  []byte("module github.com/NYTimes/gizmo"),
+	}}
+	addr, closeProxy := s.getProxy(mp)
+	defer closeProxy()
+
+	fetcher, err := NewGoProxyFetcher(addr, nil)
+	r.NoError(err)
+	ver, err := fetcher.(ModFetcher).FetchMod(s.T().Context(), repoURI, "master")
+	r.NoError(err)
+	r.Equal("v1.2.3", ver.Semver)
+	r.Equal(`{"Version":"v1.2.3"}`, string(ver.Info))
+	r.Equal("module github.com/NYTimes/gizmo", string(ver.Mod))
+	r.Nil(ver.Zip)
+}
+
+func (s *ModuleSuite) TestGoProxyFetcherErrors() {
+	r := s.Require()
+	_, err := NewGoProxyFetcher(" ", nil)
+	r.Error(err)
+
+	missing, closeMissing := s.getProxy(&mockProxy{paths: map[string][]byte{}})
+	defer closeMissing()
+	fetcher, err := NewGoProxyFetcher(missing, nil)
+	r.NoError(err)
+	_, err = fetcher.Fetch(s.T().Context(), repoURI, version)
+	r.Equal(errors.KindNotFound, errors.Kind(err))
+
+	limited, closeLimited := s.getProxy(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer closeLimited()
+	fetcher, err = NewGoProxyFetcher(limited, nil)
+	r.NoError(err)
+	_, err = fetcher.Fetch(s.T().Context(), repoURI, version)
+	r.Equal(errors.KindRateLimit, errors.Kind(err))
+}
+
+func (s *ModuleSuite) TestGoProxyLister() {
+	r := s.Require()
+	mp := &mockProxy{paths: map[string][]byte{
+		"/github.com/!n!y!times/gizmo/@v/list":        []byte("v0.1.0\nv0.2.0-pre\nv0.1.4\n"),
+		"/github.com/!n!y!times/gizmo/@v/v0.1.4.info": []byte(`{"Version":"v0.1.4"}`),
+		"/nolist.xyz/@latest":                         []byte(`{"Version":"v0.0.0-20190101000000-abcdefabcdef"}`),
+	}}
+	addr, closeProxy := s.getProxy(mp)
+	defer closeProxy()
+	lister, err := NewGoProxyLister(addr, nil, time.Minute, nil)
+	r.NoError(err)
+
+	// @latest is not served, so the highest release is used instead.
+	rev, versions, err := lister.List(s.T().Context(), repoURI)
+	r.NoError(err)
+	r.Equal([]string{"v0.1.0", "v0.2.0-pre", "v0.1.4"}, versions)
+	r.Equal("v0.1.4", rev.Version)
+
+	rev, versions, err = lister.List(s.T().Context(), "nolist.xyz")
+	r.NoError(err)
+	r.Empty(versions)
+	r.Equal("v0.0.0-20190101000000-abcdefabcdef", rev.Version)
+
+	_, _, err = lister.List(s.T().Context(), "missing.xyz")
+	r.Equal(errors.KindNotFound, errors.Kind(err))
+}
+
+func (s *ModuleSuite) TestGoProxyListerTimeout() {
+	r := s.Require()
+	mp := &mockProxy{paths: map[string][]byte{
+		"/github.com/!n!y!times/gizmo/@v/list":        []byte("v0.1.4\n"),
+		"/github.com/!n!y!times/gizmo/@v/v0.1.4.info": []byte(`{"Version":"v0.1.4"}`),
+	}}
+	addr, closeProxy := s.getProxy(mp)
+	defer closeProxy()
+	timeout := 60
+	df := &mode.DownloadFile{Mode: mode.Sync, Paths: []*mode.DownloadPath{
+		{Pattern: "github.com/*", Mode: mode.Sync, Timeout: &timeout},
+	}}
+
+	// the timeout of the matching download path replaces the one that is too short.
+	lister, err := NewGoProxyLister(addr, nil, time.Nanosecond, df)
+	r.NoError(err)
+	_, versions, err := lister.List(s.T().Context(), repoURI)
+	r.NoError(err)
+	r.Equal([]string{"v0.1.4"}, versions)
+
+	_, _, err = lister.List(s.T().Context(), "nolist.xyz")
+	r.Equal(errors.KindGatewayTimeout, errors.Kind(err))
+}
@@ -0,0 +1,133 @@
+package module
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gomods/athens/pkg/download/mode"
+	"github.com/gomods/athens/pkg/errors"
+	"github.com/gomods/athens/pkg/observ"
+	"github.com/gomods/athens/pkg/storage"
+	"golang.org/x/mod/semver"
+	"golang.org/x/sync/singleflight"
+)
+
+type goProxyLister struct {
+	upstream *proxyClient
+	sfg      *singleflight.Group
+	timeout  time.Duration
+	df       *mode.DownloadFile
+}
+
+// NewGoProxyLister creates an UpstreamLister which uses the /@v/list and /@latest
+// endpoints of the upstream module proxy to fetch a list of available versions.
+// If df is not nil, the download path that matches a module may override timeout.
+func NewGoProxyLister(upstream string, client *http.Client, timeout time.Duration, df *mode.DownloadFile) (UpstreamLister, error) {
+	const op errors.Op = "module.NewGoProxyLister"
+	p := newProxyClient(upstream, client)
+	if p == nil {
+		return nil, errors.E(op, "an upstream proxy must be provided")
+	}
+	return &goProxyLister{
+		upstream: p,
+		sfg:      &singleflight.Group{},
+		timeout:  timeout,
+		df:       df,
+	}, nil
+}
+
+func (l *goProxyLister) List(ctx context.Context, mod string) (*storage.RevInfo, []string, error) {
+	const op errors.Op = "goProxyLister.List"
+	ctx, span := observ.StartSpan(ctx, op.String())
+	defer span.End()
+	sfResp, err, _ := l.sfg.Do(mod, func() (any, error) {
+		timeout := l.timeout
+		if l.df != nil {
+			timeout = l.df.Timeout(mod, timeout)
+		}
+		timeoutCtx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+
+		resp, err := listFromProxy(timeoutCtx, l.upstream, mod)
+		if err != nil {
+			return nil, errors.E(op, errors.M(mod), err)
+		}
+		return resp, nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	ret := sfResp.(listSFResp)
+	return ret.rev, ret.versions, nil
+}
+
+func listFromProxy(ctx context.Context, p *proxyClient, mod string) (listSFResp, error) {
+	const op errors.Op = "module.listFromProxy"
+	list, err := p.getBytes(ctx, mod, "@v/list")
+	if err != nil && !errors.IsNotFoundErr(err) {
+		return listSFResp{}, errors.E(op, err)
+	}
+	versions := []string{}
+	scnr := bufio.NewScanner(bytes.NewReader(list))
+	for scnr.Scan() {
+		// Each line may be followed by optional metadata, only the version matters.
+		fields := strings.Fields(scnr.Text())
+		if len(fields) > 0 {
+			versions = append(versions, fields[0])
+		}
+	}
+
+	rev, err := latestFromProxy(ctx, p, mod, versions)
+	if err != nil {
+		return listSFResp{}, errors.E(op, err)
+	}
+	return listSFResp{rev: rev, versions: versions}, nil
+}
+
+// latestFromProxy asks the upstream for @latest. Some proxies, such as an Athens
+// in offline mode, do not serve @latest; in that case the info of the highest
+// listed version is used instead.
+func latestFromProxy(ctx context.Context, p *proxyClient, mod string, versions []string) (*storage.RevInfo, error) {
+	const op errors.Op = "module.latestFromProxy"
+	info, err := p.getBytes(ctx, mod, "@latest")
+	if errors.IsNotFoundErr(err) && len(versions) > 0 {
+		var infoPath string
+		infoPath, err = versionPath(highestVersion(versions), "info")
+		if err != nil {
+			return nil, errors.E(op, err)
+		}
+		info, err = p.getBytes(ctx, mod, infoPath)
+	}
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+	var rev storage.RevInfo
+	if err := json.Unmarshal(info, &rev); err != nil {
+		return nil, errors.E(op, err)
+	}
+	return &rev, nil
+}
+
+// highestVersion returns the highest release version in the list,
+// or the highest pre-release if there are no releases.
+func highestVersion(versions []string) string {
+	var release, prerelease string
+	for _, v := range versions {
+		if semver.Prerelease(v) == "" {
+			if release == "" || semver.Compare(v, release) > 0 {
+				release = v
+			}
+		} else if prerelease == "" || semver.Compare(v, prerelease) > 0 {
+			prerelease = v
+		}
+	}
+	if release != "" {
+		return release
+	}
+	return prerelease
+}
@@ -9,6 +9,7 @@ import (
 	"time"
 
 	"github.com/gomods/athens/pkg/config"
+	"github.com/gomods/athens/pkg/download/mode"
 	"github.com/gomods/athens/pkg/errors"
 	"github.com/gomods/athens/pkg/observ"
 	"github.com/gomods/athens/pkg/storage"
@@ -21,6 +22,7 @@ type listResp struct {
 	Version  string
 	Versions []string `json:",omitempty"`
 	Time     time.Time
+	Origin   *storage.Origin `json:",omitempty"`
 }
 
 type vcsLister struct {
@@ -29,16 +31,23 @@ type vcsLister struct {
 	fs        afero.Fs
 	sfg       *singleflight.Group
 	timeout   time.Duration
+	cache     *GoCache
+	df        *mode.DownloadFile
 }
 
 // NewVCSLister creates an UpstreamLister which uses VCS to fetch a list of available versions.
-func NewVCSLister(goBinPath string, env []string, fs afero.Fs, timeout time.Duration) UpstreamLister {
+// If cache is not nil, the repositories cloned to list versions are kept in it and
+// shared with the go get fetcher. If df is not nil, the download path that matches
+// a module may override timeout and add to env for it.
+func NewVCSLister(goBinPath string, env []string, fs afero.Fs, timeout time.Duration, cache *GoCache, df *mode.DownloadFile) UpstreamLister {
 	return &vcsLister{
 		goBinPath: goBinPath,
 		env:       env,
 		fs:        fs,
 		sfg:       &singleflight.Group{},
 		timeout:   timeout,
+		cache:     cache,
+		df:        df,
 	}
 }
 
@@ -58,7 +67,12 @@ func (l *vcsLister) List(ctx context.Context, module string) (*storage.RevInfo,
 		}
 		defer func() { _ = l.fs.RemoveAll(tmpDir) }()
 
-		timeoutCtx, cancel := context.WithTimeout(ctx, l.timeout)
+		timeout, env := l.timeout, l.env[:len(l.env):len(l.env)]
+		if l.df != nil {
+			timeout = l.df.Timeout(module, timeout)
+			env = append(env, l.df.GoEnv(module)...)
+		}
+		timeoutCtx, cancel := context.WithTimeout(ctx, timeout)
 		defer cancel()
 
 		cmd := exec.CommandContext(
@@ -78,7 +92,15 @@ func (l *vcsLister) List(ctx context.Context, module string) (*storage.RevInfo,
 			return nil, errors.E(op, err)
 		}
 		defer func() { _ = clearFiles(l.fs, gopath) }()
-		cmd.Env = prepareEnv(gopath, l.env)
+		if l.cache != nil {
+			env = append(env, l.cache.env())
+			done := l.cache.use()
+			defer func() {
+				done()
+				l.cache.evict()
+			}()
+		}
+		cmd.Env = prepareEnv(gopath, env)
 
 		err = cmd.Run()
 		if err != nil {
@@ -102,6 +124,9 @@ func (l *vcsLister) List(ctx context.Context, module string) (*storage.RevInfo,
 		if err != nil {
 			return nil, errors.E(op, err)
 		}
+		if l.cache != nil {
+			l.cache.touch(lr.Origin)
+		}
 		rev := storage.RevInfo{
 			Time:    lr.Time,
 			Version: lr.Version,
@@ -0,0 +1,155 @@
+package module
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/gomods/athens/pkg/errors"
+	"github.com/gomods/athens/pkg/log"
+	"github.com/gomods/athens/pkg/storage"
+	"golang.org/x/sync/errgroup"
+)
+
+// maxQuarantineTimes bounds the number of version times that a
+// QuarantineChecker keeps in memory. Once it is reached, a time is
+// forgotten for every time that is added.
+const maxQuarantineTimes = 10000
+
+// maxQuarantineLookups bounds the number of version times
+// that QuarantineChecker.Filter looks up at the same time.
+const maxQuarantineLookups = 8
+
+// QuarantineChecker holds back the versions that the Quarantine rules of a
+// Filter apply to, until their time is older than the quarantine of the rule.
+// The time of a version is the Time of its .info file in storage. The .info
+// files of the versions that are not there yet are fetched upstream, without
+// their zips, and their times are kept in memory.
+type QuarantineChecker struct {
+	filter  *Filter
+	storage storage.Getter
+	fetcher ModFetcher
+	now     func() time.Time
+
+	mu    sync.Mutex
+	times map[string]time.Time
+}
+
+// NewQuarantineChecker returns a QuarantineChecker for the rules of f.
+// The fetcher may be nil, as on frontends, in which case the versions
+// that are not in storage stay in quarantine. It should only fetch the
+// versions that a client could have stashed, see (./pkg/stash).WantedMods.
+func NewQuarantineChecker(f *Filter, s storage.Getter, mf ModFetcher) *QuarantineChecker {
+	return &QuarantineChecker{filter: f, storage: s, fetcher: mf, now: time.Now, times: map[string]time.Time{}}
+}
+
+// Until returns the time at which the given version leaves quarantine,
+// or the zero time if it is not in quarantine.
+func (q *QuarantineChecker) Until(ctx context.Context, mod, ver string) (time.Time, error) {
+	const op errors.Op = "module.QuarantineChecker.Until"
+	m := q.filter.Match(mod, ver)
+	if m.Rule != Quarantine {
+		return time.Time{}, nil
+	}
+	t, err := q.Time(ctx, mod, ver)
+	if err != nil {
+		return time.Time{}, errors.E(op, err, errors.M(mod), errors.V(ver))
+	}
+	until := t.Add(m.Quarantine)
+	if !until.After(q.now()) {
+		return time.Time{}, nil
+	}
+	return until, nil
+}
+
+// Filter returns the versions that are not in quarantine. The versions whose
+// time cannot be found are left out.
+func (q *QuarantineChecker) Filter(ctx context.Context, mod string, vers []string) []string {
+	keep := make([]bool, len(vers))
+	var g errgroup.Group
+	g.SetLimit(maxQuarantineLookups)
+	for i, ver := range vers {
+		g.Go(func() error {
+			until, err := q.Until(ctx, mod, ver)
+			if err != nil {
+				log.EntryFromContext(ctx).SystemErr(err)
+				return nil
+			}
+			keep[i] = until.IsZero()
+			return nil
+		})
+	}
+	_ = g.Wait()
+	res := make([]string, 0, len(vers))
+	for i, ver := range vers {
+		if keep[i] {
+			res = append(res, ver)
+		}
+	}
+	return res
+}
+
+// Remember keeps the time of a version that was found elsewhere, such as
+// the latest version that an UpstreamLister returns, so that it does not
+// need to be looked up.
+func (q *QuarantineChecker) Remember(mod string, rev *storage.RevInfo) {
+	if rev == nil || rev.Version == "" || rev.Time.IsZero() {
+		return
+	}
+	q.remember(mod+"@"+rev.Version, rev.Time)
+}
+
+func (q *QuarantineChecker) remember(key string, t time.Time) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if _, ok := q.times[key]; !ok && len(q.times) >= maxQuarantineTimes {
+		// maps are iterated in a random order, so this forgets any time.
+		for k := range q.times {
+			delete(q.times, k)
+			break
+		}
+	}
+	q.times[key] = t
+}
+
+// Time returns the time of the given version.
+func (q *QuarantineChecker) Time(ctx context.Context, mod, ver string) (time.Time, error) {
+	const op errors.Op = "module.QuarantineChecker.Time"
+	key := mod + "@" + ver
+	q.mu.Lock()
+	t, ok := q.times[key]
+	q.mu.Unlock()
+	if ok {
+		return t, nil
+	}
+
+	info, err := q.info(ctx, mod, ver)
+	if err != nil {
+		return time.Time{}, errors.E(op, err)
+	}
+	var rev storage.RevInfo
+	if err := json.Unmarshal(info, &rev); err != nil {
+		return time.Time{}, errors.E(op, err)
+	}
+	if rev.Time.IsZero() {
+		return time.Time{}, errors.E(op, "the .info file has no time")
+	}
+	// queries such as branch names resolve to other versions over time.
+	if rev.Version == ver {
+		q.remember(key, rev.Time)
+	}
+	return rev.Time, nil
+}
+
+func (q *QuarantineChecker) info(ctx context.Context, mod, ver string) ([]byte, error) {
+	info, err := q.storage.Info(ctx, mod, ver)
+	if err == nil || !errors.IsNotFoundErr(err) || q.fetcher == nil {
+		return info, err
+	}
+	v, err := q.fetcher.FetchMod(ctx, mod, ver)
+	if err != nil {
+		return nil, err
+	}
+	return v.Info, nil
+}
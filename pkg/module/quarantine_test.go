@@ -0,0 +1,114 @@
+package module
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/gomods/athens/pkg/errors"
+	"github.com/gomods/athens/pkg/storage"
+)
+
+// fakeInfos is a storage.Getter that serves .info
+// files with the given version times.
+type fakeInfos struct {
+	mu    sync.Mutex
+	times map[string]time.Time
+	calls int
+}
+
+func (f *fakeInfos) Info(ctx context.Context, mod, ver string) ([]byte, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls++
+	t, ok := f.times[ver]
+	if !ok {
+		return nil, errors.E("fakeInfos.Info", errors.KindNotFound)
+	}
+	return fmt.Appendf(nil, `{"Version":%q,"Time":%q}`, ver, t.Format(time.RFC3339)), nil
+}
+
+func (f *fakeInfos) GoMod(ctx context.Context, mod, ver string) ([]byte, error) {
+	return nil, errors.E("fakeInfos.GoMod", errors.KindNotFound)
+}
+
+func (f *fakeInfos) Zip(ctx context.Context, mod, ver string) (storage.SizeReadCloser, error) {
+	return nil, errors.E("fakeInfos.Zip", errors.KindNotFound)
+}
+
+// fakeModFetcher fetches the .info files of upstream.
+type fakeModFetcher struct {
+	upstream *fakeInfos
+}
+
+func (f *fakeModFetcher) FetchMod(ctx context.Context, mod, ver string) (*storage.Version, error) {
+	info, err := f.upstream.Info(ctx, mod, ver)
+	if err != nil {
+		return nil, err
+	}
+	return &storage.Version{Info: info, Semver: ver}, nil
+}
+
+func (s *ModuleSuite) TestQuarantineChecker() {
+	r := s.Require()
+	ctx := s.T().Context()
+	now := time.Date(2024, 6, 30, 0, 0, 0, 0, time.UTC)
+
+	filterFile := tempFilterFile(s.T())
+	defer os.Remove(filterFile)
+	input := []byte("Q7 github.com/a\nQ30 github.com/a/risky\n+ github.com/a =v1.3.0\n")
+	r.NoError(os.WriteFile(filterFile, input, 0o644))
+	f, err := NewFilter(filterFile)
+	r.NoError(err)
+
+	stored := &fakeInfos{times: map[string]time.Time{"v1.0.0": now.AddDate(0, 0, -60)}}
+	upstream := &fakeInfos{times: map[string]time.Time{
+		"v1.1.0": now.AddDate(0, 0, -10),
+		"v1.2.0": now.AddDate(0, 0, -2),
+		"v1.3.0": now.AddDate(0, 0, -1),
+	}}
+	q := NewQuarantineChecker(f, stored, &fakeModFetcher{upstream: upstream})
+	q.now = func() time.Time { return now }
+
+	until, err := q.Until(ctx, "github.com/a", "v1.2.0")
+	r.NoError(err)
+	r.Equal(now.AddDate(0, 0, 5), until)
+	// allowed by a later rule
+	until, err = q.Until(ctx, "github.com/a", "v1.3.0")
+	r.NoError(err)
+	r.True(until.IsZero())
+	// the longer quarantine of a deeper rule
+	until, err = q.Until(ctx, "github.com/a/risky", "v1.1.0")
+	r.NoError(err)
+	r.Equal(now.AddDate(0, 0, 20), until)
+	// not quarantined, so its time is not looked up
+	until, err = q.Until(ctx, "github.com/b", "v9.9.9")
+	r.NoError(err)
+	r.True(until.IsZero())
+	_, err = q.Until(ctx, "github.com/a", "v9.9.9")
+	r.True(errors.IsNotFoundErr(err))
+
+	r.Equal([]string{"v1.0.0", "v1.1.0", "v1.3.0"}, q.Filter(ctx, "github.com/a", []string{"v1.0.0", "v1.1.0", "v1.2.0", "v1.3.0", "v9.9.9"}))
+
+	// times are looked up once, and the versions are not saved to storage
+	calls := upstream.calls
+	_, err = q.Until(ctx, "github.com/a", "v1.2.0")
+	r.NoError(err)
+	r.Equal(calls, upstream.calls)
+	r.NotContains(stored.times, "v1.2.0")
+
+	// the time of the latest version that a lister returns
+	q.Remember("github.com/a", &storage.RevInfo{Version: "v1.4.0", Time: now.AddDate(0, 0, -8)})
+	until, err = q.Until(ctx, "github.com/a", "v1.4.0")
+	r.NoError(err)
+	r.True(until.IsZero())
+	r.Equal(calls, upstream.calls)
+
+	// without a fetcher, the versions that are not stored stay in quarantine
+	stored = &fakeInfos{times: map[string]time.Time{"v1.0.0": now.AddDate(0, 0, -60)}}
+	q = NewQuarantineChecker(f, stored, nil)
+	q.now = func() time.Time { return now }
+	r.Equal([]string{"v1.0.0"}, q.Filter(ctx, "github.com/a", []string{"v1.0.0", "v1.1.0"}))
+}
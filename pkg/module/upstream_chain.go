@@ -0,0 +1,330 @@
+package module
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gomods/athens/pkg/errors"
+	"github.com/gomods/athens/pkg/observ"
+	"github.com/gomods/athens/pkg/storage"
+)
+
+// Upstream is a single source of modules inside an UpstreamChain,
+// such as another module proxy or the VCS directly.
+type Upstream struct {
+	// Name identifies the upstream in logs, metrics and health reports.
+	Name    string
+	Fetcher Fetcher
+	Lister  UpstreamLister
+	// FallThroughOnError makes the chain try the next upstream on any error,
+	// and not only when this upstream does not have the module. It is the
+	// equivalent of separating two GOPROXY entries with a pipe (|) instead
+	// of a comma.
+	FallThroughOnError bool
+}
+
+// UpstreamSpec is one parsed entry of a GOPROXY-style upstream list.
+type UpstreamSpec struct {
+	// URL is either a proxy URL or "direct".
+	URL                string
+	FallThroughOnError bool
+}
+
+// ParseUpstreamList parses a GOPROXY-style list of upstreams. Entries are
+// separated either by a comma, meaning the next entry is only tried on a
+// 404 or 410, or by a pipe, meaning the next entry is tried on any error.
+// The keyword "direct" stands for fetching from the VCS.
+func ParseUpstreamList(list string) ([]UpstreamSpec, error) {
+	const op errors.Op = "module.ParseUpstreamList"
+	var specs []UpstreamSpec
+	for list != "" {
+		var entry string
+		fallThroughOnError := false
+		i := strings.IndexAny(list, ",|")
+		if i >= 0 {
+			entry = list[:i]
+			fallThroughOnError = list[i] == '|'
+			list = list[i+1:]
+		} else {
+			entry = list
+			list = ""
+		}
+		entry = strings.TrimSuffix(strings.TrimSpace(entry), "/")
+		switch entry {
+		case "":
+			continue
+		case "off", "noproxy":
+			return nil, errors.E(op, fmt.Sprintf("upstream %q is not supported", entry))
+		}
+		specs = append(specs, UpstreamSpec{URL: entry, FallThroughOnError: fallThroughOnError})
+	}
+	if len(specs) == 0 {
+		return nil, errors.E(op, "at least one upstream must be provided")
+	}
+	return specs, nil
+}
+
+// UpstreamHealth describes the circuit breaker state of an upstream.
+type UpstreamHealth struct {
+	Name                string    `json:"name"`
+	State               string    `json:"state"`
+	ConsecutiveFailures int       `json:"consecutiveFailures"`
+	LastError           string    `json:"lastError,omitempty"`
+	OpenUntil           time.Time `json:"openUntil,omitzero"`
+}
+
+// HealthReporter is implemented by fetchers that can report
+// the health of the upstreams they fetch from.
+type HealthReporter interface {
+	Health() []UpstreamHealth
+}
+
+// UpstreamChain is a Fetcher and an UpstreamLister that tries an ordered list of
+// upstreams, following the same fall through rules as the go command does for
+// GOPROXY. Each upstream has its own circuit breaker: after a number of consecutive
+// failures the upstream is skipped, moving on to the next one whatever the fall
+// through rules, until a cool-down period has passed, after which a single request
+// is let through to probe whether it has recovered.
+type UpstreamChain struct {
+	upstreams []*chainUpstream
+}
+
+type chainUpstream struct {
+	Upstream
+	breaker *breaker
+}
+
+// NewUpstreamChain returns an UpstreamChain over the given upstreams. An upstream
+// is considered unhealthy after maxFailures consecutive errors and is then
+// skipped for the cooldown duration. A maxFailures of zero disables the
+// circuit breakers.
+func NewUpstreamChain(upstreams []Upstream, maxFailures int, cooldown time.Duration) (*UpstreamChain, error) {
+	const op errors.Op = "module.NewUpstreamChain"
+	if len(upstreams) == 0 {
+		return nil, errors.E(op, "at least one upstream must be provided")
+	}
+	c := &UpstreamChain{}
+	for _, u := range upstreams {
+		if u.Fetcher == nil || u.Lister == nil {
+			return nil, errors.E(op, fmt.Sprintf("upstream %q must have a fetcher and a lister", u.Name))
+		}
+		c.upstreams = append(c.upstreams, &chainUpstream{
+			Upstream: u,
+			breaker:  newBreaker(maxFailures, cooldown),
+		})
+		observ.RecordUpstreamHealth(context.Background(), u.Name, true)
+	}
+	return c, nil
+}
+
+// Fetch fetches the module version from the first upstream that has it.
+func (c *UpstreamChain) Fetch(ctx context.Context, mod, ver string) (*storage.Version, error) {
+	const op errors.Op = "upstreamChain.Fetch"
+	ctx, span := observ.StartSpan(ctx, op.String())
+	defer span.End()
+	var v *storage.Version
+	err := c.try(ctx, func(u *chainUpstream) error {
+		var err error
+		v, err = u.Fetcher.Fetch(ctx, mod, ver)
+		return err
+	})
+	if err != nil {
+		return nil, errors.E(op, errors.M(mod), errors.V(ver), err)
+	}
+	return v, nil
+}
+
+// FetchMod fetches the .info and .mod files of the module version from the
+// first upstream that has it. Upstreams that cannot fetch them on their own
+// fetch the whole version and the zip is discarded.
+func (c *UpstreamChain) FetchMod(ctx context.Context, mod, ver string) (*storage.Version, error) {
+	const op errors.Op = "upstreamChain.FetchMod"
+	ctx, span := observ.StartSpan(ctx, op.String())
+	defer span.End()
+	var v *storage.Version
+	err := c.try(ctx, func(u *chainUpstream) error {
+		var err error
+		if mf, ok := u.Fetcher.(ModFetcher); ok {
+			v, err = mf.FetchMod(ctx, mod, ver)
+			return err
+		}
+		v, err = u.Fetcher.Fetch(ctx, mod, ver)
+		if err != nil {
+			return err
+		}
+		if v.Zip != nil {
+			_ = v.Zip.Close()
+		}
+		v.Zip, v.ZipMD5 = nil, nil
+		return nil
+	})
+	if err != nil {
+		return nil, errors.E(op, errors.M(mod), errors.V(ver), err)
+	}
+	return v, nil
+}
+
+// List lists the module versions from the first upstream that has the module.
+func (c *UpstreamChain) List(ctx context.Context, mod string) (*storage.RevInfo, []string, error) {
+	const op errors.Op = "upstreamChain.List"
+	ctx, span := observ.StartSpan(ctx, op.String())
+	defer span.End()
+	var (
+		rev      *storage.RevInfo
+		versions []string
+	)
+	err := c.try(ctx, func(u *chainUpstream) error {
+		var err error
+		rev, versions, err = u.Lister.List(ctx, mod)
+		return err
+	})
+	if err != nil {
+		return nil, nil, errors.E(op, errors.M(mod), err)
+	}
+	return rev, versions, nil
+}
+
+// Health returns the circuit breaker state of every upstream, in order.
+func (c *UpstreamChain) Health() []UpstreamHealth {
+	health := make([]UpstreamHealth, 0, len(c.upstreams))
+	for _, u := range c.upstreams {
+		h := u.breaker.health()
+		h.Name = u.Name
+		health = append(health, h)
+	}
+	return health
+}
+
+// try calls f for every upstream in order until one succeeds
+// or the fall through rules stop the chain.
+func (c *UpstreamChain) try(ctx context.Context, f func(u *chainUpstream) error) error {
+	const op errors.Op = "upstreamChain.try"
+	var err error
+	for _, u := range c.upstreams {
+		// an unhealthy upstream is skipped whatever its fall through
+		// rule, since it is not asked and so cannot fail the request.
+		if !u.breaker.allow() {
+			observ.RecordUpstreamRequest(ctx, u.Name, "skipped")
+			// the error of an upstream that was asked, such as a
+			// not found, is kept over the skip.
+			if err != nil {
+				continue
+			}
+			err = errors.E(op, fmt.Sprintf("upstream %s is unhealthy and skipped until it recovers", u.Name))
+			continue
+		}
+		err = f(u)
+		changed, healthy := u.breaker.record(err)
+		if changed {
+			observ.RecordUpstreamHealth(ctx, u.Name, healthy)
+		}
+		observ.RecordUpstreamRequest(ctx, u.Name, requestResult(err))
+		if err == nil {
+			return nil
+		}
+		if !errors.IsNotFoundErr(err) && !u.FallThroughOnError {
+			return err
+		}
+	}
+	return err
+}
+
+func requestResult(err error) string {
+	switch {
+	case err == nil:
+		return "success"
+	case errors.IsNotFoundErr(err):
+		return "not_found"
+	default:
+		return "failure"
+	}
+}
+
+// Circuit breaker states as reported by UpstreamHealth.
+const (
+	StateClosed   = "closed"
+	StateOpen     = "open"
+	StateHalfOpen = "half-open"
+)
+
+type breaker struct {
+	threshold int
+	cooldown  time.Duration
+	now       func() time.Time
+
+	mu        sync.Mutex
+	failures  int
+	openUntil time.Time
+	probing   bool
+	lastErr   string
+}
+
+func newBreaker(threshold int, cooldown time.Duration) *breaker {
+	return &breaker{threshold: threshold, cooldown: cooldown, now: time.Now}
+}
+
+// allow reports whether a request may be sent to the upstream.
+// Once the cool-down has passed a single probe request is let through.
+func (b *breaker) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.threshold <= 0 || b.failures < b.threshold {
+		return true
+	}
+	if b.now().Before(b.openUntil) || b.probing {
+		return false
+	}
+	b.probing = true
+	return true
+}
+
+// record records the outcome of a request. Not found errors
+// are a healthy answer and reset the failure count. It returns
+// whether the breaker changed from healthy to unhealthy or back,
+// along with the current health.
+func (b *breaker) record(err error) (changed, healthy bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+	if b.threshold <= 0 {
+		return false, true
+	}
+	if errors.IsErr(err, context.Canceled) {
+		return false, b.failures < b.threshold
+	}
+	wasHealthy := b.failures < b.threshold
+	if err == nil || errors.IsNotFoundErr(err) {
+		b.failures = 0
+		b.lastErr = ""
+		b.openUntil = time.Time{}
+		return !wasHealthy, true
+	}
+	b.failures++
+	b.lastErr = err.Error()
+	if b.failures >= b.threshold {
+		b.openUntil = b.now().Add(b.cooldown)
+		return wasHealthy, false
+	}
+	return false, true
+}
+
+func (b *breaker) health() UpstreamHealth {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	h := UpstreamHealth{
+		State:               StateClosed,
+		ConsecutiveFailures: b.failures,
+		LastError:           b.lastErr,
+	}
+	if b.threshold > 0 && b.failures >= b.threshold {
+		h.State = StateHalfOpen
+		if b.now().Before(b.openUntil) {
+			h.State = StateOpen
+			h.OpenUntil = b.openUntil
+		}
+	}
+	return h
+}
@@ -0,0 +1,181 @@
+package module
+
+import (
+	"context"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/gomods/athens/pkg/errors"
+	"github.com/gomods/athens/pkg/storage"
+)
+
+type fakeUpstream struct {
+	err   error
+	calls int
+}
+
+func (f *fakeUpstream) Fetch(ctx context.Context, mod, ver string) (*storage.Version, error) {
+	f.calls++
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &storage.Version{Semver: ver, Zip: io.NopCloser(strings.NewReader("zip"))}, nil
+}
+
+func (f *fakeUpstream) List(ctx context.Context, mod string) (*storage.RevInfo, []string, error) {
+	f.calls++
+	if f.err != nil {
+		return nil, nil, f.err
+	}
+	return &storage.RevInfo{Version: version}, []string{version}, nil
+}
+
+func (s *ModuleSuite) TestParseUpstreamList() {
+	r := s.Require()
+	specs, err := ParseUpstreamList(" https://a.example.com/ |https://b.example.com,direct")
+	r.NoError(err)
+	r.Equal([]UpstreamSpec{
+		{URL: "https://a.example.com", FallThroughOnError: true},
+		{URL: "https://b.example.com"},
+		{URL: "direct"},
+	}, specs)
+
+	_, err = ParseUpstreamList("https://a.example.com,off")
+	r.Error(err)
+	_, err = ParseUpstreamList(" , ")
+	r.Error(err)
+}
+
+func (s *ModuleSuite) TestUpstreamChainFallThrough() {
+	r := s.Require()
+	ctx := s.T().Context()
+	failing := &fakeUpstream{err: errors.E("test", errors.KindUnexpected)}
+	missing := &fakeUpstream{err: errors.E("test", errors.KindNotFound)}
+	ok := &fakeUpstream{}
+
+	// a comma only falls through on not found.
+	chain, err := NewUpstreamChain([]Upstream{
+		{Name: "missing", Fetcher: missing, Lister: missing},
+		{Name: "failing", Fetcher: failing, Lister: failing},
+		{Name: "ok", Fetcher: ok, Lister: ok},
+	}, 0, time.Minute)
+	r.NoError(err)
+	_, err = chain.Fetch(ctx, repoURI, version)
+	r.Equal(errors.KindUnexpected, errors.Kind(err))
+	r.Equal(0, ok.calls)
+
+	// a pipe falls through on any error.
+	chain, err = NewUpstreamChain([]Upstream{
+		{Name: "missing", Fetcher: missing, Lister: missing},
+		{Name: "failing", Fetcher: failing, Lister: failing, FallThroughOnError: true},
+		{Name: "ok", Fetcher: ok, Lister: ok},
+	}, 0, time.Minute)
+	r.NoError(err)
+	v, err := chain.Fetch(ctx, repoURI, version)
+	r.NoError(err)
+	r.Equal(version, v.Semver)
+	rev, _, err := chain.List(ctx, repoURI)
+	r.NoError(err)
+	r.Equal(version, rev.Version)
+
+	chain, err = NewUpstreamChain([]Upstream{{Name: "missing", Fetcher: missing, Lister: missing}}, 0, time.Minute)
+	r.NoError(err)
+	_, err = chain.Fetch(ctx, repoURI, version)
+	r.Equal(errors.KindNotFound, errors.Kind(err))
+}
+
+func (s *ModuleSuite) TestUpstreamChainCircuitBreaker() {
+	r := s.Require()
+	ctx := s.T().Context()
+	flaky := &fakeUpstream{err: errors.E("test", errors.KindUnexpected)}
+	ok := &fakeUpstream{}
+	chain, err := NewUpstreamChain([]Upstream{
+		{Name: "flaky", Fetcher: flaky, Lister: flaky, FallThroughOnError: true},
+		{Name: "ok", Fetcher: ok, Lister: ok},
+	}, 2, time.Minute)
+	r.NoError(err)
+	now := time.Now()
+	chain.upstreams[0].breaker.now = func() time.Time { return now }
+
+	for range 3 {
+		_, err = chain.Fetch(ctx, repoURI, version)
+		r.NoError(err)
+	}
+	// the breaker opened after two failures, so the third request skipped it.
+	r.Equal(2, flaky.calls)
+	health := chain.Health()
+	r.Equal("flaky", health[0].Name)
+	r.Equal(StateOpen, health[0].State)
+	r.Equal(2, health[0].ConsecutiveFailures)
+	r.Equal(StateClosed, health[1].State)
+
+	// after the cool-down a single probe goes through and closes the breaker.
+	now = now.Add(2 * time.Minute)
+	r.Equal(StateHalfOpen, chain.Health()[0].State)
+	flaky.err = nil
+	_, err = chain.Fetch(ctx, repoURI, version)
+	r.NoError(err)
+	r.Equal(3, flaky.calls)
+	r.Equal(StateClosed, chain.Health()[0].State)
+	r.Equal(0, chain.Health()[0].ConsecutiveFailures)
+}
+
+func (s *ModuleSuite) TestUpstreamChainOpenBreakerFailsOver() {
+	r := s.Require()
+	ctx := s.T().Context()
+	flaky := &fakeUpstream{err: errors.E("test", errors.KindUnexpected)}
+	ok := &fakeUpstream{}
+	chain, err := NewUpstreamChain([]Upstream{
+		{Name: "flaky", Fetcher: flaky, Lister: flaky},
+		{Name: "ok", Fetcher: ok, Lister: ok},
+	}, 1, time.Minute)
+	r.NoError(err)
+
+	// without fall through on errors, the failure is returned
+	_, err = chain.Fetch(ctx, repoURI, version)
+	r.Error(err)
+	r.Equal(0, ok.calls)
+	// but once the breaker is open, the next upstream is asked
+	_, err = chain.Fetch(ctx, repoURI, version)
+	r.NoError(err)
+	r.Equal(1, flaky.calls)
+	r.Equal(1, ok.calls)
+}
+
+func (s *ModuleSuite) TestUpstreamChainOpenBreakerKeepsNotFound() {
+	r := s.Require()
+	ctx := s.T().Context()
+	missing := &fakeUpstream{err: errors.E("test", errors.KindNotFound)}
+	flaky := &fakeUpstream{err: errors.E("test", errors.KindUnexpected)}
+	chain, err := NewUpstreamChain([]Upstream{
+		{Name: "missing", Fetcher: missing, Lister: missing},
+		{Name: "flaky", Fetcher: flaky, Lister: flaky},
+	}, 1, time.Minute)
+	r.NoError(err)
+
+	_, err = chain.Fetch(ctx, repoURI, version)
+	r.Equal(errors.KindUnexpected, errors.Kind(err))
+	// skipping the open breaker does not hide the not found of the first upstream.
+	_, err = chain.Fetch(ctx, repoURI, version)
+	r.Equal(errors.KindNotFound, errors.Kind(err))
+	r.Equal(1, flaky.calls)
+}
+
+func (s *ModuleSuite) TestUpstreamChainFetchMod() {
+	r := s.Require()
+	missing := &fakeUpstream{err: errors.E("test", errors.KindNotFound)}
+	ok := &fakeUpstream{}
+	chain, err := NewUpstreamChain([]Upstream{
+		{Name: "missing", Fetcher: missing, Lister: missing},
+		{Name: "ok", Fetcher: ok, Lister: ok},
+	}, 0, time.Minute)
+	r.NoError(err)
+	// upstreams without FetchMod fetch the whole version and drop the zip.
+	v, err := chain.FetchMod(s.T().Context(), "mod", version)
+	r.NoError(err)
+	r.Equal(version, v.Semver)
+	r.Nil(v.Zip)
+	r.Equal(1, missing.calls)
+	r.Equal(1, ok.calls)
+}
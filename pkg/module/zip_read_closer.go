@@ -12,12 +12,17 @@ type zipReadCloser struct {
 	zip    io.ReadCloser
 	fs     afero.Fs
 	goPath string
+	// onClose, if set, is called after the zip is closed.
+	onClose func()
 }
 
 // Close closes the zip file handle and clears up disk space used by the underlying disk ref.
 // It is the caller's responsibility to call this method to free up utilized disk space.
 func (rc *zipReadCloser) Close() error {
 	_ = rc.zip.Close()
+	if rc.onClose != nil {
+		rc.onClose()
+	}
 	return clearFiles(rc.fs, rc.goPath)
 }
 
@@ -0,0 +1,58 @@
+package compliance
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gomods/athens/pkg/notfound"
+	"github.com/stretchr/testify/require"
+)
+
+// RunTests runs compliance tests for the given Cache implementation.
+func RunTests(t *testing.T, c notfound.Cache) {
+	ctx := t.Context()
+	const mod = "github.com/athens-artifacts/missing"
+	t.Cleanup(func() {
+		require.NoError(t, c.Purge(ctx, mod, ""))
+	})
+
+	_, ok, err := c.Get(ctx, mod, "v1.0.0")
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	require.NoError(t, c.Set(ctx, mod, "", "repository not found", time.Minute))
+	require.NoError(t, c.Set(ctx, mod, "v1.0.0", "unknown revision", time.Minute))
+	require.NoError(t, c.Set(ctx, mod, "v1.1.0", "unknown revision", time.Minute))
+	require.NoError(t, c.Set(ctx, mod, "v1.2.0", "unknown revision", time.Millisecond))
+	msg, ok, err := c.Get(ctx, mod, "v1.0.0")
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, "unknown revision", msg)
+	msg, ok, err = c.Get(ctx, mod, "")
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, "repository not found", msg)
+
+	// expired entries are not found.
+	time.Sleep(10 * time.Millisecond)
+	_, ok, err = c.Get(ctx, mod, "v1.2.0")
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	// purging a version leaves the others.
+	require.NoError(t, c.Purge(ctx, mod, "v1.0.0"))
+	_, ok, err = c.Get(ctx, mod, "v1.0.0")
+	require.NoError(t, err)
+	require.False(t, ok)
+	_, ok, err = c.Get(ctx, mod, "v1.1.0")
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	// purging a module purges all of it.
+	require.NoError(t, c.Purge(ctx, mod, ""))
+	for _, ver := range []string{"", "v1.1.0"} {
+		_, ok, err = c.Get(ctx, mod, ver)
+		require.NoError(t, err)
+		require.False(t, ok)
+	}
+}
@@ -0,0 +1,114 @@
+// Package mem provides a notfound.Cache that lives in memory.
+package mem
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/gomods/athens/pkg/notfound"
+)
+
+// purgeEvery is how many entries are set between
+// two purges of the entries that expired.
+const purgeEvery = 1000
+
+// maxEntries bounds the entries of the cache, which clients that
+// probe made up modules would otherwise grow without limit.
+const maxEntries = 100_000
+
+// New returns a notfound.Cache that is lost when Athens restarts.
+// Once it holds maxEntries entries, setting a new one evicts another.
+func New() notfound.Cache {
+	return &cache{mods: map[string]map[string]entry{}, max: maxEntries}
+}
+
+type entry struct {
+	msg     string
+	expires time.Time
+}
+
+type cache struct {
+	mu   sync.Mutex
+	mods map[string]map[string]entry
+	sets int
+	// n is the number of entries in mods, max the most it may hold.
+	n   int
+	max int
+}
+
+func (c *cache) Get(ctx context.Context, mod, ver string) (string, bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.mods[mod][ver]
+	if !ok || time.Now().After(e.expires) {
+		return "", false, nil
+	}
+	return e.msg, true, nil
+}
+
+func (c *cache) Set(ctx context.Context, mod, ver, msg string, ttl time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.sets++
+	if c.sets%purgeEvery == 0 {
+		c.purgeExpired()
+	}
+	if _, ok := c.mods[mod][ver]; !ok {
+		if c.n >= c.max {
+			c.evict()
+		}
+		c.n++
+	}
+	if c.mods[mod] == nil {
+		c.mods[mod] = map[string]entry{}
+	}
+	c.mods[mod][ver] = entry{msg: msg, expires: time.Now().Add(ttl)}
+	return nil
+}
+
+func (c *cache) Purge(ctx context.Context, mod, ver string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if ver == "" {
+		c.n -= len(c.mods[mod])
+		delete(c.mods, mod)
+		return nil
+	}
+	c.delete(mod, ver)
+	return nil
+}
+
+// evict deletes an arbitrary entry, which is cheaper than
+// finding the one that expires first and as good for a cache
+// of entries that are all as likely to be probed again.
+func (c *cache) evict() {
+	for mod, vers := range c.mods {
+		for ver := range vers {
+			c.delete(mod, ver)
+			return
+		}
+	}
+}
+
+func (c *cache) delete(mod, ver string) {
+	if _, ok := c.mods[mod][ver]; !ok {
+		return
+	}
+	delete(c.mods[mod], ver)
+	c.n--
+	if len(c.mods[mod]) == 0 {
+		delete(c.mods, mod)
+	}
+}
+
+func (c *cache) purgeExpired() {
+	now := time.Now()
+	for mod, vers := range c.mods {
+		for ver, e := range vers {
+			if now.After(e.expires) {
+				c.delete(mod, ver)
+			}
+		}
+	}
+}
@@ -0,0 +1,33 @@
+package mem
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gomods/athens/pkg/notfound/compliance"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMem(t *testing.T) {
+	compliance.RunTests(t, New())
+}
+
+func TestMaxEntries(t *testing.T) {
+	ctx := t.Context()
+	c := &cache{mods: map[string]map[string]entry{}, max: 2}
+	require.NoError(t, c.Set(ctx, "mod", "v1.0.0", "not found", time.Hour))
+	require.NoError(t, c.Set(ctx, "mod", "v1.0.0", "not found", time.Hour))
+	require.NoError(t, c.Set(ctx, "other", "", "not found", time.Hour))
+	require.Equal(t, 2, c.n)
+
+	// a new entry evicts one of the others.
+	require.NoError(t, c.Set(ctx, "mod", "v2.0.0", "not found", time.Hour))
+	require.Equal(t, 2, c.n)
+	_, ok, err := c.Get(ctx, "mod", "v2.0.0")
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	require.NoError(t, c.Purge(ctx, "mod", ""))
+	require.NoError(t, c.Purge(ctx, "other", ""))
+	require.Equal(t, 0, c.n)
+}
@@ -0,0 +1,24 @@
+// Package notfound caches the module versions that upstream does not have,
+// so that probing the same nonexistent module paths and versions again and
+// again does not reach upstream every time.
+package notfound
+
+import (
+	"context"
+	"time"
+)
+
+// Cache stores why upstream did not find a module version, or a
+// module as a whole. Throughout, an empty ver stands for the module.
+type Cache interface {
+	// Get returns the error message that upstream returned for mod@ver,
+	// and whether it is cached and has not expired.
+	Get(ctx context.Context, mod, ver string) (string, bool, error)
+
+	// Set caches msg for mod@ver, for ttl.
+	Set(ctx context.Context, mod, ver, msg string, ttl time.Duration) error
+
+	// Purge removes what is cached for mod@ver. If ver is
+	// empty, everything that is cached for mod is removed.
+	Purge(ctx context.Context, mod, ver string) error
+}
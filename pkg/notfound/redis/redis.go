@@ -0,0 +1,90 @@
+// Package redis provides a notfound.Cache that is kept in redis,
+// so that several Athens instances can share it.
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/gomods/athens/pkg/errors"
+	"github.com/gomods/athens/pkg/notfound"
+	"github.com/gomods/athens/pkg/redisclient"
+	"github.com/redis/go-redis/v9"
+)
+
+// keyPrefix prefixes the hash that holds the entries
+// of a module, keyed by their version.
+const keyPrefix = "athens:notfound:"
+
+// New returns a notfound.Cache kept in the redis at endpoint, which is a
+// comma separated list of addresses if cluster is set.
+func New(endpoint, password string, cluster bool) (notfound.Cache, error) {
+	const op errors.Op = "redis.New"
+	client, err := redisclient.New(endpoint, password, cluster)
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+	return &cache{client: client}, nil
+}
+
+type cache struct {
+	client redis.UniversalClient
+}
+
+type entry struct {
+	Msg     string    `json:"msg"`
+	Expires time.Time `json:"expires"`
+}
+
+func (c *cache) Get(ctx context.Context, mod, ver string) (string, bool, error) {
+	const op errors.Op = "redis.Get"
+	b, err := c.client.HGet(ctx, keyPrefix+mod, ver).Bytes()
+	if errors.IsErr(err, redis.Nil) {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, errors.E(op, errors.M(mod), errors.V(ver), err)
+	}
+	var e entry
+	if err := json.Unmarshal(b, &e); err != nil {
+		return "", false, errors.E(op, errors.M(mod), errors.V(ver), err)
+	}
+	if time.Now().After(e.Expires) {
+		return "", false, nil
+	}
+	return e.Msg, true, nil
+}
+
+func (c *cache) Set(ctx context.Context, mod, ver, msg string, ttl time.Duration) error {
+	const op errors.Op = "redis.Set"
+	b, err := json.Marshal(entry{Msg: msg, Expires: time.Now().Add(ttl)})
+	if err != nil {
+		return errors.E(op, errors.M(mod), errors.V(ver), err)
+	}
+	// the hash of a module lives as long as its latest entry,
+	// expired entries are left out by Get until then.
+	_, err = c.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(ctx, keyPrefix+mod, ver, b)
+		pipe.Expire(ctx, keyPrefix+mod, ttl)
+		return nil
+	})
+	if err != nil {
+		return errors.E(op, errors.M(mod), errors.V(ver), err)
+	}
+	return nil
+}
+
+func (c *cache) Purge(ctx context.Context, mod, ver string) error {
+	const op errors.Op = "redis.Purge"
+	var err error
+	if ver == "" {
+		err = c.client.Del(ctx, keyPrefix+mod).Err()
+	} else {
+		err = c.client.HDel(ctx, keyPrefix+mod, ver).Err()
+	}
+	if err != nil {
+		return errors.E(op, errors.M(mod), errors.V(ver), err)
+	}
+	return nil
+}
@@ -0,0 +1,20 @@
+package redis
+
+import (
+	"os"
+	"testing"
+
+	"github.com/gomods/athens/pkg/notfound/compliance"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedis(t *testing.T) {
+	endpoint := os.Getenv("REDIS_TEST_ENDPOINT")
+	password := os.Getenv("ATHENS_REDIS_PASSWORD")
+	if len(endpoint) == 0 {
+		t.SkipNow()
+	}
+	c, err := New(endpoint, password, false)
+	require.NoError(t, err)
+	compliance.RunTests(t, c)
+}
@@ -0,0 +1,116 @@
+package notfound
+
+import (
+	"context"
+	"time"
+
+	"github.com/gomods/athens/pkg/errors"
+	"github.com/gomods/athens/pkg/log"
+	"github.com/gomods/athens/pkg/module"
+	"github.com/gomods/athens/pkg/observ"
+	"github.com/gomods/athens/pkg/storage"
+)
+
+// NewFetcher returns a Fetcher that caches in c, for ttl, the module
+// versions that f does not find. The Fetcher is a module.ModFetcher
+// if f is one.
+func NewFetcher(f module.Fetcher, c Cache, ttl time.Duration) module.Fetcher {
+	nf := &fetcher{fetcher: f, cache: c, ttl: ttl}
+	if mf, ok := f.(module.ModFetcher); ok {
+		return &modFetcher{nf, mf}
+	}
+	return nf
+}
+
+type fetcher struct {
+	fetcher module.Fetcher
+	cache   Cache
+	ttl     time.Duration
+}
+
+func (f *fetcher) Fetch(ctx context.Context, mod, ver string) (*storage.Version, error) {
+	const op errors.Op = "notfound.Fetch"
+	if err := cached(ctx, f.cache, mod, ver, "fetch"); err != nil {
+		return nil, errors.E(op, err)
+	}
+	v, err := f.fetcher.Fetch(ctx, mod, ver)
+	if err != nil {
+		remember(ctx, f.cache, mod, ver, err, f.ttl)
+		return nil, errors.E(op, err)
+	}
+	return v, nil
+}
+
+type modFetcher struct {
+	*fetcher
+	mf module.ModFetcher
+}
+
+func (f *modFetcher) FetchMod(ctx context.Context, mod, ver string) (*storage.Version, error) {
+	const op errors.Op = "notfound.FetchMod"
+	if err := cached(ctx, f.cache, mod, ver, "fetch"); err != nil {
+		return nil, errors.E(op, err)
+	}
+	v, err := f.mf.FetchMod(ctx, mod, ver)
+	if err != nil {
+		remember(ctx, f.cache, mod, ver, err, f.ttl)
+		return nil, errors.E(op, err)
+	}
+	return v, nil
+}
+
+// NewLister returns an UpstreamLister that caches in c,
+// for ttl, the modules that l does not find.
+func NewLister(l module.UpstreamLister, c Cache, ttl time.Duration) module.UpstreamLister {
+	return &lister{lister: l, cache: c, ttl: ttl}
+}
+
+type lister struct {
+	lister module.UpstreamLister
+	cache  Cache
+	ttl    time.Duration
+}
+
+func (l *lister) List(ctx context.Context, mod string) (*storage.RevInfo, []string, error) {
+	const op errors.Op = "notfound.List"
+	if err := cached(ctx, l.cache, mod, "", "list"); err != nil {
+		return nil, nil, errors.E(op, err)
+	}
+	latest, vers, err := l.lister.List(ctx, mod)
+	if err != nil {
+		remember(ctx, l.cache, mod, "", err, l.ttl)
+		return nil, nil, errors.E(op, err)
+	}
+	return latest, vers, nil
+}
+
+// cached returns a KindNotFound error if mod@ver is cached as not found.
+// The error says it was cached, to tell it from what upstream returns.
+func cached(ctx context.Context, c Cache, mod, ver, typ string) error {
+	const op errors.Op = "notfound.cached"
+	msg, ok, err := c.Get(ctx, mod, ver)
+	if err != nil {
+		// a cache that fails is a cache that misses.
+		log.EntryFromContext(ctx).SystemErr(errors.E(op, err))
+		return nil
+	}
+	if !ok {
+		return nil
+	}
+	observ.RecordNotFoundCacheHit(ctx, typ)
+	log.EntryFromContext(ctx).Debugf("%s@%s is cached as not found upstream", mod, ver)
+	return errors.E(op, errors.M(mod), errors.V(ver), "cached not found: "+msg, errors.KindNotFound)
+}
+
+// remember caches err if it tells that upstream does not have mod@ver.
+func remember(ctx context.Context, c Cache, mod, ver string, err error, ttl time.Duration) {
+	const op errors.Op = "notfound.remember"
+	// do not cache a not found that comes from the context, as it
+	// says nothing about upstream.
+	if !errors.IsNotFoundErr(err) || ctx.Err() != nil {
+		return
+	}
+	if err := c.Set(ctx, mod, ver, err.Error(), ttl); err != nil {
+		log.EntryFromContext(ctx).SystemErr(errors.E(op, err))
+	}
+}
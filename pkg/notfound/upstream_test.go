@@ -0,0 +1,93 @@
+package notfound_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gomods/athens/pkg/errors"
+	"github.com/gomods/athens/pkg/module"
+	"github.com/gomods/athens/pkg/notfound"
+	"github.com/gomods/athens/pkg/notfound/mem"
+	"github.com/gomods/athens/pkg/storage"
+	"github.com/stretchr/testify/require"
+)
+
+type upstream struct {
+	calls int
+	err   error
+}
+
+func (u *upstream) Fetch(ctx context.Context, mod, ver string) (*storage.Version, error) {
+	u.calls++
+	return &storage.Version{Semver: ver}, u.err
+}
+
+func (u *upstream) FetchMod(ctx context.Context, mod, ver string) (*storage.Version, error) {
+	u.calls++
+	return &storage.Version{Semver: ver}, u.err
+}
+
+func (u *upstream) List(ctx context.Context, mod string) (*storage.RevInfo, []string, error) {
+	u.calls++
+	return nil, nil, u.err
+}
+
+func TestFetcher(t *testing.T) {
+	ctx := t.Context()
+	c := mem.New()
+	up := &upstream{err: errors.E("test", "unknown revision v1.0.0", errors.KindNotFound)}
+	f := notfound.NewFetcher(up, c, time.Minute)
+	_, ok := f.(module.ModFetcher)
+	require.True(t, ok, "a ModFetcher must stay one")
+
+	for range 2 {
+		_, err := f.Fetch(ctx, "mod", "v1.0.0")
+		require.True(t, errors.IsNotFoundErr(err))
+	}
+	require.Equal(t, 1, up.calls)
+	_, err := f.Fetch(ctx, "mod", "v1.0.0")
+	require.True(t, strings.Contains(err.Error(), "cached not found"), err.Error())
+	_, err = f.(module.ModFetcher).FetchMod(ctx, "mod", "v1.0.0")
+	require.True(t, errors.IsNotFoundErr(err))
+	require.Equal(t, 1, up.calls)
+
+	// other errors are not cached.
+	up.err = errors.E("test", "upstream is down", errors.KindUnexpected)
+	for range 2 {
+		_, err := f.Fetch(ctx, "mod", "v1.1.0")
+		require.Error(t, err)
+	}
+	require.Equal(t, 3, up.calls)
+
+	// a purged version is fetched again.
+	up.err = nil
+	require.NoError(t, c.Purge(ctx, "mod", ""))
+	v, err := f.Fetch(ctx, "mod", "v1.0.0")
+	require.NoError(t, err)
+	require.Equal(t, "v1.0.0", v.Semver)
+}
+
+func TestLister(t *testing.T) {
+	ctx := t.Context()
+	up := &upstream{err: errors.E("test", "remote: Repository not found", errors.KindNotFound)}
+	l := notfound.NewLister(up, mem.New(), time.Minute)
+	for range 2 {
+		_, _, err := l.List(ctx, "github.com/org/repo/sub")
+		require.True(t, errors.IsNotFoundErr(err))
+	}
+	require.Equal(t, 1, up.calls)
+}
+
+func TestFetcherNotModFetcher(t *testing.T) {
+	f := notfound.NewFetcher(fetchOnly{}, mem.New(), time.Minute)
+	_, ok := f.(module.ModFetcher)
+	require.False(t, ok)
+}
+
+type fetchOnly struct{}
+
+func (fetchOnly) Fetch(ctx context.Context, mod, ver string) (*storage.Version, error) {
+	return nil, nil
+}
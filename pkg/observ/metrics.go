@@ -15,12 +15,22 @@ const (
 	attrCacheResult = "cache_result"
 	attrCacheType   = "cache_type"
 	attrFetchResult = "fetch_result"
+	attrUpstream    = "upstream"
+	attrResult      = "result"
+	attrLookupType  = "lookup_type"
+	attrReloadFile  = "file"
+	attrTarget      = "target"
 )
 
 // upstreamExponentialBuckets are the histogram boundaries (in seconds) for
 // upstream fetch latency.
 var upstreamExponentialBuckets = []float64{0.05, 0.1, 0.2, 0.5, 1, 2, 5, 10, 30}
 
+// replicationLagBuckets are the histogram boundaries (in seconds) for the
+// time between a module version being queued for replication and it being
+// replicated, which includes the backoffs of failed attempts.
+var replicationLagBuckets = []float64{1, 5, 15, 60, 300, 900, 3600, 4 * 3600, 24 * 3600}
+
 // Custom instruments. They are nil until initMetrics runs (i.e. when the stats
 // exporter is registered); the Record* helpers guard against that so recording
 // is a no-op when metrics are disabled, mirroring OpenCensus' behavior.
@@ -28,6 +38,12 @@ var (
 	cacheLookupCounter    metric.Int64Counter
 	upstreamFetchCounter  metric.Int64Counter
 	upstreamFetchDuration metric.Float64Histogram
+	upstreamHealthGauge   metric.Int64Gauge
+	upstreamRequestCount  metric.Int64Counter
+	notFoundCacheHits     metric.Int64Counter
+	configReloadCounter   metric.Int64Counter
+	replicationCounter    metric.Int64Counter
+	replicationLag        metric.Float64Histogram
 )
 
 // initMetrics creates Athens' custom instruments from the global MeterProvider.
@@ -62,6 +78,55 @@ func initMetrics() error {
 		return errors.E(op, err)
 	}
 
+	upstreamHealthGauge, err = meter.Int64Gauge(
+		"upstream_health",
+		metric.WithDescription("Whether an upstream's circuit breaker is closed (1) or open (0)"),
+	)
+	if err != nil {
+		return errors.E(op, err)
+	}
+
+	upstreamRequestCount, err = meter.Int64Counter(
+		"upstream_request_total",
+		metric.WithDescription("Count of requests to each upstream by result"),
+	)
+	if err != nil {
+		return errors.E(op, err)
+	}
+
+	notFoundCacheHits, err = meter.Int64Counter(
+		"not_found_cache_hit_total",
+		metric.WithDescription("Count of upstream lookups answered by the cache of not found modules"),
+	)
+	if err != nil {
+		return errors.E(op, err)
+	}
+
+	configReloadCounter, err = meter.Int64Counter(
+		"config_reload_total",
+		metric.WithDescription("Count of configuration reloads by result"),
+	)
+	if err != nil {
+		return errors.E(op, err)
+	}
+
+	replicationCounter, err = meter.Int64Counter(
+		"replication_total",
+		metric.WithDescription("Count of module version replications to each target by result"),
+	)
+	if err != nil {
+		return errors.E(op, err)
+	}
+
+	replicationLag, err = meter.Float64Histogram(
+		"replication_lag_seconds",
+		metric.WithDescription("Distribution of the time module versions waited to be replicated to each target, in seconds"),
+		metric.WithExplicitBucketBoundaries(replicationLagBuckets...),
+	)
+	if err != nil {
+		return errors.E(op, err)
+	}
+
 	return nil
 }
 
@@ -92,3 +157,71 @@ func RecordUpstreamFetchDuration(ctx context.Context, result string, duration ti
 		attribute.String(attrFetchResult, result),
 	))
 }
+
+// RecordUpstreamHealth sets the health of upstream to whether
+// its circuit breaker lets requests through.
+func RecordUpstreamHealth(ctx context.Context, upstream string, healthy bool) {
+	if upstreamHealthGauge == nil {
+		return
+	}
+	var v int64
+	if healthy {
+		v = 1
+	}
+	upstreamHealthGauge.Record(ctx, v, metric.WithAttributes(
+		attribute.String(attrUpstream, upstream),
+	))
+}
+
+// RecordUpstreamRequest counts a request of the upstream chain to upstream
+// by its result, which is one of "success", "not_found", "failure" or "skipped".
+func RecordUpstreamRequest(ctx context.Context, upstream, result string) {
+	if upstreamRequestCount == nil {
+		return
+	}
+	upstreamRequestCount.Add(ctx, 1, metric.WithAttributes(
+		attribute.String(attrUpstream, upstream),
+		attribute.String(attrResult, result),
+	))
+}
+
+// RecordNotFoundCacheHit counts a lookup answered by the not found
+// cache, by its type, which is one of "fetch" or "list".
+func RecordNotFoundCacheHit(ctx context.Context, typ string) {
+	if notFoundCacheHits == nil {
+		return
+	}
+	notFoundCacheHits.Add(ctx, 1, metric.WithAttributes(
+		attribute.String(attrLookupType, typ),
+	))
+}
+
+// RecordConfigReload counts a reload of file, which is one of
+// "config", "filter" or "download", by its result.
+func RecordConfigReload(ctx context.Context, file, result string) {
+	if configReloadCounter == nil {
+		return
+	}
+	configReloadCounter.Add(ctx, 1, metric.WithAttributes(
+		attribute.String(attrReloadFile, file),
+		attribute.String(attrResult, result),
+	))
+}
+
+// RecordReplication counts a replication of a module version to target
+// by its result, which is one of "success", "skipped" or "failure". The
+// lag of successful replications is recorded unless it is negative.
+func RecordReplication(ctx context.Context, target, result string, lag time.Duration) {
+	if replicationCounter == nil {
+		return
+	}
+	replicationCounter.Add(ctx, 1, metric.WithAttributes(
+		attribute.String(attrTarget, target),
+		attribute.String(attrResult, result),
+	))
+	if result == "success" && lag >= 0 {
+		replicationLag.Record(ctx, lag.Seconds(), metric.WithAttributes(
+			attribute.String(attrTarget, target),
+		))
+	}
+}
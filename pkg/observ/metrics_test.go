@@ -104,3 +104,87 @@ func TestUpstreamFetchDurationHistogram(t *testing.T) {
 		t.Fatalf("expected sample sum 2, got %v", got)
 	}
 }
+
+func TestUpstreamHealthGauge(t *testing.T) {
+	registry := setupTestMetrics(t)
+
+	RecordUpstreamHealth(t.Context(), "https://proxy.golang.org", true)
+	RecordUpstreamHealth(t.Context(), "https://proxy.golang.org", false)
+
+	fam := findMetricFamily(t, registry, "proxy_upstream_health")
+	if fam == nil {
+		t.Fatal("expected metric family proxy_upstream_health to be present")
+	}
+	if got := fam.GetMetric()[0].GetGauge().GetValue(); got != 0 {
+		t.Fatalf("expected gauge value 0, got %v", got)
+	}
+}
+
+func TestUpstreamRequestCounter(t *testing.T) {
+	registry := setupTestMetrics(t)
+
+	RecordUpstreamRequest(t.Context(), "direct", "failure")
+	RecordUpstreamRequest(t.Context(), "direct", "failure")
+
+	fam := findMetricFamily(t, registry, "proxy_upstream_request_total")
+	if fam == nil {
+		t.Fatal("expected metric family proxy_upstream_request_total to be present")
+	}
+	if got := fam.GetMetric()[0].GetCounter().GetValue(); got != 2 {
+		t.Fatalf("expected counter value 2, got %v", got)
+	}
+}
+
+func TestNotFoundCacheHitCounter(t *testing.T) {
+	registry := setupTestMetrics(t)
+
+	RecordNotFoundCacheHit(t.Context(), "list")
+
+	fam := findMetricFamily(t, registry, "proxy_not_found_cache_hit_total")
+	if fam == nil {
+		t.Fatal("expected metric family proxy_not_found_cache_hit_total to be present")
+	}
+	if got := fam.GetMetric()[0].GetCounter().GetValue(); got != 1 {
+		t.Fatalf("expected counter value 1, got %v", got)
+	}
+}
+
+func TestConfigReloadCounter(t *testing.T) {
+	registry := setupTestMetrics(t)
+
+	RecordConfigReload(t.Context(), "filter", "failure")
+
+	fam := findMetricFamily(t, registry, "proxy_config_reload_total")
+	if fam == nil {
+		t.Fatal("expected metric family proxy_config_reload_total to be present")
+	}
+	if got := fam.GetMetric()[0].GetCounter().GetValue(); got != 1 {
+		t.Fatalf("expected counter value 1, got %v", got)
+	}
+}
+
+func TestReplicationMetrics(t *testing.T) {
+	registry := setupTestMetrics(t)
+
+	RecordReplication(t.Context(), "dr", "success", 2*time.Second)
+	RecordReplication(t.Context(), "dr", "failure", 0)
+
+	fam := findMetricFamily(t, registry, "proxy_replication_total")
+	if fam == nil {
+		t.Fatal("expected metric family proxy_replication_total to be present")
+	}
+	if got := len(fam.GetMetric()); got != 2 {
+		t.Fatalf("expected 2 metrics, got %d", got)
+	}
+	fam = findMetricFamily(t, registry, "proxy_replication_lag_seconds")
+	if fam == nil {
+		t.Fatal("expected metric family proxy_replication_lag_seconds to be present")
+	}
+	h := fam.GetMetric()[0].GetHistogram()
+	if got := h.GetSampleCount(); got != 1 {
+		t.Fatalf("expected 1 sample, got %d", got)
+	}
+	if got := h.GetSampleSum(); got != 2 {
+		t.Fatalf("expected a sum of 2, got %v", got)
+	}
+}
@@ -0,0 +1,356 @@
+package bolt
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/gomods/athens/pkg/config"
+	"github.com/gomods/athens/pkg/errors"
+	"github.com/gomods/athens/pkg/queue"
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	// jobsBucket holds the jobs as JSON, by module@version.
+	jobsBucket = []byte("jobs")
+	// dueBucket indexes the pending jobs by their next attempt, doneBucket
+	// the done jobs and deadBucket the dead letters by when they finished,
+	// so that the ones that are due or expired are found without going
+	// through every job. Their keys are the time followed by module@version.
+	dueBucket  = []byte("due")
+	doneBucket = []byte("done")
+	deadBucket = []byte("dead")
+
+	indexBuckets = [][]byte{dueBucket, doneBucket, deadBucket}
+)
+
+// New returns a queue that keeps its jobs in the bolt database at path,
+// which is created if it does not exist. Jobs that were running when the
+// database was last closed are put back on the queue, so that whatever
+// was pending when the process stopped is resumed.
+//
+// A bolt database can only be opened by one process at a time.
+func New(path string) (queue.Queue, error) {
+	const op errors.Op = "bolt.New"
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: 10 * time.Second})
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists(jobsBucket)
+		if err != nil {
+			return err
+		}
+		// the indexes are rebuilt, as they may predate them.
+		for _, name := range indexBuckets {
+			if tx.Bucket(name) != nil {
+				if err := tx.DeleteBucket(name); err != nil {
+					return err
+				}
+			}
+			if _, err := tx.CreateBucket(name); err != nil {
+				return err
+			}
+		}
+		var jobs []*queue.Job
+		err = b.ForEach(func(k, v []byte) error {
+			var j queue.Job
+			if err := json.Unmarshal(v, &j); err != nil {
+				return err
+			}
+			if j.State == queue.StateRunning {
+				j.State = queue.StatePending
+			}
+			jobs = append(jobs, &j)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		for _, j := range jobs {
+			if err := putJob(tx, j); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		_ = db.Close()
+		return nil, errors.E(op, err)
+	}
+	return &boltQueue{db: db, changed: make(chan struct{})}, nil
+}
+
+type boltQueue struct {
+	db *bolt.DB
+
+	mu sync.Mutex
+	// changed is closed and replaced every time a job becomes pending.
+	changed chan struct{}
+}
+
+func (q *boltQueue) Enqueue(_ context.Context, mod, ver string) error {
+	const op errors.Op = "bolt.Enqueue"
+	err := q.db.Update(func(tx *bolt.Tx) error {
+		j, err := getJob(tx, key(mod, ver))
+		if err != nil {
+			return err
+		}
+		if j != nil && (j.State == queue.StatePending || j.State == queue.StateRunning) {
+			return nil
+		}
+		now := time.Now()
+		return putJob(tx, &queue.Job{Module: mod, Version: ver, State: queue.StatePending, EnqueuedAt: now, NextAttempt: now})
+	})
+	if err != nil {
+		return errors.E(op, errors.M(mod), errors.V(ver), err)
+	}
+	q.notify()
+	return nil
+}
+
+func (q *boltQueue) Dequeue(ctx context.Context) (*queue.Job, error) {
+	const op errors.Op = "bolt.Dequeue"
+	for ctx.Err() == nil {
+		q.mu.Lock()
+		changed := q.changed
+		q.mu.Unlock()
+
+		var job *queue.Job
+		var wait time.Duration
+		err := q.db.Update(func(tx *bolt.Tx) error {
+			if _, err := deleteBefore(tx, doneBucket, time.Now().Add(-queue.DoneRetention)); err != nil {
+				return err
+			}
+			k, _ := tx.Bucket(dueBucket).Cursor().First()
+			if k == nil {
+				return nil
+			}
+			due, jobKey := splitIndexKey(k)
+			if wait = time.Until(due); wait > 0 {
+				return nil
+			}
+			next, err := getJob(tx, jobKey)
+			if err != nil {
+				return err
+			}
+			next.State = queue.StateRunning
+			next.StartedAt = time.Now()
+			job = next
+			return putJob(tx, job)
+		})
+		if err != nil {
+			return nil, errors.E(op, err)
+		}
+		if job != nil {
+			return job, nil
+		}
+
+		var timer *time.Timer
+		var due <-chan time.Time
+		if wait > 0 {
+			timer = time.NewTimer(wait)
+			due = timer.C
+		}
+		select {
+		case <-changed:
+		case <-due:
+		case <-ctx.Done():
+		}
+		if timer != nil {
+			timer.Stop()
+		}
+	}
+	return nil, errors.E(op, ctx.Err())
+}
+
+func (q *boltQueue) Complete(_ context.Context, job *queue.Job) error {
+	const op errors.Op = "bolt.Complete"
+	if err := q.put(job, queue.StateDone); err != nil {
+		return errors.E(op, errors.M(job.Module), errors.V(job.Version), err)
+	}
+	return nil
+}
+
+func (q *boltQueue) Retry(_ context.Context, job *queue.Job) error {
+	const op errors.Op = "bolt.Retry"
+	if err := q.put(job, queue.StatePending); err != nil {
+		return errors.E(op, errors.M(job.Module), errors.V(job.Version), err)
+	}
+	q.notify()
+	return nil
+}
+
+func (q *boltQueue) Bury(_ context.Context, job *queue.Job) error {
+	const op errors.Op = "bolt.Bury"
+	if err := q.put(job, queue.StateDead); err != nil {
+		return errors.E(op, errors.M(job.Module), errors.V(job.Version), err)
+	}
+	return nil
+}
+
+func (q *boltQueue) put(job *queue.Job, state queue.State) error {
+	j := *job
+	j.State = state
+	if state != queue.StatePending {
+		j.FinishedAt = time.Now()
+	}
+	return q.db.Update(func(tx *bolt.Tx) error {
+		return putJob(tx, &j)
+	})
+}
+
+func (q *boltQueue) Get(_ context.Context, mod, ver string) (*queue.Job, error) {
+	const op errors.Op = "bolt.Get"
+	var job *queue.Job
+	err := q.db.View(func(tx *bolt.Tx) error {
+		var err error
+		job, err = getJob(tx, key(mod, ver))
+		return err
+	})
+	if err != nil {
+		return nil, errors.E(op, errors.M(mod), errors.V(ver), err)
+	}
+	if job == nil {
+		return nil, errors.E(op, errors.M(mod), errors.V(ver), errors.KindNotFound)
+	}
+	return job, nil
+}
+
+func (q *boltQueue) Dead(_ context.Context) ([]*queue.Job, error) {
+	const op errors.Op = "bolt.Dead"
+	dead := []*queue.Job{}
+	err := q.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(deadBucket).ForEach(func(k, _ []byte) error {
+			_, jobKey := splitIndexKey(k)
+			j, err := getJob(tx, jobKey)
+			if err != nil {
+				return err
+			}
+			dead = append(dead, j)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+	return dead, nil
+}
+
+func (q *boltQueue) Purge(_ context.Context, before time.Time) (int, error) {
+	const op errors.Op = "bolt.Purge"
+	var n int
+	err := q.db.Update(func(tx *bolt.Tx) error {
+		var err error
+		n, err = deleteBefore(tx, deadBucket, before)
+		return err
+	})
+	if err != nil {
+		return 0, errors.E(op, err)
+	}
+	return n, nil
+}
+
+func (q *boltQueue) Close() error {
+	return q.db.Close()
+}
+
+func (q *boltQueue) notify() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	close(q.changed)
+	q.changed = make(chan struct{})
+}
+
+func key(mod, ver string) []byte {
+	return []byte(config.FmtModVer(mod, ver))
+}
+
+func getJob(tx *bolt.Tx, k []byte) (*queue.Job, error) {
+	v := tx.Bucket(jobsBucket).Get(k)
+	if v == nil {
+		return nil, nil
+	}
+	var j queue.Job
+	if err := json.Unmarshal(v, &j); err != nil {
+		return nil, err
+	}
+	return &j, nil
+}
+
+// putJob saves job, and moves it from the index of the job it replaces,
+// if any, to the index of its state.
+func putJob(tx *bolt.Tx, job *queue.Job) error {
+	k := key(job.Module, job.Version)
+	old, err := getJob(tx, k)
+	if err != nil {
+		return err
+	}
+	if old != nil {
+		if b, t := index(old); b != nil {
+			if err := tx.Bucket(b).Delete(indexKey(t, k)); err != nil {
+				return err
+			}
+		}
+	}
+	if b, t := index(job); b != nil {
+		if err := tx.Bucket(b).Put(indexKey(t, k), nil); err != nil {
+			return err
+		}
+	}
+	v, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+	return tx.Bucket(jobsBucket).Put(k, v)
+}
+
+// deleteBefore removes the jobs of the index bucket
+// whose time is before t, and returns how many.
+func deleteBefore(tx *bolt.Tx, bucket []byte, t time.Time) (int, error) {
+	c := tx.Bucket(bucket).Cursor()
+	n := 0
+	for k, _ := c.First(); k != nil; k, _ = c.First() {
+		at, jobKey := splitIndexKey(k)
+		if !at.Before(t) {
+			break
+		}
+		if err := c.Delete(); err != nil {
+			return n, err
+		}
+		if err := tx.Bucket(jobsBucket).Delete(jobKey); err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, nil
+}
+
+// index returns the index bucket of job and the time it is indexed
+// by, or a nil bucket if running jobs, which are not indexed.
+func index(job *queue.Job) ([]byte, time.Time) {
+	switch job.State {
+	case queue.StatePending:
+		return dueBucket, job.NextAttempt
+	case queue.StateDone:
+		return doneBucket, job.FinishedAt
+	case queue.StateDead:
+		return deadBucket, job.FinishedAt
+	default:
+		return nil, time.Time{}
+	}
+}
+
+// indexKey sorts by t, as big endian nanoseconds since the epoch.
+func indexKey(t time.Time, k []byte) []byte {
+	b := binary.BigEndian.AppendUint64(nil, uint64(max(t.UnixNano(), 0)))
+	return append(b, k...)
+}
+
+func splitIndexKey(k []byte) (time.Time, []byte) {
+	return time.Unix(0, int64(binary.BigEndian.Uint64(k[:8]))), bytes.Clone(k[8:])
+}
@@ -0,0 +1,56 @@
+package bolt
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/gomods/athens/pkg/queue/compliance"
+	"github.com/stretchr/testify/require"
+	bolt "go.etcd.io/bbolt"
+)
+
+func TestBolt(t *testing.T) {
+	q, err := New(filepath.Join(t.TempDir(), "queue.db"))
+	require.NoError(t, err)
+	defer q.Close()
+	compliance.RunTests(t, q, q.(*boltQueue).clear)
+}
+
+func TestBoltResume(t *testing.T) {
+	ctx := t.Context()
+	path := filepath.Join(t.TempDir(), "queue.db")
+	q, err := New(path)
+	require.NoError(t, err)
+	require.NoError(t, q.Enqueue(ctx, "mod", "v1.0.0"))
+	require.NoError(t, q.Enqueue(ctx, "mod", "v2.0.0"))
+	_, err = q.Dequeue(ctx)
+	require.NoError(t, err)
+	require.NoError(t, q.Close())
+
+	// both the pending job and the one that was running
+	// when the queue was closed are handed out again.
+	q, err = New(path)
+	require.NoError(t, err)
+	defer q.Close()
+	vers := map[string]bool{}
+	for range 2 {
+		job, err := q.Dequeue(ctx)
+		require.NoError(t, err)
+		vers[job.Version] = true
+	}
+	require.Equal(t, map[string]bool{"v1.0.0": true, "v2.0.0": true}, vers)
+}
+
+func (q *boltQueue) clear() error {
+	return q.db.Update(func(tx *bolt.Tx) error {
+		for _, name := range append([][]byte{jobsBucket}, indexBuckets...) {
+			if err := tx.DeleteBucket(name); err != nil {
+				return err
+			}
+			if _, err := tx.CreateBucket(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
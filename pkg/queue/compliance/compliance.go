@@ -0,0 +1,195 @@
+package compliance
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gomods/athens/pkg/errors"
+	"github.com/gomods/athens/pkg/queue"
+	"github.com/stretchr/testify/require"
+)
+
+// RunTests runs compliance tests for the given Queue implementation.
+// clearQueue is a function that must remove every job so that
+// tests can assume a clean state.
+func RunTests(t *testing.T, q queue.Queue, clearQueue func() error) {
+	tests := []struct {
+		name string
+		desc string
+		test func(t *testing.T, q queue.Queue)
+	}{
+		{
+			name: "dequeue",
+			desc: "an enqueued job is dequeued as running",
+			test: testDequeue,
+		},
+		{
+			name: "deduplicate",
+			desc: "enqueuing a job that is pending or running does nothing",
+			test: testDeduplicate,
+		},
+		{
+			name: "complete",
+			desc: "a completed job is done until it is enqueued again",
+			test: testComplete,
+		},
+		{
+			name: "retry",
+			desc: "a retried job is dequeued again at its next attempt",
+			test: testRetry,
+		},
+		{
+			name: "bury",
+			desc: "a buried job is a dead letter until it is enqueued again",
+			test: testBury,
+		},
+		{
+			name: "purge",
+			desc: "purging removes the dead letters that were buried before a time",
+			test: testPurge,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Log(tc.desc)
+			require.NoError(t, clearQueue())
+			t.Cleanup(func() {
+				require.NoError(t, clearQueue())
+			})
+			tc.test(t, q)
+		})
+	}
+}
+
+func testDequeue(t *testing.T, q queue.Queue) {
+	ctx := t.Context()
+	_, err := q.Get(ctx, "mod", "v1.0.0")
+	require.True(t, errors.IsNotFoundErr(err))
+
+	require.NoError(t, q.Enqueue(ctx, "mod", "v1.0.0"))
+	job, err := q.Get(ctx, "mod", "v1.0.0")
+	require.NoError(t, err)
+	require.Equal(t, queue.StatePending, job.State)
+
+	job, err = q.Dequeue(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "mod", job.Module)
+	require.Equal(t, "v1.0.0", job.Version)
+	require.Equal(t, queue.StateRunning, job.State)
+	require.Equal(t, 0, job.Attempts)
+	require.False(t, job.StartedAt.IsZero())
+	job, err = q.Get(ctx, "mod", "v1.0.0")
+	require.NoError(t, err)
+	require.Equal(t, queue.StateRunning, job.State)
+	require.False(t, job.StartedAt.IsZero())
+}
+
+func testDeduplicate(t *testing.T, q queue.Queue) {
+	ctx := t.Context()
+	require.NoError(t, q.Enqueue(ctx, "mod", "v1.0.0"))
+	require.NoError(t, q.Enqueue(ctx, "mod", "v1.0.0"))
+	_, err := q.Dequeue(ctx)
+	require.NoError(t, err)
+	require.NoError(t, q.Enqueue(ctx, "mod", "v1.0.0"))
+	requireEmpty(t, q)
+}
+
+func testComplete(t *testing.T, q queue.Queue) {
+	ctx := t.Context()
+	require.NoError(t, q.Enqueue(ctx, "mod", "v1.0.0"))
+	job, err := q.Dequeue(ctx)
+	require.NoError(t, err)
+	job.Semver = "v1.0.1"
+	require.NoError(t, q.Complete(ctx, job))
+	job, err = q.Get(ctx, "mod", "v1.0.0")
+	require.NoError(t, err)
+	require.Equal(t, queue.StateDone, job.State)
+	require.Equal(t, "v1.0.1", job.Semver)
+	require.False(t, job.FinishedAt.IsZero())
+	requireEmpty(t, q)
+
+	require.NoError(t, q.Enqueue(ctx, "mod", "v1.0.0"))
+	job, err = q.Get(ctx, "mod", "v1.0.0")
+	require.NoError(t, err)
+	require.Equal(t, queue.StatePending, job.State)
+}
+
+func testRetry(t *testing.T, q queue.Queue) {
+	ctx := t.Context()
+	require.NoError(t, q.Enqueue(ctx, "mod", "v1.0.0"))
+	job, err := q.Dequeue(ctx)
+	require.NoError(t, err)
+	job.Attempts = 1
+	job.LastError = "upstream is down"
+	job.NextAttempt = time.Now().Add(time.Second)
+	require.NoError(t, q.Retry(ctx, job))
+	retried, err := q.Get(ctx, "mod", "v1.0.0")
+	require.NoError(t, err)
+	require.Equal(t, queue.StatePending, retried.State)
+	requireEmpty(t, q)
+
+	job, err = q.Dequeue(ctx)
+	require.NoError(t, err)
+	require.False(t, time.Now().Before(retried.NextAttempt), "a job must not be dequeued before its next attempt")
+	require.Equal(t, 1, job.Attempts)
+	require.Equal(t, "upstream is down", job.LastError)
+}
+
+func testBury(t *testing.T, q queue.Queue) {
+	ctx := t.Context()
+	require.NoError(t, q.Enqueue(ctx, "mod", "v1.0.0"))
+	job, err := q.Dequeue(ctx)
+	require.NoError(t, err)
+	job.Attempts = 5
+	job.LastError = "upstream is down"
+	require.NoError(t, q.Bury(ctx, job))
+	dead, err := q.Dead(ctx)
+	require.NoError(t, err)
+	require.Len(t, dead, 1)
+	require.Equal(t, queue.StateDead, dead[0].State)
+	require.Equal(t, "upstream is down", dead[0].LastError)
+	requireEmpty(t, q)
+
+	// enqueuing a dead job brings it back to life.
+	require.NoError(t, q.Enqueue(ctx, "mod", "v1.0.0"))
+	dead, err = q.Dead(ctx)
+	require.NoError(t, err)
+	require.Empty(t, dead)
+	job, err = q.Dequeue(ctx)
+	require.NoError(t, err)
+	require.Equal(t, 0, job.Attempts)
+}
+
+func testPurge(t *testing.T, q queue.Queue) {
+	ctx := t.Context()
+	require.NoError(t, q.Enqueue(ctx, "mod", "v1.0.0"))
+	job, err := q.Dequeue(ctx)
+	require.NoError(t, err)
+	require.NoError(t, q.Bury(ctx, job))
+
+	n, err := q.Purge(ctx, time.Now().Add(-time.Hour))
+	require.NoError(t, err)
+	require.Equal(t, 0, n)
+	dead, err := q.Dead(ctx)
+	require.NoError(t, err)
+	require.Len(t, dead, 1)
+
+	n, err = q.Purge(ctx, time.Now().Add(time.Second))
+	require.NoError(t, err)
+	require.Equal(t, 1, n)
+	dead, err = q.Dead(ctx)
+	require.NoError(t, err)
+	require.Empty(t, dead)
+	_, err = q.Get(ctx, "mod", "v1.0.0")
+	require.True(t, errors.IsNotFoundErr(err))
+}
+
+// requireEmpty requires that no job is due.
+func requireEmpty(t *testing.T, q queue.Queue) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(t.Context(), 100*time.Millisecond)
+	defer cancel()
+	job, err := q.Dequeue(ctx)
+	require.Error(t, err, "unexpected job %+v", job)
+}
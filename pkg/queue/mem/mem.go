@@ -0,0 +1,153 @@
+package mem
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/gomods/athens/pkg/config"
+	"github.com/gomods/athens/pkg/errors"
+	"github.com/gomods/athens/pkg/queue"
+)
+
+// New returns a new in-memory queue.
+// Its jobs are lost when the process exits.
+func New() queue.Queue {
+	return &memQueue{jobs: map[string]*queue.Job{}, changed: make(chan struct{})}
+}
+
+type memQueue struct {
+	mu   sync.Mutex
+	jobs map[string]*queue.Job
+	// changed is closed and replaced every time a job becomes pending.
+	changed chan struct{}
+}
+
+func (q *memQueue) Enqueue(_ context.Context, mod, ver string) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	key := config.FmtModVer(mod, ver)
+	if j, ok := q.jobs[key]; ok && (j.State == queue.StatePending || j.State == queue.StateRunning) {
+		return nil
+	}
+	now := time.Now()
+	q.jobs[key] = &queue.Job{Module: mod, Version: ver, State: queue.StatePending, EnqueuedAt: now, NextAttempt: now}
+	q.notify()
+	return nil
+}
+
+func (q *memQueue) Dequeue(ctx context.Context) (*queue.Job, error) {
+	const op errors.Op = "mem.Dequeue"
+	for ctx.Err() == nil {
+		q.mu.Lock()
+		var next *queue.Job
+		for key, j := range q.jobs {
+			if j.State == queue.StateDone && time.Since(j.FinishedAt) > queue.DoneRetention {
+				delete(q.jobs, key)
+				continue
+			}
+			if j.State == queue.StatePending && (next == nil || j.NextAttempt.Before(next.NextAttempt)) {
+				next = j
+			}
+		}
+		changed := q.changed
+		var timer *time.Timer
+		var wait <-chan time.Time
+		if next != nil {
+			d := time.Until(next.NextAttempt)
+			if d <= 0 {
+				next.State = queue.StateRunning
+				next.StartedAt = time.Now()
+				job := *next
+				q.mu.Unlock()
+				return &job, nil
+			}
+			timer = time.NewTimer(d)
+			wait = timer.C
+		}
+		q.mu.Unlock()
+		select {
+		case <-changed:
+		case <-wait:
+		case <-ctx.Done():
+		}
+		if timer != nil {
+			timer.Stop()
+		}
+	}
+	return nil, errors.E(op, ctx.Err())
+}
+
+func (q *memQueue) Complete(_ context.Context, job *queue.Job) error {
+	return q.put(job, queue.StateDone)
+}
+
+func (q *memQueue) Retry(_ context.Context, job *queue.Job) error {
+	return q.put(job, queue.StatePending)
+}
+
+func (q *memQueue) Bury(_ context.Context, job *queue.Job) error {
+	return q.put(job, queue.StateDead)
+}
+
+func (q *memQueue) put(job *queue.Job, state queue.State) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	j := *job
+	j.State = state
+	if state != queue.StatePending {
+		j.FinishedAt = time.Now()
+	}
+	q.jobs[config.FmtModVer(job.Module, job.Version)] = &j
+	if state == queue.StatePending {
+		q.notify()
+	}
+	return nil
+}
+
+func (q *memQueue) Get(_ context.Context, mod, ver string) (*queue.Job, error) {
+	const op errors.Op = "mem.Get"
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	j, ok := q.jobs[config.FmtModVer(mod, ver)]
+	if !ok {
+		return nil, errors.E(op, errors.M(mod), errors.V(ver), errors.KindNotFound)
+	}
+	job := *j
+	return &job, nil
+}
+
+func (q *memQueue) Dead(_ context.Context) ([]*queue.Job, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	dead := []*queue.Job{}
+	for _, j := range q.jobs {
+		if j.State == queue.StateDead {
+			job := *j
+			dead = append(dead, &job)
+		}
+	}
+	return dead, nil
+}
+
+func (q *memQueue) Purge(_ context.Context, before time.Time) (int, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	n := 0
+	for key, j := range q.jobs {
+		if j.State == queue.StateDead && j.FinishedAt.Before(before) {
+			delete(q.jobs, key)
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (q *memQueue) Close() error {
+	return nil
+}
+
+func (q *memQueue) notify() {
+	close(q.changed)
+	q.changed = make(chan struct{})
+}
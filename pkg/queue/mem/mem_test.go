@@ -0,0 +1,20 @@
+package mem
+
+import (
+	"testing"
+
+	"github.com/gomods/athens/pkg/queue"
+	"github.com/gomods/athens/pkg/queue/compliance"
+)
+
+func TestMem(t *testing.T) {
+	q := New().(*memQueue)
+	compliance.RunTests(t, q, q.clear)
+}
+
+func (q *memQueue) clear() error {
+	q.mu.Lock()
+	q.jobs = map[string]*queue.Job{}
+	q.mu.Unlock()
+	return nil
+}
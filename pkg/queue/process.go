@@ -0,0 +1,148 @@
+package queue
+
+import (
+	"context"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"github.com/gomods/athens/pkg/errors"
+	"github.com/gomods/athens/pkg/log"
+)
+
+// Handler handles a job, typically by stashing its module version,
+// and returns the version it resolved to. The job is in ctx, see
+// JobFromContext.
+type Handler func(ctx context.Context, mod, ver string) (string, error)
+
+type jobKey struct{}
+
+// JobFromContext returns the job that the ctx of a Handler was
+// passed for, or nil if the handler was called by something other
+// than Process.
+func JobFromContext(ctx context.Context) *Job {
+	job, _ := ctx.Value(jobKey{}).(*Job)
+	return job
+}
+
+// RetryPolicy decides what happens to a job whose handler failed.
+//
+// Jobs that fail with a KindNotFound error are buried right away,
+// as retrying them would not help. The others are retried with an
+// exponential backoff between MinBackoff and MaxBackoff until they
+// failed MaxAttempts times, and are buried then. An upstream that
+// rate limits us is left alone for at least RateLimitBackoff.
+type RetryPolicy struct {
+	MaxAttempts      int
+	MinBackoff       time.Duration
+	MaxBackoff       time.Duration
+	RateLimitBackoff time.Duration
+}
+
+// Backoff returns how long to wait before the next attempt
+// of a job that failed attempts times, the last time with err.
+func (p RetryPolicy) Backoff(attempts int, err error) time.Duration {
+	d := p.MinBackoff
+	for i := 1; i < attempts && d < p.MaxBackoff; i++ {
+		d *= 2
+	}
+	if d > p.MaxBackoff {
+		d = p.MaxBackoff
+	}
+	// add up to 50% of jitter so that jobs that failed
+	// together are not all retried at the same time.
+	if d > 0 {
+		d += rand.N(d/2 + 1) //nolint:gosec // jitter does not need a secure random number.
+	}
+	if errors.Is(err, errors.KindRateLimit) && d < p.RateLimitBackoff {
+		d = p.RateLimitBackoff
+	}
+	return d
+}
+
+// purgeInterval is how often Process purges the
+// dead letters that are older than DeadRetention.
+const purgeInterval = time.Hour
+
+// Process runs workers that dequeue the jobs of q and handle them with h,
+// until ctx is done. It returns once all the workers are done. Jobs that
+// are interrupted by ctx being done are put back on the queue as they were.
+// Dead letters are purged once they are older than DeadRetention.
+func Process(ctx context.Context, q Queue, workers int, policy RetryPolicy, lggr log.Entry, h Handler) {
+	var wg sync.WaitGroup
+	wg.Go(func() {
+		t := time.NewTicker(purgeInterval)
+		defer t.Stop()
+		for {
+			if _, err := q.Purge(ctx, time.Now().Add(-DeadRetention)); err != nil && ctx.Err() == nil {
+				lggr.SystemErr(err)
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-t.C:
+			}
+		}
+	})
+	for range workers {
+		wg.Go(func() {
+			for {
+				job, err := q.Dequeue(ctx)
+				if ctx.Err() != nil {
+					return
+				}
+				if err != nil {
+					lggr.SystemErr(err)
+					// do not spin on a queue that keeps failing.
+					select {
+					case <-ctx.Done():
+						return
+					case <-time.After(time.Second):
+					}
+					continue
+				}
+				handle(ctx, q, job, policy, lggr, h)
+			}
+		})
+	}
+	wg.Wait()
+}
+
+func handle(ctx context.Context, q Queue, job *Job, policy RetryPolicy, lggr log.Entry, h Handler) {
+	const op errors.Op = "queue.handle"
+	lggr = lggr.WithFields(map[string]any{"module": job.Module, "version": job.Version, "attempts": job.Attempts})
+	// handlers get a copy of the job so that they cannot change its outcome.
+	j := *job
+	semver, err := h(context.WithValue(log.SetEntryInContext(ctx, lggr), jobKey{}, &j), job.Module, job.Version)
+	// the job's outcome must be saved even if we are shutting down.
+	saveCtx := context.WithoutCancel(ctx)
+	switch {
+	case err == nil:
+		job.Semver = semver
+		err = q.Complete(saveCtx, job)
+	case ctx.Err() != nil:
+		lggr.Debugf("putting %s@%s back on the queue", job.Module, job.Version)
+		job.NextAttempt = time.Now()
+		err = q.Retry(saveCtx, job)
+	case errors.IsNotFoundErr(err):
+		lggr.Infof("burying %s@%s as it cannot be found: %v", job.Module, job.Version, err)
+		job.Attempts++
+		job.LastError, job.ErrorKind = err.Error(), errors.Kind(err)
+		err = q.Bury(saveCtx, job)
+	default:
+		job.Attempts++
+		job.LastError, job.ErrorKind = err.Error(), errors.Kind(err)
+		if job.Attempts >= policy.MaxAttempts {
+			lggr.Warnf("burying %s@%s after %d failed attempts: %v", job.Module, job.Version, job.Attempts, err)
+			err = q.Bury(saveCtx, job)
+			break
+		}
+		backoff := policy.Backoff(job.Attempts, err)
+		lggr.Infof("retrying %s@%s in %v: %v", job.Module, job.Version, backoff, err)
+		job.NextAttempt = time.Now().Add(backoff)
+		err = q.Retry(saveCtx, job)
+	}
+	if err != nil {
+		lggr.SystemErr(errors.E(op, errors.M(job.Module), errors.V(job.Version), err))
+	}
+}
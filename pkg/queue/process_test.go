@@ -0,0 +1,114 @@
+package queue_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gomods/athens/pkg/errors"
+	"github.com/gomods/athens/pkg/log"
+	"github.com/gomods/athens/pkg/queue"
+	"github.com/gomods/athens/pkg/queue/mem"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBackoff(t *testing.T) {
+	p := queue.RetryPolicy{MinBackoff: time.Second, MaxBackoff: 10 * time.Second, RateLimitBackoff: time.Minute}
+	unexpected := errors.E("op", "boom")
+	for attempts, want := range map[int]time.Duration{1: time.Second, 2: 2 * time.Second, 3: 4 * time.Second, 10: 10 * time.Second} {
+		d := p.Backoff(attempts, unexpected)
+		require.GreaterOrEqual(t, d, want, "attempt %d", attempts)
+		require.LessOrEqual(t, d, want+want/2, "attempt %d", attempts)
+	}
+	d := p.Backoff(1, errors.E("op", "slow down", errors.KindRateLimit))
+	require.Equal(t, time.Minute, d)
+}
+
+func TestProcess(t *testing.T) {
+	ctx, cancel := context.WithCancel(t.Context())
+	q := mem.New()
+	policy := queue.RetryPolicy{MaxAttempts: 3, MinBackoff: time.Millisecond, MaxBackoff: time.Millisecond}
+
+	var mu sync.Mutex
+	calls := map[string]int{}
+	done := make(chan struct{}, 3)
+	h := func(ctx context.Context, mod, ver string) (string, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		calls[mod]++
+		defer func() { done <- struct{}{} }()
+		switch {
+		case mod == "flaky" && calls[mod] == 1:
+			return "", errors.E("op", "connection reset")
+		case mod == "flaky":
+			return "v1.0.1", nil
+		case mod == "missing":
+			return "", errors.E("op", "not found", errors.KindNotFound)
+		default:
+			return "", errors.E("op", "connection reset")
+		}
+	}
+	for _, mod := range []string{"flaky", "missing", "broken"} {
+		require.NoError(t, q.Enqueue(ctx, mod, "v1.0.0"))
+	}
+	var wg sync.WaitGroup
+	wg.Go(func() { queue.Process(ctx, q, 2, policy, log.NoOpLogger(), h) })
+	for range 2 + 1 + 3 {
+		<-done
+	}
+	cancel()
+	wg.Wait()
+
+	require.Equal(t, map[string]int{"flaky": 2, "missing": 1, "broken": 3}, calls)
+	job, err := q.Get(t.Context(), "flaky", "v1.0.0")
+	require.NoError(t, err)
+	require.Equal(t, queue.StateDone, job.State)
+	require.Equal(t, "v1.0.1", job.Semver)
+	job, err = q.Get(t.Context(), "missing", "v1.0.0")
+	require.NoError(t, err)
+	require.Equal(t, queue.StateDead, job.State, "a job that is not found must be buried right away")
+	require.Equal(t, errors.KindNotFound, job.ErrorKind)
+	job, err = q.Get(t.Context(), "broken", "v1.0.0")
+	require.NoError(t, err)
+	require.Equal(t, queue.StateDead, job.State)
+	require.Equal(t, 3, job.Attempts)
+	require.Contains(t, job.LastError, "connection reset")
+}
+
+func TestProcessInterrupted(t *testing.T) {
+	ctx, cancel := context.WithCancel(t.Context())
+	q := mem.New()
+	require.NoError(t, q.Enqueue(ctx, "mod", "v1.0.0"))
+	h := func(ctx context.Context, mod, ver string) (string, error) {
+		cancel()
+		<-ctx.Done()
+		return "", ctx.Err()
+	}
+	queue.Process(ctx, q, 1, queue.RetryPolicy{MaxAttempts: 1}, log.NoOpLogger(), h)
+
+	// shutting down puts the job back on the queue as it was.
+	job, err := q.Get(t.Context(), "mod", "v1.0.0")
+	require.NoError(t, err)
+	require.Equal(t, queue.StatePending, job.State)
+	require.Equal(t, 0, job.Attempts)
+}
+
+func TestJobFromContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(t.Context())
+	q := mem.New()
+	require.NoError(t, q.Enqueue(ctx, "mod", "v1.0.0"))
+	var job *queue.Job
+	h := func(ctx context.Context, mod, ver string) (string, error) {
+		defer cancel()
+		job = queue.JobFromContext(ctx)
+		return ver, nil
+	}
+	queue.Process(ctx, q, 1, queue.RetryPolicy{MaxAttempts: 1}, log.NoOpLogger(), h)
+
+	require.NotNil(t, job)
+	require.Equal(t, "mod", job.Module)
+	require.Equal(t, queue.StateRunning, job.State)
+	require.False(t, job.EnqueuedAt.IsZero())
+	require.Nil(t, queue.JobFromContext(t.Context()))
+}
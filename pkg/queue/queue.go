@@ -0,0 +1,90 @@
+// Package queue holds the module versions that are stashed in the
+// background by the async download modes, so that a stash that fails
+// is retried and one that is pending is not lost on a restart.
+package queue
+
+import (
+	"context"
+	"time"
+)
+
+// State is the state of a Job.
+type State string
+
+// Job states.
+const (
+	// StatePending jobs wait to be dequeued at their NextAttempt.
+	StatePending State = "pending"
+	// StateRunning jobs are being handled by a worker.
+	StateRunning State = "running"
+	// StateDead jobs failed for good and are not retried.
+	StateDead State = "dead"
+	// StateDone jobs were handled, they are kept for DoneRetention.
+	StateDone State = "done"
+)
+
+// DoneRetention is how long queues keep done jobs, so that
+// those who wait for a job can learn how it went.
+const DoneRetention = 10 * time.Minute
+
+// DeadRetention is how long Process keeps dead letters, so that they
+// can be looked into, before it purges them.
+const DeadRetention = 7 * 24 * time.Hour
+
+// Job is a module version to be stashed. Semver is the version
+// it resolved to once it is done, and ErrorKind is the errors.Kind
+// of LastError.
+type Job struct {
+	Module      string    `json:"module"`
+	Version     string    `json:"version"`
+	State       State     `json:"state"`
+	Semver      string    `json:"semver,omitempty"`
+	Attempts    int       `json:"attempts"`
+	LastError   string    `json:"lastError,omitempty"`
+	ErrorKind   int       `json:"errorKind,omitempty"`
+	EnqueuedAt  time.Time `json:"enqueuedAt,omitzero"`
+	StartedAt   time.Time `json:"startedAt,omitzero"`
+	NextAttempt time.Time `json:"nextAttempt,omitzero"`
+	FinishedAt  time.Time `json:"finishedAt,omitzero"`
+}
+
+// Queue is a queue of module versions to be stashed.
+type Queue interface {
+	// Enqueue adds a job for mod@ver that is due right away.
+	// Enqueuing a module version that is already pending or running
+	// does nothing, while a dead or done one is started over.
+	Enqueue(ctx context.Context, mod, ver string) error
+
+	// Dequeue blocks until a job is due and returns it as running and
+	// started now, or until ctx is done. A job that is left running by a
+	// worker that went away is handed out again: implementers must either
+	// put running jobs back on the queue when they are opened or
+	// hand them out again once they have been running for too long.
+	Dequeue(ctx context.Context) (*Job, error)
+
+	// Complete marks a running job as done with its Semver and finished
+	// now. It is removed from the queue after DoneRetention.
+	Complete(ctx context.Context, job *Job) error
+
+	// Retry puts a running job back on the queue, to be dequeued again
+	// at its NextAttempt. Its Attempts and LastError are saved.
+	Retry(ctx context.Context, job *Job) error
+
+	// Bury moves a running job to the dead letters, finished now,
+	// where it stays until it is enqueued again.
+	Bury(ctx context.Context, job *Job) error
+
+	// Get returns the job of mod@ver, whatever its state.
+	// It returns a KindNotFound error if there is none.
+	Get(ctx context.Context, mod, ver string) (*Job, error)
+
+	// Dead returns the dead letters.
+	Dead(ctx context.Context) ([]*Job, error)
+
+	// Purge removes the dead letters that finished before the given
+	// time and returns how many it removed.
+	Purge(ctx context.Context, before time.Time) (int, error)
+
+	// Close releases the resources held by the queue.
+	Close() error
+}
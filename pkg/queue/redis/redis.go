@@ -0,0 +1,355 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gomods/athens/pkg/config"
+	"github.com/gomods/athens/pkg/errors"
+	"github.com/gomods/athens/pkg/queue"
+	"github.com/gomods/athens/pkg/redisclient"
+	"github.com/redis/go-redis/v9"
+)
+
+// queueKeys are the keys of a queue named name, which share a hash tag
+// to live in the same slot of a cluster:
+// jobs holds the pending and running jobs as JSON, by module@version,
+// due holds the pending jobs scored by their next attempt,
+// running holds the running jobs scored by when their lease expires,
+// dead holds the dead letters as JSON, by module@version,
+// deadAt holds the dead letters scored by when they were buried,
+// and the done jobs are kept as JSON under donePrefix+module@version.
+type queueKeys struct {
+	jobs, due, running, dead, deadAt, donePrefix string
+}
+
+func newKeys(name string) queueKeys {
+	tag := "{athens:" + name + "}:"
+	return queueKeys{
+		jobs:       tag + "jobs",
+		due:        tag + "due",
+		running:    tag + "running",
+		dead:       tag + "dead",
+		deadAt:     tag + "deadat",
+		donePrefix: tag + "done:",
+	}
+}
+
+// enqueueScript adds a pending job unless one is pending or running already.
+var enqueueScript = redis.NewScript(`
+if redis.call('HEXISTS', KEYS[1], ARGV[1]) == 1 then
+	return 0
+end
+redis.call('HDEL', KEYS[3], ARGV[1])
+redis.call('ZREM', KEYS[5], ARGV[1])
+redis.call('DEL', KEYS[4])
+redis.call('HSET', KEYS[1], ARGV[1], ARGV[2])
+redis.call('ZADD', KEYS[2], ARGV[3], ARGV[1])
+return 1
+`)
+
+// dequeueScript puts the jobs whose lease expired back on the queue,
+// then leases the job that is due first, if any, and returns it.
+var dequeueScript = redis.NewScript(`
+local now = tonumber(ARGV[1])
+for _, k in ipairs(redis.call('ZRANGEBYSCORE', KEYS[3], '-inf', now)) do
+	redis.call('ZREM', KEYS[3], k)
+	redis.call('ZADD', KEYS[2], now, k)
+end
+local due = redis.call('ZRANGEBYSCORE', KEYS[2], '-inf', now, 'LIMIT', 0, 1)
+if #due == 0 then
+	return false
+end
+local k = due[1]
+redis.call('ZREM', KEYS[2], k)
+local raw = redis.call('HGET', KEYS[1], k)
+if not raw then
+	return false
+end
+local job = cjson.decode(raw)
+job['state'] = 'running'
+job['startedAt'] = ARGV[3]
+raw = cjson.encode(job)
+redis.call('HSET', KEYS[1], k, raw)
+redis.call('ZADD', KEYS[3], now + tonumber(ARGV[2]), k)
+return raw
+`)
+
+// New returns a queue that is kept in redis, so that it can be shared by
+// several processes. A job is leased to the worker that dequeues it and is
+// handed out again if it is still running once the lease expires. The
+// lease is renewed while the job runs, so it only expires when the worker
+// went away without completing, retrying or burying the job.
+//
+// The endpoint may be a redis URL or a host:port address, or a comma
+// separated list of host:port addresses of the nodes of a cluster.
+func New(endpoint, password string, cluster bool, lease time.Duration) (queue.Queue, error) {
+	return NewNamed(endpoint, password, cluster, lease, "queue")
+}
+
+// NewNamed returns a queue as New does, whose keys are namespaced
+// with name, so that several queues can share the same redis.
+func NewNamed(endpoint, password string, cluster bool, lease time.Duration, name string) (queue.Queue, error) {
+	const op errors.Op = "redis.New"
+	client, err := redisclient.New(endpoint, password, cluster)
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+	return &redisQueue{
+		client:       client,
+		keys:         newKeys(name),
+		lease:        lease,
+		pollInterval: time.Second,
+		renewals:     map[string]context.CancelFunc{},
+	}, nil
+}
+
+type redisQueue struct {
+	client       redis.UniversalClient
+	keys         queueKeys
+	lease        time.Duration
+	pollInterval time.Duration
+
+	mu sync.Mutex
+	// renewals stops renewing the leases of the running jobs, by module@version.
+	renewals map[string]context.CancelFunc
+}
+
+func (q *redisQueue) Enqueue(ctx context.Context, mod, ver string) error {
+	const op errors.Op = "redis.Enqueue"
+	now := time.Now()
+	job, err := json.Marshal(&queue.Job{Module: mod, Version: ver, State: queue.StatePending, EnqueuedAt: now, NextAttempt: now})
+	if err != nil {
+		return errors.E(op, errors.M(mod), errors.V(ver), err)
+	}
+	k := config.FmtModVer(mod, ver)
+	keys := []string{q.keys.jobs, q.keys.due, q.keys.dead, q.keys.donePrefix + k, q.keys.deadAt}
+	err = enqueueScript.Run(ctx, q.client, keys, k, job, now.UnixMilli()).Err()
+	if err != nil {
+		return errors.E(op, errors.M(mod), errors.V(ver), err)
+	}
+	return nil
+}
+
+func (q *redisQueue) Dequeue(ctx context.Context) (*queue.Job, error) {
+	const op errors.Op = "redis.Dequeue"
+	keys := []string{q.keys.jobs, q.keys.due, q.keys.running}
+	for {
+		now := time.Now()
+		raw, err := dequeueScript.Run(ctx, q.client, keys, now.UnixMilli(), q.lease.Milliseconds(), now.Format(time.RFC3339Nano)).Text()
+		switch {
+		case err == nil:
+			var job queue.Job
+			if err := json.Unmarshal([]byte(raw), &job); err != nil {
+				return nil, errors.E(op, err)
+			}
+			q.renew(config.FmtModVer(job.Module, job.Version))
+			return &job, nil
+		case err != redis.Nil:
+			return nil, errors.E(op, err)
+		}
+		select {
+		case <-time.After(q.pollInterval):
+		case <-ctx.Done():
+			return nil, errors.E(op, ctx.Err())
+		}
+	}
+}
+
+func (q *redisQueue) Complete(ctx context.Context, job *queue.Job) error {
+	const op errors.Op = "redis.Complete"
+	j := *job
+	j.State = queue.StateDone
+	j.FinishedAt = time.Now()
+	raw, err := json.Marshal(&j)
+	if err != nil {
+		return errors.E(op, errors.M(job.Module), errors.V(job.Version), err)
+	}
+	k := config.FmtModVer(job.Module, job.Version)
+	q.stopRenewing(k)
+	_, err = q.client.TxPipelined(ctx, func(p redis.Pipeliner) error {
+		p.HDel(ctx, q.keys.jobs, k)
+		p.ZRem(ctx, q.keys.running, k)
+		p.ZRem(ctx, q.keys.due, k)
+		p.Set(ctx, q.keys.donePrefix+k, raw, queue.DoneRetention)
+		return nil
+	})
+	if err != nil {
+		return errors.E(op, errors.M(job.Module), errors.V(job.Version), err)
+	}
+	return nil
+}
+
+func (q *redisQueue) Retry(ctx context.Context, job *queue.Job) error {
+	const op errors.Op = "redis.Retry"
+	j := *job
+	j.State = queue.StatePending
+	raw, err := json.Marshal(&j)
+	if err != nil {
+		return errors.E(op, errors.M(job.Module), errors.V(job.Version), err)
+	}
+	k := config.FmtModVer(job.Module, job.Version)
+	q.stopRenewing(k)
+	_, err = q.client.TxPipelined(ctx, func(p redis.Pipeliner) error {
+		p.HSet(ctx, q.keys.jobs, k, raw)
+		p.ZRem(ctx, q.keys.running, k)
+		p.ZAdd(ctx, q.keys.due, redis.Z{Score: float64(j.NextAttempt.UnixMilli()), Member: k})
+		return nil
+	})
+	if err != nil {
+		return errors.E(op, errors.M(job.Module), errors.V(job.Version), err)
+	}
+	return nil
+}
+
+func (q *redisQueue) Bury(ctx context.Context, job *queue.Job) error {
+	const op errors.Op = "redis.Bury"
+	j := *job
+	j.State = queue.StateDead
+	j.FinishedAt = time.Now()
+	raw, err := json.Marshal(&j)
+	if err != nil {
+		return errors.E(op, errors.M(job.Module), errors.V(job.Version), err)
+	}
+	k := config.FmtModVer(job.Module, job.Version)
+	q.stopRenewing(k)
+	_, err = q.client.TxPipelined(ctx, func(p redis.Pipeliner) error {
+		p.HDel(ctx, q.keys.jobs, k)
+		p.ZRem(ctx, q.keys.running, k)
+		p.ZRem(ctx, q.keys.due, k)
+		p.HSet(ctx, q.keys.dead, k, raw)
+		p.ZAdd(ctx, q.keys.deadAt, redis.Z{Score: float64(j.FinishedAt.UnixMilli()), Member: k})
+		return nil
+	})
+	if err != nil {
+		return errors.E(op, errors.M(job.Module), errors.V(job.Version), err)
+	}
+	return nil
+}
+
+func (q *redisQueue) Get(ctx context.Context, mod, ver string) (*queue.Job, error) {
+	const op errors.Op = "redis.Get"
+	k := config.FmtModVer(mod, ver)
+	for _, get := range []func() *redis.StringCmd{
+		func() *redis.StringCmd { return q.client.HGet(ctx, q.keys.jobs, k) },
+		func() *redis.StringCmd { return q.client.HGet(ctx, q.keys.dead, k) },
+		func() *redis.StringCmd { return q.client.Get(ctx, q.keys.donePrefix+k) },
+	} {
+		raw, err := get().Bytes()
+		if err == redis.Nil {
+			continue
+		}
+		if err != nil {
+			return nil, errors.E(op, errors.M(mod), errors.V(ver), err)
+		}
+		var job queue.Job
+		if err := json.Unmarshal(raw, &job); err != nil {
+			return nil, errors.E(op, errors.M(mod), errors.V(ver), err)
+		}
+		return &job, nil
+	}
+	return nil, errors.E(op, errors.M(mod), errors.V(ver), errors.KindNotFound)
+}
+
+func (q *redisQueue) Dead(ctx context.Context) ([]*queue.Job, error) {
+	const op errors.Op = "redis.Dead"
+	all, err := q.client.HGetAll(ctx, q.keys.dead).Result()
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+	dead := make([]*queue.Job, 0, len(all))
+	for _, raw := range all {
+		var job queue.Job
+		if err := json.Unmarshal([]byte(raw), &job); err != nil {
+			return nil, errors.E(op, err)
+		}
+		dead = append(dead, &job)
+	}
+	return dead, nil
+}
+
+func (q *redisQueue) Purge(ctx context.Context, before time.Time) (int, error) {
+	const op errors.Op = "redis.Purge"
+	// the scores are exclusive of before.
+	until := "(" + strconv.FormatInt(before.UnixMilli(), 10)
+	keys, err := q.client.ZRangeByScore(ctx, q.keys.deadAt, &redis.ZRangeBy{Min: "-inf", Max: until}).Result()
+	if err != nil {
+		return 0, errors.E(op, err)
+	}
+	if len(keys) == 0 {
+		return 0, nil
+	}
+	_, err = q.client.TxPipelined(ctx, func(p redis.Pipeliner) error {
+		p.HDel(ctx, q.keys.dead, keys...)
+		p.ZRem(ctx, q.keys.deadAt, toAny(keys)...)
+		return nil
+	})
+	if err != nil {
+		return 0, errors.E(op, err)
+	}
+	return len(keys), nil
+}
+
+func (q *redisQueue) Close() error {
+	q.mu.Lock()
+	for k, stop := range q.renewals {
+		stop()
+		delete(q.renewals, k)
+	}
+	q.mu.Unlock()
+	return q.client.Close()
+}
+
+// renew extends the lease of the running job k every third of the lease,
+// until the job is completed, retried or buried, or is no longer running.
+func (q *redisQueue) renew(k string) {
+	ctx, cancel := context.WithCancel(context.Background())
+	q.mu.Lock()
+	if stop, ok := q.renewals[k]; ok {
+		stop()
+	}
+	q.renewals[k] = cancel
+	q.mu.Unlock()
+	go func() {
+		t := time.NewTicker(q.lease / 3)
+		defer t.Stop()
+		for {
+			select {
+			case <-t.C:
+			case <-ctx.Done():
+				return
+			}
+			expiry := time.Now().Add(q.lease).UnixMilli()
+			n, err := q.client.ZAddArgs(ctx, q.keys.running, redis.ZAddArgs{
+				XX:      true,
+				Ch:      true,
+				Members: []redis.Z{{Score: float64(expiry), Member: k}},
+			}).Result()
+			// a failed renewal is tried again on the next tick, while
+			// there is still time left on the lease.
+			if err == nil && n == 0 {
+				return
+			}
+		}
+	}()
+}
+
+func (q *redisQueue) stopRenewing(k string) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if stop, ok := q.renewals[k]; ok {
+		stop()
+		delete(q.renewals, k)
+	}
+}
+
+func toAny(keys []string) []any {
+	members := make([]any, len(keys))
+	for i, k := range keys {
+		members[i] = k
+	}
+	return members
+}
@@ -0,0 +1,85 @@
+package redis
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/gomods/athens/pkg/config"
+	"github.com/gomods/athens/pkg/queue/compliance"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedis(t *testing.T) {
+	endpoint := os.Getenv("REDIS_TEST_ENDPOINT")
+	password := os.Getenv("ATHENS_REDIS_PASSWORD")
+	if len(endpoint) == 0 {
+		t.SkipNow()
+	}
+	q, err := New(endpoint, password, false, time.Minute)
+	require.NoError(t, err)
+	defer q.Close()
+	rq := q.(*redisQueue)
+	rq.pollInterval = 10 * time.Millisecond
+	compliance.RunTests(t, q, rq.clear)
+}
+
+func TestRedisLeaseExpiry(t *testing.T) {
+	endpoint := os.Getenv("REDIS_TEST_ENDPOINT")
+	password := os.Getenv("ATHENS_REDIS_PASSWORD")
+	if len(endpoint) == 0 {
+		t.SkipNow()
+	}
+	ctx := t.Context()
+	q, err := New(endpoint, password, false, 50*time.Millisecond)
+	require.NoError(t, err)
+	defer q.Close()
+	rq := q.(*redisQueue)
+	rq.pollInterval = 10 * time.Millisecond
+	require.NoError(t, rq.clear())
+	defer rq.clear()
+
+	require.NoError(t, q.Enqueue(ctx, "mod", "v1.0.0"))
+	_, err = q.Dequeue(ctx)
+	require.NoError(t, err)
+	// the worker that dequeued the job went away without completing it.
+	rq.stopRenewing(config.FmtModVer("mod", "v1.0.0"))
+	job, err := q.Dequeue(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "v1.0.0", job.Version)
+}
+
+func TestRedisLeaseRenewal(t *testing.T) {
+	endpoint := os.Getenv("REDIS_TEST_ENDPOINT")
+	password := os.Getenv("ATHENS_REDIS_PASSWORD")
+	if len(endpoint) == 0 {
+		t.SkipNow()
+	}
+	ctx := t.Context()
+	q, err := New(endpoint, password, false, 50*time.Millisecond)
+	require.NoError(t, err)
+	defer q.Close()
+	rq := q.(*redisQueue)
+	rq.pollInterval = 10 * time.Millisecond
+	require.NoError(t, rq.clear())
+	defer rq.clear()
+
+	require.NoError(t, q.Enqueue(ctx, "mod", "v1.0.0"))
+	_, err = q.Dequeue(ctx)
+	require.NoError(t, err)
+	// the job outlives its lease, which is renewed while it runs.
+	wait, cancel := context.WithTimeout(ctx, 200*time.Millisecond)
+	defer cancel()
+	job, err := q.Dequeue(wait)
+	require.Error(t, err, "unexpected job %+v", job)
+}
+
+func (q *redisQueue) clear() error {
+	ctx := context.Background()
+	done, err := q.client.Keys(ctx, q.keys.donePrefix+"*").Result()
+	if err != nil {
+		return err
+	}
+	return q.client.Del(ctx, append(done, q.keys.jobs, q.keys.due, q.keys.running, q.keys.dead, q.keys.deadAt)...).Err()
+}
@@ -0,0 +1,134 @@
+// Package redisclient builds the redis clients that the redis backed
+// singleflight, queue and caches connect with.
+package redisclient
+
+import (
+	"context"
+	goerrors "errors"
+	"strings"
+
+	"github.com/gomods/athens/pkg/errors"
+	"github.com/redis/go-redis/v9"
+)
+
+var errPasswordsDoNotMatch = goerrors.New("a redis url was parsed that contained a password but the configuration also defined a specific redis password, please ensure these values match or use only one of them")
+
+// getRedisClientOptions takes an endpoint and password and returns *redis.Options to use
+// with the redis client. endpoint may be a redis url or host:port combination. If a redis
+// url is used and a password is also used this function checks to make sure the parsed redis
+// url has produced the same password. Preferably, one should use EITHER a redis url or a host:port
+// combination w/password but not both. More information on the redis url structure can be found
+// here: https://github.com/redis/redis-specifications/blob/master/uri/redis.txt
+func getRedisClientOptions(endpoint, password string) (*redis.Options, error) {
+	// Try parsing the endpoint as a redis url first. The redis library does not define
+	// a specific error when parsing the url so we fall back on the old config here
+	// which passed in arguments.
+	options, err := redis.ParseURL(endpoint)
+	if err != nil {
+		return &redis.Options{ //nolint:nilerr // We are specifically falling back here and ignoring the error on purpose.
+			Network:  "tcp",
+			Addr:     endpoint,
+			Password: password,
+		}, nil
+	}
+
+	// Ensure the passwords are consistent:
+	// - If the URL contains a password and a separate password is also provided,
+	//   they must match to avoid silent misconfigurations.
+	// - If the URL contains no password (e.g. rediss://host:6379) but a separate
+	//   password is provided, apply it to the options so it is used for AUTH.
+	//   This supports TLS endpoints (rediss://) with separately-configured passwords.
+	if options.Password != "" && password != "" && options.Password != password {
+		return nil, errPasswordsDoNotMatch
+	}
+	if options.Password == "" && password != "" {
+		options.Password = password
+	}
+
+	return options, nil
+}
+
+// getRedisClusterClientOptions takes a (possibly comma-separated) endpoint and
+// a password and returns *redis.ClusterOptions suitable for redis.NewClusterClient.
+// Each comma-separated entry is treated as a host:port pair or a redis[s]:// URL.
+// URL-embedded passwords are validated against the explicit password the same
+// way getRedisClientOptions does.
+func getRedisClusterClientOptions(endpoint, password string) (*redis.ClusterOptions, error) {
+	rawAddrs := strings.Split(endpoint, ",")
+	addrs := make([]string, 0, len(rawAddrs))
+	clusterOpts := &redis.ClusterOptions{
+		Password: password,
+	}
+	var (
+		username string
+		urlPwd   string
+	)
+
+	for _, raw := range rawAddrs {
+		raw = strings.TrimSpace(raw)
+		if raw == "" {
+			continue
+		}
+		// Try parsing as a redis[s]:// URL so TLS and credentials are honored.
+		// Fall back to host:port if it doesn't parse as a URL.
+		opts, err := redis.ParseURL(raw)
+		if err != nil {
+			addrs = append(addrs, raw)
+			continue
+		}
+		addrs = append(addrs, opts.Addr)
+		// First URL contributes TLS/username/embedded-password; subsequent URLs
+		// are expected to be homogeneous (same auth/TLS), which is the normal
+		// AWS ElastiCache / Redis Cluster setup.
+		if clusterOpts.TLSConfig == nil {
+			clusterOpts.TLSConfig = opts.TLSConfig
+		}
+		if username == "" {
+			username = opts.Username
+		}
+		if urlPwd == "" {
+			urlPwd = opts.Password
+		}
+	}
+
+	if username != "" {
+		clusterOpts.Username = username
+	}
+	// Apply the same password-consistency rule as the single-node path.
+	if urlPwd != "" && password != "" && urlPwd != password {
+		return nil, errPasswordsDoNotMatch
+	}
+	if urlPwd != "" && password == "" {
+		clusterOpts.Password = urlPwd
+	}
+
+	clusterOpts.Addrs = addrs
+	return clusterOpts, nil
+}
+
+// New returns a client of the redis at endpoint, once it answers
+// a ping. endpoint is a redis url or a host:port address, or a comma separated
+// list of them, the nodes of a cluster, if cluster is set. See
+// getRedisClientOptions for how password is used.
+func New(endpoint, password string, cluster bool) (redis.UniversalClient, error) {
+	const op errors.Op = "redisclient.New"
+	var client redis.UniversalClient
+	if cluster {
+		clusterOpts, err := getRedisClusterClientOptions(endpoint, password)
+		if err != nil {
+			return nil, errors.E(op, err)
+		}
+		client = redis.NewClusterClient(clusterOpts)
+	} else {
+		options, err := getRedisClientOptions(endpoint, password)
+		if err != nil {
+			return nil, errors.E(op, err)
+		}
+		client = redis.NewClient(options)
+	}
+	if _, err := client.Ping(context.Background()).Result(); err != nil {
+		_ = client.Close()
+		return nil, errors.E(op, err)
+	}
+	return client, nil
+}
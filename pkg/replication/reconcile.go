@@ -0,0 +1,164 @@
+package replication
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/gomods/athens/pkg/errors"
+	"github.com/gomods/athens/pkg/log"
+	"github.com/gomods/athens/pkg/observ"
+	"github.com/gomods/athens/pkg/paths"
+	"github.com/gomods/athens/pkg/stash"
+	"github.com/gomods/athens/pkg/storage"
+	"golang.org/x/mod/semver"
+)
+
+// catalogPageSize is the number of module versions
+// that Reconcile reads from a catalog at a time.
+const catalogPageSize = 1000
+
+// RoundModule is the module whose versions mark the reconcile rounds that
+// are done in the source storage, see Reconciler. The .invalid top level
+// domain keeps it out of the catalog, and away from the replication targets.
+const RoundModule = "athens.invalid/replication"
+
+// Reconcile queues the module versions of the source storage that one of
+// the targets misses, such as the versions that were saved before the target
+// was added, or whose replication failed for good. It goes through the
+// catalog of the source a page at a time, and lists the versions of the
+// modules of the page in the targets. It returns the number of versions
+// queued.
+func (r *Replicator) Reconcile(ctx context.Context) (int, error) {
+	const op errors.Op = "replication.Reconcile"
+	ctx, span := observ.StartSpan(ctx, op.String())
+	defer span.End()
+	source, ok := r.source.(storage.Cataloger)
+	if !ok {
+		return 0, errors.E(op, "the source storage has no catalog", errors.KindNotImplemented)
+	}
+
+	queued := 0
+	token := ""
+	for {
+		page, next, err := source.Catalog(ctx, token, catalogPageSize)
+		if err != nil {
+			return queued, errors.E(op, err)
+		}
+		missing, err := r.missing(ctx, page)
+		if err != nil {
+			return queued, errors.E(op, err)
+		}
+		for _, p := range missing {
+			if err := r.q.Enqueue(ctx, p.Module, p.Version); err != nil {
+				return queued, errors.E(op, err)
+			}
+			queued++
+		}
+		if next == "" {
+			return queued, nil
+		}
+		token = next
+	}
+}
+
+// missing returns the module versions of page that one of the targets misses.
+func (r *Replicator) missing(ctx context.Context, page []paths.AllPathParams) ([]paths.AllPathParams, error) {
+	// the versions of the modules of the page that each target has.
+	have := make([]map[paths.AllPathParams]bool, len(r.targets))
+	for i := range have {
+		have[i] = map[paths.AllPathParams]bool{}
+	}
+	listed := map[string]bool{}
+	var missing []paths.AllPathParams
+	for _, p := range page {
+		if storage.IsInternal(p.Module) {
+			continue
+		}
+		if !listed[p.Module] {
+			listed[p.Module] = true
+			for i, t := range r.targets {
+				vers, err := t.Storage.List(ctx, p.Module)
+				if err != nil && !errors.IsNotFoundErr(err) {
+					return nil, err
+				}
+				for _, v := range vers {
+					have[i][paths.AllPathParams{Module: p.Module, Version: v}] = true
+				}
+			}
+		}
+		for i := range r.targets {
+			if !have[i][p] {
+				missing = append(missing, p)
+				break
+			}
+		}
+	}
+	return missing, nil
+}
+
+// Round returns the version of RoundModule that stands for the reconcile
+// round that t is in, the rounds being interval long.
+func Round(t time.Time, interval time.Duration) string {
+	start := t.UTC().Truncate(interval)
+	return "v0.0.0-" + start.Format("20060102150405") + "-000000000000"
+}
+
+// Reconciler returns a stasher of the Round versions of RoundModule, which
+// reconciles the targets and saves the version to the source storage, to
+// mark the round as done. Wrapped with a singleflight, which only stashes the
+// versions that are not in storage yet, it makes a single one of the Athens
+// instances that share the source storage reconcile in each round.
+func (r *Replicator) Reconciler() stash.Stasher {
+	return reconciler{r}
+}
+
+type reconciler struct {
+	r *Replicator
+}
+
+func (rc reconciler) Stash(ctx context.Context, mod, ver string) (string, error) {
+	const op errors.Op = "replication.reconciler.Stash"
+	if mod != RoundModule {
+		return "", errors.E(op, errors.M(mod), errors.V(ver), errors.KindNotFound)
+	}
+	queued, err := rc.r.Reconcile(ctx)
+	if queued > 0 {
+		log.EntryFromContext(ctx).WithFields(map[string]any{"queued": queued}).Infof("Queued module versions missing from the replication targets")
+	}
+	if err != nil {
+		return "", errors.E(op, err)
+	}
+	info, err := json.Marshal(storage.RevInfo{Version: ver, Time: time.Now()})
+	if err != nil {
+		return "", errors.E(op, err)
+	}
+	gomod := []byte("module " + RoundModule + "\n")
+	err = rc.r.source.Save(ctx, RoundModule, ver, gomod, bytes.NewReader(nil), nil, info)
+	if err != nil && !errors.Is(err, errors.KindAlreadyExists) {
+		return "", errors.E(op, err)
+	}
+	if err := rc.prune(ctx, ver); err != nil {
+		return "", errors.E(op, err)
+	}
+	return ver, nil
+}
+
+// prune deletes the marks of the rounds before ver.
+func (rc reconciler) prune(ctx context.Context, ver string) error {
+	vers, err := rc.r.source.List(ctx, RoundModule)
+	if err != nil {
+		return err
+	}
+	for _, v := range vers {
+		if semver.Compare(v, ver) >= 0 {
+			continue
+		}
+		// another instance may be pruning at the same time
+		if err := rc.r.source.Delete(ctx, RoundModule, v); err != nil && !errors.IsNotFoundErr(err) {
+			return err
+		}
+	}
+	return nil
+}
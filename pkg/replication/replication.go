@@ -0,0 +1,108 @@
+// Package replication copies the module versions that are saved to storage
+// to secondary storage backends, for disaster recovery.
+package replication
+
+import (
+	"context"
+	"time"
+
+	"github.com/gomods/athens/pkg/errors"
+	"github.com/gomods/athens/pkg/log"
+	"github.com/gomods/athens/pkg/observ"
+	"github.com/gomods/athens/pkg/queue"
+	"github.com/gomods/athens/pkg/storage"
+)
+
+// Target is a storage backend that module versions are replicated to.
+type Target struct {
+	Name    string
+	Storage storage.Backend
+}
+
+// Replicator replicates the module versions of a source storage to its
+// targets. Module versions are queued for replication, so that a failed
+// replication is retried and a pending one is not lost on a restart, and
+// a queued version is replicated to all the targets that miss it.
+type Replicator struct {
+	source  storage.Backend
+	targets []Target
+	q       queue.Queue
+}
+
+// New returns a Replicator of the module versions of source to targets,
+// which queues them in q. q must not be the queue of the stashes.
+func New(source storage.Backend, q queue.Queue, targets ...Target) *Replicator {
+	return &Replicator{source: source, targets: targets, q: q}
+}
+
+// Replicate copies mod@ver from the source storage to the targets that do
+// not have it yet. It is a queue.Handler, and the lag of a replication is
+// how long the job that it handles was queued. A KindNotFound error means
+// the source storage does not have the version, which was deleted since
+// it was queued.
+func (r *Replicator) Replicate(ctx context.Context, mod, ver string) (string, error) {
+	const op errors.Op = "replication.Replicate"
+	ctx, span := observ.StartSpan(ctx, op.String())
+	defer span.End()
+	lag := time.Duration(-1)
+	if job := queue.JobFromContext(ctx); job != nil && !job.EnqueuedAt.IsZero() {
+		lag = time.Since(job.EnqueuedAt)
+	}
+
+	var firstErr error
+	for _, t := range r.targets {
+		replicated, err := r.replicate(ctx, t, mod, ver)
+		switch {
+		case err != nil:
+			observ.RecordReplication(ctx, t.Name, "failure", 0)
+			if errors.IsNotFoundErr(err) {
+				return "", errors.E(op, err, errors.M(mod), errors.V(ver))
+			}
+			log.EntryFromContext(ctx).Warnf("replicating %s@%s to %s: %v", mod, ver, t.Name, err)
+			if firstErr == nil {
+				firstErr = err
+			}
+		case replicated:
+			observ.RecordReplication(ctx, t.Name, "success", lag)
+		default:
+			observ.RecordReplication(ctx, t.Name, "skipped", 0)
+		}
+	}
+	if firstErr != nil {
+		// the targets that have the version now are skipped on the next attempt.
+		return "", errors.E(op, firstErr, errors.M(mod), errors.V(ver))
+	}
+	return ver, nil
+}
+
+// replicate copies mod@ver to t, unless t has it already,
+// and reports whether it did.
+func (r *Replicator) replicate(ctx context.Context, t Target, mod, ver string) (bool, error) {
+	const op errors.Op = "replication.replicate"
+	ok, err := storage.WithChecker(t.Storage).Exists(ctx, mod, ver)
+	if err != nil {
+		return false, errors.E(op, err)
+	}
+	if ok {
+		return false, nil
+	}
+	info, err := r.source.Info(ctx, mod, ver)
+	if err != nil {
+		return false, errors.E(op, err)
+	}
+	gomod, err := r.source.GoMod(ctx, mod, ver)
+	if err != nil {
+		return false, errors.E(op, err)
+	}
+	zip, err := r.source.Zip(ctx, mod, ver)
+	if err != nil {
+		return false, errors.E(op, err)
+	}
+	defer func() { _ = zip.Close() }()
+	err = t.Storage.Save(ctx, mod, ver, gomod, zip, nil, info)
+	// another replicator saved it in the meantime.
+	if err != nil && !errors.Is(err, errors.KindAlreadyExists) {
+		return false, errors.E(op, err)
+	}
+	return err == nil, nil
+}
@@ -0,0 +1,158 @@
+package replication
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/gomods/athens/pkg/errors"
+	"github.com/gomods/athens/pkg/log"
+	"github.com/gomods/athens/pkg/queue"
+	queuemem "github.com/gomods/athens/pkg/queue/mem"
+	"github.com/gomods/athens/pkg/stash"
+	"github.com/gomods/athens/pkg/storage"
+	"github.com/gomods/athens/pkg/storage/compliance/fixtures"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	waitFor = 5 * time.Second
+	tick    = 10 * time.Millisecond
+)
+
+func TestReplicate(t *testing.T) {
+	source, dr, onPrem := fixtures.NewStorage(t), fixtures.NewStorage(t), fixtures.NewStorage(t)
+	ctx := t.Context()
+	fixtures.Save(t, source, "v1.0.0", "zip")
+	fixtures.Save(t, onPrem, "v1.0.0", "zip")
+	r := New(source, queuemem.New(), Target{Name: "dr", Storage: dr}, Target{Name: "on-prem", Storage: onPrem})
+
+	ver, err := r.Replicate(ctx, fixtures.Module, "v1.0.0")
+	require.NoError(t, err)
+	require.Equal(t, "v1.0.0", ver)
+	fixtures.RequireExists(t, dr, "v1.0.0", true)
+	info, err := dr.Info(ctx, fixtures.Module, "v1.0.0")
+	require.NoError(t, err)
+	require.Equal(t, []byte("v1.0.0"), info)
+
+	// versions that the source storage does not have are buried.
+	_, err = r.Replicate(ctx, fixtures.Module, "v2.0.0")
+	require.True(t, errors.IsNotFoundErr(err))
+}
+
+func TestReplicateFailure(t *testing.T) {
+	source, dr, broken := fixtures.NewStorage(t), fixtures.NewStorage(t), fixtures.NewStorage(t)
+	ctx := t.Context()
+	fixtures.Save(t, source, "v1.0.0", "zip")
+	r := New(source, queuemem.New(), Target{Name: "broken", Storage: failingSaver{broken}}, Target{Name: "dr", Storage: dr})
+
+	_, err := r.Replicate(ctx, fixtures.Module, "v1.0.0")
+	require.Error(t, err)
+	require.False(t, errors.IsNotFoundErr(err))
+	// the other targets are replicated to all the same.
+	fixtures.RequireExists(t, dr, "v1.0.0", true)
+}
+
+func TestReconcile(t *testing.T) {
+	source, dr, onPrem := fixtures.NewStorage(t), fixtures.NewStorage(t), fixtures.NewStorage(t)
+	ctx := t.Context()
+	for _, v := range []string{"v1.0.0", "v1.1.0", "v1.2.0"} {
+		fixtures.Save(t, source, v, "zip")
+	}
+	fixtures.Save(t, dr, "v1.0.0", "zip")
+	fixtures.Save(t, dr, "v1.1.0", "zip")
+	fixtures.Save(t, onPrem, "v1.0.0", "zip")
+	q := queuemem.New()
+	// the targets are listed, whether they have a catalog or not.
+	r := New(source, q, Target{Name: "dr", Storage: dr}, Target{Name: "on-prem", Storage: fixtures.NoCatalog{Backend: onPrem}})
+
+	queued, err := r.Reconcile(ctx)
+	require.NoError(t, err)
+	require.Equal(t, 2, queued)
+	for v, want := range map[string]bool{"v1.0.0": false, "v1.1.0": true, "v1.2.0": true} {
+		_, err := q.Get(ctx, fixtures.Module, v)
+		require.Equal(t, want, err == nil, v)
+	}
+
+	// processing the queue fills the gaps.
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		queue.Process(ctx, q, 1, queue.RetryPolicy{MaxAttempts: 1}, log.NoOpLogger(), r.Replicate)
+	}()
+	require.Eventually(t, func() bool {
+		job, err := q.Get(ctx, fixtures.Module, "v1.2.0")
+		return err == nil && job.State == queue.StateDone
+	}, waitFor, tick)
+	cancel()
+	<-done
+	for _, v := range []string{"v1.1.0", "v1.2.0"} {
+		fixtures.RequireExists(t, dr, v, true)
+		fixtures.RequireExists(t, onPrem, v, true)
+	}
+
+	queued, err = r.Reconcile(t.Context())
+	require.NoError(t, err)
+	require.Equal(t, 0, queued)
+}
+
+func TestReconciler(t *testing.T) {
+	source, dr := fixtures.NewStorage(t), fixtures.NewStorage(t)
+	ctx := t.Context()
+	fixtures.Save(t, source, "v1.0.0", "zip")
+	q := queuemem.New()
+	r := New(source, q, Target{Name: "dr", Storage: dr})
+	reconciler := lock{r.Reconciler(), storage.WithChecker(source)}
+	now := time.Now()
+	round := Round(now, time.Hour)
+
+	_, err := reconciler.Stash(ctx, RoundModule, round)
+	require.NoError(t, err)
+	job, err := q.Dequeue(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "v1.0.0", job.Version)
+	require.NoError(t, q.Bury(ctx, job))
+
+	// the round is done already, another instance does not reconcile it.
+	_, err = reconciler.Stash(ctx, RoundModule, round)
+	require.NoError(t, err)
+	job, err = q.Get(ctx, fixtures.Module, "v1.0.0")
+	require.NoError(t, err)
+	require.Equal(t, queue.StateDead, job.State)
+
+	// the next round is reconciled, and only its mark is kept.
+	next := Round(now.Add(time.Hour), time.Hour)
+	_, err = reconciler.Stash(ctx, RoundModule, next)
+	require.NoError(t, err)
+	job, err = q.Get(ctx, fixtures.Module, "v1.0.0")
+	require.NoError(t, err)
+	require.Equal(t, queue.StatePending, job.State)
+	vers, err := source.List(ctx, RoundModule)
+	require.NoError(t, err)
+	require.Equal(t, []string{next}, vers)
+}
+
+// lock stashes the versions that are not in storage yet,
+// as the distributed singleflights do.
+type lock struct {
+	stasher stash.Stasher
+	checker storage.Checker
+}
+
+func (l lock) Stash(ctx context.Context, mod, ver string) (string, error) {
+	ok, err := l.checker.Exists(ctx, mod, ver)
+	if err != nil || ok {
+		return ver, err
+	}
+	return l.stasher.Stash(ctx, mod, ver)
+}
+
+type failingSaver struct {
+	storage.Backend
+}
+
+func (failingSaver) Save(context.Context, string, string, []byte, io.Reader, []byte, []byte) error {
+	return errors.E("failingSaver.Save", "connection reset")
+}
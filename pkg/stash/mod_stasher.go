@@ -0,0 +1,95 @@
+package stash
+
+import (
+	"context"
+	"time"
+
+	"github.com/gomods/athens/pkg/errors"
+	"github.com/gomods/athens/pkg/log"
+	"github.com/gomods/athens/pkg/module"
+	"github.com/gomods/athens/pkg/observ"
+	"github.com/gomods/athens/pkg/storage"
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/sync/singleflight"
+)
+
+// ModStasher has the job of taking the .info and .mod files of a module
+// version from an upstream entity and stashing them to a Storage Backend,
+// leaving the zip to be stashed by a Stasher once it is requested.
+// Like a Stasher, it returns the semver version of what was requested.
+type ModStasher interface {
+	StashMod(ctx context.Context, mod, ver string) (string, error)
+}
+
+// NewModStasher returns a ModStasher if the fetcher implements module.ModFetcher
+// and the backend implements storage.MetadataSaver. Otherwise ok is false and
+// versions can only be stashed as a whole.
+//
+// Unlike stashing a zip, saving the same .info and .mod files twice is harmless,
+// so concurrent requests are only deduplicated within this process.
+func NewModStasher(f module.Fetcher, s storage.Backend, timeout time.Duration) (ms ModStasher, ok bool) {
+	fetcher, ok := f.(module.ModFetcher)
+	if !ok {
+		return nil, false
+	}
+	saver, ok := s.(storage.MetadataSaver)
+	if !ok {
+		return nil, false
+	}
+	return &modStasher{fetcher, s, saver, &singleflight.Group{}, timeout}, true
+}
+
+type modStasher struct {
+	fetcher module.ModFetcher
+	storage storage.Backend
+	saver   storage.MetadataSaver
+	sfg     *singleflight.Group
+	timeout time.Duration
+}
+
+func (s *modStasher) StashMod(ctx context.Context, mod, ver string) (string, error) {
+	const op errors.Op = "modStasher.StashMod"
+	ctx, span := observ.StartSpan(ctx, op.String())
+	defer span.End()
+	log.EntryFromContext(ctx).Debugf("saving %s@%s metadata to storage...", mod, ver)
+
+	semver_, err, _ := s.sfg.Do(mod+"###"+ver, func() (any, error) {
+		ctx, cancel := context.WithTimeout(trace.ContextWithSpan(context.Background(), span), stashTimeout(ctx, s.timeout))
+		defer cancel()
+		start := time.Now()
+		v, err := s.fetcher.FetchMod(ctx, mod, ver)
+		duration := time.Since(start)
+		if err != nil {
+			observ.RecordUpstreamFetch(ctx, "failure")
+			observ.RecordUpstreamFetchDuration(ctx, "failure", duration)
+			return "", errors.E(op, err)
+		}
+		observ.RecordUpstreamFetch(ctx, "success")
+		observ.RecordUpstreamFetchDuration(ctx, "success", duration)
+
+		if v.Semver != ver {
+			// do not rewrite the files of a version that
+			// is already there, it may be read right now.
+			_, err := s.storage.GoMod(ctx, mod, v.Semver)
+			if err == nil {
+				return v.Semver, nil
+			}
+			if !errors.IsNotFoundErr(err) {
+				return "", errors.E(op, err)
+			}
+		}
+		if err := s.saver.SaveMetadata(ctx, mod, v.Semver, v.Mod, v.Info); err != nil {
+			return "", errors.E(op, err)
+		}
+		return v.Semver, nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	semver, ok := semver_.(string)
+	if !ok {
+		return "", errors.E(op, "unexpected type assertion failure for semver", errors.KindUnexpected)
+	}
+	return semver, nil
+}
@@ -0,0 +1,61 @@
+package stash
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gomods/athens/pkg/errors"
+	"github.com/gomods/athens/pkg/index/nop"
+	"github.com/gomods/athens/pkg/storage"
+	"github.com/gomods/athens/pkg/storage/mem"
+	"github.com/stretchr/testify/require"
+)
+
+type mockModFetcher struct {
+	mockFetcher
+	modCalls int
+}
+
+func (mf *mockModFetcher) FetchMod(ctx context.Context, mod, ver string) (*storage.Version, error) {
+	mf.modCalls++
+	return &storage.Version{
+		Info:   []byte("info"),
+		Mod:    []byte("gomod"),
+		Semver: mf.ver,
+	}, nil
+}
+
+func TestModStasher(t *testing.T) {
+	ctx := t.Context()
+	strg, err := mem.NewStorage()
+	require.NoError(t, err)
+
+	_, ok := NewModStasher(&mockFetcher{ver: "v1.2.3"}, strg, time.Minute)
+	require.False(t, ok, "fetchers without FetchMod cannot stash metadata only")
+
+	mf := &mockModFetcher{mockFetcher: mockFetcher{ver: "v1.2.3"}}
+	ms, ok := NewModStasher(mf, strg, time.Minute)
+	require.True(t, ok)
+	newVer, err := ms.StashMod(ctx, "module", "master")
+	require.NoError(t, err)
+	require.Equal(t, "v1.2.3", newVer)
+
+	mod, err := strg.GoMod(ctx, "module", "v1.2.3")
+	require.NoError(t, err)
+	require.Equal(t, "gomod", string(mod))
+	_, err = strg.Zip(ctx, "module", "v1.2.3")
+	require.Equal(t, errors.KindNotFound, errors.Kind(err))
+	exists, err := storage.WithChecker(strg).Exists(ctx, "module", "v1.2.3")
+	require.NoError(t, err)
+	require.False(t, exists)
+
+	// a later stash of the whole version completes it.
+	newVer, err = New(mf, strg, nop.New(), time.Minute).Stash(ctx, "module", "v1.2.3")
+	require.NoError(t, err)
+	require.Equal(t, "v1.2.3", newVer)
+	exists, err = storage.WithChecker(strg).Exists(ctx, "module", "v1.2.3")
+	require.NoError(t, err)
+	require.True(t, exists)
+	require.Equal(t, 1, mf.modCalls)
+}
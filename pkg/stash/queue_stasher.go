@@ -0,0 +1,72 @@
+package stash
+
+import (
+	"context"
+	"time"
+
+	"github.com/gomods/athens/pkg/errors"
+	"github.com/gomods/athens/pkg/observ"
+	"github.com/gomods/athens/pkg/queue"
+)
+
+// NewQueueStasher returns a Stasher that leaves stashing to the workers
+// draining q. Stash enqueues the module version and waits up to timeout
+// for a worker to handle it, so that the caller can serve it from storage
+// afterwards without fetching anything itself.
+func NewQueueStasher(q queue.Queue, timeout time.Duration) Stasher {
+	return &queueStasher{q: q, timeout: timeout, minPoll: 50 * time.Millisecond, maxPoll: time.Second}
+}
+
+type queueStasher struct {
+	q       queue.Queue
+	timeout time.Duration
+	minPoll time.Duration
+	maxPoll time.Duration
+}
+
+func (s *queueStasher) Stash(ctx context.Context, mod, ver string) (string, error) {
+	const op errors.Op = "queueStasher.Stash"
+	ctx, span := observ.StartSpan(ctx, op.String())
+	defer span.End()
+
+	// remember how often the job failed already, if it is being retried,
+	// to tell the failures that happen while we wait.
+	attempts := 0
+	job, err := s.q.Get(ctx, mod, ver)
+	if err == nil && (job.State == queue.StatePending || job.State == queue.StateRunning) {
+		attempts = job.Attempts
+	}
+	if err := s.q.Enqueue(ctx, mod, ver); err != nil {
+		return "", errors.E(op, err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, stashTimeout(ctx, s.timeout))
+	defer cancel()
+	poll := s.minPoll
+	for {
+		job, err := s.q.Get(ctx, mod, ver)
+		if err != nil {
+			return "", errors.E(op, err)
+		}
+		switch {
+		case job.State == queue.StateDone:
+			return job.Semver, nil
+		case job.State == queue.StateDead || job.Attempts > attempts:
+			// the job is retried in the background, if it is not dead.
+			return "", errors.E(op, errors.M(mod), errors.V(ver), job.LastError, jobErrorKind(job))
+		}
+		select {
+		case <-time.After(poll):
+		case <-ctx.Done():
+			return "", errors.E(op, errors.M(mod), errors.V(ver), "timed out waiting for the stash job", errors.KindGatewayTimeout)
+		}
+		poll = min(2*poll, s.maxPoll)
+	}
+}
+
+func jobErrorKind(job *queue.Job) int {
+	if job.ErrorKind == 0 {
+		return errors.KindUnexpected
+	}
+	return job.ErrorKind
+}
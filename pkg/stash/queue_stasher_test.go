@@ -0,0 +1,56 @@
+package stash
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gomods/athens/pkg/errors"
+	"github.com/gomods/athens/pkg/log"
+	"github.com/gomods/athens/pkg/queue"
+	"github.com/gomods/athens/pkg/queue/mem"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueueStasher(t *testing.T) {
+	ctx, cancel := context.WithCancel(t.Context())
+	q := mem.New()
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	defer cancel()
+	wg.Go(func() {
+		policy := queue.RetryPolicy{MaxAttempts: 3, MinBackoff: time.Hour, MaxBackoff: time.Hour}
+		queue.Process(ctx, q, 1, policy, log.NoOpLogger(), func(ctx context.Context, mod, ver string) (string, error) {
+			switch mod {
+			case "missing":
+				return "", errors.E("op", "not found", errors.KindNotFound)
+			case "broken":
+				return "", errors.E("op", "connection reset")
+			}
+			return "v1.2.3", nil
+		})
+	})
+	s := NewQueueStasher(q, time.Minute)
+
+	newVer, err := s.Stash(t.Context(), "module", "master")
+	require.NoError(t, err)
+	require.Equal(t, "v1.2.3", newVer)
+
+	_, err = s.Stash(t.Context(), "missing", "v1.0.0")
+	require.Equal(t, errors.KindNotFound, errors.Kind(err))
+
+	// a failure is reported right away, while the job is retried in the background.
+	_, err = s.Stash(t.Context(), "broken", "v1.0.0")
+	require.Equal(t, errors.KindUnexpected, errors.Kind(err))
+	require.Contains(t, err.Error(), "connection reset")
+	job, err := q.Get(t.Context(), "broken", "v1.0.0")
+	require.NoError(t, err)
+	require.Equal(t, queue.StatePending, job.State)
+}
+
+func TestQueueStasherTimeout(t *testing.T) {
+	s := NewQueueStasher(mem.New(), 10*time.Millisecond)
+	_, err := s.Stash(t.Context(), "module", "v1.0.0")
+	require.Equal(t, errors.KindGatewayTimeout, errors.Kind(err))
+}
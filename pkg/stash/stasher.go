@@ -56,7 +56,7 @@ func (s *stasher) Stash(ctx context.Context, mod, ver string) (string, error) {
 	semver_, err, _ := s.sfg.Do(mod+"###"+ver, func() (any, error) {
 		// create a new context that ditches whatever deadline the caller passed
 		// but keep the tracing info so that we can properly trace the whole thing.
-		ctx, cancel := context.WithTimeout(trace.ContextWithSpan(context.Background(), span), s.timeout)
+		ctx, cancel := context.WithTimeout(trace.ContextWithSpan(context.Background(), span), stashTimeout(ctx, s.timeout))
 		defer cancel()
 		v, err := s.fetchModule(ctx, mod, ver)
 		if err != nil {
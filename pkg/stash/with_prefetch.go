@@ -0,0 +1,168 @@
+package stash
+
+import (
+	"context"
+	"sync"
+
+	"github.com/gomods/athens/pkg/config"
+	"github.com/gomods/athens/pkg/download/mode"
+	"github.com/gomods/athens/pkg/errors"
+	"github.com/gomods/athens/pkg/log"
+	"github.com/gomods/athens/pkg/module"
+	"github.com/gomods/athens/pkg/observ"
+	"github.com/gomods/athens/pkg/storage"
+	"golang.org/x/mod/modfile"
+	"golang.org/x/mod/semver"
+)
+
+type prefetchDepthKey struct{}
+
+// prefetchBacklog is how many prefetches may wait for a worker.
+// The requirements found once it is full are not prefetched.
+const prefetchBacklog = 10_000
+
+type withPrefetch struct {
+	stasher Stasher
+	storage storage.Backend
+	checker storage.Checker
+	depth   int
+	workers int
+	filter  *module.Filter
+	df      *mode.DownloadFile
+
+	// jobs holds the prefetches waiting for one of the workers,
+	// which are started with the first stash.
+	jobs  chan func()
+	start sync.Once
+
+	mu       sync.Mutex
+	inFlight map[string]bool
+}
+
+// WithPrefetch returns a stasher that, once a module version is stashed,
+// stashes the module versions it requires in the background, down to
+// depth levels of requirements. The requirements are read from the go.mod
+// that the stash saved to s. The go.mod of a module that uses go 1.17 or
+// later lists its whole build list, so the requirements of its requirements
+// are not walked. Requirements that are excluded by filter, which may be
+// nil, or whose download mode in df does not stash them are left out.
+//
+// Prefetches are run by a pool of workers, workers at a time. They go
+// through the stashers that WithPrefetch wraps, so it should wrap the
+// others, to have them limited by WithPool as any other stash.
+func WithPrefetch(depth, workers int, s storage.Backend, filter *module.Filter, df *mode.DownloadFile) Wrapper {
+	return func(st Stasher) Stasher {
+		return &withPrefetch{
+			stasher:  st,
+			storage:  s,
+			checker:  storage.WithChecker(s),
+			depth:    depth,
+			workers:  max(workers, 1),
+			filter:   filter,
+			df:       df,
+			jobs:     make(chan func(), prefetchBacklog),
+			inFlight: map[string]bool{},
+		}
+	}
+}
+
+func (p *withPrefetch) Stash(ctx context.Context, mod, ver string) (string, error) {
+	const op errors.Op = "stash.Prefetch"
+	ctx, span := observ.StartSpan(ctx, op.String())
+	defer span.End()
+	newVer, err := p.stasher.Stash(ctx, mod, ver)
+	if err != nil {
+		return "", errors.E(op, err)
+	}
+	// the depth of mod is 0 unless it is being prefetched itself.
+	depth, _ := ctx.Value(prefetchDepthKey{}).(int)
+	if depth < p.depth {
+		ctx := context.WithoutCancel(ctx)
+		p.enqueue(func() { p.prefetch(ctx, mod, newVer, depth+1) })
+	}
+	return newVer, nil
+}
+
+// enqueue hands job to the workers, unless the backlog is full,
+// and reports whether it did.
+func (p *withPrefetch) enqueue(job func()) bool {
+	p.start.Do(func() {
+		for range p.workers {
+			go func() {
+				for job := range p.jobs {
+					job()
+				}
+			}()
+		}
+	})
+	select {
+	case p.jobs <- job:
+		return true
+	default:
+		return false
+	}
+}
+
+// prefetch stashes the requirements of mod@ver, which are at depth.
+func (p *withPrefetch) prefetch(ctx context.Context, mod, ver string, depth int) {
+	const op errors.Op = "stash.prefetch"
+	lggr := log.EntryFromContext(ctx)
+	gomod, err := p.storage.GoMod(ctx, mod, ver)
+	if err != nil {
+		lggr.SystemErr(errors.E(op, err))
+		return
+	}
+	f, err := modfile.ParseLax("go.mod", gomod, nil)
+	if err != nil {
+		lggr.SystemErr(errors.E(op, errors.M(mod), errors.V(ver), err))
+		return
+	}
+	if f.Go != nil && semver.Compare("v"+f.Go.Version, "v1.17") >= 0 {
+		depth = p.depth
+	}
+	ctx = context.WithValue(ctx, prefetchDepthKey{}, depth)
+	for _, r := range f.Require {
+		req := r.Mod
+		if !wanted(p.filter, p.df, req.Path, req.Version) || !p.begin(req.Path, req.Version) {
+			continue
+		}
+		queued := p.enqueue(func() {
+			defer p.finish(req.Path, req.Version)
+			exists, err := p.checker.Exists(ctx, req.Path, req.Version)
+			if err != nil {
+				lggr.SystemErr(errors.E(op, err))
+				return
+			}
+			if exists {
+				return
+			}
+			lggr.Debugf("prefetching %s@%s, required by %s@%s", req.Path, req.Version, mod, ver)
+			if _, err := p.Stash(ctx, req.Path, req.Version); err != nil {
+				lggr.Infof("could not prefetch %s@%s: %v", req.Path, req.Version, err)
+			}
+		})
+		if !queued {
+			p.finish(req.Path, req.Version)
+			lggr.Debugf("not prefetching %s@%s, too many prefetches are waiting", req.Path, req.Version)
+		}
+	}
+}
+
+// begin reports whether mod@ver is not being prefetched already,
+// and marks it as being prefetched if so.
+func (p *withPrefetch) begin(mod, ver string) bool {
+	key := config.FmtModVer(mod, ver)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.inFlight[key] {
+		return false
+	}
+	p.inFlight[key] = true
+	return true
+}
+
+func (p *withPrefetch) finish(mod, ver string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.inFlight, config.FmtModVer(mod, ver))
+}
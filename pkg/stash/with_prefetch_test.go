@@ -0,0 +1,117 @@
+package stash
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gomods/athens/pkg/download/mode"
+	"github.com/gomods/athens/pkg/errors"
+	"github.com/gomods/athens/pkg/module"
+	"github.com/gomods/athens/pkg/storage"
+	"github.com/gomods/athens/pkg/storage/mem"
+	"github.com/stretchr/testify/require"
+)
+
+// goModStasher saves the go.mod files of mods, keyed by mod@ver,
+// and records what it stashed.
+type goModStasher struct {
+	s    storage.Backend
+	mods map[string]string
+
+	mu      sync.Mutex
+	stashed []string
+}
+
+func (g *goModStasher) Stash(ctx context.Context, mod, ver string) (string, error) {
+	gomod, ok := g.mods[mod+"@"+ver]
+	if !ok {
+		return "", errors.E("goModStasher.Stash", errors.M(mod), errors.V(ver), errors.KindNotFound)
+	}
+	g.mu.Lock()
+	g.stashed = append(g.stashed, mod+"@"+ver)
+	g.mu.Unlock()
+	return ver, g.s.Save(ctx, mod, ver, []byte(gomod), strings.NewReader("zip"), nil, []byte("info"))
+}
+
+func (g *goModStasher) Stashed() []string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return append([]string(nil), g.stashed...)
+}
+
+func TestWithPrefetch(t *testing.T) {
+	mods := map[string]string{
+		// unpruned modules are walked down to the depth.
+		"a@v1.0.0": "module a\n\ngo 1.16\n\nrequire (\n\tb v1.0.0\n\tnone v1.0.0\n\texcluded v1.0.0\n)\n",
+		"b@v1.0.0": "module b\n\ngo 1.16\n\nrequire c v1.0.0\n",
+		"c@v1.0.0": "module c\n\nrequire d v1.0.0\n",
+		"d@v1.0.0": "module d\n",
+		// pruned modules list their whole build list.
+		"p@v1.0.0": "module p\n\ngo 1.21\n\nrequire (\n\tq v1.0.0\n\tr v1.0.0\n)\n",
+		"q@v1.0.0": "module q\n\nrequire s v1.0.0\n",
+		"r@v1.0.0": "module r\n",
+		"s@v1.0.0": "module s\n",
+		// requirements that are cached already are not stashed again.
+		"cached@v1.0.0":   "module cached\n",
+		"e@v1.0.0":        "module e\n\nrequire cached v1.0.0\n",
+		"none@v1.0.0":     "module none\n",
+		"excluded@v1.0.0": "module excluded\n",
+	}
+	df := &mode.DownloadFile{Mode: mode.Sync, Paths: []*mode.DownloadPath{{Pattern: "none", Mode: mode.None}}}
+	filterFile := filepath.Join(t.TempDir(), "filter.conf")
+	require.NoError(t, os.WriteFile(filterFile, []byte("- excluded\n"), 0o600))
+	filter, err := module.NewFilter(filterFile)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name string
+		mod  string
+		want []string
+	}{
+		{name: "unpruned", mod: "a", want: []string{"a@v1.0.0", "b@v1.0.0", "c@v1.0.0"}},
+		{name: "pruned", mod: "p", want: []string{"p@v1.0.0", "q@v1.0.0", "r@v1.0.0"}},
+		{name: "cached", mod: "e", want: []string{"e@v1.0.0"}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			s, err := mem.NewStorage()
+			require.NoError(t, err)
+			g := &goModStasher{s: s, mods: mods}
+			_, err = g.Stash(t.Context(), "cached", "v1.0.0")
+			require.NoError(t, err)
+			g.stashed = nil
+
+			st := WithPrefetch(2, 2, s, filter, df)(g)
+			ver, err := st.Stash(t.Context(), tc.mod, "v1.0.0")
+			require.NoError(t, err)
+			require.Equal(t, "v1.0.0", ver)
+			require.Eventually(t, func() bool {
+				return len(g.Stashed()) >= len(tc.want)
+			}, time.Second, 10*time.Millisecond)
+			// leave time for the prefetches that should not happen.
+			time.Sleep(100 * time.Millisecond)
+			require.ElementsMatch(t, tc.want, g.Stashed())
+		})
+	}
+}
+
+func TestPrefetchBacklog(t *testing.T) {
+	p := WithPrefetch(1, 1, nil, nil, nil)(nil).(*withPrefetch)
+	p.jobs = make(chan func(), 1)
+	running, release := make(chan struct{}), make(chan struct{})
+	defer close(release)
+
+	require.True(t, p.enqueue(func() {
+		close(running)
+		<-release
+	}))
+	<-running
+	// the only worker is busy, one prefetch waits for it and the next is dropped.
+	require.True(t, p.enqueue(func() {}))
+	require.False(t, p.enqueue(func() {}))
+}
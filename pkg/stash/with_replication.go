@@ -0,0 +1,40 @@
+package stash
+
+import (
+	"context"
+
+	"github.com/gomods/athens/pkg/errors"
+	"github.com/gomods/athens/pkg/log"
+	"github.com/gomods/athens/pkg/observ"
+	"github.com/gomods/athens/pkg/queue"
+)
+
+type withReplication struct {
+	stasher Stasher
+	q       queue.Queue
+}
+
+// WithReplication returns a stasher that queues every module version
+// it stashes in q, the queue of a replication.Replicator, so that it is
+// replicated to secondary storage in the background. A version that
+// cannot be queued is only logged, since the stash succeeded and the
+// reconciliation of the replicator catches up with it.
+func WithReplication(q queue.Queue) Wrapper {
+	return func(st Stasher) Stasher {
+		return &withReplication{stasher: st, q: q}
+	}
+}
+
+func (r *withReplication) Stash(ctx context.Context, mod, ver string) (string, error) {
+	const op errors.Op = "stash.Replication"
+	ctx, span := observ.StartSpan(ctx, op.String())
+	defer span.End()
+	newVer, err := r.stasher.Stash(ctx, mod, ver)
+	if err != nil {
+		return "", err
+	}
+	if err := r.q.Enqueue(context.WithoutCancel(ctx), mod, newVer); err != nil {
+		log.EntryFromContext(ctx).SystemErr(errors.E(op, err, errors.M(mod), errors.V(newVer)))
+	}
+	return newVer, nil
+}
@@ -0,0 +1,30 @@
+package stash
+
+import (
+	"testing"
+
+	"github.com/gomods/athens/pkg/queue"
+	"github.com/gomods/athens/pkg/queue/mem"
+	storagemem "github.com/gomods/athens/pkg/storage/mem"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithReplication(t *testing.T) {
+	s, err := storagemem.NewStorage()
+	require.NoError(t, err)
+	q := mem.New()
+	st := WithReplication(q)(&goModStasher{s: s, mods: map[string]string{"a@v1.0.0": "module a\n"}})
+
+	ver, err := st.Stash(t.Context(), "a", "v1.0.0")
+	require.NoError(t, err)
+	require.Equal(t, "v1.0.0", ver)
+	job, err := q.Get(t.Context(), "a", "v1.0.0")
+	require.NoError(t, err)
+	require.Equal(t, queue.StatePending, job.State)
+
+	// failed stashes are not replicated.
+	_, err = st.Stash(t.Context(), "b", "v1.0.0")
+	require.Error(t, err)
+	_, err = q.Get(t.Context(), "b", "v1.0.0")
+	require.Error(t, err)
+}
@@ -0,0 +1,60 @@
+package stash
+
+import (
+	"context"
+	"time"
+
+	"github.com/gomods/athens/pkg/download/mode"
+)
+
+type stashTimeoutKey struct{}
+
+type withTimeouts struct {
+	stasher Stasher
+	df      *mode.DownloadFile
+}
+
+// WithTimeouts returns a stasher that stashes the modules that match a
+// download path with a stashTimeout in df within that timeout, rather than
+// the one that the stashers it wraps were created with. It should be the
+// first wrapper, so that the stashes that others make, such as prefetches,
+// go through it too.
+func WithTimeouts(df *mode.DownloadFile) Wrapper {
+	return func(st Stasher) Stasher {
+		return &withTimeouts{stasher: st, df: df}
+	}
+}
+
+func (s *withTimeouts) Stash(ctx context.Context, mod, ver string) (string, error) {
+	return s.stasher.Stash(withStashTimeout(ctx, s.df, mod), mod, ver)
+}
+
+type modWithTimeouts struct {
+	modStasher ModStasher
+	df         *mode.DownloadFile
+}
+
+// ModWithTimeouts is WithTimeouts for a ModStasher.
+func ModWithTimeouts(ms ModStasher, df *mode.DownloadFile) ModStasher {
+	return &modWithTimeouts{modStasher: ms, df: df}
+}
+
+func (s *modWithTimeouts) StashMod(ctx context.Context, mod, ver string) (string, error) {
+	return s.modStasher.StashMod(withStashTimeout(ctx, s.df, mod), mod, ver)
+}
+
+// withStashTimeout sets the stashTimeout of mod in ctx, which is 0 if no
+// download path sets it, so that the timeout of a module that another
+// one requires is not inherited when it is prefetched.
+func withStashTimeout(ctx context.Context, df *mode.DownloadFile, mod string) context.Context {
+	return context.WithValue(ctx, stashTimeoutKey{}, df.StashTimeout(mod, 0))
+}
+
+// stashTimeout returns the timeout that WithTimeouts set in
+// ctx for the stash, or def if it did not set any.
+func stashTimeout(ctx context.Context, def time.Duration) time.Duration {
+	if timeout, _ := ctx.Value(stashTimeoutKey{}).(time.Duration); timeout > 0 {
+		return timeout
+	}
+	return def
+}
@@ -0,0 +1,39 @@
+package stash
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gomods/athens/pkg/download/mode"
+	"github.com/gomods/athens/pkg/index/nop"
+	"github.com/gomods/athens/pkg/storage"
+	"github.com/stretchr/testify/require"
+)
+
+type deadlineFetcher struct {
+	mockFetcher
+	timeouts map[string]time.Duration
+}
+
+func (f *deadlineFetcher) Fetch(ctx context.Context, mod, ver string) (*storage.Version, error) {
+	deadline, _ := ctx.Deadline()
+	f.timeouts[mod] = time.Until(deadline).Round(time.Minute)
+	return f.mockFetcher.Fetch(ctx, mod, ver)
+}
+
+func TestWithTimeouts(t *testing.T) {
+	timeout := 1200
+	df := &mode.DownloadFile{Mode: mode.Sync, Paths: []*mode.DownloadPath{
+		{Pattern: "git.mycompany.com/*", Mode: mode.Sync, StashTimeout: &timeout},
+	}}
+	f := &deadlineFetcher{mockFetcher: mockFetcher{ver: "v1.0.0"}, timeouts: map[string]time.Duration{}}
+	s := New(f, &mockStorage{}, nop.New(), 10*time.Minute, WithTimeouts(df))
+
+	_, err := s.Stash(t.Context(), "git.mycompany.com/lib", "v1.0.0")
+	require.NoError(t, err)
+	_, err = s.Stash(t.Context(), "github.com/pkg/errors", "v1.0.0")
+	require.NoError(t, err)
+	require.Equal(t, 20*time.Minute, f.timeouts["git.mycompany.com/lib"])
+	require.Equal(t, 10*time.Minute, f.timeouts["github.com/pkg/errors"])
+}
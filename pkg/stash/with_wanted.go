@@ -0,0 +1,72 @@
+package stash
+
+import (
+	"context"
+
+	"github.com/gomods/athens/pkg/download/mode"
+	"github.com/gomods/athens/pkg/errors"
+	"github.com/gomods/athens/pkg/module"
+	"github.com/gomods/athens/pkg/storage"
+)
+
+type withWanted struct {
+	stasher Stasher
+	filter  *module.Filter
+	df      *mode.DownloadFile
+}
+
+// WithWanted returns a stasher that only stashes the module versions that
+// would be stashed if a client requested them: the ones that filter, which
+// may be nil, neither excludes nor sends to the VCS directly, and whose
+// download mode in df stashes them. It answers a KindNotFound error for
+// the others. It is meant for the stashes that Athens starts on its own,
+// rather than for a client.
+func WithWanted(filter *module.Filter, df *mode.DownloadFile) Wrapper {
+	return func(st Stasher) Stasher {
+		return &withWanted{stasher: st, filter: filter, df: df}
+	}
+}
+
+func (w *withWanted) Stash(ctx context.Context, mod, ver string) (string, error) {
+	const op errors.Op = "stash.Wanted"
+	if !wanted(w.filter, w.df, mod, ver) {
+		return "", errors.E(op, "the module is not stashed by this proxy", errors.M(mod), errors.V(ver), errors.KindNotFound)
+	}
+	return w.stasher.Stash(ctx, mod, ver)
+}
+
+type wantedMods struct {
+	fetcher module.ModFetcher
+	filter  *module.Filter
+	df      *mode.DownloadFile
+}
+
+// WantedMods returns a ModFetcher that only fetches the .info and .mod files
+// of the module versions that WithWanted stashes, and answers a KindNotFound
+// error for the others, so that only those reach upstream.
+func WantedMods(mf module.ModFetcher, filter *module.Filter, df *mode.DownloadFile) module.ModFetcher {
+	return &wantedMods{fetcher: mf, filter: filter, df: df}
+}
+
+func (w *wantedMods) FetchMod(ctx context.Context, mod, ver string) (*storage.Version, error) {
+	const op errors.Op = "stash.WantedMods"
+	if !wanted(w.filter, w.df, mod, ver) {
+		return nil, errors.E(op, "the module is not stashed by this proxy", errors.M(mod), errors.V(ver), errors.KindNotFound)
+	}
+	return w.fetcher.FetchMod(ctx, mod, ver)
+}
+
+// wanted reports whether mod@ver would be stashed if it were requested.
+func wanted(filter *module.Filter, df *mode.DownloadFile, mod, ver string) bool {
+	if filter != nil {
+		switch filter.Rule(mod, ver) {
+		case module.Exclude, module.Direct:
+			return false
+		}
+	}
+	switch df.Match(mod) {
+	case mode.None, mode.Redirect:
+		return false
+	}
+	return true
+}
@@ -0,0 +1,47 @@
+package stash
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gomods/athens/pkg/download/mode"
+	"github.com/gomods/athens/pkg/errors"
+	"github.com/gomods/athens/pkg/module"
+	"github.com/gomods/athens/pkg/storage/mem"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithWanted(t *testing.T) {
+	s, err := mem.NewStorage()
+	require.NoError(t, err)
+	g := &goModStasher{s: s, mods: map[string]string{
+		"a@v1.0.0":        "module a\n",
+		"none@v1.0.0":     "module none\n",
+		"excluded@v1.0.0": "module excluded\n",
+	}}
+	df := &mode.DownloadFile{Mode: mode.Sync, Paths: []*mode.DownloadPath{{Pattern: "none", Mode: mode.None}}}
+	filterFile := filepath.Join(t.TempDir(), "filter.conf")
+	require.NoError(t, os.WriteFile(filterFile, []byte("- excluded\n"), 0o600))
+	filter, err := module.NewFilter(filterFile)
+	require.NoError(t, err)
+	st := WithWanted(filter, df)(g)
+
+	_, err = st.Stash(t.Context(), "a", "v1.0.0")
+	require.NoError(t, err)
+	for _, mod := range []string{"none", "excluded"} {
+		_, err = st.Stash(t.Context(), mod, "v1.0.0")
+		require.True(t, errors.IsNotFoundErr(err), mod)
+	}
+	require.Equal(t, []string{"a@v1.0.0"}, g.Stashed())
+
+	mf := &mockModFetcher{mockFetcher: mockFetcher{ver: "v1.0.0"}}
+	wm := WantedMods(mf, filter, df)
+	_, err = wm.FetchMod(t.Context(), "a", "v1.0.0")
+	require.NoError(t, err)
+	for _, mod := range []string{"none", "excluded"} {
+		_, err = wm.FetchMod(t.Context(), mod, "v1.0.0")
+		require.True(t, errors.IsNotFoundErr(err), mod)
+	}
+	require.Equal(t, 1, mf.modCalls)
+}
@@ -0,0 +1,293 @@
+package stash
+
+import (
+	"context"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/gomods/athens/pkg/config"
+	"github.com/gomods/athens/pkg/errors"
+	"github.com/gomods/athens/pkg/module"
+	"github.com/gomods/athens/pkg/observ"
+	"github.com/gomods/athens/pkg/storage"
+)
+
+// errSpoolAborted is returned to readers of a spool
+// whose upstream stopped being read by everyone.
+var errSpoolAborted = errors.E("stash.zipSpool", "zip stream aborted")
+
+// ZipStreams lets the zip of a module version be read while it is still being
+// stashed, instead of after it is saved and read back from storage.
+//
+// The fetcher returned by Fetcher spools every zip it fetches to a temporary
+// file, which storage and any number of Follow callers read from at their own
+// pace. Readers do not depend on each other: a client going away does not stop
+// the upload and a failing upload does not cut off the clients. A failing
+// upstream is reported to every reader.
+type ZipStreams struct {
+	dir string
+
+	mu     sync.Mutex
+	spools map[string]*zipSpool
+	// changed is closed and replaced every time a spool is added.
+	changed chan struct{}
+}
+
+// NewZipStreams returns a ZipStreams that spools zips to files in dir,
+// or in the default directory for temporary files if dir is empty.
+func NewZipStreams(dir string) *ZipStreams {
+	return &ZipStreams{dir: dir, spools: map[string]*zipSpool{}, changed: make(chan struct{})}
+}
+
+// Fetcher wraps f so that the zips it fetches can be followed.
+func (z *ZipStreams) Fetcher(f module.Fetcher) module.Fetcher {
+	return &spoolingFetcher{f, z}
+}
+
+// Follow returns a reader of the zip of mod@ver as soon as it is being fetched
+// by the wrapped fetcher. It gives up and returns false once done is closed or
+// ctx is done without the zip being fetched, as well as when the zip is fetched
+// but nobody needs it anymore.
+func (z *ZipStreams) Follow(ctx context.Context, mod, ver string, done <-chan struct{}) (storage.SizeReadCloser, bool) {
+	key := config.FmtModVer(mod, ver)
+	for {
+		z.mu.Lock()
+		sp, ok := z.spools[key]
+		changed := z.changed
+		z.mu.Unlock()
+		if ok {
+			r, ok := sp.follow()
+			return r, ok
+		}
+		select {
+		case <-changed:
+		case <-done:
+			return nil, false
+		case <-ctx.Done():
+			return nil, false
+		}
+	}
+}
+
+func (z *ZipStreams) add(sp *zipSpool, keys ...string) {
+	z.mu.Lock()
+	defer z.mu.Unlock()
+	for _, key := range keys {
+		if _, ok := z.spools[key]; !ok {
+			z.spools[key] = sp
+		}
+	}
+	close(z.changed)
+	z.changed = make(chan struct{})
+}
+
+func (z *ZipStreams) remove(sp *zipSpool) {
+	z.mu.Lock()
+	defer z.mu.Unlock()
+	for key, s := range z.spools {
+		if s == sp {
+			delete(z.spools, key)
+		}
+	}
+}
+
+type spoolingFetcher struct {
+	fetcher module.Fetcher
+	streams *ZipStreams
+}
+
+func (f *spoolingFetcher) Fetch(ctx context.Context, mod, ver string) (*storage.Version, error) {
+	const op errors.Op = "spoolingFetcher.Fetch"
+	ctx, span := observ.StartSpan(ctx, op.String())
+	defer span.End()
+
+	file, err := os.CreateTemp(f.streams.dir, "athens-zip")
+	if err != nil {
+		// the zip can still be stashed, it just cannot be followed.
+		return f.fetcher.Fetch(ctx, mod, ver)
+	}
+	// The upstream may be read after the caller is done with the zip,
+	// for as long as others follow it, so only keep the deadline.
+	fetchCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	if deadline, ok := ctx.Deadline(); ok {
+		var cancelDeadline context.CancelFunc
+		fetchCtx, cancelDeadline = context.WithDeadline(fetchCtx, deadline)
+		cancelFetch := cancel
+		cancel = func() {
+			cancelDeadline()
+			cancelFetch()
+		}
+	}
+	v, err := f.fetcher.Fetch(fetchCtx, mod, ver)
+	if err != nil {
+		cancel()
+		_ = file.Close()
+		_ = os.Remove(file.Name())
+		return nil, errors.E(op, err)
+	}
+
+	size := int64(-1)
+	if s, ok := v.Zip.(interface{ Size() int64 }); ok {
+		size = s.Size()
+	}
+	sp := &zipSpool{file: file, size: size, upstream: v.Zip, cancel: cancel, refs: 1}
+	sp.cond = sync.NewCond(&sp.mu)
+	stashReader, _ := sp.follow()
+	f.streams.add(sp, config.FmtModVer(mod, ver), config.FmtModVer(mod, v.Semver))
+	go func() {
+		sp.pump()
+		f.streams.remove(sp)
+		sp.release()
+	}()
+	v.Zip = stashReader
+	return v, nil
+}
+
+// zipSpool copies a zip from upstream to a temporary file that readers follow.
+type zipSpool struct {
+	file      *os.File
+	size      int64
+	upstream  io.ReadCloser
+	cancel    context.CancelFunc
+	closeOnce sync.Once
+
+	mu      sync.Mutex
+	cond    *sync.Cond
+	written int64
+	done    bool
+	err     error
+	readers int
+	// refs counts the readers and the pump, the file is removed once it drops to zero.
+	refs    int
+	aborted bool
+	closed  bool
+}
+
+// pump copies upstream to the file until it is read completely,
+// it fails, or there are no readers left.
+func (sp *zipSpool) pump() {
+	defer sp.closeUpstream()
+	buf := make([]byte, 32<<10)
+	for {
+		n, err := sp.upstream.Read(buf)
+		if n > 0 {
+			if _, werr := sp.file.WriteAt(buf[:n], sp.written); werr != nil {
+				n, err = 0, werr
+			}
+		}
+		sp.mu.Lock()
+		sp.written += int64(n)
+		if sp.aborted {
+			err = errSpoolAborted
+		}
+		if err != nil {
+			sp.done = true
+			if err != io.EOF {
+				sp.err = err
+			}
+		}
+		sp.cond.Broadcast()
+		sp.mu.Unlock()
+		if err != nil {
+			return
+		}
+	}
+}
+
+// closeUpstream stops fetching the zip, which also unblocks a pending read.
+func (sp *zipSpool) closeUpstream() {
+	sp.closeOnce.Do(func() {
+		sp.cancel()
+		_ = sp.upstream.Close()
+	})
+}
+
+// follow returns a new reader of the spool,
+// unless it has no readers left or has failed.
+func (sp *zipSpool) follow() (*spoolReader, bool) {
+	sp.mu.Lock()
+	defer sp.mu.Unlock()
+	if sp.closed || sp.aborted || sp.err != nil {
+		return nil, false
+	}
+	sp.readers++
+	sp.refs++
+	return &spoolReader{sp: sp}, true
+}
+
+func (sp *zipSpool) release() {
+	sp.mu.Lock()
+	defer sp.mu.Unlock()
+	sp.refs--
+	if sp.refs == 0 {
+		sp.closed = true
+		_ = sp.file.Close()
+		_ = os.Remove(sp.file.Name())
+	}
+}
+
+type spoolReader struct {
+	sp     *zipSpool
+	off    int64
+	closed bool
+}
+
+// Read blocks until more of the zip is fetched.
+func (r *spoolReader) Read(p []byte) (int, error) {
+	sp := r.sp
+	sp.mu.Lock()
+	for r.off >= sp.written && !sp.done {
+		sp.cond.Wait()
+	}
+	written, err := sp.written, sp.err
+	sp.mu.Unlock()
+	if r.off < written {
+		if avail := written - r.off; int64(len(p)) > avail {
+			p = p[:avail]
+		}
+		n, err := sp.file.ReadAt(p, r.off)
+		r.off += int64(n)
+		if err == io.EOF {
+			err = nil
+		}
+		return n, err
+	}
+	if err != nil {
+		return 0, err
+	}
+	return 0, io.EOF
+}
+
+// Size returns the size of the zip, or -1 if it is not known yet.
+func (r *spoolReader) Size() int64 {
+	sp := r.sp
+	sp.mu.Lock()
+	defer sp.mu.Unlock()
+	if sp.done && sp.err == nil {
+		return sp.written
+	}
+	return sp.size
+}
+
+// Close stops following the spool. Once nobody follows it anymore,
+// fetching the rest of the zip is aborted.
+func (r *spoolReader) Close() error {
+	if r.closed {
+		return nil
+	}
+	r.closed = true
+	sp := r.sp
+	sp.mu.Lock()
+	sp.readers--
+	if sp.readers == 0 && !sp.done {
+		sp.aborted = true
+	}
+	aborted := sp.aborted
+	sp.mu.Unlock()
+	if aborted {
+		sp.closeUpstream()
+	}
+	sp.release()
+	return nil
+}
@@ -0,0 +1,137 @@
+package stash
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/gomods/athens/pkg/storage"
+	"github.com/stretchr/testify/require"
+)
+
+// pipeFetcher returns zips whose contents are written to the pipe by the test.
+type pipeFetcher struct {
+	r *io.PipeReader
+}
+
+func (f *pipeFetcher) Fetch(ctx context.Context, mod, ver string) (*storage.Version, error) {
+	return &storage.Version{Semver: "v1.0.0", Mod: []byte("mod"), Info: []byte("info"), Zip: f.r}, nil
+}
+
+func newPipeFetcher() (*pipeFetcher, *io.PipeWriter) {
+	r, w := io.Pipe()
+	return &pipeFetcher{r}, w
+}
+
+func readAsync(r io.Reader) <-chan []byte {
+	ch := make(chan []byte, 1)
+	go func() {
+		b, _ := io.ReadAll(r)
+		ch <- b
+	}()
+	return ch
+}
+
+func TestZipStreamsFollow(t *testing.T) {
+	ctx := t.Context()
+	dir := t.TempDir()
+	streams := NewZipStreams(dir)
+	pf, w := newPipeFetcher()
+
+	// followers may wait before the fetch starts, by requested or resolved version.
+	followed := make(chan storage.SizeReadCloser, 2)
+	for _, ver := range []string{"master", "v1.0.0"} {
+		go func() {
+			zip, ok := streams.Follow(ctx, "mod", ver, nil)
+			require.True(t, ok)
+			followed <- zip
+		}()
+	}
+	time.Sleep(10 * time.Millisecond)
+	v, err := streams.Fetcher(pf).Fetch(ctx, "mod", "master")
+	require.NoError(t, err)
+	stashed := readAsync(v.Zip)
+	client1, client2 := <-followed, <-followed
+	clientZip1, clientZip2 := readAsync(client1), readAsync(client2)
+
+	_, err = w.Write([]byte("zip "))
+	require.NoError(t, err)
+	_, err = w.Write([]byte("contents"))
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	require.Equal(t, "zip contents", string(<-stashed))
+	require.Equal(t, "zip contents", string(<-clientZip1))
+	require.Equal(t, "zip contents", string(<-clientZip2))
+	require.Equal(t, int64(len("zip contents")), client1.Size())
+
+	require.NoError(t, v.Zip.Close())
+	require.NoError(t, client1.Close())
+	require.NoError(t, client2.Close())
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Empty(t, entries, "the spool must be removed once everyone is done")
+}
+
+func TestZipStreamsSaveFailure(t *testing.T) {
+	ctx := t.Context()
+	streams := NewZipStreams(t.TempDir())
+	pf, w := newPipeFetcher()
+	v, err := streams.Fetcher(pf).Fetch(ctx, "mod", "v1.0.0")
+	require.NoError(t, err)
+	client, ok := streams.Follow(ctx, "mod", "v1.0.0", nil)
+	require.True(t, ok)
+	defer client.Close()
+	clientZip := readAsync(client)
+
+	// the upload giving up half way does not cut off the client.
+	_, err = w.Write([]byte("zip "))
+	require.NoError(t, err)
+	require.NoError(t, v.Zip.Close())
+	_, err = w.Write([]byte("contents"))
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	require.Equal(t, "zip contents", string(<-clientZip))
+}
+
+func TestZipStreamsUpstreamFailure(t *testing.T) {
+	ctx := t.Context()
+	streams := NewZipStreams(t.TempDir())
+	pf, w := newPipeFetcher()
+	v, err := streams.Fetcher(pf).Fetch(ctx, "mod", "v1.0.0")
+	require.NoError(t, err)
+	defer v.Zip.Close()
+	client, ok := streams.Follow(ctx, "mod", "v1.0.0", nil)
+	require.True(t, ok)
+	defer client.Close()
+
+	upstreamErr := errors.New("connection reset")
+	go func() {
+		_, _ = w.Write([]byte("zip "))
+		_ = w.CloseWithError(upstreamErr)
+	}()
+	_, err = io.ReadAll(client)
+	require.ErrorIs(t, err, upstreamErr)
+	_, err = io.ReadAll(v.Zip)
+	require.ErrorIs(t, err, upstreamErr)
+}
+
+func TestZipStreamsFollowGivesUp(t *testing.T) {
+	streams := NewZipStreams(t.TempDir())
+	done := make(chan struct{})
+	close(done)
+	_, ok := streams.Follow(t.Context(), "mod", "v1.0.0", done)
+	require.False(t, ok)
+
+	// nobody reading the zip anymore aborts its fetch.
+	pf, w := newPipeFetcher()
+	v, err := streams.Fetcher(pf).Fetch(t.Context(), "mod", "v1.0.0")
+	require.NoError(t, err)
+	require.NoError(t, v.Zip.Close())
+	_, err = w.Write([]byte("zip"))
+	require.ErrorIs(t, err, io.ErrClosedPipe)
+	_, ok = streams.Follow(t.Context(), "mod", "v1.0.0", done)
+	require.False(t, ok)
+}
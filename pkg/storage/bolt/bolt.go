@@ -0,0 +1,151 @@
+// Package bolt implements a storage backend that keeps all the module
+// versions in a single bolt database file, with their .info and .mod files
+// inline and their zips in chunks, so that large caches do not need a
+// directory tree with three files per version.
+package bolt
+
+import (
+	"bytes"
+	"encoding/binary"
+	"time"
+
+	"github.com/gomods/athens/pkg/errors"
+	"github.com/gomods/athens/pkg/storage"
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	// versionsBucket has a bucket for every module version, keyed by
+	// versionKey, so that the versions of a module are next to each other.
+	versionsBucket = []byte("versions")
+	// blobsBucket has a bucket for every zip, keyed by a sequence number,
+	// which holds its chunks keyed by their index.
+	blobsBucket = []byte("blobs")
+
+	infoKey = []byte("info")
+	modKey  = []byte("mod")
+	// zipKey is the key of the blob of the zip, which a version
+	// whose metadata is saved ahead of its zip does not have yet.
+	zipKey  = []byte("zip")
+	sizeKey = []byte("size")
+)
+
+const (
+	// chunkSize is the size of the chunks that zips are stored in.
+	chunkSize = 256 << 10
+	// chunksPerTx is the number of chunks of a zip written in
+	// the same transaction, which holds them in memory.
+	chunksPerTx = 16
+)
+
+type storageImpl struct {
+	db *bolt.DB
+}
+
+// New returns a storage backend that keeps its module versions in the bolt
+// database at path, which is created if it does not exist. The chunks of the
+// zips that were being saved when the database was last closed are removed.
+//
+// A bolt database can only be opened by one process at a time.
+func New(path string) (storage.Backend, error) {
+	const op errors.Op = "bolt.New"
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: 10 * time.Second})
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{versionsBucket, blobsBucket} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		return removeOrphans(tx)
+	})
+	if err != nil {
+		_ = db.Close()
+		return nil, errors.E(op, err)
+	}
+	return &storageImpl{db: db}, nil
+}
+
+// removeOrphans removes the blobs that no version refers to.
+func removeOrphans(tx *bolt.Tx) error {
+	used := map[string]bool{}
+	versions := tx.Bucket(versionsBucket)
+	err := versions.ForEachBucket(func(k []byte) error {
+		if id := versions.Bucket(k).Get(zipKey); id != nil {
+			used[string(id)] = true
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	blobs := tx.Bucket(blobsBucket)
+	var orphans [][]byte
+	err = blobs.ForEachBucket(func(k []byte) error {
+		if !used[string(k)] {
+			orphans = append(orphans, bytes.Clone(k))
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for _, id := range orphans {
+		if err := blobs.DeleteBucket(id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Clear removes all the module versions.
+func (s *storageImpl) Clear() error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{versionsBucket, blobsBucket} {
+			if err := tx.DeleteBucket(name); err != nil {
+				return err
+			}
+			if _, err := tx.CreateBucket(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Close closes the database.
+func (s *storageImpl) Close() error {
+	return s.db.Close()
+}
+
+// versionKey separates module and version with a byte that
+// neither has, so that a module is not a prefix of another one.
+func versionKey(module, version string) []byte {
+	return []byte(module + "\x00" + version)
+}
+
+func moduleKey(module string) []byte {
+	return []byte(module + "\x00")
+}
+
+func itob(n uint64) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, n)
+	return b
+}
+
+// versionBucket returns the bucket of module@version, or nil if there is none.
+func versionBucket(tx *bolt.Tx, module, version string) *bolt.Bucket {
+	return tx.Bucket(versionsBucket).Bucket(versionKey(module, version))
+}
+
+// deleteBlob removes the blob id, if it exists.
+func deleteBlob(tx *bolt.Tx, id []byte) error {
+	blobs := tx.Bucket(blobsBucket)
+	if blobs.Bucket(id) == nil {
+		return nil
+	}
+	return blobs.DeleteBucket(id)
+}
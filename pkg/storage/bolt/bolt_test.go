@@ -0,0 +1,136 @@
+package bolt
+
+import (
+	"bytes"
+	"io"
+	"path/filepath"
+	"testing"
+
+	"github.com/gomods/athens/pkg/errors"
+	"github.com/gomods/athens/pkg/paths"
+	"github.com/gomods/athens/pkg/storage/compliance"
+	"github.com/gomods/athens/pkg/storage/compliance/fixtures"
+	"github.com/stretchr/testify/require"
+	bolt "go.etcd.io/bbolt"
+)
+
+func TestBackend(t *testing.T) {
+	b := getStorage(t)
+	compliance.RunTests(t, b, b.Clear)
+}
+
+func BenchmarkBackend(b *testing.B) {
+	backend := getStorage(b)
+	compliance.RunBenchmarks(b, backend, backend.Clear)
+}
+
+func TestChunkedZip(t *testing.T) {
+	s := getStorage(t)
+	ctx := t.Context()
+	// more chunks than are written in one transaction.
+	zip := bytes.Repeat([]byte("0123456789abcdef"), (chunksPerTx*chunkSize+chunkSize/2)/16)
+	fixtures.Save(t, s, "v1.0.0", string(zip))
+
+	rc, err := s.Zip(ctx, fixtures.Module, "v1.0.0")
+	require.NoError(t, err)
+	defer func() { _ = rc.Close() }()
+	require.Equal(t, int64(len(zip)), rc.Size())
+	got, err := io.ReadAll(rc)
+	require.NoError(t, err)
+	require.Equal(t, zip, got)
+
+	// replacing the version removes the chunks of its former zip.
+	fixtures.Save(t, s, "v1.0.0", "zip")
+	require.Equal(t, 1, blobs(t, s))
+}
+
+func TestFailedSave(t *testing.T) {
+	s := getStorage(t)
+	ctx := t.Context()
+	zip := io.MultiReader(bytes.NewReader(make([]byte, chunksPerTx*chunkSize)), errReader{})
+	err := s.Save(ctx, fixtures.Module, "v1.0.0", []byte("module"), zip, nil, []byte("info"))
+	require.Error(t, err)
+
+	_, err = s.Info(ctx, fixtures.Module, "v1.0.0")
+	require.True(t, errors.IsNotFoundErr(err))
+	require.Equal(t, 0, blobs(t, s))
+}
+
+func TestRemoveOrphans(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "storage.db")
+	b, err := New(path)
+	require.NoError(t, err)
+	s := b.(*storageImpl)
+	fixtures.Save(t, s, "v1.0.0", "zip")
+	// the chunks of a zip that was being saved when the process stopped.
+	require.NoError(t, s.db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.Bucket(blobsBucket).CreateBucket(itob(42))
+		if err != nil {
+			return err
+		}
+		return b.Put(itob(0), []byte("chunk"))
+	}))
+	require.NoError(t, s.Close())
+
+	b, err = New(path)
+	require.NoError(t, err)
+	s = b.(*storageImpl)
+	defer func() { _ = s.Close() }()
+	require.Equal(t, 1, blobs(t, s))
+	fixtures.RequireExists(t, s, "v1.0.0", true)
+}
+
+func TestCatalog(t *testing.T) {
+	s := getStorage(t)
+	ctx := t.Context()
+	fixtures.Save(t, s, "v1.0.0", "zip")
+	fixtures.Save(t, s, "v1.1.0", "zip")
+	require.NoError(t, s.Save(ctx, fixtures.Module+"/v2", "v2.0.0", []byte("module"), bytes.NewReader([]byte("zip")), nil, []byte("info")))
+	require.NoError(t, s.SaveMetadata(ctx, fixtures.Module, "v1.2.0", []byte("module"), []byte("info")))
+
+	var all []paths.AllPathParams
+	token := ""
+	for {
+		page, next, err := s.Catalog(ctx, token, 2)
+		require.NoError(t, err)
+		all = append(all, page...)
+		if next == "" {
+			break
+		}
+		token = next
+	}
+	require.Equal(t, []paths.AllPathParams{
+		{Module: fixtures.Module, Version: "v1.0.0"},
+		{Module: fixtures.Module, Version: "v1.1.0"},
+		{Module: fixtures.Module + "/v2", Version: "v2.0.0"},
+	}, all)
+
+	_, _, err := s.Catalog(ctx, "invalid", 2)
+	require.True(t, errors.Is(err, errors.KindBadRequest))
+}
+
+type errReader struct{}
+
+func (errReader) Read([]byte) (int, error) {
+	return 0, errors.E("errReader.Read", "connection reset")
+}
+
+func blobs(t *testing.T, s *storageImpl) int {
+	t.Helper()
+	n := 0
+	require.NoError(t, s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(blobsBucket).ForEachBucket(func([]byte) error {
+			n++
+			return nil
+		})
+	}))
+	return n
+}
+
+func getStorage(tb testing.TB) *storageImpl {
+	tb.Helper()
+	b, err := New(filepath.Join(tb.TempDir(), "storage.db"))
+	require.NoError(tb, err)
+	tb.Cleanup(func() { _ = b.(*storageImpl).Close() })
+	return b.(*storageImpl)
+}
@@ -0,0 +1,61 @@
+package bolt
+
+import (
+	"bytes"
+	"context"
+	"strings"
+
+	"github.com/gomods/athens/pkg/errors"
+	"github.com/gomods/athens/pkg/observ"
+	"github.com/gomods/athens/pkg/paths"
+	bolt "go.etcd.io/bbolt"
+)
+
+const tokenSeparator = "|"
+
+// Catalog implements the (./pkg/storage).Cataloger interface.
+// The versions are keyed by module and version, so that a page
+// starts with a seek to the version after the token.
+func (s *storageImpl) Catalog(ctx context.Context, token string, pageSize int) ([]paths.AllPathParams, string, error) {
+	const op errors.Op = "bolt.Catalog"
+	_, span := observ.StartSpan(ctx, op.String())
+	defer span.End()
+	var from []byte
+	if token != "" {
+		module, version, ok := strings.Cut(token, tokenSeparator)
+		if !ok {
+			return nil, "", errors.E(op, "Invalid token", errors.KindBadRequest)
+		}
+		from = versionKey(module, version)
+	}
+
+	res := make([]paths.AllPathParams, 0, pageSize)
+	resToken := ""
+	err := s.db.View(func(tx *bolt.Tx) error {
+		versions := tx.Bucket(versionsBucket)
+		c := versions.Cursor()
+		k, _ := c.First()
+		if from != nil {
+			if k, _ = c.Seek(from); bytes.Equal(k, from) {
+				k, _ = c.Next()
+			}
+		}
+		for ; k != nil; k, _ = c.Next() {
+			// versions whose zip is not saved yet are left out.
+			if versions.Bucket(k).Get(zipKey) == nil {
+				continue
+			}
+			module, version, _ := bytes.Cut(k, []byte("\x00"))
+			res = append(res, paths.AllPathParams{Module: string(module), Version: string(version)})
+			if len(res) == pageSize {
+				resToken = string(module) + tokenSeparator + string(version)
+				return nil
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, "", errors.E(op, err, errors.KindUnexpected)
+	}
+	return res, resToken, nil
+}
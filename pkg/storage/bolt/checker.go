@@ -0,0 +1,27 @@
+package bolt
+
+import (
+	"context"
+
+	"github.com/gomods/athens/pkg/errors"
+	"github.com/gomods/athens/pkg/observ"
+	bolt "go.etcd.io/bbolt"
+)
+
+// Exists implements the (./pkg/storage).Checker interface. Versions
+// saved with SaveMetadata only exist once their zip is saved.
+func (s *storageImpl) Exists(ctx context.Context, module, version string) (bool, error) {
+	const op errors.Op = "bolt.Exists"
+	_, span := observ.StartSpan(ctx, op.String())
+	defer span.End()
+	var exists bool
+	err := s.db.View(func(tx *bolt.Tx) error {
+		vb := versionBucket(tx, module, version)
+		exists = vb != nil && vb.Get(zipKey) != nil
+		return nil
+	})
+	if err != nil {
+		return false, errors.E(op, errors.M(module), errors.V(version), err)
+	}
+	return exists, nil
+}
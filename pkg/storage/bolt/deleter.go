@@ -0,0 +1,34 @@
+package bolt
+
+import (
+	"bytes"
+	"context"
+
+	"github.com/gomods/athens/pkg/errors"
+	"github.com/gomods/athens/pkg/observ"
+	bolt "go.etcd.io/bbolt"
+)
+
+// Delete removes a specific version of a module,
+// partially populated or not, along with its zip.
+func (s *storageImpl) Delete(ctx context.Context, module, version string) error {
+	const op errors.Op = "bolt.Delete"
+	_, span := observ.StartSpan(ctx, op.String())
+	defer span.End()
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		vb := versionBucket(tx, module, version)
+		if vb == nil {
+			return errors.E(op, errors.KindNotFound)
+		}
+		if id := vb.Get(zipKey); id != nil {
+			if err := deleteBlob(tx, bytes.Clone(id)); err != nil {
+				return err
+			}
+		}
+		return tx.Bucket(versionsBucket).DeleteBucket(versionKey(module, version))
+	})
+	if err != nil {
+		return errors.E(op, err, errors.M(module), errors.V(version))
+	}
+	return nil
+}
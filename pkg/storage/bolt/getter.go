@@ -0,0 +1,124 @@
+package bolt
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"io"
+	"sync"
+
+	"github.com/gomods/athens/pkg/errors"
+	"github.com/gomods/athens/pkg/observ"
+	"github.com/gomods/athens/pkg/storage"
+	bolt "go.etcd.io/bbolt"
+)
+
+func (s *storageImpl) Info(ctx context.Context, module, version string) ([]byte, error) {
+	const op errors.Op = "bolt.Info"
+	_, span := observ.StartSpan(ctx, op.String())
+	defer span.End()
+	info, err := s.get(module, version, infoKey)
+	if err != nil {
+		return nil, errors.E(op, err, errors.M(module), errors.V(version))
+	}
+	return info, nil
+}
+
+func (s *storageImpl) GoMod(ctx context.Context, module, version string) ([]byte, error) {
+	const op errors.Op = "bolt.GoMod"
+	_, span := observ.StartSpan(ctx, op.String())
+	defer span.End()
+	mod, err := s.get(module, version, modKey)
+	if err != nil {
+		return nil, errors.E(op, err, errors.M(module), errors.V(version))
+	}
+	return mod, nil
+}
+
+// Zip reads the chunks of the zip one transaction at a time, so that
+// reading a large zip does not keep a transaction open. Reading the zip
+// of a version that is deleted or replaced in the meantime fails.
+func (s *storageImpl) Zip(ctx context.Context, module, version string) (storage.SizeReadCloser, error) {
+	const op errors.Op = "bolt.Zip"
+	_, span := observ.StartSpan(ctx, op.String())
+	defer span.End()
+	var (
+		id   []byte
+		size int64
+	)
+	err := s.db.View(func(tx *bolt.Tx) error {
+		vb := versionBucket(tx, module, version)
+		if vb == nil || vb.Get(zipKey) == nil {
+			return errors.E(op, errors.KindNotFound)
+		}
+		id = bytes.Clone(vb.Get(zipKey))
+		size = int64(binary.BigEndian.Uint64(vb.Get(sizeKey)))
+		return nil
+	})
+	if err != nil {
+		return nil, errors.E(op, err, errors.M(module), errors.V(version))
+	}
+	return storage.NewSizer(&zipReader{db: s.db, id: id}, size), nil
+}
+
+// get returns a copy of the value of key in the bucket of module@version.
+func (s *storageImpl) get(module, version string, key []byte) ([]byte, error) {
+	const op errors.Op = "bolt.get"
+	var v []byte
+	err := s.db.View(func(tx *bolt.Tx) error {
+		vb := versionBucket(tx, module, version)
+		if vb == nil || vb.Get(key) == nil {
+			return errors.E(op, errors.KindNotFound)
+		}
+		v = bytes.Clone(vb.Get(key))
+		return nil
+	})
+	return v, err
+}
+
+// zipReader reads the chunks of the blob id in order.
+type zipReader struct {
+	db *bolt.DB
+	id []byte
+
+	mu     sync.Mutex
+	next   uint64
+	chunk  []byte
+	closed bool
+}
+
+func (z *zipReader) Read(p []byte) (int, error) {
+	const op errors.Op = "bolt.zipReader.Read"
+	z.mu.Lock()
+	defer z.mu.Unlock()
+	if z.closed {
+		return 0, errors.E(op, "read of a closed zip")
+	}
+	if len(z.chunk) == 0 {
+		err := z.db.View(func(tx *bolt.Tx) error {
+			b := tx.Bucket(blobsBucket).Bucket(z.id)
+			if b == nil {
+				return errors.E(op, "the zip was deleted while it was read")
+			}
+			z.chunk = bytes.Clone(b.Get(itob(z.next)))
+			return nil
+		})
+		if err != nil {
+			return 0, err
+		}
+		if z.chunk == nil {
+			return 0, io.EOF
+		}
+		z.next++
+	}
+	n := copy(p, z.chunk)
+	z.chunk = z.chunk[n:]
+	return n, nil
+}
+
+func (z *zipReader) Close() error {
+	z.mu.Lock()
+	defer z.mu.Unlock()
+	z.closed = true
+	return nil
+}
@@ -0,0 +1,32 @@
+package bolt
+
+import (
+	"bytes"
+	"context"
+
+	"github.com/gomods/athens/pkg/errors"
+	"github.com/gomods/athens/pkg/observ"
+	bolt "go.etcd.io/bbolt"
+)
+
+func (s *storageImpl) List(ctx context.Context, module string) ([]string, error) {
+	const op errors.Op = "bolt.List"
+	_, span := observ.StartSpan(ctx, op.String())
+	defer span.End()
+	ret := []string{}
+	prefix := moduleKey(module)
+	err := s.db.View(func(tx *bolt.Tx) error {
+		versions := tx.Bucket(versionsBucket)
+		c := versions.Cursor()
+		for k, _ := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, _ = c.Next() {
+			if versions.Bucket(k).Get(zipKey) != nil {
+				ret = append(ret, string(k[len(prefix):]))
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, errors.E(op, errors.M(module), err, errors.KindUnexpected)
+	}
+	return ret, nil
+}
@@ -0,0 +1,134 @@
+package bolt
+
+import (
+	"bytes"
+	"context"
+	"io"
+
+	"github.com/gomods/athens/pkg/errors"
+	"github.com/gomods/athens/pkg/observ"
+	bolt "go.etcd.io/bbolt"
+)
+
+// Save writes the chunks of the zip in as many transactions as it takes to
+// read it, and the version in the same transaction as the last chunks, so
+// that the version is never seen with part of its zip. Saving a version
+// that exists replaces it.
+func (s *storageImpl) Save(ctx context.Context, module, version string, mod []byte, zip io.Reader, _, info []byte) error {
+	const op errors.Op = "bolt.Save"
+	ctx, span := observ.StartSpan(ctx, op.String())
+	defer span.End()
+	var (
+		id   []byte
+		next uint64
+		size int64
+	)
+	for {
+		chunks, done, err := readChunks(zip)
+		if err == nil {
+			err = ctx.Err()
+		}
+		if err == nil {
+			err = s.db.Update(func(tx *bolt.Tx) error {
+				blobs := tx.Bucket(blobsBucket)
+				if id == nil {
+					seq, err := blobs.NextSequence()
+					if err != nil {
+						return err
+					}
+					if _, err := blobs.CreateBucket(itob(seq)); err != nil {
+						return err
+					}
+					id = itob(seq)
+				}
+				b := blobs.Bucket(id)
+				for _, c := range chunks {
+					if err := b.Put(itob(next), c); err != nil {
+						return err
+					}
+					next++
+					size += int64(len(c))
+				}
+				if !done {
+					return nil
+				}
+				return putVersion(tx, module, version, mod, info, id, size)
+			})
+		}
+		if err != nil {
+			if id != nil {
+				_ = s.db.Update(func(tx *bolt.Tx) error { return deleteBlob(tx, id) })
+			}
+			return errors.E(op, err, errors.M(module), errors.V(version))
+		}
+		if done {
+			return nil
+		}
+	}
+}
+
+// readChunks reads up to chunksPerTx chunks of r,
+// and reports whether r is read to the end.
+func readChunks(r io.Reader) ([][]byte, bool, error) {
+	var chunks [][]byte
+	for range chunksPerTx {
+		c := make([]byte, chunkSize)
+		n, err := io.ReadFull(r, c)
+		if n > 0 {
+			chunks = append(chunks, c[:n])
+		}
+		if errors.IsErr(err, io.EOF) || errors.IsErr(err, io.ErrUnexpectedEOF) {
+			return chunks, true, nil
+		}
+		if err != nil {
+			return nil, false, err
+		}
+	}
+	return chunks, false, nil
+}
+
+// putVersion saves the files of module@version, whose zip is the blob id,
+// and removes the blob of the zip that it replaces.
+func putVersion(tx *bolt.Tx, module, version string, mod, info, id []byte, size int64) error {
+	vb, err := tx.Bucket(versionsBucket).CreateBucketIfNotExists(versionKey(module, version))
+	if err != nil {
+		return err
+	}
+	if old := vb.Get(zipKey); old != nil {
+		if err := deleteBlob(tx, bytes.Clone(old)); err != nil {
+			return err
+		}
+	}
+	if err := vb.Put(infoKey, info); err != nil {
+		return err
+	}
+	if err := vb.Put(modKey, mod); err != nil {
+		return err
+	}
+	if err := vb.Put(sizeKey, itob(uint64(size))); err != nil {
+		return err
+	}
+	return vb.Put(zipKey, id)
+}
+
+// SaveMetadata implements the (./pkg/storage).MetadataSaver interface.
+// The version only exists once its zip is saved with Save.
+func (s *storageImpl) SaveMetadata(ctx context.Context, module, version string, mod, info []byte) error {
+	const op errors.Op = "bolt.SaveMetadata"
+	_, span := observ.StartSpan(ctx, op.String())
+	defer span.End()
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		vb, err := tx.Bucket(versionsBucket).CreateBucketIfNotExists(versionKey(module, version))
+		if err != nil {
+			return err
+		}
+		if err := vb.Put(infoKey, info); err != nil {
+			return err
+		}
+		return vb.Put(modKey, mod)
+	})
+	if err != nil {
+		return errors.E(op, err, errors.M(module), errors.V(version))
+	}
+	return nil
+}
@@ -9,7 +9,8 @@ import (
 // Checker is the interface that checks if the version of the module exists.
 type Checker interface {
 	// Exists checks whether or not module in specified version is present
-	// in the backing storage.
+	// in the backing storage. A version whose zip has not been saved yet,
+	// see MetadataSaver, does not exist.
 	Exists(ctx context.Context, module, version string) (bool, error)
 }
 
@@ -25,6 +26,8 @@ type checker struct {
 	strg Backend
 }
 
+// Exists reports whether the .info file of the version is there. Backends
+// that implement MetadataSaver implement Checker, so they never get here.
 func (c *checker) Exists(ctx context.Context, module, version string) (bool, error) {
 	_, err := c.strg.Info(ctx, module, version)
 	if err != nil {
@@ -0,0 +1,57 @@
+// Package fixtures provides the helpers that the tests of the packages
+// built on storage backends share. It is kept apart from package compliance
+// because the storages it returns are themselves tested with compliance.
+package fixtures
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/gomods/athens/pkg/storage"
+	"github.com/gomods/athens/pkg/storage/mem"
+	"github.com/stretchr/testify/require"
+)
+
+// Module is the module whose versions the helpers save and check.
+const Module = "github.com/gomods/athens"
+
+// NewStorage returns an empty storage that lives in memory.
+func NewStorage(tb testing.TB) storage.Backend {
+	tb.Helper()
+	s, err := mem.NewStorage()
+	require.NoError(tb, err)
+	return s
+}
+
+// Save saves ver of Module to s, with zip as its zip. Its go.mod
+// is "module" and its .info file is the version itself.
+func Save(tb testing.TB, s storage.Backend, ver, zip string) {
+	tb.Helper()
+	require.NoError(tb, s.Save(tb.Context(), Module, ver, []byte("module"), strings.NewReader(zip), nil, []byte(ver)))
+}
+
+// RequireZip requires the zip of ver of Module in s to be want.
+func RequireZip(tb testing.TB, s storage.Backend, ver, want string) {
+	tb.Helper()
+	zip, err := s.Zip(tb.Context(), Module, ver)
+	require.NoError(tb, err)
+	defer func() { _ = zip.Close() }()
+	b, err := io.ReadAll(zip)
+	require.NoError(tb, err)
+	require.Equal(tb, want, string(b))
+}
+
+// RequireExists requires s to have ver of Module if want is true,
+// and not to have it otherwise.
+func RequireExists(tb testing.TB, s storage.Backend, ver string, want bool) {
+	tb.Helper()
+	ok, err := storage.WithChecker(s).Exists(tb.Context(), Module, ver)
+	require.NoError(tb, err)
+	require.Equal(tb, want, ok, ver)
+}
+
+// NoCatalog is a storage without a catalog.
+type NoCatalog struct {
+	storage.Backend
+}
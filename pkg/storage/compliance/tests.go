@@ -25,6 +25,7 @@ func RunTests(t *testing.T, b storage.Backend, clearBackend func() error) {
 	testGet(t, b)
 	testExists(t, b)
 	testShouldNotExist(t, b)
+	testPartialVersion(t, b)
 }
 
 // testNotFound ensures that a storage Backend
@@ -209,6 +210,53 @@ func testDelete(t *testing.T, b storage.Backend) {
 	require.Equal(t, false, exists)
 }
 
+// testPartialVersion tests that a version whose metadata is saved
+// ahead of its zip is only served by Info and GoMod until the zip
+// is saved, if the backend supports it.
+func testPartialVersion(t *testing.T, b storage.Backend) {
+	ms, ok := b.(storage.MetadataSaver)
+	if !ok {
+		return
+	}
+	ctx := t.Context()
+	modname := "github.com/gomods/partial"
+	ver := "v1.0.0"
+	mock := getMockModule()
+	require.NoError(t, ms.SaveMetadata(ctx, modname, ver, mock.Mod, mock.Info))
+	defer b.Delete(ctx, modname, ver)
+
+	info, err := b.Info(ctx, modname, ver)
+	require.NoError(t, err)
+	require.Equal(t, mock.Info, info)
+	mod, err := b.GoMod(ctx, modname, ver)
+	require.NoError(t, err)
+	require.Equal(t, mock.Mod, mod)
+	_, err = b.Zip(ctx, modname, ver)
+	require.Equal(t, errors.KindNotFound, errors.Kind(err))
+	exists, err := storage.WithChecker(b).Exists(ctx, modname, ver)
+	require.NoError(t, err)
+	require.False(t, exists)
+	vs, err := b.List(ctx, modname)
+	require.NoError(t, err)
+	require.Empty(t, vs)
+
+	err = b.Save(ctx, modname, ver, mock.Mod, mock.Zip, mock.ZipMD5, mock.Info)
+	require.NoError(t, err)
+	exists, err = storage.WithChecker(b).Exists(ctx, modname, ver)
+	require.NoError(t, err)
+	require.True(t, exists)
+	vs, err = b.List(ctx, modname)
+	require.NoError(t, err)
+	require.Equal(t, []string{ver}, vs)
+
+	// a partially populated version can be deleted.
+	partialVer := "v1.0.1"
+	require.NoError(t, ms.SaveMetadata(ctx, modname, partialVer, mock.Mod, mock.Info))
+	require.NoError(t, b.Delete(ctx, modname, partialVer))
+	_, err = b.Info(ctx, modname, partialVer)
+	require.Equal(t, errors.KindNotFound, errors.Kind(err))
+}
+
 func getMockModule() *storage.Version {
 	return &storage.Version{
 		Info:   []byte("123"),
@@ -50,6 +50,10 @@ func (s *storageImpl) Catalog(ctx context.Context, token string, pageSize int) (
 			if fromVersion != "" && version <= fromVersion { // we must skip same version
 				return nil
 			}
+			// versions whose zip is not saved yet are left out.
+			if ok, err := afero.Exists(s.filesystem, filepath.Join(verDir, "source.zip")); err != nil || !ok {
+				return err
+			}
 
 			res = append(res, paths.AllPathParams{Module: module, Version: version})
 			count--
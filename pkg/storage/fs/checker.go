@@ -3,25 +3,37 @@ package fs
 import (
 	"context"
 	"os"
+	"path/filepath"
 
 	"github.com/gomods/athens/pkg/errors"
 	"github.com/gomods/athens/pkg/observ"
-	"github.com/spf13/afero"
 )
 
 func (s *storageImpl) Exists(ctx context.Context, module, version string) (bool, error) {
 	const op errors.Op = "fs.Exists"
 	_, span := observ.StartSpan(ctx, op.String())
 	defer span.End()
-	versionedPath := s.versionLocation(module, version)
 
-	files, err := afero.ReadDir(s.filesystem, versionedPath)
+	exists, err := s.complete(module, version)
 	if err != nil {
+		return false, errors.E(op, errors.M(module), errors.V(version), err)
+	}
+	return exists, nil
+}
+
+// complete reports whether all of the .info, .mod and .zip files of the version
+// are saved. Versions saved with SaveMetadata only have the first two until
+// their zip is saved.
+func (s *storageImpl) complete(module, version string) (bool, error) {
+	versionedPath := s.versionLocation(module, version)
+	for _, name := range []string{version + ".info", "go.mod", "source.zip"} {
+		_, err := s.filesystem.Stat(filepath.Join(versionedPath, name))
 		if os.IsNotExist(err) {
 			return false, nil
 		}
-		return false, errors.E(op, errors.M(module), errors.V(version), err)
+		if err != nil {
+			return false, err
+		}
 	}
-
-	return len(files) == 3, nil
+	return true, nil
 }
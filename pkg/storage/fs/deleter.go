@@ -2,9 +2,11 @@ package fs
 
 import (
 	"context"
+	"path/filepath"
 
 	"github.com/gomods/athens/pkg/errors"
 	"github.com/gomods/athens/pkg/observ"
+	"github.com/spf13/afero"
 )
 
 // Delete removes a specific version of a module.
@@ -13,7 +15,9 @@ func (s *storageImpl) Delete(ctx context.Context, module, version string) error
 	ctx, span := observ.StartSpan(ctx, op.String())
 	defer span.End()
 	versionedPath := s.versionLocation(module, version)
-	exists, err := s.Exists(ctx, module, version)
+	// partially populated versions are deleted as well,
+	// so only check that anything was saved at all.
+	exists, err := afero.Exists(s.filesystem, filepath.Join(versionedPath, version+".info"))
 	if err != nil {
 		return errors.E(op, err, errors.M(module), errors.V(version))
 	}
@@ -30,7 +30,14 @@ func (s *storageImpl) List(ctx context.Context, module string) ([]string, error)
 			continue
 		}
 		ver := fileInfo.Name()
-		if v := semver.Canonical(ver); v != "" && strings.HasPrefix(ver, v) {
+		if v := semver.Canonical(ver); v == "" || !strings.HasPrefix(ver, v) {
+			continue
+		}
+		complete, err := s.complete(module, ver)
+		if err != nil {
+			return nil, errors.E(op, errors.M(module), err, errors.KindUnexpected)
+		}
+		if complete {
 			ret = append(ret, ver)
 		}
 	}
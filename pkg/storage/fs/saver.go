@@ -49,3 +49,22 @@ func (s *storageImpl) Save(ctx context.Context, module, version string, mod []by
 	}
 	return nil
 }
+
+// SaveMetadata implements the (./pkg/storage).MetadataSaver interface.
+// The version only exists once its zip is saved with Save.
+func (s *storageImpl) SaveMetadata(ctx context.Context, module, version string, mod, info []byte) error {
+	const op errors.Op = "fs.SaveMetadata"
+	_, span := observ.StartSpan(ctx, op.String())
+	defer span.End()
+	dir := s.versionLocation(module, version)
+	if err := s.filesystem.MkdirAll(dir, 0o777); err != nil {
+		return errors.E(op, err, errors.M(module), errors.V(version))
+	}
+	if err := afero.WriteFile(s.filesystem, filepath.Join(dir, "go.mod"), mod, 0o666); err != nil {
+		return errors.E(op, err, errors.M(module), errors.V(version))
+	}
+	if err := afero.WriteFile(s.filesystem, filepath.Join(dir, version+".info"), info, 0o666); err != nil {
+		return errors.E(op, err, errors.M(module), errors.V(version))
+	}
+	return nil
+}
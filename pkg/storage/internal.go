@@ -0,0 +1,13 @@
+package storage
+
+import "strings"
+
+// IsInternal reports whether module is one that Athens keeps in storage for
+// its own use, such as the archives of the vulnerability database mirror.
+// Their paths are under the .invalid top level domain, which no real module
+// has, and they are left out of the catalog, the download protocol,
+// replication and migration.
+func IsInternal(module string) bool {
+	host, _, _ := strings.Cut(module, "/")
+	return strings.HasSuffix(host, ".invalid")
+}
@@ -0,0 +1,76 @@
+package overlay
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	"github.com/gomods/athens/pkg/errors"
+	"github.com/gomods/athens/pkg/observ"
+	"github.com/gomods/athens/pkg/paths"
+	"github.com/gomods/athens/pkg/storage"
+)
+
+// tokenSeparator separates the index of a layer from
+// the catalog token of that layer in an overlay token.
+const tokenSeparator = ":"
+
+// Catalog implements the (./pkg/storage).Cataloger interface. It goes through
+// the catalogs of the layers one after the other, leaving out the versions
+// that a layer before has, so that every version is listed once. It returns
+// a KindNotImplemented error if one of the layers has no catalog.
+func (s *storageImpl) Catalog(ctx context.Context, token string, pageSize int) ([]paths.AllPathParams, string, error) {
+	const op errors.Op = "overlay.Catalog"
+	ctx, span := observ.StartSpan(ctx, op.String())
+	defer span.End()
+	catalogers := make([]storage.Cataloger, len(s.layers))
+	for i, l := range s.layers {
+		cs, ok := l.(storage.Cataloger)
+		if !ok {
+			return nil, "", errors.E(op, "layer "+strconv.Itoa(i)+" has no catalog", errors.KindNotImplemented)
+		}
+		catalogers[i] = cs
+	}
+	layer, layerToken, err := parseToken(token, len(s.layers))
+	if err != nil {
+		return nil, "", errors.E(op, err, errors.KindBadRequest)
+	}
+
+	res := make([]paths.AllPathParams, 0, pageSize)
+	for layer < len(s.layers) && len(res) < pageSize {
+		page, next, err := catalogers[layer].Catalog(ctx, layerToken, pageSize-len(res))
+		if err != nil {
+			return nil, "", errors.E(op, err)
+		}
+		for _, p := range page {
+			shadowed, err := s.shadowed(ctx, layer, p.Module, p.Version)
+			if err != nil {
+				return nil, "", errors.E(op, err)
+			}
+			if !shadowed {
+				res = append(res, p)
+			}
+		}
+		layerToken = next
+		if next == "" {
+			layer++
+		}
+	}
+	if layer == len(s.layers) {
+		return res, "", nil
+	}
+	return res, strconv.Itoa(layer) + tokenSeparator + layerToken, nil
+}
+
+func parseToken(token string, layers int) (int, string, error) {
+	const op errors.Op = "overlay.parseToken"
+	if token == "" {
+		return 0, "", nil
+	}
+	idx, layerToken, ok := strings.Cut(token, tokenSeparator)
+	layer, err := strconv.Atoi(idx)
+	if !ok || err != nil || layer < 0 || layer >= layers {
+		return 0, "", errors.E(op, "invalid token")
+	}
+	return layer, layerToken, nil
+}
@@ -0,0 +1,27 @@
+package overlay
+
+import (
+	"context"
+
+	"github.com/gomods/athens/pkg/errors"
+	"github.com/gomods/athens/pkg/observ"
+	"github.com/gomods/athens/pkg/storage"
+)
+
+// Exists implements the (./pkg/storage).Checker interface.
+// A version exists if any of the layers has it.
+func (s *storageImpl) Exists(ctx context.Context, module, version string) (bool, error) {
+	const op errors.Op = "overlay.Exists"
+	ctx, span := observ.StartSpan(ctx, op.String())
+	defer span.End()
+	for _, l := range s.layers {
+		ok, err := storage.WithChecker(l).Exists(ctx, module, version)
+		if err != nil {
+			return false, errors.E(op, err, errors.M(module), errors.V(version))
+		}
+		if ok {
+			return true, nil
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,35 @@
+package overlay
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/gomods/athens/pkg/errors"
+	"github.com/gomods/athens/pkg/observ"
+)
+
+// Delete implements the (./pkg/storage).Deleter interface. Only the writable
+// layer is written to, so a version that a read-only layer has cannot be
+// deleted: Delete returns a KindBadRequest error for it and deletes nothing,
+// rather than leaving the version served by the read-only layer.
+func (s *storageImpl) Delete(ctx context.Context, module, version string) error {
+	const op errors.Op = "overlay.Delete"
+	ctx, span := observ.StartSpan(ctx, op.String())
+	defer span.End()
+	for i, l := range s.layers {
+		if i == s.writable {
+			continue
+		}
+		_, err := l.Info(ctx, module, version)
+		if err == nil {
+			return errors.E(op, fmt.Sprintf("the read-only layer %d has the version", i), errors.KindBadRequest, errors.M(module), errors.V(version))
+		}
+		if !errors.IsNotFoundErr(err) {
+			return errors.E(op, err, errors.M(module), errors.V(version))
+		}
+	}
+	if err := s.layers[s.writable].Delete(ctx, module, version); err != nil {
+		return errors.E(op, err, errors.M(module), errors.V(version))
+	}
+	return nil
+}
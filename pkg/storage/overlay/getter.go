@@ -0,0 +1,51 @@
+package overlay
+
+import (
+	"context"
+
+	"github.com/gomods/athens/pkg/errors"
+	"github.com/gomods/athens/pkg/observ"
+	"github.com/gomods/athens/pkg/storage"
+)
+
+// Info implements the (./pkg/storage).Getter interface.
+func (s *storageImpl) Info(ctx context.Context, module, version string) ([]byte, error) {
+	const op errors.Op = "overlay.Info"
+	ctx, span := observ.StartSpan(ctx, op.String())
+	defer span.End()
+	info, err := first(s.layers, func(l storage.Backend) ([]byte, error) {
+		return l.Info(ctx, module, version)
+	})
+	if err != nil {
+		return nil, errors.E(op, err, errors.M(module), errors.V(version))
+	}
+	return info, nil
+}
+
+// GoMod implements the (./pkg/storage).Getter interface.
+func (s *storageImpl) GoMod(ctx context.Context, module, version string) ([]byte, error) {
+	const op errors.Op = "overlay.GoMod"
+	ctx, span := observ.StartSpan(ctx, op.String())
+	defer span.End()
+	mod, err := first(s.layers, func(l storage.Backend) ([]byte, error) {
+		return l.GoMod(ctx, module, version)
+	})
+	if err != nil {
+		return nil, errors.E(op, err, errors.M(module), errors.V(version))
+	}
+	return mod, nil
+}
+
+// Zip implements the (./pkg/storage).Getter interface.
+func (s *storageImpl) Zip(ctx context.Context, module, version string) (storage.SizeReadCloser, error) {
+	const op errors.Op = "overlay.Zip"
+	ctx, span := observ.StartSpan(ctx, op.String())
+	defer span.End()
+	zip, err := first(s.layers, func(l storage.Backend) (storage.SizeReadCloser, error) {
+		return l.Zip(ctx, module, version)
+	})
+	if err != nil {
+		return nil, errors.E(op, err, errors.M(module), errors.V(version))
+	}
+	return zip, nil
+}
@@ -0,0 +1,31 @@
+package overlay
+
+import (
+	"context"
+
+	"github.com/gomods/athens/pkg/errors"
+	"github.com/gomods/athens/pkg/observ"
+)
+
+// List implements the (./pkg/storage).Lister interface.
+// It returns the versions of all the layers, without duplicates.
+func (s *storageImpl) List(ctx context.Context, module string) ([]string, error) {
+	const op errors.Op = "overlay.List"
+	ctx, span := observ.StartSpan(ctx, op.String())
+	defer span.End()
+	var res []string
+	seen := map[string]bool{}
+	for _, l := range s.layers {
+		vers, err := l.List(ctx, module)
+		if err != nil && !errors.IsNotFoundErr(err) {
+			return nil, errors.E(op, err, errors.M(module))
+		}
+		for _, v := range vers {
+			if !seen[v] {
+				seen[v] = true
+				res = append(res, v)
+			}
+		}
+	}
+	return res, nil
+}
@@ -0,0 +1,70 @@
+// Package overlay provides a storage backend that merges several backends in
+// priority order, such as a read-only bundle of modules on local disk in front
+// of the storage that Athens saves modules to.
+package overlay
+
+import (
+	"context"
+
+	"github.com/gomods/athens/pkg/errors"
+	"github.com/gomods/athens/pkg/storage"
+)
+
+type storageImpl struct {
+	layers   []storage.Backend
+	writable int
+}
+
+// metadataStorage is a storageImpl whose writable layer implements
+// the (./pkg/storage).MetadataSaver interface.
+type metadataStorage struct {
+	*storageImpl
+}
+
+// New returns a storage.Backend that serves the versions of all the layers,
+// the first layer that has a version taking precedence over the others.
+// Versions are only saved to and deleted from the layer at index writable,
+// the other layers are never written to.
+func New(layers []storage.Backend, writable int) (storage.Backend, error) {
+	const op errors.Op = "overlay.New"
+	if len(layers) == 0 {
+		return nil, errors.E(op, "an overlay needs at least one layer")
+	}
+	if writable < 0 || writable >= len(layers) {
+		return nil, errors.E(op, "the writable layer is not one of the layers")
+	}
+	s := &storageImpl{layers: layers, writable: writable}
+	if _, ok := layers[writable].(storage.MetadataSaver); ok {
+		return &metadataStorage{s}, nil
+	}
+	return s, nil
+}
+
+// first returns the result of get for the first layer that does not return
+// a KindNotFound error. Other errors are returned right away, rather than
+// serving a version from a lower layer than the one that may have it.
+func first[T any](layers []storage.Backend, get func(storage.Backend) (T, error)) (T, error) {
+	var (
+		res T
+		err error
+	)
+	for _, l := range layers {
+		res, err = get(l)
+		if err == nil || !errors.IsNotFoundErr(err) {
+			return res, err
+		}
+	}
+	return res, err
+}
+
+// shadowed reports whether one of the layers before the one at index i has
+// the given version, which hides the version that layer i has.
+func (s *storageImpl) shadowed(ctx context.Context, i int, module, version string) (bool, error) {
+	for _, l := range s.layers[:i] {
+		ok, err := storage.WithChecker(l).Exists(ctx, module, version)
+		if err != nil || ok {
+			return ok, err
+		}
+	}
+	return false, nil
+}
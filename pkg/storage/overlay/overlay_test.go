@@ -0,0 +1,113 @@
+package overlay
+
+import (
+	"testing"
+
+	"github.com/gomods/athens/pkg/errors"
+	"github.com/gomods/athens/pkg/paths"
+	"github.com/gomods/athens/pkg/storage"
+	"github.com/gomods/athens/pkg/storage/compliance"
+	"github.com/gomods/athens/pkg/storage/compliance/fixtures"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBackend(t *testing.T) {
+	bundle, writable := fixtures.NewStorage(t), fixtures.NewStorage(t)
+	b, err := New([]storage.Backend{bundle, writable}, 1)
+	require.NoError(t, err)
+	compliance.RunTests(t, b, func() error {
+		for _, l := range []storage.Backend{bundle, writable} {
+			if err := l.(interface{ Clear() error }).Clear(); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func TestPrecedence(t *testing.T) {
+	bundle, writable := fixtures.NewStorage(t), fixtures.NewStorage(t)
+	b, err := New([]storage.Backend{bundle, writable}, 1)
+	require.NoError(t, err)
+	ctx := t.Context()
+	fixtures.Save(t, bundle, "v1.0.0", "bundle")
+	fixtures.Save(t, writable, "v1.0.0", "writable")
+	fixtures.Save(t, writable, "v1.1.0", "writable")
+
+	fixtures.RequireZip(t, b, "v1.0.0", "bundle")
+	fixtures.RequireZip(t, b, "v1.1.0", "writable")
+
+	vers, err := b.List(ctx, fixtures.Module)
+	require.NoError(t, err)
+	require.ElementsMatch(t, []string{"v1.0.0", "v1.1.0"}, vers)
+}
+
+func TestSave(t *testing.T) {
+	bundle, writable := fixtures.NewStorage(t), fixtures.NewStorage(t)
+	b, err := New([]storage.Backend{bundle, writable}, 1)
+	require.NoError(t, err)
+	fixtures.Save(t, b, "v1.0.0", "zip")
+
+	fixtures.RequireExists(t, writable, "v1.0.0", true)
+	fixtures.RequireExists(t, bundle, "v1.0.0", false)
+}
+
+func TestDelete(t *testing.T) {
+	bundle, writable := fixtures.NewStorage(t), fixtures.NewStorage(t)
+	b, err := New([]storage.Backend{bundle, writable}, 1)
+	require.NoError(t, err)
+	ctx := t.Context()
+	fixtures.Save(t, bundle, "v1.0.0", "bundle")
+	fixtures.Save(t, writable, "v1.0.0", "writable")
+	fixtures.Save(t, writable, "v1.1.0", "writable")
+
+	err = b.Delete(ctx, fixtures.Module, "v1.0.0")
+	require.True(t, errors.Is(err, errors.KindBadRequest))
+	fixtures.RequireZip(t, writable, "v1.0.0", "writable")
+
+	require.NoError(t, b.Delete(ctx, fixtures.Module, "v1.1.0"))
+	_, err = b.Info(ctx, fixtures.Module, "v1.1.0")
+	require.True(t, errors.IsNotFoundErr(err))
+}
+
+func TestCatalog(t *testing.T) {
+	bundle, writable := fixtures.NewStorage(t), fixtures.NewStorage(t)
+	b, err := New([]storage.Backend{bundle, writable}, 1)
+	require.NoError(t, err)
+	for _, v := range []string{"v1.0.0", "v1.1.0"} {
+		fixtures.Save(t, bundle, v, "bundle")
+	}
+	for _, v := range []string{"v1.1.0", "v1.2.0", "v1.3.0"} {
+		fixtures.Save(t, writable, v, "writable")
+	}
+
+	cs := b.(storage.Cataloger)
+	var all []paths.AllPathParams
+	token := ""
+	for {
+		page, next, err := cs.Catalog(t.Context(), token, 2)
+		require.NoError(t, err)
+		require.LessOrEqual(t, len(page), 2)
+		all = append(all, page...)
+		if next == "" {
+			break
+		}
+		token = next
+	}
+	var vers []string
+	for _, p := range all {
+		require.Equal(t, fixtures.Module, p.Module)
+		vers = append(vers, p.Version)
+	}
+	require.Equal(t, []string{"v1.0.0", "v1.1.0", "v1.2.0", "v1.3.0"}, vers)
+
+	_, _, err = cs.Catalog(t.Context(), "7:x", 2)
+	require.True(t, errors.Is(err, errors.KindBadRequest))
+}
+
+func TestNew(t *testing.T) {
+	_, err := New(nil, 0)
+	require.Error(t, err)
+	_, err = New([]storage.Backend{fixtures.NewStorage(t)}, 1)
+	require.Error(t, err)
+}
@@ -0,0 +1,35 @@
+package overlay
+
+import (
+	"context"
+	"io"
+
+	"github.com/gomods/athens/pkg/errors"
+	"github.com/gomods/athens/pkg/observ"
+	"github.com/gomods/athens/pkg/storage"
+)
+
+// Save implements the (./pkg/storage).Saver interface.
+// The version is saved to the writable layer.
+func (s *storageImpl) Save(ctx context.Context, module, version string, mod []byte, zip io.Reader, zipMD5, info []byte) error {
+	const op errors.Op = "overlay.Save"
+	ctx, span := observ.StartSpan(ctx, op.String())
+	defer span.End()
+	if err := s.layers[s.writable].Save(ctx, module, version, mod, zip, zipMD5, info); err != nil {
+		return errors.E(op, err, errors.M(module), errors.V(version))
+	}
+	return nil
+}
+
+// SaveMetadata implements the (./pkg/storage).MetadataSaver interface.
+// The files are saved to the writable layer.
+func (s *metadataStorage) SaveMetadata(ctx context.Context, module, version string, mod, info []byte) error {
+	const op errors.Op = "overlay.SaveMetadata"
+	ctx, span := observ.StartSpan(ctx, op.String())
+	defer span.End()
+	err := s.layers[s.writable].(storage.MetadataSaver).SaveMetadata(ctx, module, version, mod, info)
+	if err != nil {
+		return errors.E(op, err, errors.M(module), errors.V(version))
+	}
+	return nil
+}
@@ -13,3 +13,21 @@ type Saver interface {
 	// The storage implementation MAY use the zipMD5 to verify the integrity of the zip file.
 	Save(ctx context.Context, module, version string, mod []byte, zip io.Reader, zipMD5, info []byte) error
 }
+
+// MetadataSaver is implemented by backends that can save the .info and .mod
+// files of a module version ahead of its source, so that the zip is only
+// downloaded once it is requested.
+//
+// Until Save is called for the same version, the version is partially
+// populated: Info and GoMod return its files, but Zip returns a KindNotFound
+// error and the version is left out of List, Catalog and Exists. Delete
+// removes partially populated versions as well.
+//
+// A MetadataSaver is a Checker too, so that Exists tells the versions that
+// miss their zip apart without reading their zip.
+type MetadataSaver interface {
+	Checker
+
+	// SaveMetadata saves the .info and .mod files of the module version.
+	SaveMetadata(ctx context.Context, module, version string, mod, info []byte) error
+}
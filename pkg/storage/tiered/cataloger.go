@@ -0,0 +1,28 @@
+package tiered
+
+import (
+	"context"
+
+	"github.com/gomods/athens/pkg/errors"
+	"github.com/gomods/athens/pkg/observ"
+	"github.com/gomods/athens/pkg/paths"
+	"github.com/gomods/athens/pkg/storage"
+)
+
+// Catalog implements the (./pkg/storage).Cataloger interface.
+// It returns the catalog of the remote storage, or a KindNotImplemented
+// error if the remote storage has none.
+func (s *storageImpl) Catalog(ctx context.Context, token string, pageSize int) ([]paths.AllPathParams, string, error) {
+	const op errors.Op = "tiered.Catalog"
+	ctx, span := observ.StartSpan(ctx, op.String())
+	defer span.End()
+	cs, ok := s.remote.(storage.Cataloger)
+	if !ok {
+		return nil, "", errors.E(op, "the remote storage has no catalog", errors.KindNotImplemented)
+	}
+	res, next, err := cs.Catalog(ctx, token, pageSize)
+	if err != nil {
+		return nil, "", errors.E(op, err)
+	}
+	return res, next, nil
+}
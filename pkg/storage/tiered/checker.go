@@ -0,0 +1,25 @@
+package tiered
+
+import (
+	"context"
+
+	"github.com/gomods/athens/pkg/errors"
+	"github.com/gomods/athens/pkg/observ"
+	"github.com/gomods/athens/pkg/storage"
+)
+
+// Exists implements the (./pkg/storage).Checker interface. Versions
+// whose zip is cached exist without asking the remote storage.
+func (s *storageImpl) Exists(ctx context.Context, module, version string) (bool, error) {
+	const op errors.Op = "tiered.Exists"
+	ctx, span := observ.StartSpan(ctx, op.String())
+	defer span.End()
+	if ok, err := storage.WithChecker(s.local).Exists(ctx, module, version); err == nil && ok {
+		return true, nil
+	}
+	ok, err := storage.WithChecker(s.remote).Exists(ctx, module, version)
+	if err != nil {
+		return false, errors.E(op, err, errors.M(module), errors.V(version))
+	}
+	return ok, nil
+}
@@ -0,0 +1,37 @@
+package tiered
+
+import (
+	"context"
+
+	"github.com/gomods/athens/pkg/errors"
+	"github.com/gomods/athens/pkg/observ"
+	"github.com/spf13/afero"
+)
+
+// Delete implements the (./pkg/storage).Deleter interface. The version is
+// deleted from the remote storage, and then from the cache, whether or not
+// the remote storage had it.
+func (s *storageImpl) Delete(ctx context.Context, module, version string) error {
+	const op errors.Op = "tiered.Delete"
+	ctx, span := observ.StartSpan(ctx, op.String())
+	defer span.End()
+	remoteErr := s.remote.Delete(ctx, module, version)
+	if remoteErr != nil && !errors.IsNotFoundErr(remoteErr) {
+		return errors.E(op, remoteErr, errors.M(module), errors.V(version))
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	dir := s.versionDir(module, version)
+	if ok, err := afero.DirExists(s.fs, dir); err != nil {
+		return errors.E(op, err, errors.M(module), errors.V(version))
+	} else if ok {
+		if err := s.remove(dir); err != nil {
+			return errors.E(op, err, errors.M(module), errors.V(version))
+		}
+	}
+	if remoteErr != nil {
+		return errors.E(op, remoteErr, errors.M(module), errors.V(version))
+	}
+	return nil
+}
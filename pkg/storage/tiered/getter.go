@@ -0,0 +1,134 @@
+package tiered
+
+import (
+	"context"
+
+	"github.com/gomods/athens/pkg/errors"
+	"github.com/gomods/athens/pkg/log"
+	"github.com/gomods/athens/pkg/observ"
+	"github.com/gomods/athens/pkg/storage"
+)
+
+// Info implements the (./pkg/storage).Getter interface.
+func (s *storageImpl) Info(ctx context.Context, module, version string) ([]byte, error) {
+	const op errors.Op = "tiered.Info"
+	ctx, span := observ.StartSpan(ctx, op.String())
+	defer span.End()
+	if info, err := s.local.Info(ctx, module, version); err == nil {
+		s.touch(module, version)
+		return info, nil
+	}
+	_, info, err := s.cacheMetadata(ctx, module, version)
+	if err != nil {
+		return nil, errors.E(op, err, errors.M(module), errors.V(version))
+	}
+	return info, nil
+}
+
+// GoMod implements the (./pkg/storage).Getter interface.
+func (s *storageImpl) GoMod(ctx context.Context, module, version string) ([]byte, error) {
+	const op errors.Op = "tiered.GoMod"
+	ctx, span := observ.StartSpan(ctx, op.String())
+	defer span.End()
+	if mod, err := s.local.GoMod(ctx, module, version); err == nil {
+		s.touch(module, version)
+		return mod, nil
+	}
+	mod, _, err := s.cacheMetadata(ctx, module, version)
+	if err != nil {
+		return nil, errors.E(op, err, errors.M(module), errors.V(version))
+	}
+	return mod, nil
+}
+
+// Zip implements the (./pkg/storage).Getter interface. A version that is not
+// cached yet is cached as a whole before its zip is served, or is served
+// from the remote storage if that fails.
+func (s *storageImpl) Zip(ctx context.Context, module, version string) (storage.SizeReadCloser, error) {
+	const op errors.Op = "tiered.Zip"
+	ctx, span := observ.StartSpan(ctx, op.String())
+	defer span.End()
+	if zip, err := s.local.Zip(ctx, module, version); err == nil {
+		s.touch(module, version)
+		return zip, nil
+	}
+
+	_, err, _ := s.group.Do("zip "+module+"@"+version, func() (any, error) {
+		return nil, s.cacheVersion(ctx, module, version)
+	})
+	switch {
+	case err == nil:
+		if zip, err := s.local.Zip(ctx, module, version); err == nil {
+			return zip, nil
+		}
+		// the version was evicted right away, it is
+		// larger than the maximum size of the cache.
+	case errors.IsNotFoundErr(err):
+		return nil, errors.E(op, err, errors.M(module), errors.V(version))
+	default:
+		log.EntryFromContext(ctx).SystemErr(errors.E(op, err, errors.M(module), errors.V(version)))
+	}
+	zip, err := s.remote.Zip(ctx, module, version)
+	if err != nil {
+		return nil, errors.E(op, err, errors.M(module), errors.V(version))
+	}
+	return zip, nil
+}
+
+// cacheMetadata reads the .mod and .info files of a version from the remote
+// storage and caches them, unless the version is cached already. The files
+// are returned even if they cannot be cached.
+func (s *storageImpl) cacheMetadata(ctx context.Context, module, version string) ([]byte, []byte, error) {
+	res, err, _ := s.group.Do("metadata "+module+"@"+version, func() (any, error) {
+		info, err := s.remote.Info(ctx, module, version)
+		if err != nil {
+			return nil, err
+		}
+		mod, err := s.remote.GoMod(ctx, module, version)
+		if err != nil {
+			return nil, err
+		}
+		tmp, _, err := s.stage(func(st storage.Backend) error {
+			return st.(storage.MetadataSaver).SaveMetadata(ctx, module, version, mod, info)
+		})
+		if err == nil {
+			err = s.commit(tmp, module, version, false)
+			_ = s.fs.RemoveAll(tmp)
+		}
+		if err != nil {
+			log.EntryFromContext(ctx).SystemErr(err)
+		}
+		return [2][]byte{mod, info}, nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	files := res.([2][]byte)
+	return files[0], files[1], nil
+}
+
+// cacheVersion reads a version from the remote storage and caches it,
+// replacing its .mod and .info files if only those are cached.
+func (s *storageImpl) cacheVersion(ctx context.Context, module, version string) error {
+	zip, err := s.remote.Zip(ctx, module, version)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = zip.Close() }()
+	info, err := s.remote.Info(ctx, module, version)
+	if err != nil {
+		return err
+	}
+	mod, err := s.remote.GoMod(ctx, module, version)
+	if err != nil {
+		return err
+	}
+	tmp, _, err := s.stage(func(st storage.Backend) error {
+		return st.Save(ctx, module, version, mod, zip, nil, info)
+	})
+	if err != nil {
+		return err
+	}
+	defer func() { _ = s.fs.RemoveAll(tmp) }()
+	return s.commit(tmp, module, version, true)
+}
@@ -0,0 +1,22 @@
+package tiered
+
+import (
+	"context"
+
+	"github.com/gomods/athens/pkg/errors"
+	"github.com/gomods/athens/pkg/observ"
+)
+
+// List implements the (./pkg/storage).Lister interface. The cache
+// may hold only some of the versions, so they come from the remote
+// storage.
+func (s *storageImpl) List(ctx context.Context, module string) ([]string, error) {
+	const op errors.Op = "tiered.List"
+	ctx, span := observ.StartSpan(ctx, op.String())
+	defer span.End()
+	vers, err := s.remote.List(ctx, module)
+	if err != nil {
+		return nil, errors.E(op, err, errors.M(module))
+	}
+	return vers, nil
+}
@@ -0,0 +1,55 @@
+package tiered
+
+import (
+	"context"
+	"io"
+
+	"github.com/gomods/athens/pkg/errors"
+	"github.com/gomods/athens/pkg/log"
+	"github.com/gomods/athens/pkg/observ"
+	"github.com/gomods/athens/pkg/storage"
+)
+
+// Save implements the (./pkg/storage).Saver interface. The version is
+// written to the cache first, then saved in the remote storage from
+// there, and only becomes visible in the cache once that succeeds.
+func (s *storageImpl) Save(ctx context.Context, module, version string, mod []byte, zip io.Reader, zipMD5, info []byte) error {
+	const op errors.Op = "tiered.Save"
+	ctx, span := observ.StartSpan(ctx, op.String())
+	defer span.End()
+	tmp, st, err := s.stage(func(st storage.Backend) error {
+		return st.Save(ctx, module, version, mod, zip, zipMD5, info)
+	})
+	if err != nil {
+		return errors.E(op, err, errors.M(module), errors.V(version))
+	}
+	defer func() { _ = s.fs.RemoveAll(tmp) }()
+
+	staged, err := st.Zip(ctx, module, version)
+	if err != nil {
+		return errors.E(op, err, errors.M(module), errors.V(version))
+	}
+	defer func() { _ = staged.Close() }()
+	if err := s.remote.Save(ctx, module, version, mod, staged, zipMD5, info); err != nil {
+		return errors.E(op, err, errors.M(module), errors.V(version))
+	}
+	if err := s.commit(tmp, module, version, true); err != nil {
+		// the version is saved, it is cached once it is read.
+		log.EntryFromContext(ctx).SystemErr(errors.E(op, err, errors.M(module), errors.V(version)))
+	}
+	return nil
+}
+
+// SaveMetadata implements the (./pkg/storage).MetadataSaver interface.
+// The files are only saved in the remote storage, and cached once
+// they are read.
+func (s *metadataStorage) SaveMetadata(ctx context.Context, module, version string, mod, info []byte) error {
+	const op errors.Op = "tiered.SaveMetadata"
+	ctx, span := observ.StartSpan(ctx, op.String())
+	defer span.End()
+	err := s.remote.(storage.MetadataSaver).SaveMetadata(ctx, module, version, mod, info)
+	if err != nil {
+		return errors.E(op, err, errors.M(module), errors.V(version))
+	}
+	return nil
+}
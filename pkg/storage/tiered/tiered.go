@@ -0,0 +1,270 @@
+// Package tiered provides a storage backend that caches the versions of a
+// durable remote backend, such as S3 or GCS, on local disk.
+package tiered
+
+import (
+	"container/list"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gomods/athens/pkg/errors"
+	"github.com/gomods/athens/pkg/storage"
+	fsstorage "github.com/gomods/athens/pkg/storage/fs"
+	"github.com/spf13/afero"
+	"golang.org/x/sync/singleflight"
+)
+
+// stagingDir is the directory under the root of the cache where versions are
+// written before they are moved into the cache. No module path starts with a
+// dot, so it never clashes with a cached module.
+const stagingDir = ".staging"
+
+type storageImpl struct {
+	remote  storage.Backend
+	local   storage.Backend
+	fs      afero.Fs
+	rootDir string
+	maxSize int64
+	now     func() time.Time
+	group   singleflight.Group
+
+	// mu is held while versions are moved into the cache, used, deleted
+	// from it or evicted, and guards size, the bytes the cache takes, and
+	// the index of the cached versions: lru holds them from the most to the
+	// least recently used, and index holds the element of lru of each, by
+	// the directory of the version.
+	mu    sync.Mutex
+	size  int64
+	lru   *list.List
+	index map[string]*list.Element
+}
+
+// metadataStorage is a storageImpl whose remote storage
+// implements the (./pkg/storage).MetadataSaver interface.
+type metadataStorage struct {
+	*storageImpl
+}
+
+// New returns a storage.Backend that stores everything in remote and caches
+// the versions it reads under rootDir, in the layout of the disk storage.
+// The least recently used versions are evicted once the cache takes more than
+// maxSize bytes. A maxSize of zero means the cache is unbounded.
+//
+// Versions never change once they are saved, so cached versions are only
+// invalidated by Delete. Other Athens instances sharing the same remote
+// storage keep serving a deleted version from their cache until they evict it.
+func New(remote storage.Backend, rootDir string, fs afero.Fs, maxSize int64) (storage.Backend, error) {
+	const op errors.Op = "tiered.New"
+	if err := fs.MkdirAll(rootDir, os.ModeDir|os.ModePerm); err != nil {
+		return nil, errors.E(op, err)
+	}
+	// versions left over by a previous run were never moved into the cache.
+	if err := fs.RemoveAll(filepath.Join(rootDir, stagingDir)); err != nil {
+		return nil, errors.E(op, err)
+	}
+	local, err := fsstorage.NewStorage(rootDir, fs)
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+	s := &storageImpl{
+		remote:  remote,
+		local:   local,
+		fs:      fs,
+		rootDir: rootDir,
+		maxSize: maxSize,
+		now:     time.Now,
+		lru:     list.New(),
+		index:   map[string]*list.Element{},
+	}
+	// the index is built from the cache left by a previous
+	// run, and kept up to date from then on.
+	versions, err := s.cached()
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+	sort.Slice(versions, func(i, j int) bool { return versions[i].lastUsed.After(versions[j].lastUsed) })
+	for _, v := range versions {
+		s.index[v.dir] = s.lru.PushBack(v)
+		s.size += v.size
+	}
+	s.mu.Lock()
+	err = s.evict()
+	s.mu.Unlock()
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+	if _, ok := remote.(storage.MetadataSaver); ok {
+		return &metadataStorage{s}, nil
+	}
+	return s, nil
+}
+
+func (s *storageImpl) versionDir(mod, ver string) string {
+	return filepath.Join(s.rootDir, mod, ver)
+}
+
+// touch marks a cached version as recently used.
+func (s *storageImpl) touch(mod, ver string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.used(s.versionDir(mod, ver))
+}
+
+// used moves the version in dir to the front of the index. The modification
+// time of the .info file of a version is the last time it was used, so that
+// the index is rebuilt in the same order on restarts. The caller must hold s.mu.
+func (s *storageImpl) used(dir string) {
+	now := s.now()
+	_ = s.fs.Chtimes(filepath.Join(dir, filepath.Base(dir)+".info"), now, now)
+	if e, ok := s.index[dir]; ok {
+		s.lru.MoveToFront(e)
+	}
+}
+
+// stage saves a version with save into a new directory under the staging
+// directory, so that readers of the cache never see partially written
+// files. The directory must be removed once the version is committed.
+func (s *storageImpl) stage(save func(st storage.Backend) error) (string, storage.Backend, error) {
+	staging := filepath.Join(s.rootDir, stagingDir)
+	if err := s.fs.MkdirAll(staging, os.ModeDir|os.ModePerm); err != nil {
+		return "", nil, err
+	}
+	tmp, err := afero.TempDir(s.fs, staging, "")
+	if err != nil {
+		return "", nil, err
+	}
+	st, err := fsstorage.NewStorage(tmp, s.fs)
+	if err == nil {
+		err = save(st)
+	}
+	if err != nil {
+		_ = s.fs.RemoveAll(tmp)
+		return "", nil, err
+	}
+	return tmp, st, nil
+}
+
+// commit moves a version staged in tmp into the cache and evicts the least
+// recently used versions if the cache outgrows its maximum size. A version
+// already in the cache is replaced if replace is true, and kept otherwise.
+func (s *storageImpl) commit(tmp, mod, ver string, replace bool) error {
+	staged := filepath.Join(tmp, mod, ver)
+	size, err := dirSize(s.fs, staged)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	dir := s.versionDir(mod, ver)
+	if ok, err := afero.DirExists(s.fs, dir); err != nil {
+		return err
+	} else if ok {
+		if !replace {
+			return nil
+		}
+		if err := s.remove(dir); err != nil {
+			return err
+		}
+	} else if _, ok := s.index[dir]; ok {
+		// the version was removed from the disk behind our back.
+		if err := s.remove(dir); err != nil {
+			return err
+		}
+	}
+	if err := s.fs.MkdirAll(filepath.Dir(dir), os.ModeDir|os.ModePerm); err != nil {
+		return err
+	}
+	if err := s.fs.Rename(staged, dir); err != nil {
+		return err
+	}
+	s.index[dir] = s.lru.PushFront(cachedVersion{dir: dir, size: size})
+	s.used(dir)
+	s.size += size
+	return s.evict()
+}
+
+// remove removes a version from the cache. The caller must hold s.mu.
+func (s *storageImpl) remove(dir string) error {
+	e, ok := s.index[dir]
+	if !ok {
+		// not indexed, so not counted in size either.
+		return s.fs.RemoveAll(dir)
+	}
+	if err := s.fs.RemoveAll(dir); err != nil {
+		return err
+	}
+	s.lru.Remove(e)
+	delete(s.index, dir)
+	s.size -= e.Value.(cachedVersion).size
+	return nil
+}
+
+type cachedVersion struct {
+	dir      string
+	lastUsed time.Time
+	size     int64
+}
+
+// cached returns the versions in the cache, going through all of it.
+func (s *storageImpl) cached() ([]cachedVersion, error) {
+	var versions []cachedVersion
+	staging := filepath.Join(s.rootDir, stagingDir)
+	err := afero.Walk(s.fs, s.rootDir, func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if fi.IsDir() {
+			if path == staging {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		// every cached version has a {version}.info
+		// file in the directory named after it.
+		dir := filepath.Dir(path)
+		if fi.Name() != filepath.Base(dir)+".info" {
+			return nil
+		}
+		size, err := dirSize(s.fs, dir)
+		if err != nil {
+			return err
+		}
+		versions = append(versions, cachedVersion{dir: dir, lastUsed: fi.ModTime(), size: size})
+		return nil
+	})
+	return versions, err
+}
+
+// evict removes the least recently used versions until the cache fits in its
+// maximum size. The caller must hold s.mu.
+func (s *storageImpl) evict() error {
+	const op errors.Op = "tiered.evict"
+	if s.maxSize <= 0 {
+		return nil
+	}
+	for s.size > s.maxSize && s.lru.Len() > 0 {
+		if err := s.remove(s.lru.Back().Value.(cachedVersion).dir); err != nil {
+			return errors.E(op, err)
+		}
+	}
+	return nil
+}
+
+// dirSize returns the size of the files in dir, not counting subdirectories.
+func dirSize(fs afero.Fs, dir string) (int64, error) {
+	fis, err := afero.ReadDir(fs, dir)
+	if err != nil {
+		return 0, err
+	}
+	var size int64
+	for _, fi := range fis {
+		if !fi.IsDir() {
+			size += fi.Size()
+		}
+	}
+	return size, nil
+}
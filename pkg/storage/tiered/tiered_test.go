@@ -0,0 +1,164 @@
+package tiered
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gomods/athens/pkg/errors"
+	"github.com/gomods/athens/pkg/storage"
+	"github.com/gomods/athens/pkg/storage/compliance"
+	"github.com/gomods/athens/pkg/storage/compliance/fixtures"
+	fsstorage "github.com/gomods/athens/pkg/storage/fs"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBackend(t *testing.T) {
+	remote, clearRemote := getRemote(t)
+	fs := afero.NewMemMapFs()
+	b, err := New(remote, "/cache", fs, 1<<20)
+	require.NoError(t, err)
+	compliance.RunTests(t, b, func() error {
+		if err := clearRemote(); err != nil {
+			return err
+		}
+		return fs.RemoveAll("/cache/github.com")
+	})
+}
+
+func TestCachesOnRead(t *testing.T) {
+	remote, _ := getRemote(t)
+	b := getStorage(t, remote, 1<<20)
+	ctx := t.Context()
+	fixtures.Save(t, remote, "v1.0.0", zipOf(10))
+
+	fixtures.RequireZip(t, b, "v1.0.0", zipOf(10))
+	require.True(t, b.cachedVersion("v1.0.0"))
+
+	// the cached version is served without the remote storage.
+	require.NoError(t, remote.Delete(ctx, fixtures.Module, "v1.0.0"))
+	fixtures.RequireZip(t, b, "v1.0.0", zipOf(10))
+	info, err := b.Info(ctx, fixtures.Module, "v1.0.0")
+	require.NoError(t, err)
+	require.Equal(t, []byte("v1.0.0"), info)
+	ok, err := b.Exists(ctx, fixtures.Module, "v1.0.0")
+	require.NoError(t, err)
+	require.True(t, ok)
+}
+
+func TestSaveWritesThrough(t *testing.T) {
+	remote, _ := getRemote(t)
+	b := getStorage(t, remote, 1<<20)
+
+	fixtures.Save(t, b, "v1.0.0", zipOf(10))
+	fixtures.RequireExists(t, remote, "v1.0.0", true)
+	require.True(t, b.cachedVersion("v1.0.0"))
+
+	staged, err := afero.ReadDir(b.fs, filepath.Join(b.rootDir, stagingDir))
+	require.NoError(t, err)
+	require.Empty(t, staged)
+}
+
+func TestEvictsLeastRecentlyUsed(t *testing.T) {
+	remote, _ := getRemote(t)
+	// every version takes 100 bytes for its zip and
+	// 6 bytes each for its .mod and .info files.
+	b := getStorage(t, remote, 250)
+	clock := time.Now()
+	b.now = func() time.Time {
+		clock = clock.Add(time.Minute)
+		return clock
+	}
+	for _, v := range []string{"v1.0.0", "v1.1.0", "v1.2.0"} {
+		fixtures.Save(t, remote, v, zipOf(100))
+	}
+
+	fixtures.RequireZip(t, b, "v1.0.0", zipOf(100))
+	fixtures.RequireZip(t, b, "v1.1.0", zipOf(100))
+	// v1.0.0 is used again, so v1.1.0 is the least recently used.
+	fixtures.RequireZip(t, b, "v1.0.0", zipOf(100))
+	fixtures.RequireZip(t, b, "v1.2.0", zipOf(100))
+
+	require.True(t, b.cachedVersion("v1.0.0"))
+	require.False(t, b.cachedVersion("v1.1.0"))
+	require.True(t, b.cachedVersion("v1.2.0"))
+	require.Equal(t, int64(224), b.size)
+}
+
+func TestLargerThanCache(t *testing.T) {
+	remote, _ := getRemote(t)
+	b := getStorage(t, remote, 50)
+	fixtures.Save(t, remote, "v1.0.0", zipOf(100))
+
+	fixtures.RequireZip(t, b, "v1.0.0", zipOf(100))
+	require.False(t, b.cachedVersion("v1.0.0"))
+	require.Equal(t, int64(0), b.size)
+}
+
+func TestEvictsOnStartup(t *testing.T) {
+	remote, _ := getRemote(t)
+	b := getStorage(t, remote, 1<<20)
+	clock := time.Now()
+	b.now = func() time.Time {
+		clock = clock.Add(time.Minute)
+		return clock
+	}
+	for _, v := range []string{"v1.0.0", "v1.1.0"} {
+		fixtures.Save(t, remote, v, zipOf(100))
+		fixtures.RequireZip(t, b, v, zipOf(100))
+	}
+	fixtures.RequireZip(t, b, "v1.0.0", zipOf(100))
+	require.Equal(t, int64(224), b.size)
+
+	// the versions are indexed in the order they were last used.
+	s, err := New(remote, b.rootDir, b.fs, 150)
+	require.NoError(t, err)
+	restarted := s.(*metadataStorage).storageImpl
+	require.Equal(t, int64(112), restarted.size)
+	require.True(t, restarted.cachedVersion("v1.0.0"))
+	require.False(t, restarted.cachedVersion("v1.1.0"))
+}
+
+func TestCatalog(t *testing.T) {
+	remote, _ := getRemote(t)
+	b := getStorage(t, remote, 1<<20)
+	fixtures.Save(t, remote, "v1.0.0", zipOf(10))
+
+	res, _, err := b.Catalog(t.Context(), "", 10)
+	require.NoError(t, err)
+	require.Len(t, res, 1)
+	require.Equal(t, fixtures.Module, res[0].Module)
+	require.Equal(t, "v1.0.0", res[0].Version)
+
+	b.remote = fixtures.NoCatalog{Backend: remote}
+	_, _, err = b.Catalog(t.Context(), "", 10)
+	require.True(t, errors.Is(err, errors.KindNotImplemented))
+}
+
+func getRemote(t *testing.T) (storage.Backend, func() error) {
+	t.Helper()
+	fs := afero.NewMemMapFs()
+	require.NoError(t, fs.MkdirAll("/remote", 0o777))
+	remote, err := fsstorage.NewStorage("/remote", fs)
+	require.NoError(t, err)
+	return remote, func() error { return fs.RemoveAll("/remote/github.com") }
+}
+
+func getStorage(t *testing.T, remote storage.Backend, maxSize int64) *storageImpl {
+	t.Helper()
+	b, err := New(remote, "/cache", afero.NewMemMapFs(), maxSize)
+	require.NoError(t, err)
+	return b.(*metadataStorage).storageImpl
+}
+
+// zipOf returns a zip of size bytes.
+func zipOf(size int) string {
+	return strings.Repeat("z", size)
+}
+
+func (s *storageImpl) cachedVersion(ver string) bool {
+	ok, _ := afero.Exists(s.fs, filepath.Join(s.versionDir(fixtures.Module, ver), "source.zip"))
+	return ok
+}
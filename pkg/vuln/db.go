@@ -0,0 +1,180 @@
+// Package vuln denies the module versions that an offline Go vulnerability
+// database has advisories for, and serves mirrors of such databases.
+package vuln
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/gomods/athens/pkg/errors"
+	"golang.org/x/mod/semver"
+)
+
+// Entry is an OSV advisory, with the fields that DB reads.
+type Entry struct {
+	ID               string     `json:"id"`
+	Aliases          []string   `json:"aliases,omitempty"`
+	Summary          string     `json:"summary,omitempty"`
+	Withdrawn        string     `json:"withdrawn,omitempty"`
+	Affected         []Affected `json:"affected"`
+	DatabaseSpecific struct {
+		Severity string `json:"severity,omitempty"`
+	} `json:"database_specific"`
+}
+
+// Affected is a package that an advisory affects, and its affected versions.
+type Affected struct {
+	Package struct {
+		Name      string `json:"name"`
+		Ecosystem string `json:"ecosystem"`
+	} `json:"package"`
+	Ranges []Range `json:"ranges,omitempty"`
+}
+
+// Range is a list of events, in version order, that
+// introduce or fix an advisory.
+type Range struct {
+	Type   string       `json:"type"`
+	Events []RangeEvent `json:"events"`
+}
+
+// RangeEvent is an event of a Range. Versions have no v prefix, and
+// an Introduced version of "0" is the first version of a module.
+type RangeEvent struct {
+	Introduced string `json:"introduced,omitempty"`
+	Fixed      string `json:"fixed,omitempty"`
+}
+
+// DB is an offline Go vulnerability database, in the layout that vuln.go.dev
+// serves, and the policy for the module versions it has advisories for.
+// It is safe for concurrent use, and can be replaced with Update while it
+// is in use. The zero DB has no advisories.
+type DB struct {
+	mu      sync.RWMutex
+	entries map[string][]*Entry
+	policy  Policy
+}
+
+// Open loads the advisories of the ID directory of the database at path,
+// which is a directory or a zip archive.
+func Open(path string, p Policy) (*DB, error) {
+	const op errors.Op = "vuln.Open"
+	var fsys fs.FS
+	if strings.HasSuffix(path, ".zip") {
+		zr, err := zip.OpenReader(filepath.Clean(path))
+		if err != nil {
+			return nil, errors.E(op, err)
+		}
+		defer func() { _ = zr.Close() }()
+		fsys = zr
+	} else {
+		fi, err := os.Stat(path)
+		if err != nil {
+			return nil, errors.E(op, err)
+		}
+		if !fi.IsDir() {
+			return nil, errors.E(op, path+" is neither a directory nor a zip archive")
+		}
+		fsys = os.DirFS(path)
+	}
+
+	names, err := fs.Glob(fsys, "ID/*.json")
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+	if len(names) == 0 {
+		return nil, errors.E(op, path+" has no advisories in its ID directory")
+	}
+	db := &DB{entries: map[string][]*Entry{}, policy: p}
+	for _, name := range names {
+		// older databases also have an index of the advisories there.
+		if name == "ID/index.json" {
+			continue
+		}
+		b, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return nil, errors.E(op, err)
+		}
+		var e Entry
+		if err := json.Unmarshal(b, &e); err != nil {
+			return nil, errors.E(op, name+": "+err.Error())
+		}
+		if e.Withdrawn != "" {
+			continue
+		}
+		seen := map[string]bool{}
+		for _, a := range e.Affected {
+			if mod := a.Package.Name; !seen[mod] {
+				seen[mod] = true
+				db.entries[mod] = append(db.entries[mod], &e)
+			}
+		}
+	}
+	return db, nil
+}
+
+// Update replaces the advisories and the policy of db with the
+// ones of other, which must not be used afterwards.
+func (db *DB) Update(other *DB) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	db.entries, db.policy = other.entries, other.policy
+}
+
+// Vulns returns the advisories that affect the given module version.
+func (db *DB) Vulns(mod, ver string) []*Entry {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	return db.vulns(mod, ver)
+}
+
+func (db *DB) vulns(mod, ver string) []*Entry {
+	if !semver.IsValid(ver) {
+		return nil
+	}
+	var res []*Entry
+	for _, e := range db.entries[mod] {
+		if e.affects(mod, ver) {
+			res = append(res, e)
+		}
+	}
+	return res
+}
+
+func (e *Entry) affects(mod, ver string) bool {
+	for _, a := range e.Affected {
+		if a.Package.Name != mod {
+			continue
+		}
+		// an advisory without ranges affects every version.
+		if len(a.Ranges) == 0 {
+			return true
+		}
+		for _, r := range a.Ranges {
+			if r.Type == "SEMVER" && r.affects(ver) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (r Range) affects(ver string) bool {
+	affected := false
+	for _, ev := range r.Events {
+		switch {
+		case ev.Introduced == "0":
+			affected = true
+		case ev.Introduced != "" && semver.Compare(ver, "v"+ev.Introduced) >= 0:
+			affected = true
+		case ev.Fixed != "" && semver.Compare(ver, "v"+ev.Fixed) >= 0:
+			affected = false
+		}
+	}
+	return affected
+}
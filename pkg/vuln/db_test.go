@@ -0,0 +1,143 @@
+package vuln
+
+import (
+	"archive/zip"
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+var testEntries = map[string]string{
+	"ID/GO-2024-0001.json": `{
+		"id": "GO-2024-0001",
+		"aliases": ["CVE-2024-0001"],
+		"affected": [{
+			"package": {"name": "github.com/a/b", "ecosystem": "Go"},
+			"ranges": [{"type": "SEMVER", "events": [{"introduced": "0"}, {"fixed": "1.2.0"}, {"introduced": "1.3.0"}, {"fixed": "1.3.5"}]}]
+		}]
+	}`,
+	"ID/GO-2024-0002.json": `{
+		"id": "GO-2024-0002",
+		"affected": [{
+			"package": {"name": "github.com/a/b", "ecosystem": "Go"},
+			"ranges": [{"type": "SEMVER", "events": [{"introduced": "1.1.0"}]}]
+		}],
+		"database_specific": {"severity": "LOW"}
+	}`,
+	"ID/GO-2024-0003.json": `{
+		"id": "GO-2024-0003",
+		"withdrawn": "2024-02-01T00:00:00Z",
+		"affected": [{"package": {"name": "github.com/c/d", "ecosystem": "Go"}}]
+	}`,
+	"index/db.json":      `{"modified": "2024-02-01T00:00:00Z"}`,
+	"index/modules.json": `[{"path": "github.com/a/b", "vulns": [{"id": "GO-2024-0001"}, {"id": "GO-2024-0002"}]}]`,
+}
+
+func writeDir(t *testing.T) string {
+	dir := t.TempDir()
+	for name, content := range testEntries {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(p), 0o755))
+		require.NoError(t, os.WriteFile(p, []byte(content), 0o600))
+	}
+	return dir
+}
+
+func writeZip(t *testing.T) string {
+	p := filepath.Join(t.TempDir(), "vulndb.zip")
+	require.NoError(t, os.WriteFile(p, testArchive(t, testEntries), 0o600))
+	return p
+}
+
+func testArchive(t *testing.T, entries map[string]string) []byte {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range entries {
+		w, err := zw.Create(name)
+		require.NoError(t, err)
+		_, err = w.Write([]byte(content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, zw.Close())
+	return buf.Bytes()
+}
+
+func TestVulns(t *testing.T) {
+	for name, path := range map[string]string{"dir": writeDir(t), "zip": writeZip(t)} {
+		t.Run(name, func(t *testing.T) {
+			db, err := Open(path, Policy{Action: Deny})
+			require.NoError(t, err)
+			ids := func(ver string) []string {
+				var res []string
+				for _, e := range db.Vulns("github.com/a/b", ver) {
+					res = append(res, e.ID)
+				}
+				return res
+			}
+			require.Equal(t, []string{"GO-2024-0001"}, ids("v1.0.0"))
+			require.ElementsMatch(t, []string{"GO-2024-0001", "GO-2024-0002"}, ids("v1.1.0"))
+			require.Equal(t, []string{"GO-2024-0002"}, ids("v1.2.0"))
+			require.ElementsMatch(t, []string{"GO-2024-0001", "GO-2024-0002"}, ids("v1.3.4"))
+			require.Empty(t, ids("not-a-version"))
+			// withdrawn
+			require.Empty(t, db.Vulns("github.com/c/d", "v1.0.0"))
+		})
+	}
+
+	_, err := Open(t.TempDir(), Policy{})
+	require.Error(t, err)
+	var zero DB
+	require.Empty(t, zero.Check("github.com/a/b", "v1.0.0"))
+}
+
+func TestCheck(t *testing.T) {
+	dir := writeDir(t)
+	for _, tc := range []struct {
+		name   string
+		policy Policy
+		mod    string
+		want   Result
+	}{
+		{
+			name:   "deny",
+			policy: Policy{Action: Deny},
+			want:   Result{Denied: []string{"GO-2024-0001", "GO-2024-0002"}},
+		},
+		{
+			name:   "flag",
+			policy: Policy{Action: Flag},
+			want:   Result{Flagged: []string{"GO-2024-0001", "GO-2024-0002"}},
+		},
+		{
+			name:   "min severity",
+			policy: Policy{Action: Deny, MinSeverity: "moderate"},
+			want:   Result{Denied: []string{"GO-2024-0001"}, Flagged: []string{"GO-2024-0002"}},
+		},
+		{
+			name:   "ignore alias",
+			policy: Policy{Action: Deny, Ignore: []string{"CVE-2024-0001"}},
+			want:   Result{Denied: []string{"GO-2024-0002"}},
+		},
+		{
+			name:   "exception",
+			policy: Policy{Action: Deny, Exceptions: []string{"github.com/a"}},
+			want:   Result{Flagged: []string{"GO-2024-0001", "GO-2024-0002"}},
+		},
+		{
+			name:   "exception for an advisory",
+			policy: Policy{Action: Deny, Exceptions: []string{"github.com/*/b@GO-2024-0002"}},
+			want:   Result{Denied: []string{"GO-2024-0001"}, Flagged: []string{"GO-2024-0002"}},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			db, err := Open(dir, tc.policy)
+			require.NoError(t, err)
+			res := db.Check("github.com/a/b", "v1.1.0")
+			require.ElementsMatch(t, tc.want.Denied, res.Denied)
+			require.ElementsMatch(t, tc.want.Flagged, res.Flagged)
+		})
+	}
+}
@@ -0,0 +1,271 @@
+package vuln
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gomods/athens/pkg/errors"
+	"github.com/gomods/athens/pkg/observ"
+	"github.com/gomods/athens/pkg/storage"
+	"golang.org/x/mod/semver"
+)
+
+// MirrorModule is the module path under which a Mirror keeps the
+// archive of the database in storage, as a version named after the
+// time it was modified. The .invalid top level domain makes sure no
+// real module has it, and keeps it out of the module endpoints, see
+// (./pkg/storage).IsInternal.
+const MirrorModule = "athens.invalid/vulndb"
+
+// MaxArchiveSize is the size that a database archive may have.
+const MaxArchiveSize = 128 << 20
+
+// maxFilesSize is the size that the files of a database archive may
+// have once uncompressed, since a Mirror holds them in memory.
+var maxFilesSize int64 = 512 << 20
+
+// dbIndex is the index/db.json file of a database.
+type dbIndex struct {
+	Modified time.Time `json:"modified"`
+}
+
+// Mirror serves a vuln.go.dev compatible vulnerability database, whose
+// archives it keeps in storage, so that every Athens instance with the
+// same storage serves the same database. It is safe for concurrent use.
+type Mirror struct {
+	s storage.Backend
+
+	mu       sync.RWMutex
+	ver      string
+	modified time.Time
+	files    map[string][]byte
+}
+
+// NewMirror returns a Mirror that keeps its archives in s.
+// It serves nothing until Load or Import is called.
+func NewMirror(s storage.Backend) *Mirror {
+	return &Mirror{s: s}
+}
+
+// Modified returns the time the database that m serves was modified,
+// or the zero time if it serves none.
+func (m *Mirror) Modified() time.Time {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.modified
+}
+
+// File returns the file of the database at the given
+// path, such as index/db.json or ID/GO-2023-1234.json.
+func (m *Mirror) File(name string) ([]byte, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	b, ok := m.files[name]
+	return b, ok
+}
+
+// Load serves the latest archive in storage, if it is newer than the
+// one that m serves.
+func (m *Mirror) Load(ctx context.Context) error {
+	const op errors.Op = "vuln.Mirror.Load"
+	ctx, span := observ.StartSpan(ctx, op.String())
+	defer span.End()
+	vers, err := m.s.List(ctx, MirrorModule)
+	if err != nil {
+		return errors.E(op, err)
+	}
+	latest := ""
+	for _, v := range vers {
+		if latest == "" || semver.Compare(v, latest) > 0 {
+			latest = v
+		}
+	}
+	m.mu.RLock()
+	current := m.ver
+	m.mu.RUnlock()
+	if latest == "" || (current != "" && semver.Compare(latest, current) <= 0) {
+		return nil
+	}
+
+	zip, err := m.s.Zip(ctx, MirrorModule, latest)
+	if err != nil {
+		return errors.E(op, err)
+	}
+	defer func() { _ = zip.Close() }()
+	archive, err := io.ReadAll(zip)
+	if err != nil {
+		return errors.E(op, err)
+	}
+	files, idx, err := readArchive(archive)
+	if err != nil {
+		return errors.E(op, err)
+	}
+	m.serve(latest, idx.Modified, files)
+	return nil
+}
+
+// Import saves the given database archive in storage, and serves it if it
+// is newer than the database that m serves. Only the latest archive is kept
+// in storage. The archive must have the layout of vuln.go.dev, with
+// index/db.json, index/modules.json and the ID directory at its root.
+func (m *Mirror) Import(ctx context.Context, archive []byte) error {
+	const op errors.Op = "vuln.Mirror.Import"
+	ctx, span := observ.StartSpan(ctx, op.String())
+	defer span.End()
+	files, idx, err := readArchive(archive)
+	if err != nil {
+		return errors.E(op, err, errors.KindBadRequest)
+	}
+	ver := mirrorVersion(idx.Modified)
+	info, err := json.Marshal(storage.RevInfo{Version: ver, Time: idx.Modified})
+	if err != nil {
+		return errors.E(op, err)
+	}
+	mod := []byte("module " + MirrorModule + "\n")
+	err = m.s.Save(ctx, MirrorModule, ver, mod, bytes.NewReader(archive), nil, info)
+	// another instance may have imported the same database
+	if err != nil && !errors.Is(err, errors.KindAlreadyExists) {
+		return errors.E(op, err)
+	}
+
+	m.mu.RLock()
+	current := m.ver
+	m.mu.RUnlock()
+	if current == "" || semver.Compare(ver, current) > 0 {
+		m.serve(ver, idx.Modified, files)
+	}
+	if err := m.prune(ctx); err != nil {
+		return errors.E(op, err)
+	}
+	return nil
+}
+
+// prune deletes all the archives in storage but the latest one.
+func (m *Mirror) prune(ctx context.Context) error {
+	vers, err := m.s.List(ctx, MirrorModule)
+	if err != nil {
+		return err
+	}
+	semver.Sort(vers)
+	for _, v := range vers[:max(len(vers)-1, 0)] {
+		// another instance may be pruning at the same time
+		if err := m.s.Delete(ctx, MirrorModule, v); err != nil && !errors.IsNotFoundErr(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// Sync imports the database at upstream, such as https://vuln.go.dev,
+// if it was modified since the database that m serves.
+func (m *Mirror) Sync(ctx context.Context, client *http.Client, upstream string) error {
+	const op errors.Op = "vuln.Mirror.Sync"
+	ctx, span := observ.StartSpan(ctx, op.String())
+	defer span.End()
+	upstream = strings.TrimSuffix(upstream, "/")
+	b, err := get(ctx, client, upstream+"/index/db.json")
+	if err != nil {
+		return errors.E(op, err)
+	}
+	var idx dbIndex
+	if err := json.Unmarshal(b, &idx); err != nil {
+		return errors.E(op, err)
+	}
+	if !idx.Modified.After(m.Modified()) {
+		return nil
+	}
+	archive, err := get(ctx, client, upstream+"/vuln.zip")
+	if err != nil {
+		return errors.E(op, err)
+	}
+	if err := m.Import(ctx, archive); err != nil {
+		return errors.E(op, err)
+	}
+	return nil
+}
+
+func (m *Mirror) serve(ver string, modified time.Time, files map[string][]byte) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.ver, m.modified, m.files = ver, modified, files
+}
+
+// mirrorVersion returns the version under which the database
+// modified at t is saved, so that later databases sort higher.
+func mirrorVersion(t time.Time) string {
+	return "v0.0.0-" + t.UTC().Format("20060102150405") + "-000000000000"
+}
+
+// readArchive reads the files of a database archive, which may
+// add up to maxFilesSize once uncompressed.
+func readArchive(archive []byte) (map[string][]byte, dbIndex, error) {
+	var idx dbIndex
+	if len(archive) > MaxArchiveSize {
+		return nil, idx, fmt.Errorf("the archive is larger than %d bytes", MaxArchiveSize)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(archive), int64(len(archive)))
+	if err != nil {
+		return nil, idx, err
+	}
+	files := map[string][]byte{}
+	left := maxFilesSize
+	for _, f := range zr.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, idx, err
+		}
+		// the sizes in the zip headers cannot be trusted.
+		b, err := io.ReadAll(io.LimitReader(rc, left+1))
+		_ = rc.Close()
+		if err != nil {
+			return nil, idx, err
+		}
+		if left -= int64(len(b)); left < 0 {
+			return nil, idx, fmt.Errorf("the files of the archive are larger than %d bytes", maxFilesSize)
+		}
+		files[f.Name] = b
+	}
+	for _, name := range []string{"index/db.json", "index/modules.json"} {
+		if _, ok := files[name]; !ok {
+			return nil, idx, fmt.Errorf("the archive has no %s", name)
+		}
+	}
+	if err := json.Unmarshal(files["index/db.json"], &idx); err != nil {
+		return nil, idx, fmt.Errorf("index/db.json: %w", err)
+	}
+	if idx.Modified.IsZero() {
+		return nil, idx, fmt.Errorf("index/db.json has no modified time")
+	}
+	return files, idx, nil
+}
+
+func get(ctx context.Context, client *http.Client, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET %s: %s", url, resp.Status)
+	}
+	b, err := io.ReadAll(io.LimitReader(resp.Body, MaxArchiveSize+1))
+	if err == nil && len(b) > MaxArchiveSize {
+		err = fmt.Errorf("GET %s: the response is larger than %d bytes", url, MaxArchiveSize)
+	}
+	return b, err
+}
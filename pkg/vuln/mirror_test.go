@@ -0,0 +1,104 @@
+package vuln
+
+import (
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gomods/athens/pkg/storage/mem"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMirror(t *testing.T) {
+	ctx := t.Context()
+	s, err := mem.NewStorage()
+	require.NoError(t, err)
+	m := NewMirror(s)
+	_, ok := m.File("index/db.json")
+	require.False(t, ok)
+
+	require.NoError(t, m.Import(ctx, testArchive(t, testEntries)))
+	b, ok := m.File("ID/GO-2024-0001.json")
+	require.True(t, ok)
+	require.Equal(t, testEntries["ID/GO-2024-0001.json"], string(b))
+	require.Equal(t, time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC), m.Modified())
+
+	// an older archive is neither served nor kept in storage
+	older := maps.Clone(testEntries)
+	older["index/db.json"] = `{"modified": "2024-01-01T00:00:00Z"}`
+	require.NoError(t, m.Import(ctx, testArchive(t, older)))
+	require.Equal(t, time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC), m.Modified())
+	vers, err := s.List(ctx, MirrorModule)
+	require.NoError(t, err)
+	require.Equal(t, []string{mirrorVersion(m.Modified())}, vers)
+	// importing the same archive again is not an error
+	require.NoError(t, m.Import(ctx, testArchive(t, testEntries)))
+
+	// another instance loads the latest archive from storage
+	other := NewMirror(s)
+	require.NoError(t, other.Load(ctx))
+	require.Equal(t, m.Modified(), other.Modified())
+	_, ok = other.File("index/modules.json")
+	require.True(t, ok)
+
+	// a newer archive replaces the former one in storage
+	newer := maps.Clone(testEntries)
+	newer["index/db.json"] = `{"modified": "2024-03-01T00:00:00Z"}`
+	require.NoError(t, m.Import(ctx, testArchive(t, newer)))
+	require.Equal(t, time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), m.Modified())
+	vers, err = s.List(ctx, MirrorModule)
+	require.NoError(t, err)
+	require.Equal(t, []string{mirrorVersion(m.Modified())}, vers)
+
+	invalid := maps.Clone(testEntries)
+	delete(invalid, "index/modules.json")
+	require.Error(t, m.Import(ctx, testArchive(t, invalid)))
+	require.Error(t, m.Import(ctx, []byte("not a zip")))
+}
+
+func TestMirrorMaxFilesSize(t *testing.T) {
+	defer func(n int64) { maxFilesSize = n }(maxFilesSize)
+	archive := testArchive(t, testEntries)
+	_, _, err := readArchive(archive)
+	require.NoError(t, err)
+
+	maxFilesSize = int64(len(testEntries["index/db.json"]))
+	_, _, err = readArchive(archive)
+	require.Error(t, err)
+}
+
+func TestMirrorSync(t *testing.T) {
+	ctx := t.Context()
+	newer := maps.Clone(testEntries)
+	newer["index/db.json"] = `{"modified": "2024-03-01T00:00:00Z"}`
+	entries := testEntries
+	zips := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/index/db.json":
+			_, _ = w.Write([]byte(entries["index/db.json"]))
+		case "/vuln.zip":
+			zips++
+			_, _ = w.Write(testArchive(t, entries))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	s, err := mem.NewStorage()
+	require.NoError(t, err)
+	m := NewMirror(s)
+	require.NoError(t, m.Sync(ctx, srv.Client(), srv.URL+"/"))
+	require.Equal(t, time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC), m.Modified())
+	// the archive is only downloaded when the database was modified
+	require.NoError(t, m.Sync(ctx, srv.Client(), srv.URL))
+	require.Equal(t, 1, zips)
+
+	entries = newer
+	require.NoError(t, m.Sync(ctx, srv.Client(), srv.URL))
+	require.Equal(t, 2, zips)
+	require.Equal(t, time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), m.Modified())
+}
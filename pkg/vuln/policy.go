@@ -0,0 +1,96 @@
+package vuln
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/gomods/athens/pkg/paths"
+)
+
+// Actions of a Policy.
+const (
+	Deny = "deny"
+	Flag = "flag"
+)
+
+// severities are the severities of the GitHub advisories
+// that some OSV databases have, in increasing order.
+var severities = []string{"low", "moderate", "high", "critical"}
+
+// Policy tells which advisories deny a module version,
+// and which ones only flag it.
+type Policy struct {
+	// Action is Deny or Flag. Flag never denies a version.
+	Action string
+	// MinSeverity, if set, only flags the versions for the advisories
+	// whose severity is lower. Advisories without a severity deny.
+	MinSeverity string
+	// Ignore lists the IDs or aliases of the advisories that
+	// neither deny nor flag a version.
+	Ignore []string
+	// Exceptions lists the modules, as path patterns such as
+	// github.com/acme/*, that advisories only flag. An exception
+	// of the form pattern@ID only applies to that advisory.
+	Exceptions []string
+	// FilterList leaves the versions that are denied out of the version lists.
+	FilterList bool
+}
+
+// Result lists the IDs of the advisories that deny a
+// module version, and of the ones that only flag it.
+type Result struct {
+	Denied  []string
+	Flagged []string
+}
+
+// Check returns the advisories that deny or flag the given module version.
+func (db *DB) Check(mod, ver string) Result {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	var res Result
+	for _, e := range db.vulns(mod, ver) {
+		switch {
+		case db.policy.ignores(e):
+		case db.policy.denies(e, mod):
+			res.Denied = append(res.Denied, e.ID)
+		default:
+			res.Flagged = append(res.Flagged, e.ID)
+		}
+	}
+	return res
+}
+
+// FilterList reports whether the denied versions are left out of the version lists.
+func (db *DB) FilterList() bool {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	return db.policy.FilterList
+}
+
+func (p Policy) ignores(e *Entry) bool {
+	for _, id := range p.Ignore {
+		if id == e.ID || slices.Contains(e.Aliases, id) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p Policy) denies(e *Entry, mod string) bool {
+	if p.Action == Flag {
+		return false
+	}
+	if p.MinSeverity != "" && e.DatabaseSpecific.Severity != "" {
+		sev := slices.Index(severities, strings.ToLower(e.DatabaseSpecific.Severity))
+		if sev < slices.Index(severities, strings.ToLower(p.MinSeverity)) {
+			return false
+		}
+	}
+	for _, ex := range p.Exceptions {
+		pattern, id, ok := strings.Cut(ex, "@")
+		if paths.MatchesPattern(pattern, mod) && (!ok || id == e.ID || slices.Contains(e.Aliases, id)) {
+			return false
+		}
+	}
+	return true
+}
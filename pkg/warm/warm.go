@@ -0,0 +1,303 @@
+// Package warm stashes every module version that a go.mod, go.sum
+// or go.work file needs, so that builds against Athens never miss.
+package warm
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/gomods/athens/pkg/config"
+	"github.com/gomods/athens/pkg/errors"
+	"github.com/gomods/athens/pkg/observ"
+	"github.com/gomods/athens/pkg/stash"
+	"github.com/gomods/athens/pkg/storage"
+	"golang.org/x/mod/modfile"
+	modpath "golang.org/x/mod/module"
+	"golang.org/x/mod/semver"
+	"golang.org/x/sync/errgroup"
+)
+
+// Report tells what became of the module versions that were warmed.
+// Cached and Fetched hold mod@ver strings.
+type Report struct {
+	Cached  []string  `json:"cached"`
+	Fetched []string  `json:"fetched"`
+	Failed  []Failure `json:"failed"`
+}
+
+// Failure is a module version that could not be stashed.
+type Failure struct {
+	Module  string `json:"module"`
+	Version string `json:"version"`
+	Error   string `json:"error"`
+}
+
+// Warmer stashes the build list of the files it is given.
+type Warmer struct {
+	st      stash.Stasher
+	s       storage.Backend
+	checker storage.Checker
+	workers int
+}
+
+// New returns a Warmer that stashes the module versions missing
+// from s with st, workers at a time.
+func New(st stash.Stasher, s storage.Backend, workers int) *Warmer {
+	return &Warmer{st: st, s: s, checker: storage.WithChecker(s), workers: max(workers, 1)}
+}
+
+// Warm stashes the module versions that data, the contents of a go.sum,
+// go.mod or go.work file, references. The entries of a go.sum are
+// stashed as they are. The requirements of a go.mod are walked through
+// the go.mod files of the module versions they need, as the go command
+// loads its module graph: when the graph is pruned, only the requirements
+// of the requirements whose own graph is pruned are stashed, without
+// walking them further. A go.work only references the module versions
+// that it replaces others with. Warm returns a KindBadRequest error if
+// data cannot be parsed, and otherwise reports every module version it
+// warmed.
+func (w *Warmer) Warm(ctx context.Context, data []byte) (*Report, error) {
+	const op errors.Op = "warm.Warm"
+	ctx, span := observ.StartSpan(ctx, op.String())
+	defer span.End()
+	bl, err := parse(data)
+	if err != nil {
+		return nil, errors.E(op, err, errors.KindBadRequest)
+	}
+
+	var (
+		mu     sync.Mutex
+		report = &Report{}
+		// the walk that a module version was visited with, since one
+		// that was only stashed may need to be walked later.
+		visited  = map[modpath.Version]walk{}
+		reported = map[modpath.Version]bool{}
+	)
+	var next []node
+	for _, m := range bl.roots {
+		next = append(next, node{m, bl.walk})
+	}
+	for len(next) > 0 {
+		var level []node
+		for _, n := range next {
+			if w, ok := visited[n.m]; !ok || w < n.walk {
+				visited[n.m] = n.walk
+				level = append(level, n)
+			}
+		}
+		next = nil
+		var g errgroup.Group
+		g.SetLimit(w.workers)
+		for _, n := range level {
+			g.Go(func() error {
+				m := n.m
+				cached, reqs, err := w.warm(ctx, n, bl)
+				mu.Lock()
+				defer mu.Unlock()
+				next = append(next, reqs...)
+				if reported[m] && err == nil {
+					return nil
+				}
+				reported[m] = true
+				switch {
+				case err != nil:
+					report.Failed = append(report.Failed, Failure{Module: m.Path, Version: m.Version, Error: err.Error()})
+				case cached:
+					report.Cached = append(report.Cached, config.FmtModVer(m.Path, m.Version))
+				default:
+					report.Fetched = append(report.Fetched, config.FmtModVer(m.Path, m.Version))
+				}
+				return nil
+			})
+		}
+		_ = g.Wait()
+	}
+	sort.Strings(report.Cached)
+	sort.Strings(report.Fetched)
+	sort.Slice(report.Failed, func(i, j int) bool {
+		return config.FmtModVer(report.Failed[i].Module, report.Failed[i].Version) <
+			config.FmtModVer(report.Failed[j].Module, report.Failed[j].Version)
+	})
+	return report, nil
+}
+
+// warm stashes n unless it is cached already and returns the requirements
+// that its walk needs. A module version whose go.mod cannot be read is
+// reported as failed even if it was stashed.
+func (w *Warmer) warm(ctx context.Context, n node, bl *buildList) (bool, []node, error) {
+	const op errors.Op = "warm.warm"
+	m, ver := n.m, n.m.Version
+	cached, err := w.checker.Exists(ctx, m.Path, m.Version)
+	if err != nil {
+		return false, nil, errors.E(op, err)
+	}
+	if !cached {
+		ver, err = w.st.Stash(ctx, m.Path, m.Version)
+		if err != nil {
+			return false, nil, errors.E(op, err)
+		}
+	}
+	if n.walk == walkNone {
+		return cached, nil, nil
+	}
+	gomod, err := w.s.GoMod(ctx, m.Path, ver)
+	if err != nil {
+		return false, nil, errors.E(op, err)
+	}
+	f, err := modfile.ParseLax("go.mod", gomod, nil)
+	if err != nil {
+		return false, nil, errors.E(op, errors.M(m.Path), errors.V(ver), err)
+	}
+	// the requirements of a module version whose graph is pruned are
+	// in the graph, while theirs are not, unlike in an unpruned graph.
+	reqWalk := walkAll
+	if n.walk == walkPruned && pruned(f.Go) {
+		reqWalk = walkNone
+	}
+	var reqs []node
+	for _, r := range bl.requirements(f.Require) {
+		reqs = append(reqs, node{r, reqWalk})
+	}
+	return cached, reqs, nil
+}
+
+// walk tells which requirements of a module version are warmed.
+type walk int
+
+const (
+	// walkNone warms the module version only.
+	walkNone walk = iota
+	// walkPruned warms the requirements of the module version, and
+	// walks them as well if its go.mod does not prune its graph.
+	walkPruned
+	// walkAll warms the requirements of the module version transitively.
+	walkAll
+)
+
+// node is a module version to warm, and how to walk its requirements.
+type node struct {
+	m    modpath.Version
+	walk walk
+}
+
+// buildList is what a parsed file needs: its roots, walked as walk says.
+// The replacements and exclusions of the main module apply to all of them.
+type buildList struct {
+	roots    []modpath.Version
+	walk     walk
+	replace  []*modfile.Replace
+	excluded map[modpath.Version]bool
+}
+
+// pruned reports whether a go.mod with the given go directive prunes its
+// module graph: from go 1.17 on, a go.mod lists every module that its
+// packages need, so that the go.mod files of their requirements need not
+// be walked.
+func pruned(g *modfile.Go) bool {
+	return g != nil && semver.Compare("v"+g.Version, "v1.17") >= 0
+}
+
+func parse(data []byte) (*buildList, error) {
+	if isGoSum(data) {
+		return parseGoSum(data), nil
+	}
+	if modfile.ModulePath(data) != "" {
+		f, err := modfile.Parse("go.mod", data, nil)
+		if err != nil {
+			return nil, err
+		}
+		bl := &buildList{replace: f.Replace, excluded: map[modpath.Version]bool{}, walk: walkAll}
+		if pruned(f.Go) {
+			bl.walk = walkPruned
+		}
+		for _, e := range f.Exclude {
+			bl.excluded[e.Mod] = true
+		}
+		bl.roots = bl.requirements(f.Require)
+		return bl, nil
+	}
+	wf, err := modfile.ParseWork("go.work", data, nil)
+	if err != nil {
+		return nil, err
+	}
+	bl := &buildList{}
+	for _, r := range wf.Replace {
+		if r.New.Version != "" {
+			bl.roots = append(bl.roots, r.New)
+		}
+	}
+	return bl, nil
+}
+
+// requirements applies the replacements and exclusions of bl to reqs.
+// Requirements that are replaced by a directory are left out.
+func (bl *buildList) requirements(reqs []*modfile.Require) []modpath.Version {
+	var vers []modpath.Version
+	for _, r := range reqs {
+		if bl.excluded[r.Mod] {
+			continue
+		}
+		m := bl.replaced(r.Mod)
+		if m.Version == "" {
+			continue
+		}
+		vers = append(vers, m)
+	}
+	return vers
+}
+
+func (bl *buildList) replaced(m modpath.Version) modpath.Version {
+	// a replacement of a specific version wins over one of every version.
+	var found *modfile.Replace
+	for _, r := range bl.replace {
+		if r.Old.Path != m.Path {
+			continue
+		}
+		if r.Old.Version == m.Version {
+			return r.New
+		}
+		if r.Old.Version == "" {
+			found = r
+		}
+	}
+	if found != nil {
+		return found.New
+	}
+	return m
+}
+
+// isGoSum reports whether every line of data is a go.sum entry.
+func isGoSum(data []byte) bool {
+	lines := 0
+	for line := range strings.Lines(string(data)) {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) != 3 || !strings.HasPrefix(fields[2], "h1:") {
+			return false
+		}
+		lines++
+	}
+	return lines > 0
+}
+
+func parseGoSum(data []byte) *buildList {
+	bl := &buildList{}
+	seen := map[modpath.Version]bool{}
+	for line := range strings.Lines(string(data)) {
+		fields := strings.Fields(line)
+		if len(fields) != 3 {
+			continue
+		}
+		// entries that only hash a go.mod still need their module version.
+		m := modpath.Version{Path: fields[0], Version: strings.TrimSuffix(fields[1], "/go.mod")}
+		if !seen[m] {
+			seen[m] = true
+			bl.roots = append(bl.roots, m)
+		}
+	}
+	return bl
+}
@@ -0,0 +1,113 @@
+package warm
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/gomods/athens/pkg/errors"
+	"github.com/gomods/athens/pkg/storage"
+	"github.com/gomods/athens/pkg/storage/mem"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeStasher saves the go.mod files of mods, keyed by mod@ver,
+// and fails to stash any other module version.
+type fakeStasher struct {
+	s    storage.Backend
+	mods map[string]string
+}
+
+func (f *fakeStasher) Stash(ctx context.Context, mod, ver string) (string, error) {
+	gomod, ok := f.mods[mod+"@"+ver]
+	if !ok {
+		return "", errors.E("fakeStasher.Stash", errors.M(mod), errors.V(ver), errors.KindNotFound)
+	}
+	return ver, f.s.Save(ctx, mod, ver, []byte(gomod), strings.NewReader("zip"), nil, []byte("info"))
+}
+
+func TestWarm(t *testing.T) {
+	mods := map[string]string{
+		"a@v1.0.0":  "module a\n\ngo 1.21\n\nrequire b v1.0.0\n",
+		"b@v1.0.0":  "module b\n\nrequire (\n\tc v1.0.0\n\td v1.0.0\n)\n",
+		"c@v1.0.0":  "module c\n",
+		"c@v1.1.0":  "module c\n",
+		"d2@v2.0.0": "module d2\n",
+		"e@v1.0.0":  "module e\n\nrequire b v1.0.0\n",
+	}
+	tests := []struct {
+		name    string
+		file    string
+		cached  []string
+		fetched []string
+		failed  []string
+	}{
+		{
+			name:    "go.sum",
+			file:    "a v1.0.0 h1:abc=\na v1.0.0/go.mod h1:def=\nc v1.1.0/go.mod h1:ghi=\nmissing v1.0.0 h1:jkl=\n",
+			cached:  []string{"a@v1.0.0"},
+			fetched: []string{"c@v1.1.0"},
+			failed:  []string{"missing@v1.0.0"},
+		},
+		{
+			name:    "unpruned go.mod",
+			file:    "module main\n\ngo 1.16\n\nrequire a v1.0.0\n\nexclude c v1.0.0\n\nreplace d => d2 v2.0.0\n",
+			cached:  []string{"a@v1.0.0"},
+			fetched: []string{"b@v1.0.0", "d2@v2.0.0"},
+		},
+		{
+			name:    "pruned go.mod",
+			file:    "module main\n\ngo 1.21\n\nrequire (\n\ta v1.0.0\n\tc v1.0.0\n\tlocal v1.0.0\n)\n\nreplace local => ../local\n",
+			cached:  []string{"a@v1.0.0"},
+			fetched: []string{"b@v1.0.0", "c@v1.0.0"},
+		},
+		{
+			name:    "pruned go.mod with an unpruned requirement",
+			file:    "module main\n\ngo 1.21\n\nrequire e v1.0.0\n\nreplace d => d2 v2.0.0\n",
+			fetched: []string{"b@v1.0.0", "c@v1.0.0", "d2@v2.0.0", "e@v1.0.0"},
+		},
+		{
+			name:   "go.work",
+			file:   "go 1.21\n\nuse ./main\n\nreplace c v1.0.0 => c v1.1.0\n",
+			cached: []string{"c@v1.1.0"},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := t.Context()
+			s, err := mem.NewStorage()
+			require.NoError(t, err)
+			st := &fakeStasher{s: s, mods: mods}
+			for _, m := range tc.cached {
+				mod, ver, _ := strings.Cut(m, "@")
+				_, err := st.Stash(ctx, mod, ver)
+				require.NoError(t, err)
+			}
+
+			report, err := New(st, s, 2).Warm(ctx, []byte(tc.file))
+			require.NoError(t, err)
+			require.Equal(t, tc.cached, emptyToNil(report.Cached))
+			require.Equal(t, tc.fetched, emptyToNil(report.Fetched))
+			var failed []string
+			for _, f := range report.Failed {
+				failed = append(failed, f.Module+"@"+f.Version)
+				require.NotEmpty(t, f.Error)
+			}
+			require.Equal(t, tc.failed, failed)
+		})
+	}
+}
+
+func TestWarmBadFile(t *testing.T) {
+	s, err := mem.NewStorage()
+	require.NoError(t, err)
+	_, err = New(&fakeStasher{s: s}, s, 1).Warm(t.Context(), []byte("module main\n\nrequire\n"))
+	require.True(t, errors.Is(err, errors.KindBadRequest))
+}
+
+func emptyToNil(s []string) []string {
+	if len(s) == 0 {
+		return nil
+	}
+	return s
+}